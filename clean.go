@@ -0,0 +1,96 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/nicehiro/org-roam-web/internal/config"
+)
+
+func cleanCmd(args []string) {
+	fs := flag.NewFlagSet("clean", flag.ExitOnError)
+	configPath := fs.String("config", "config.yaml", "Path to config file")
+	outputDir := fs.String("output", "", "Output directory")
+	yes := fs.Bool("yes", false, "Don't ask for confirmation")
+	fs.Parse(args)
+
+	cfg, err := config.Load(*configPath)
+	if err != nil {
+		log.Fatalf("Failed to load config: %v", err)
+	}
+	if *outputDir != "" {
+		cfg.Paths.OutputDir = *outputDir
+	}
+	resolvePaths(cfg)
+
+	dir, err := cleanTarget(cfg)
+	if err != nil {
+		log.Fatalf("Refusing to clean: %v", err)
+	}
+	if _, err := os.Stat(dir); errors.Is(err, os.ErrNotExist) {
+		fmt.Printf("Nothing to clean: %s does not exist\n", dir)
+		return
+	}
+
+	if !*yes {
+		fmt.Printf("Remove %s and everything in it? [y/N] ", dir)
+		answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+		if a := strings.ToLower(strings.TrimSpace(answer)); a != "y" && a != "yes" {
+			fmt.Println("Aborted")
+			return
+		}
+	}
+
+	if err := os.RemoveAll(dir); err != nil {
+		log.Fatalf("Failed to remove %s: %v", dir, err)
+	}
+	fmt.Printf("Removed %s\n", dir)
+}
+
+// cleanTarget returns the absolute output directory, or an error if
+// removing it could take anything besides generated files with it: the
+// filesystem root, the home directory, or the roam directory or one of
+// its parents.
+func cleanTarget(cfg *config.Config) (string, error) {
+	dir, err := filepath.Abs(cfg.Paths.OutputDir)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve output directory: %w", err)
+	}
+	if filepath.Dir(dir) == dir {
+		return "", fmt.Errorf("output directory %s is the filesystem root", dir)
+	}
+	if home, err := os.UserHomeDir(); err == nil && dir == filepath.Clean(home) {
+		return "", fmt.Errorf("output directory %s is the home directory", dir)
+	}
+	roamDir, err := filepath.Abs(cfg.Paths.RoamDir)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve roam directory: %w", err)
+	}
+	if rel, err := filepath.Rel(dir, roamDir); err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("output directory %s contains the roam directory %s", dir, roamDir)
+	}
+	return dir, nil
+}
+
+// emptyDir removes everything inside dir, keeping dir itself
+func emptyDir(dir string) error {
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	for _, e := range entries {
+		if err := os.RemoveAll(filepath.Join(dir, e.Name())); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/nicehiro/org-roam-web/internal/config"
+)
+
+func TestCleanTarget(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	base := t.TempDir()
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(base); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(wd) })
+
+	tests := []struct {
+		name    string
+		output  string
+		roam    string
+		want    string // Cleaned directory, "" for a refusal
+		wantErr string
+	}{
+		{"sibling", filepath.Join(base, "dist"), filepath.Join(base, "roam"), filepath.Join(base, "dist"), ""},
+		{"inside the roam dir", filepath.Join(base, "roam", "dist"), filepath.Join(base, "roam"), filepath.Join(base, "roam", "dist"), ""},
+		{"relative", "dist", "roam", filepath.Join(base, "dist"), ""},
+		{"dotted name beside the output", filepath.Join(base, "dist"), filepath.Join(base, "..roam"), filepath.Join(base, "dist"), ""},
+		{"root", "/", filepath.Join(base, "roam"), "", "filesystem root"},
+		{"home", home, filepath.Join(base, "roam"), "", "home directory"},
+		{"home with a trailing slash", home + "/", filepath.Join(base, "roam"), "", "home directory"},
+		{"the roam dir", filepath.Join(base, "roam"), filepath.Join(base, "roam"), "", "contains the roam directory"},
+		{"parent of the roam dir", base, filepath.Join(base, "notes", "roam"), "", "contains the roam directory"},
+		{"dotted roam dir inside", filepath.Join(base, "dist"), filepath.Join(base, "dist", "..roam"), "", "contains the roam directory"},
+		{"relative roam dir", ".", "roam", "", "contains the roam directory"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := config.DefaultConfig()
+			cfg.Paths.OutputDir = tt.output
+			cfg.Paths.RoamDir = tt.roam
+
+			got, err := cleanTarget(cfg)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("cleanTarget = %q, %v, want an error about the %s", got, err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("cleanTarget: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("cleanTarget = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEmptyDir(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "dist")
+	for _, name := range []string{"index.html", "notes/a1.html", "tags/go/index.html"} {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte("x"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := emptyDir(dir); err != nil {
+		t.Fatalf("emptyDir: %v", err)
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("output directory removed: %v", err)
+	}
+	if len(entries) != 0 {
+		t.Errorf("%d entries left", len(entries))
+	}
+	if err := emptyDir(filepath.Join(dir, "missing")); err != nil {
+		t.Errorf("emptyDir of a missing directory: %v", err)
+	}
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// siteDiff lists the output paths that differ between two builds
+type siteDiff struct {
+	Added   []string
+	Removed []string
+	Changed []string
+}
+
+func diffCmd(args []string) {
+	fs := flag.NewFlagSet("diff", flag.ExitOnError)
+	fs.Parse(args)
+
+	if fs.NArg() != 2 {
+		log.Fatalf("Usage: org-roam-web diff <old-dir> <new-dir>")
+	}
+
+	d, err := diffSites(fs.Arg(0), fs.Arg(1))
+	if err != nil {
+		log.Fatalf("Failed to compare builds: %v", err)
+	}
+
+	for _, p := range d.Added {
+		fmt.Printf("A  %s\n", p)
+	}
+	for _, p := range d.Removed {
+		fmt.Printf("D  %s\n", p)
+	}
+	for _, p := range d.Changed {
+		fmt.Printf("M  %s\n", p)
+	}
+	fmt.Printf("%d added, %d removed, %d changed\n", len(d.Added), len(d.Removed), len(d.Changed))
+}
+
+// diffSites compares the files under two output directories by content hash
+func diffSites(oldDir, newDir string) (*siteDiff, error) {
+	oldHashes, err := hashTree(oldDir)
+	if err != nil {
+		return nil, err
+	}
+	newHashes, err := hashTree(newDir)
+	if err != nil {
+		return nil, err
+	}
+
+	d := &siteDiff{}
+	for p, h := range newHashes {
+		old, ok := oldHashes[p]
+		switch {
+		case !ok:
+			d.Added = append(d.Added, p)
+		case old != h:
+			d.Changed = append(d.Changed, p)
+		}
+	}
+	for p := range oldHashes {
+		if _, ok := newHashes[p]; !ok {
+			d.Removed = append(d.Removed, p)
+		}
+	}
+
+	sort.Strings(d.Added)
+	sort.Strings(d.Removed)
+	sort.Strings(d.Changed)
+	return d, nil
+}
+
+// hashTree returns the SHA-256 of every file under dir, keyed by its
+// slash-separated path relative to dir. The build cache is skipped.
+func hashTree(dir string) (map[string]string, error) {
+	hashes := make(map[string]string)
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || d.Name() == ".build-cache.json" {
+			return nil
+		}
+
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+
+		h := sha256.New()
+		if _, err := io.Copy(h, f); err != nil {
+			return err
+		}
+		hashes[filepath.ToSlash(rel)] = hex.EncodeToString(h.Sum(nil))
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", dir, err)
+	}
+	return hashes, nil
+}
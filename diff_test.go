@@ -0,0 +1,49 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDiffSites(t *testing.T) {
+	tests := []struct {
+		name     string
+		old, new string
+		want     siteDiff
+	}{
+		{
+			"changes",
+			"testdata/diff/old", "testdata/diff/new",
+			siteDiff{
+				Added:   []string{"notes/d4.html", "tags/web.html"},
+				Removed: []string{"notes/c3.html"},
+				Changed: []string{"graph.json", "notes/a1.html"},
+			},
+		},
+		{
+			"reversed",
+			"testdata/diff/new", "testdata/diff/old",
+			siteDiff{
+				Added:   []string{"notes/c3.html"},
+				Removed: []string{"notes/d4.html", "tags/web.html"},
+				Changed: []string{"graph.json", "notes/a1.html"},
+			},
+		},
+		{"same", "testdata/diff/old", "testdata/diff/old", siteDiff{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := diffSites(tt.old, tt.new)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(*got, tt.want) {
+				t.Errorf("diffSites(%s, %s) = %+v, want %+v", tt.old, tt.new, *got, tt.want)
+			}
+		})
+	}
+
+	if _, err := diffSites("testdata/diff/old", "testdata/diff/missing"); err == nil {
+		t.Error("diffSites with a missing directory succeeded")
+	}
+}
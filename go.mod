@@ -3,13 +3,17 @@ module github.com/nicehiro/org-roam-web
 go 1.23.0
 
 require (
+	github.com/alecthomas/chroma/v2 v2.5.0
+	github.com/andybalholm/brotli v1.2.5
 	github.com/fsnotify/fsnotify v1.9.0
 	github.com/mattn/go-sqlite3 v1.14.32
 	github.com/niklasfasching/go-org v1.9.1
+	golang.org/x/image v0.30.0
 	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
+	github.com/dlclark/regexp2 v1.4.0 // indirect
 	golang.org/x/net v0.38.0 // indirect
 	golang.org/x/sys v0.31.0 // indirect
 )
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+
+	"github.com/nicehiro/org-roam-web/internal/config"
+)
+
+// roamDirCandidates are checked in order for an existing org-roam directory
+var roamDirCandidates = []string{"~/org-roam", "~/org/roam", "~/roam", "~/Documents/roam", "~/org"}
+
+// dbNames are database file names looked for inside the roam directory
+var dbNames = []string{"org-roam.db", "roam.db"}
+
+// dbCandidates are checked when the roam directory has no database; they
+// cover org-roam's default org-roam-db-location
+var dbCandidates = []string{"~/.emacs.d/org-roam.db", "~/.config/emacs/org-roam.db"}
+
+func initCmd(args []string) {
+	fs := flag.NewFlagSet("init", flag.ExitOnError)
+	configPath := fs.String("config", "config.yaml", "Path of the config file to write")
+	force := fs.Bool("force", false, "Overwrite an existing config file")
+	fs.Parse(args)
+
+	if _, err := os.Stat(*configPath); err == nil && !*force {
+		log.Fatalf("%s already exists (use -force to overwrite it)", *configPath)
+	} else if err != nil && !errors.Is(err, os.ErrNotExist) {
+		log.Fatalf("Failed to check %s: %v", *configPath, err)
+	}
+
+	cfg := config.DefaultConfig()
+	roamDir, found := detectRoamDir()
+	if found {
+		cfg.Paths.RoamDir = roamDir
+	}
+	dbPath, dbFound := detectDBPath(cfg.Paths.RoamDir)
+	if dbFound {
+		cfg.Paths.DBPath = dbPath
+	}
+
+	if err := os.WriteFile(*configPath, config.Sample(cfg), 0644); err != nil {
+		log.Fatalf("Failed to write %s: %v", *configPath, err)
+	}
+
+	fmt.Printf("Wrote %s\n", *configPath)
+	if found {
+		fmt.Printf("  roam_dir: %s (detected)\n", cfg.Paths.RoamDir)
+	} else {
+		fmt.Printf("  roam_dir: %s (no org-roam directory found, please set it)\n", cfg.Paths.RoamDir)
+	}
+	if dbFound {
+		fmt.Printf("  db_path:  %s (detected)\n", cfg.Paths.DBPath)
+	} else {
+		fmt.Printf("  db_path:  %s (no database found, please set it)\n", cfg.Paths.DBPath)
+	}
+
+	fmt.Println("\nNext steps:")
+	fmt.Printf("  1. Review %s and set the site title\n", *configPath)
+	fmt.Printf("  2. org-roam-web build -config %s\n", *configPath)
+	fmt.Printf("  3. org-roam-web serve -config %s\n", *configPath)
+}
+
+// detectRoamDir returns the first existing directory from
+// roamDirCandidates, in the ~ form written to the config
+func detectRoamDir() (string, bool) {
+	for _, dir := range roamDirCandidates {
+		if isDir(config.ExpandPath(dir)) {
+			return dir, true
+		}
+	}
+	return "", false
+}
+
+// detectDBPath looks for an org-roam database inside roamDir, returned as
+// a file name relative to it, then at the usual Emacs locations
+func detectDBPath(roamDir string) (string, bool) {
+	for _, name := range dbNames {
+		if isFile(filepath.Join(config.ExpandPath(roamDir), name)) {
+			return name, true
+		}
+	}
+	for _, path := range dbCandidates {
+		if isFile(config.ExpandPath(path)) {
+			return path, true
+		}
+	}
+	return "", false
+}
+
+func isDir(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.IsDir()
+}
+
+func isFile(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.Mode().IsRegular()
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDetectPaths(t *testing.T) {
+	tests := []struct {
+		name     string
+		files    []string // Created under the home directory; a trailing / makes a directory
+		wantRoam string
+		wantDB   string
+	}{
+		{"nothing found", nil, "", ""},
+		{"roam dir with a database", []string{"org-roam/", "org-roam/org-roam.db"}, "~/org-roam", "org-roam.db"},
+		{"first candidate wins", []string{"roam/", "org/roam/roam.db"}, "~/org/roam", "roam.db"},
+		{"emacs database", []string{"org/", ".config/emacs/org-roam.db"}, "~/org", "~/.config/emacs/org-roam.db"},
+		{"database is a directory", []string{"roam/", "roam/org-roam.db/"}, "~/roam", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			home := t.TempDir()
+			t.Setenv("HOME", home)
+			for _, name := range tt.files {
+				path := filepath.Join(home, name)
+				if name[len(name)-1] == '/' {
+					if err := os.MkdirAll(path, 0755); err != nil {
+						t.Fatal(err)
+					}
+					continue
+				}
+				if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(path, nil, 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			roamDir, found := detectRoamDir()
+			if roamDir != tt.wantRoam || found != (tt.wantRoam != "") {
+				t.Errorf("detectRoamDir = %q, %v, want %q", roamDir, found, tt.wantRoam)
+			}
+			dbPath, found := detectDBPath(roamDir)
+			if dbPath != tt.wantDB || found != (tt.wantDB != "") {
+				t.Errorf("detectDBPath = %q, %v, want %q", dbPath, found, tt.wantDB)
+			}
+		})
+	}
+}
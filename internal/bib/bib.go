@@ -0,0 +1,84 @@
+// Package bib reads bibliographies in BibTeX or CSL-JSON format and formats
+// their entries as short citations.
+package bib
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Entry is a bibliography entry
+type Entry struct {
+	Key       string
+	Authors   []string // Family names, in order
+	Title     string
+	Year      string
+	Container string // Journal, book or proceedings the work appeared in
+	DOI       string
+	URL       string
+}
+
+// Load reads a bibliography file, by extension: .json for CSL-JSON and
+// anything else as BibTeX. Entries are keyed by cite key.
+func Load(path string) (map[string]Entry, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read bibliography: %w", err)
+	}
+
+	var entries []Entry
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		entries, err = parseCSLJSON(data)
+	} else {
+		entries, err = parseBibTeX(string(data))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", filepath.Base(path), err)
+	}
+
+	byKey := make(map[string]Entry, len(entries))
+	for _, e := range entries {
+		byKey[e.Key] = e
+	}
+	return byKey, nil
+}
+
+// Citation formats the entry as "Authors (Year). Title. Container."
+func (e Entry) Citation() string {
+	var b strings.Builder
+	switch len(e.Authors) {
+	case 0:
+	case 1:
+		b.WriteString(e.Authors[0])
+	case 2:
+		b.WriteString(e.Authors[0] + " & " + e.Authors[1])
+	default:
+		b.WriteString(e.Authors[0] + " et al.")
+	}
+	if e.Year != "" {
+		if b.Len() > 0 {
+			b.WriteString(" ")
+		}
+		b.WriteString("(" + e.Year + ")")
+	}
+	if b.Len() > 0 {
+		b.WriteString(". ")
+	}
+	if e.Title != "" {
+		b.WriteString(strings.TrimSuffix(e.Title, ".") + ".")
+	}
+	if e.Container != "" {
+		b.WriteString(" " + strings.TrimSuffix(e.Container, ".") + ".")
+	}
+	return strings.TrimSpace(b.String())
+}
+
+// Link returns the DOI link of the entry, or else its URL
+func (e Entry) Link() string {
+	if e.DOI != "" {
+		return "https://doi.org/" + strings.TrimPrefix(e.DOI, "https://doi.org/")
+	}
+	return e.URL
+}
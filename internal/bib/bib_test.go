@@ -0,0 +1,150 @@
+package bib
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestLoad(t *testing.T) {
+	dijkstra := Entry{
+		Key:       "dijkstra68",
+		Authors:   []string{"Dijkstra"},
+		Title:     "Go To Statement Considered Harmful",
+		Year:      "1968",
+		Container: "Communications of the ACM",
+		DOI:       "10.1145/362929.362947",
+	}
+	tests := []struct {
+		file string
+		want map[string]Entry
+	}{
+		{"refs.bib", map[string]Entry{
+			"dijkstra68": dijkstra,
+			"knuth84": {
+				Key:     "knuth84",
+				Authors: []string{"Knuth"},
+				Title:   "The TeXbook",
+				Year:    "1984",
+				URL:     "https://example.org/texbook",
+			},
+			"lamport78": {
+				Key:       "lamport78",
+				Authors:   []string{"Lamport", "Shostak", "Pease"},
+				Title:     "Time, Clocks, and the Ordering of Events",
+				Year:      "1978",
+				Container: "Proceedings",
+			},
+		}},
+		{"refs.json", map[string]Entry{
+			"dijkstra68": dijkstra,
+			"team20": {
+				Key:     "team20",
+				Authors: []string{"The Team", "Roe"},
+				Title:   "A Report",
+				URL:     "https://example.org/report",
+			},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			got, err := Load(filepath.Join("testdata", tt.file))
+			if err != nil {
+				t.Fatalf("Load: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Load = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	dir := t.TempDir()
+	tests := []struct {
+		name    string
+		file    string
+		content string // "" to leave the file missing
+		wantErr string
+	}{
+		{"missing file", "none.bib", "", "failed to read bibliography"},
+		{"invalid JSON", "refs.json", "[{", "failed to parse refs.json"},
+		{"unbalanced entry", "refs.bib", "@article{key, title = {Open", "failed to parse refs.bib"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(dir, tt.file)
+			if tt.content != "" {
+				if err := os.WriteFile(path, []byte(tt.content), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if _, err := Load(path); err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Load: err = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCitation(t *testing.T) {
+	tests := []struct {
+		name  string
+		entry Entry
+		want  string
+	}{
+		{"one author", Entry{Authors: []string{"Knuth"}, Year: "1984", Title: "The TeXbook"}, "Knuth (1984). The TeXbook."},
+		{"two authors", Entry{Authors: []string{"Kernighan", "Ritchie"}, Year: "1978", Title: "The C Programming Language"}, "Kernighan & Ritchie (1978). The C Programming Language."},
+		{"many authors", Entry{Authors: []string{"Lamport", "Shostak", "Pease"}, Year: "1982", Title: "Byzantine Generals."}, "Lamport et al. (1982). Byzantine Generals."},
+		{"container", Entry{Authors: []string{"Dijkstra"}, Year: "1968", Title: "Go To", Container: "CACM"}, "Dijkstra (1968). Go To. CACM."},
+		{"no author", Entry{Year: "2001", Title: "Anonymous"}, "(2001). Anonymous."},
+		{"title only", Entry{Title: "Untitled work"}, "Untitled work."},
+		{"empty", Entry{}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.entry.Citation(); got != tt.want {
+				t.Errorf("Citation() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLink(t *testing.T) {
+	tests := []struct {
+		name  string
+		entry Entry
+		want  string
+	}{
+		{"DOI", Entry{DOI: "10.1145/362929.362947", URL: "https://example.org"}, "https://doi.org/10.1145/362929.362947"},
+		{"DOI as link", Entry{DOI: "https://doi.org/10.1/x"}, "https://doi.org/10.1/x"},
+		{"URL", Entry{URL: "https://example.org"}, "https://example.org"},
+		{"none", Entry{}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.entry.Link(); got != tt.want {
+				t.Errorf("Link() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBibTeXAuthors(t *testing.T) {
+	tests := []struct {
+		field string
+		want  []string
+	}{
+		{"Dijkstra, Edsger W.", []string{"Dijkstra"}},
+		{"Donald E. Knuth", []string{"Knuth"}},
+		{"Kernighan, Brian and Dennis Ritchie", []string{"Kernighan", "Ritchie"}},
+		{"", nil},
+	}
+	for _, tt := range tests {
+		if got := bibTeXAuthors(tt.field); !slices.Equal(got, tt.want) {
+			t.Errorf("bibTeXAuthors(%q) = %v, want %v", tt.field, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,175 @@
+package bib
+
+import (
+	"fmt"
+	"strings"
+)
+
+// parseBibTeX parses the entries of a BibTeX file. @string, @preamble and
+// @comment blocks are skipped; @string abbreviations are not expanded. Text
+// outside entries is ignored, as BibTeX does.
+func parseBibTeX(src string) ([]Entry, error) {
+	var entries []Entry
+	for {
+		at := strings.IndexByte(src, '@')
+		if at < 0 {
+			return entries, nil
+		}
+		src = src[at+1:]
+
+		open := strings.IndexAny(src, "{(")
+		if open < 0 {
+			return entries, nil
+		}
+		kind := strings.ToLower(strings.TrimSpace(src[:open]))
+		if !isBibTeXType(kind) {
+			continue
+		}
+		body, rest, err := balanced(src[open:])
+		if err != nil {
+			return nil, fmt.Errorf("@%s: %w", kind, err)
+		}
+		src = rest
+
+		switch kind {
+		case "string", "preamble", "comment":
+			continue
+		}
+		if e, ok := parseBibTeXEntry(body); ok {
+			entries = append(entries, e)
+		}
+	}
+}
+
+// isBibTeXType reports whether s can be an entry type such as "article"
+func isBibTeXType(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, c := range s {
+		if c < 'a' || c > 'z' {
+			return false
+		}
+	}
+	return true
+}
+
+// balanced splits s, which starts with "{" or "(", into the text inside the
+// matching closing delimiter and the text after it. Field values nest
+// braces, so only a closing delimiter outside them counts.
+func balanced(s string) (string, string, error) {
+	closer := byte('}')
+	if s[0] == '(' {
+		closer = ')'
+	}
+	depth := 0
+	for i := 1; i < len(s); i++ {
+		switch {
+		case s[i] == closer && depth == 0:
+			return s[1:i], s[i+1:], nil
+		case s[i] == '{':
+			depth++
+		case s[i] == '}':
+			depth--
+		}
+	}
+	return "", "", fmt.Errorf("unbalanced braces")
+}
+
+// parseBibTeXEntry parses "key, field = value, ..." of one entry
+func parseBibTeXEntry(body string) (Entry, bool) {
+	key, fields, _ := strings.Cut(body, ",")
+	key = strings.TrimSpace(key)
+	if key == "" {
+		return Entry{}, false
+	}
+
+	e := Entry{Key: key}
+	for _, f := range splitFields(fields) {
+		name, value, ok := strings.Cut(f, "=")
+		if !ok {
+			continue
+		}
+		value = cleanBibTeX(value)
+		switch strings.ToLower(strings.TrimSpace(name)) {
+		case "author":
+			e.Authors = bibTeXAuthors(value)
+		case "title":
+			e.Title = value
+		case "year":
+			e.Year = value
+		case "date":
+			if e.Year == "" && len(value) >= 4 {
+				e.Year = value[:4]
+			}
+		case "journal", "journaltitle", "booktitle":
+			e.Container = value
+		case "doi":
+			e.DOI = value
+		case "url":
+			e.URL = value
+		}
+	}
+	return e, true
+}
+
+// splitFields splits an entry's fields on the commas outside braces and
+// quotes
+func splitFields(s string) []string {
+	var fields []string
+	depth, quoted, start := 0, false, 0
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '{':
+			depth++
+		case '}':
+			depth--
+		case '"':
+			if depth == 0 {
+				quoted = !quoted
+			}
+		case ',':
+			if depth == 0 && !quoted {
+				fields = append(fields, s[start:i])
+				start = i + 1
+			}
+		}
+	}
+	return append(fields, s[start:])
+}
+
+var bibTeXReplacer = strings.NewReplacer(
+	`\&`, "&", `\%`, "%", `\_`, "_", `\$`, "$",
+	"---", "—", "--", "–", "~", " ",
+	"{", "", "}", "",
+)
+
+// cleanBibTeX turns a field value into plain text: outer quotes or braces
+// are dropped, common escapes are resolved, other commands lose their
+// backslash and whitespace is collapsed
+func cleanBibTeX(v string) string {
+	v = strings.TrimSpace(v)
+	if len(v) >= 2 && v[0] == '"' && v[len(v)-1] == '"' {
+		v = v[1 : len(v)-1]
+	}
+	v = strings.ReplaceAll(bibTeXReplacer.Replace(v), `\`, "")
+	return strings.Join(strings.Fields(v), " ")
+}
+
+// bibTeXAuthors returns the family names of an author field, whose names
+// are joined by "and" and written "Last, First" or "First Last"
+func bibTeXAuthors(v string) []string {
+	var names []string
+	for _, name := range strings.Split(v, " and ") {
+		name = strings.TrimSpace(name)
+		if last, _, ok := strings.Cut(name, ","); ok {
+			name = last
+		} else if fields := strings.Fields(name); len(fields) > 0 {
+			name = fields[len(fields)-1]
+		}
+		if name = strings.TrimSpace(name); name != "" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
@@ -0,0 +1,58 @@
+package bib
+
+import (
+	"encoding/json"
+	"strconv"
+)
+
+// cslItem is the part of a CSL-JSON item that citations use
+type cslItem struct {
+	ID     string `json:"id"`
+	Title  string `json:"title"`
+	Author []struct {
+		Family  string `json:"family"`
+		Literal string `json:"literal"`
+	} `json:"author"`
+	Issued struct {
+		DateParts [][]json.Number `json:"date-parts"`
+	} `json:"issued"`
+	Container string `json:"container-title"`
+	DOI       string `json:"DOI"`
+	URL       string `json:"URL"`
+}
+
+// parseCSLJSON parses a CSL-JSON array of items
+func parseCSLJSON(data []byte) ([]Entry, error) {
+	var items []cslItem
+	if err := json.Unmarshal(data, &items); err != nil {
+		return nil, err
+	}
+
+	entries := make([]Entry, 0, len(items))
+	for _, item := range items {
+		if item.ID == "" {
+			continue
+		}
+		e := Entry{
+			Key:       item.ID,
+			Title:     item.Title,
+			Container: item.Container,
+			DOI:       item.DOI,
+			URL:       item.URL,
+		}
+		for _, a := range item.Author {
+			if a.Family != "" {
+				e.Authors = append(e.Authors, a.Family)
+			} else if a.Literal != "" {
+				e.Authors = append(e.Authors, a.Literal)
+			}
+		}
+		if parts := item.Issued.DateParts; len(parts) > 0 && len(parts[0]) > 0 {
+			if year, err := parts[0][0].Int64(); err == nil {
+				e.Year = strconv.FormatInt(year, 10)
+			}
+		}
+		entries = append(entries, e)
+	}
+	return entries, nil
+}
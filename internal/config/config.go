@@ -1,8 +1,11 @@
 package config
 
 import (
+	"fmt"
 	"os"
 	"path/filepath"
+	"regexp"
+	"strings"
 
 	"gopkg.in/yaml.v3"
 )
@@ -13,50 +16,384 @@ type Config struct {
 	Paths   PathsConfig   `yaml:"paths"`
 	Exclude ExcludeConfig `yaml:"exclude"`
 	Display DisplayConfig `yaml:"display"`
+	Graph   GraphConfig   `yaml:"graph"`
 }
 
 type SiteConfig struct {
-	Title   string `yaml:"title"`
-	BaseURL string `yaml:"base_url"`
+	Title    string `yaml:"title"`
+	BaseURL  string `yaml:"base_url"`
+	Language string `yaml:"language"` // Default lang attribute of pages
+
+	// Link from each note to this URL, with {path} replaced by the note's
+	// file path relative to roam_dir (e.g. https://github.com/me/notes/edit/main/{path})
+	EditURLTemplate string `yaml:"edit_url_template"`
 }
 
 type PathsConfig struct {
-	RoamDir   string `yaml:"roam_dir"`
-	DBPath    string `yaml:"db_path"`
-	OutputDir string `yaml:"output_dir"`
+	RoamDir        string   `yaml:"roam_dir"`
+	DBPath         string   `yaml:"db_path"`
+	OutputDir      string   `yaml:"output_dir"`
+	NoteExtensions []string `yaml:"note_extensions"`
+
+	// When output_dir is inside roam_dir, skip it while scanning and
+	// watching instead of refusing to build
+	AllowNestedOutput bool `yaml:"allow_nested_output"`
+
+	// BibTeX (.bib) or CSL-JSON (.json) file that ROAM_REFS cite keys are
+	// looked up in, relative to roam_dir
+	Bibliography string `yaml:"bibliography"`
 }
 
 type ExcludeConfig struct {
 	Tags  []string `yaml:"tags"`
 	Files []string `yaml:"files"`
 	IDs   []string `yaml:"ids"`
+	Links []string `yaml:"links"` // "source->target" or "a<->b"; either side may be "*"
+}
+
+// GraphConfig holds options for the site and local graphs
+type GraphConfig struct {
+	// Notes left out of the graphs, along with their links. Unlike
+	// exclude, their pages, search entries and backlinks are kept.
+	ExcludeTags []string `yaml:"exclude_tags"`
+	ExcludeIDs  []string `yaml:"exclude_ids"`
+}
+
+// LinkRule is a parsed exclude.links entry
+type LinkRule struct {
+	Source string
+	Target string
+	Both   bool // Also matches links from Target to Source
+}
+
+// ParseLinkRule parses "source->target" or "a<->b"
+func ParseLinkRule(s string) (LinkRule, error) {
+	sep, both := "->", false
+	if strings.Contains(s, "<->") {
+		sep, both = "<->", true
+	}
+	source, target, ok := strings.Cut(s, sep)
+	source, target = strings.TrimSpace(source), strings.TrimSpace(target)
+	if !ok || source == "" || target == "" {
+		return LinkRule{}, fmt.Errorf("invalid link %q (want \"source->target\" or \"a<->b\")", s)
+	}
+	if source == "*" && target == "*" {
+		return LinkRule{}, fmt.Errorf("invalid link %q: only one side may be \"*\"", s)
+	}
+	return LinkRule{Source: source, Target: target, Both: both}, nil
+}
+
+// Matches reports whether a link from source to target is excluded
+func (r LinkRule) Matches(source, target string) bool {
+	match := func(pattern, id string) bool { return pattern == "*" || pattern == id }
+	if match(r.Source, source) && match(r.Target, target) {
+		return true
+	}
+	return r.Both && match(r.Source, target) && match(r.Target, source)
+}
+
+// LinkRules returns the parsed exclude.links entries, skipping invalid ones
+func (e ExcludeConfig) LinkRules() []LinkRule {
+	var rules []LinkRule
+	for _, s := range e.Links {
+		if rule, err := ParseLinkRule(s); err == nil {
+			rules = append(rules, rule)
+		}
+	}
+	return rules
 }
 
 type DisplayConfig struct {
-	RecentCount     int `yaml:"recent_count"`
-	LocalGraphDepth int `yaml:"local_graph_depth"`
+	RecentCount      int      `yaml:"recent_count"`
+	LocalGraphDepth  int      `yaml:"local_graph_depth"`
+	ShowUntagged     bool     `yaml:"show_untagged"`
+	VerifyImageHash  bool     `yaml:"verify_image_hash"`
+	CopyWorkers      int      `yaml:"copy_workers"`     // 0 means runtime.NumCPU()
+	NotesSubdir      string   `yaml:"notes_subdir"`     // Empty places note pages at the site root
+	MaxParseErrors   int      `yaml:"max_parse_errors"` // Negative means unlimited
+	DateFormat       string   `yaml:"date_format"`      // Go time layout for dates and org timestamps
+	Timeline         bool     `yaml:"timeline"`
+	RecentWindowDays int      `yaml:"recent_window_days"` // Flag graph nodes dated within this many days
+	HomeSort         []string `yaml:"home_sort"`
+	StrictIDs        bool     `yaml:"strict_ids"`
+
+	// Links from notes matching these are left out of backlink lists
+	// (but still shown in the graph), e.g. for map-of-content hubs
+	BacklinkExcludeTags []string `yaml:"backlink_exclude_tags"`
+	BacklinkExcludeIDs  []string `yaml:"backlink_exclude_ids"`
+
+	// Strip :noexport: subtrees and #+begin_private blocks from content
+	RespectNoExport bool `yaml:"respect_noexport"`
+
+	// Cap on nodes in a note's local graph (0 = unlimited)
+	LocalGraphMaxNodes int `yaml:"local_graph_max_nodes"`
+
+	// Note properties shown in a metadata table on note pages, in order
+	ShowProperties []string `yaml:"show_properties"`
+
+	// Write tags.json and tags/<tag>.json next to the tag pages
+	EmitTagJSON bool `yaml:"emit_tag_json"`
+
+	// Write graph.gexf for Gephi, dated by note dates
+	EmitGEXF bool `yaml:"emit_gexf"`
+
+	// Write graph-component.json with only the connected component
+	// containing this note ID
+	GraphSeed string `yaml:"graph_seed"`
+
+	// Graph colors for specific tags; other tags get a stable palette color
+	TagColors map[string]string `yaml:"tag_colors"`
+
+	// Seed for what is picked at random in the output: the palette colors
+	// of tags and the starting layout of graphs. Builds with the same seed,
+	// notes and options are byte-identical; empty keeps the default picks.
+	Seed string `yaml:"seed"`
+
+	// Note maturity badges, chosen by the STATUS property
+	Statuses           []StatusConfig `yaml:"statuses"`
+	GraphColorByStatus bool           `yaml:"graph_color_by_status"`
+
+	// Notes without content show the placeholder; hidden ones are left
+	// out of the recent list and timeline but keep their pages
+	EmptyNotePlaceholder string `yaml:"empty_note_placeholder"`
+	HideEmptyNotes       bool   `yaml:"hide_empty_notes"`
+
+	// Write api/backlinks/<id>.json for each note
+	EmitBacklinkJSON bool `yaml:"emit_backlink_json"`
+
+	// Notes with more links than this are removed from (or, with mode
+	// "dim", dimmed in) the full graph; 0 disables
+	GraphHideHubsOver int    `yaml:"graph_hide_hubs_over"`
+	GraphHubMode      string `yaml:"graph_hub_mode"` // "hide" or "dim"
+
+	// Reading progress bar and heading mini-map on notes with several headings
+	ReadingProgress bool `yaml:"reading_progress"`
+
+	// Order of notes on tag pages (same keys as home_sort)
+	TagSort []string `yaml:"tag_sort"`
+
+	// Add lazy loading and, for local images, dimensions to <img> tags
+	LazyImages bool `yaml:"lazy_images"`
+
+	// Note shown above the recent list on the home page (else the first
+	// note with a FEATURED property)
+	FeaturedID string `yaml:"featured_id"`
+
+	// Write notes.csv with per-note tags, link counts, dates and files
+	EmitCSV bool `yaml:"emit_csv"`
+
+	// Keep heading anchors ASCII, hashing titles that can't be transliterated
+	SlugTransliterate bool `yaml:"slug_transliterate"`
+
+	// Search box in the header of every page
+	GlobalSearch bool `yaml:"global_search"`
+
+	// Show only this many best-connected notes on graph.html (0 = all);
+	// graph.json always has the full graph
+	GraphPreviewNodes int `yaml:"graph_preview_nodes"`
+
+	// Draw graph links as arrows; when off, links between the same two
+	// notes are merged into one
+	GraphDirected bool `yaml:"graph_directed"`
+
+	// Downscale wider JPEG and PNG images to this width, linking to the
+	// original (0 = off)
+	MaxImageWidth int `yaml:"max_image_width"`
+
+	// Write llms.txt listing notes for language model tools, and
+	// llms-full.txt with their text
+	EmitLLMsTxt     bool `yaml:"emit_llms_txt"`
+	EmitLLMsFullTxt bool `yaml:"emit_llms_full_txt"`
+
+	// Write an RSS feed per tag (tags/<tag>.xml) with discovery links
+	TagFeeds bool `yaml:"tag_feeds"`
+
+	// Render note pages in batches of this size, releasing memory between
+	// batches (0 = unbatched)
+	BatchSize int `yaml:"batch_size"`
+
+	// Number of goroutines rendering note pages (0 = one per CPU, 1 =
+	// sequential)
+	Workers int `yaml:"workers"`
+
+	// Apply TITLE, AUTHOR, FILETAGS, MACRO and other keywords from
+	// #+SETUPFILE files referenced by notes
+	SetupFiles bool `yaml:"setup_files"`
+
+	// Write search.json without indentation
+	CompactSearchJSON bool `yaml:"compact_search_json"`
+
+	// Guess each note's language from its script (CJK, Cyrillic) when it
+	// has no LANGUAGE property
+	DetectLanguage bool `yaml:"detect_language"`
+
+	// Regexp removed from titles shown in link lists, previews and the
+	// graph (e.g. "^zk-\\d+ "); page headers keep the full title
+	TitleStripPattern string `yaml:"title_strip_pattern"`
+
+	// Generate sections/<folder>.html for each subfolder of roam_dir,
+	// with the top-level folders linked from the nav
+	FolderSections bool `yaml:"folder_sections"`
+
+	// Whether a search hit on a ROAM_ALIASES entry shows the note's title
+	// ("canonical") or the alias ("matched"), and how an alias match
+	// scores relative to the same match on the title (0 ignores aliases)
+	SearchAliasDisplay string  `yaml:"search_alias_display"`
+	SearchAliasWeight  float64 `yaml:"search_alias_weight"`
+
+	// Generate changes.html listing the changes_count most recent notes
+	ChangesPage  bool `yaml:"changes_page"`
+	ChangesCount int  `yaml:"changes_count"`
+
+	// Add BreadcrumbList JSON-LD (Home › parent › tag) to tag pages;
+	// "a/b" is a child of tag "a"
+	TagBreadcrumbs bool `yaml:"tag_breadcrumbs"`
+
+	// Generate tasks.html listing the open TODO headlines of all notes
+	TasksPage bool `yaml:"tasks_page"`
+
+	// List a note linking several times once in backlinks, with a count
+	DedupeBacklinks bool `yaml:"dedupe_backlinks"`
+
+	// Generate maintenance.html listing notes that need tending
+	Maintenance MaintenanceConfig `yaml:"maintenance"`
+
+	// Write feed.xml, an Atom feed of the first feed_count notes in
+	// home_sort order (0 = no feed)
+	FeedCount int `yaml:"feed_count"`
+
+	// Write sitemap.xml listing the home, graph, tag and note pages
+	GenerateSitemap bool `yaml:"generate_sitemap"`
+
+	// Publish heading nodes (headlines with an ID): "anchor" links them
+	// to their place in the file's page, "page" gives each a page of its
+	// own, which the file's page links to in place of the subtree ("" =
+	// left out)
+	IncludeHeadingNodes string `yaml:"include_heading_nodes"`
+
+	// Maximum length in characters of note excerpts in previews, feeds
+	// and search results
+	ExcerptLength int `yaml:"excerpt_length"`
+
+	// Write graph.dot for GraphViz
+	ExportDOT bool `yaml:"export_dot"`
+
+	// Write graph.graphml for Gephi or Cytoscape
+	EmitGraphML bool `yaml:"emit_graphml"`
+
+	// Write broken-links.json listing id: links to missing or excluded notes
+	BrokenLinksReport bool `yaml:"broken_links_report"`
+
+	// Highlight source blocks with this chroma style (e.g. "monokai"),
+	// writing its colors to syntax.css ("" = no highlighting)
+	SyntaxTheme string `yaml:"syntax_theme"`
+
+	// Render LaTeX math in notes and titles with KaTeX
+	Math bool `yaml:"math"`
+
+	// Note page URLs: "id" gives notes/<id>.html, "slug" gives
+	// notes/<title-slug>/ with a redirect left at the ID path
+	URLStyle string `yaml:"url_style"`
+
+	// Write .gz and .br copies of the generated text files
+	Precompress PrecompressConfig `yaml:"precompress"`
+}
+
+// MaintenanceConfig holds the thresholds of the maintenance page. A note
+// is an orphan with fewer than min_links links to or from other notes, and
+// a stub with fewer than min_words words.
+type MaintenanceConfig struct {
+	Enabled  bool `yaml:"enabled"`
+	MinLinks int  `yaml:"min_links"`
+	MinWords int  `yaml:"min_words"`
+}
+
+// PrecompressConfig controls the gzip and brotli copies written next to
+// .html, .json, .css and .js files. Files smaller than min_size bytes are
+// left uncompressed. serve never precompresses, and a build with it off
+// removes the copies of earlier builds.
+type PrecompressConfig struct {
+	Enabled     bool `yaml:"enabled"`
+	MinSize     int  `yaml:"min_size"`
+	GzipLevel   int  `yaml:"gzip_level"`   // 1 (fastest) to 9 (smallest)
+	BrotliLevel int  `yaml:"brotli_level"` // 0 (fastest) to 11 (smallest)
+}
+
+// StatusConfig describes a note status badge
+type StatusConfig struct {
+	Name  string `yaml:"name"`  // STATUS property value
+	Label string `yaml:"label"` // Badge text, defaults to the name
+	Color string `yaml:"color"` // Badge and graph node color
 }
 
+// FindStatus returns the configured status named name, ignoring case
+func (d DisplayConfig) FindStatus(name string) (StatusConfig, bool) {
+	for _, s := range d.Statuses {
+		if strings.EqualFold(s.Name, name) {
+			return s, true
+		}
+	}
+	return StatusConfig{}, false
+}
+
+// SortKeys lists the keys accepted by display.home_sort
+var SortKeys = []string{"pinned", "date_desc", "date_asc", "title_asc", "title_desc"}
+
 // DefaultConfig returns the default configuration
 func DefaultConfig() *Config {
 	return &Config{
 		Site: SiteConfig{
-			Title:   "My Notes",
-			BaseURL: "",
+			Title:    "My Notes",
+			BaseURL:  "",
+			Language: "en",
 		},
 		Paths: PathsConfig{
-			RoamDir:   ".",
-			DBPath:    "./roam.db",
-			OutputDir: "./dist",
+			RoamDir:        ".",
+			DBPath:         "./roam.db",
+			OutputDir:      "./dist",
+			NoteExtensions: []string{".org"},
+
+			AllowNestedOutput: true,
 		},
 		Exclude: ExcludeConfig{
 			Tags:  []string{"private", "draft"},
 			Files: []string{},
 			IDs:   []string{},
+			Links: []string{},
 		},
 		Display: DisplayConfig{
 			RecentCount:     20,
 			LocalGraphDepth: 2,
+			HomeSort:        []string{"date_desc"},
+			NotesSubdir:     "notes",
+			MaxParseErrors:  -1,
+			DateFormat:      "Jan 2, 2006",
+			RespectNoExport: true,
+
+			EmptyNotePlaceholder: "This note has no content yet.",
+			GraphHubMode:         "hide",
+			TagSort:              []string{"date_desc"},
+			LazyImages:           true,
+			SetupFiles:           true,
+			DetectLanguage:       true,
+			SearchAliasDisplay:   "canonical",
+			SearchAliasWeight:    0.8,
+			ChangesCount:         50,
+			DedupeBacklinks:      true,
+			FeedCount:            20,
+			GenerateSitemap:      true,
+			ExcerptLength:        160,
+			Math:                 true,
+			URLStyle:             "id",
+			Precompress: PrecompressConfig{
+				MinSize:     1024,
+				GzipLevel:   9,
+				BrotliLevel: 11,
+			},
+			Maintenance: MaintenanceConfig{
+				MinLinks: 1,
+				MinWords: 50,
+			},
 		},
 	}
 }
@@ -78,15 +415,151 @@ func Load(path string) (*Config, error) {
 	}
 
 	// Expand paths
-	cfg.Paths.RoamDir = expandPath(cfg.Paths.RoamDir)
-	cfg.Paths.DBPath = expandPath(cfg.Paths.DBPath)
-	cfg.Paths.OutputDir = expandPath(cfg.Paths.OutputDir)
+	cfg.Paths.RoamDir = ExpandPath(cfg.Paths.RoamDir)
+	cfg.Paths.DBPath = ExpandPath(cfg.Paths.DBPath)
+	cfg.Paths.OutputDir = ExpandPath(cfg.Paths.OutputDir)
+
+	cfg.Site.BaseURL = normalizeBaseURL(cfg.Site.BaseURL)
+
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
 
 	return cfg, nil
 }
 
-// expandPath expands ~ to home directory
-func expandPath(path string) string {
+// Validate checks the configuration for invalid values
+func (c *Config) Validate() error {
+	if err := validateSortKeys("display.home_sort", c.Display.HomeSort); err != nil {
+		return err
+	}
+	if err := validateSortKeys("display.tag_sort", c.Display.TagSort); err != nil {
+		return err
+	}
+	for _, s := range c.Exclude.Links {
+		if _, err := ParseLinkRule(s); err != nil {
+			return fmt.Errorf("exclude.links: %w", err)
+		}
+	}
+	if _, err := regexp.Compile(c.Display.TitleStripPattern); err != nil {
+		return fmt.Errorf("display.title_strip_pattern: %w", err)
+	}
+	switch c.Display.SearchAliasDisplay {
+	case "canonical", "matched":
+	default:
+		return fmt.Errorf("display.search_alias_display: unknown mode %q (valid: canonical, matched)", c.Display.SearchAliasDisplay)
+	}
+	if c.Display.SearchAliasWeight < 0 {
+		return fmt.Errorf("display.search_alias_weight: must not be negative")
+	}
+	switch c.Display.IncludeHeadingNodes {
+	case "", "anchor", "page":
+	default:
+		return fmt.Errorf("display.include_heading_nodes: unknown mode %q (valid: anchor, page)", c.Display.IncludeHeadingNodes)
+	}
+	switch c.Display.URLStyle {
+	case "id", "slug":
+	default:
+		return fmt.Errorf("display.url_style: unknown style %q (valid: id, slug)", c.Display.URLStyle)
+	}
+	if c.Display.Precompress.MinSize < 0 {
+		return fmt.Errorf("display.precompress.min_size: must not be negative")
+	}
+	if l := c.Display.Precompress.GzipLevel; l < 1 || l > 9 {
+		return fmt.Errorf("display.precompress.gzip_level: must be between 1 and 9")
+	}
+	if l := c.Display.Precompress.BrotliLevel; l < 0 || l > 11 {
+		return fmt.Errorf("display.precompress.brotli_level: must be between 0 and 11")
+	}
+	if c.Display.ExcerptLength <= 0 {
+		return fmt.Errorf("display.excerpt_length: must be positive")
+	}
+	if c.Display.Workers < 0 {
+		return fmt.Errorf("display.workers: must not be negative")
+	}
+	if c.Display.CopyWorkers < 0 {
+		return fmt.Errorf("display.copy_workers: must not be negative")
+	}
+	if c.Display.BatchSize < 0 {
+		return fmt.Errorf("display.batch_size: must not be negative")
+	}
+	if c.Display.FeedCount < 0 {
+		return fmt.Errorf("display.feed_count: must not be negative")
+	}
+	if c.Display.LocalGraphMaxNodes < 0 {
+		return fmt.Errorf("display.local_graph_max_nodes: must not be negative")
+	}
+	if c.Display.MaxImageWidth < 0 {
+		return fmt.Errorf("display.max_image_width: must not be negative")
+	}
+	if c.Display.Maintenance.MinLinks < 0 {
+		return fmt.Errorf("display.maintenance.min_links: must not be negative")
+	}
+	if c.Display.Maintenance.MinWords < 0 {
+		return fmt.Errorf("display.maintenance.min_words: must not be negative")
+	}
+	switch c.Display.GraphHubMode {
+	case "hide", "dim":
+	default:
+		return fmt.Errorf("display.graph_hub_mode: unknown mode %q (valid: hide, dim)", c.Display.GraphHubMode)
+	}
+	return c.Paths.CheckNesting()
+}
+
+// validateSortKeys checks that every key is one of SortKeys
+func validateSortKeys(option string, keys []string) error {
+	for _, key := range keys {
+		valid := false
+		for _, k := range SortKeys {
+			if key == k {
+				valid = true
+				break
+			}
+		}
+		if !valid {
+			return fmt.Errorf("%s: unknown sort key %q (valid: %s)", option, key, strings.Join(SortKeys, ", "))
+		}
+	}
+	return nil
+}
+
+// AbsURL joins a site-relative path onto the base URL. When the base URL
+// has no host, the result is root-relative.
+func (s SiteConfig) AbsURL(path string) string {
+	return s.BaseURL + "/" + strings.TrimLeft(path, "/")
+}
+
+// HasAbsBaseURL reports whether the base URL has a scheme and host, so
+// that AbsURL gives the absolute URLs feeds and sitemaps need
+func (s SiteConfig) HasAbsBaseURL() bool {
+	return strings.Contains(s.BaseURL, "://")
+}
+
+// normalizeBaseURL adds https:// to a base URL that names a host but has no
+// scheme, collapses repeated slashes in the path and drops the trailing
+// slash. Path-only base URLs such as "/notes" keep no scheme.
+func normalizeBaseURL(u string) string {
+	u = strings.TrimSpace(u)
+	if u == "" {
+		return ""
+	}
+
+	scheme := ""
+	if i := strings.Index(u, "://"); i >= 0 {
+		scheme, u = u[:i+3], u[i+3:]
+	} else if !strings.HasPrefix(u, "/") {
+		scheme = "https://"
+	}
+
+	for strings.Contains(u, "//") {
+		u = strings.ReplaceAll(u, "//", "/")
+	}
+
+	return scheme + strings.TrimRight(u, "/")
+}
+
+// ExpandPath replaces a leading ~ in path with the home directory
+func ExpandPath(path string) string {
 	if len(path) > 0 && path[0] == '~' {
 		home, err := os.UserHomeDir()
 		if err != nil {
@@ -96,3 +569,60 @@ func expandPath(path string) string {
 	}
 	return path
 }
+
+// NestedOutput reports whether the output directory is the roam directory
+// or inside it
+func (p PathsConfig) NestedOutput() bool {
+	return isWithin(p.RoamDir, p.OutputDir)
+}
+
+// InOutputDir reports whether path is the output directory or inside it
+func (p PathsConfig) InOutputDir(path string) bool {
+	return isWithin(p.OutputDir, path)
+}
+
+// InRoamDir reports whether path is the roam directory or inside it
+func (p PathsConfig) InRoamDir(path string) bool {
+	return isWithin(p.RoamDir, path)
+}
+
+// CheckNesting returns an error if the output directory is nested in the
+// roam directory and paths.allow_nested_output is off
+func (p PathsConfig) CheckNesting() error {
+	if p.NestedOutput() && !p.AllowNestedOutput {
+		return fmt.Errorf("paths.output_dir %s is inside paths.roam_dir %s (set paths.allow_nested_output to build anyway)", p.OutputDir, p.RoamDir)
+	}
+	return nil
+}
+
+// isWithin reports whether path is dir or a descendant of it
+func isWithin(dir, path string) bool {
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		return false
+	}
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return false
+	}
+	rel, err := filepath.Rel(absDir, absPath)
+	if err != nil {
+		return false
+	}
+	return rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// IsNoteFile reports whether path has one of the configured note extensions
+func (p PathsConfig) IsNoteFile(path string) bool {
+	ext := strings.ToLower(filepath.Ext(path))
+	for _, e := range p.NoteExtensions {
+		e = strings.ToLower(e)
+		if !strings.HasPrefix(e, ".") {
+			e = "." + e
+		}
+		if ext == e {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,199 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// loadYAML loads a config file with the given content
+func loadYAML(t *testing.T, content string) (*Config, error) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return Load(path)
+}
+
+func TestLoadSortKeys(t *testing.T) {
+	tests := []struct {
+		yaml    string
+		wantErr string
+	}{
+		{"display:\n  home_sort: [pinned, date_desc, title_asc]\n", ""},
+		{"display:\n  home_sort: [date_asc]\n  tag_sort: [title_desc]\n", ""},
+		{"display:\n  home_sort: [pinned, newest]\n", `display.home_sort: unknown sort key "newest"`},
+		{"display:\n  tag_sort: [title]\n", `display.tag_sort: unknown sort key "title"`},
+	}
+	for _, tt := range tests {
+		_, err := loadYAML(t, tt.yaml)
+		if tt.wantErr == "" {
+			if err != nil {
+				t.Errorf("%q: %v", tt.yaml, err)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("%q: error = %v, want %q", tt.yaml, err, tt.wantErr)
+		}
+	}
+}
+
+func TestBaseURL(t *testing.T) {
+	tests := []struct {
+		baseURL string
+		want    string // Normalized base URL
+		abs     bool
+		page    string // AbsURL("notes/a1.html")
+	}{
+		{"", "", false, "/notes/a1.html"},
+		{"example.com", "https://example.com", true, "https://example.com/notes/a1.html"},
+		{"example.com/notes/", "https://example.com/notes", true, "https://example.com/notes/notes/a1.html"},
+		{"https://example.com/", "https://example.com", true, "https://example.com/notes/a1.html"},
+		{"http://example.com//wiki//", "http://example.com/wiki", true, "http://example.com/wiki/notes/a1.html"},
+		{"/repo-name/", "/repo-name", false, "/repo-name/notes/a1.html"},
+		{"  https://example.com/notes  ", "https://example.com/notes", true, "https://example.com/notes/notes/a1.html"},
+	}
+	for _, tt := range tests {
+		cfg, err := loadYAML(t, "site:\n  base_url: "+strconv.Quote(tt.baseURL)+"\n")
+		if err != nil {
+			t.Fatalf("%q: %v", tt.baseURL, err)
+		}
+		if cfg.Site.BaseURL != tt.want {
+			t.Errorf("base_url %q normalized to %q, want %q", tt.baseURL, cfg.Site.BaseURL, tt.want)
+		}
+		if got := cfg.Site.HasAbsBaseURL(); got != tt.abs {
+			t.Errorf("base_url %q: HasAbsBaseURL = %t, want %t", tt.baseURL, got, tt.abs)
+		}
+		if got := cfg.Site.AbsURL("notes/a1.html"); got != tt.page {
+			t.Errorf("base_url %q: AbsURL = %q, want %q", tt.baseURL, got, tt.page)
+		}
+		if got := cfg.Site.AbsURL("/notes/a1.html"); got != tt.page {
+			t.Errorf("base_url %q: AbsURL with leading slash = %q, want %q", tt.baseURL, got, tt.page)
+		}
+	}
+}
+
+func TestNestedOutput(t *testing.T) {
+	root := t.TempDir()
+	tests := []struct {
+		name    string
+		roamDir string
+		output  string
+		nested  bool
+	}{
+		{"sibling", "roam", "dist", false},
+		{"inside", "roam", "roam/dist", true},
+		{"deep inside", "roam", "roam/site/public", true},
+		{"same directory", "roam", "roam", true},
+		{"name prefix", "roam", "roam2/dist", false},
+		{"parent", "roam/notes", "roam", false},
+		{"unclean path", "roam", "roam/../roam/./dist", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := PathsConfig{RoamDir: filepath.Join(root, tt.roamDir), OutputDir: filepath.Join(root, tt.output)}
+			if got := p.NestedOutput(); got != tt.nested {
+				t.Errorf("NestedOutput() = %t, want %t", got, tt.nested)
+			}
+			if !p.InOutputDir(filepath.Join(p.OutputDir, "notes", "a1.html")) || p.InOutputDir(filepath.Join(root, "elsewhere")) {
+				t.Error("InOutputDir doesn't match the output directory")
+			}
+
+			p.AllowNestedOutput = true
+			if err := p.CheckNesting(); err != nil {
+				t.Errorf("allowed: %v", err)
+			}
+			p.AllowNestedOutput = false
+			if err := p.CheckNesting(); (err != nil) != tt.nested {
+				t.Errorf("not allowed: error %v, want error %t", err, tt.nested)
+			}
+		})
+	}
+}
+
+func TestLinkRules(t *testing.T) {
+	tests := []struct {
+		rule    string
+		wantErr bool
+		match   []string // "source->target" links the rule matches
+		miss    []string // and ones it doesn't
+	}{
+		{"a1->b2", false, []string{"a1->b2"}, []string{"b2->a1", "a1->c3", "c3->b2"}},
+		{" a1 -> b2 ", false, []string{"a1->b2"}, []string{"b2->a1"}},
+		{"a1<->b2", false, []string{"a1->b2", "b2->a1"}, []string{"a1->c3", "c3->a1"}},
+		{"*->a1", false, []string{"b2->a1", "c3->a1"}, []string{"a1->b2"}},
+		{"a1->*", false, []string{"a1->b2", "a1->c3"}, []string{"b2->a1"}},
+		{"*<->a1", false, []string{"b2->a1", "a1->c3"}, []string{"b2->c3"}},
+		{"*->*", true, nil, nil},
+		{"a1", true, nil, nil},
+		{"->b2", true, nil, nil},
+		{"a1->", true, nil, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.rule, func(t *testing.T) {
+			rule, err := ParseLinkRule(tt.rule)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseLinkRule(%q) error = %v, want error %t", tt.rule, err, tt.wantErr)
+			}
+			if tt.wantErr {
+				cfg := DefaultConfig()
+				cfg.Exclude.Links = []string{tt.rule}
+				if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "invalid link") {
+					t.Errorf("Validate with %q = %v", tt.rule, err)
+				}
+				if rules := cfg.Exclude.LinkRules(); len(rules) != 0 {
+					t.Errorf("LinkRules kept invalid %q: %v", tt.rule, rules)
+				}
+				return
+			}
+			for _, link := range tt.match {
+				source, target, _ := strings.Cut(link, "->")
+				if !rule.Matches(source, target) {
+					t.Errorf("%q doesn't match %s", tt.rule, link)
+				}
+			}
+			for _, link := range tt.miss {
+				source, target, _ := strings.Cut(link, "->")
+				if rule.Matches(source, target) {
+					t.Errorf("%q matches %s", tt.rule, link)
+				}
+			}
+		})
+	}
+}
+
+func TestValidateCounts(t *testing.T) {
+	tests := []struct {
+		yaml    string
+		wantErr string
+	}{
+		{"display:\n  workers: 4\n  feed_count: 0\n  max_image_width: 800\n", ""},
+		{"display:\n  max_image_width: 0\n", ""},
+		{"display:\n  workers: -1\n", "display.workers: must not be negative"},
+		{"display:\n  feed_count: -1\n", "display.feed_count: must not be negative"},
+		{"display:\n  maintenance:\n    min_links: -1\n", "display.maintenance.min_links: must not be negative"},
+		{"display:\n  maintenance:\n    min_words: -1\n", "display.maintenance.min_words: must not be negative"},
+		{"display:\n  max_image_width: -800\n", "display.max_image_width: must not be negative"},
+		{"display:\n  copy_workers: 0\n  batch_size: 0\n", ""},
+		{"display:\n  local_graph_max_nodes: 0\n", ""},
+		{"display:\n  local_graph_max_nodes: -1\n", "display.local_graph_max_nodes: must not be negative"},
+		{"display:\n  copy_workers: -2\n", "display.copy_workers: must not be negative"},
+		{"display:\n  batch_size: -100\n", "display.batch_size: must not be negative"},
+	}
+	for _, tt := range tests {
+		_, err := loadYAML(t, tt.yaml)
+		if tt.wantErr == "" {
+			if err != nil {
+				t.Errorf("%q: %v", tt.yaml, err)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("%q: error = %v, want %q", tt.yaml, err, tt.wantErr)
+		}
+	}
+}
@@ -0,0 +1,154 @@
+package config
+
+import (
+	"bytes"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+	"text/template"
+)
+
+// sampleTemplate is the commented config.yaml written by Sample. It lists
+// every option, with the comments of "Full Configuration Reference" in
+// README.org.
+var sampleTemplate = template.Must(template.New("config.yaml").Funcs(template.FuncMap{
+	"quote":   strconv.Quote,
+	"list":    yamlList,
+	"mapping": yamlMapping,
+}).Parse(`# org-roam-web configuration
+# See "Full Configuration Reference" in README.org for more on each option.
+
+site:
+  title: {{quote .Site.Title}}  # Site title shown in header
+  base_url: {{quote .Site.BaseURL}}  # Base URL for links (e.g. "/notes" or "example.com/notes")
+  language: {{quote .Site.Language}}  # Default lang attribute of pages
+  edit_url_template: {{quote .Site.EditURLTemplate}}  # "Edit" link on notes; {path} is the file path relative to roam_dir
+
+paths:
+  roam_dir: {{quote .Paths.RoamDir}}  # Path to org-roam directory
+  db_path: {{quote .Paths.DBPath}}  # Path to org-roam database (relative to roam_dir)
+  output_dir: {{quote .Paths.OutputDir}}  # Output directory for generated site
+  note_extensions: {{list .Paths.NoteExtensions}}  # File extensions treated as notes by the watcher
+  allow_nested_output: {{.Paths.AllowNestedOutput}}  # If output_dir is inside roam_dir, skip it (false = refuse to build)
+  bibliography: {{quote .Paths.Bibliography}}  # BibTeX or CSL-JSON file for ROAM_REFS cite keys (relative to roam_dir)
+
+exclude:
+  tags: {{list .Exclude.Tags}}  # Notes with these tags are excluded
+  files: {{list .Exclude.Files}}  # File patterns to exclude (e.g. "daily/*.org")
+  ids: {{list .Exclude.IDs}}  # Specific node IDs to exclude
+  links: {{list .Exclude.Links}}  # Links to drop: "a1->b2" (one way), "a1<->b2" (either way); one side may be "*"
+
+graph:
+  exclude_tags: {{list .Graph.ExcludeTags}}  # Notes with these tags are left out of the graphs only
+  exclude_ids: {{list .Graph.ExcludeIDs}}  # Node IDs left out of the graphs only
+
+display:
+  recent_count: {{.Display.RecentCount}}  # Number of recent notes on home page
+  local_graph_depth: {{.Display.LocalGraphDepth}}  # Depth of local graph on note pages
+  show_untagged: {{.Display.ShowUntagged}}  # Generate tags/untagged.html listing notes without tags
+  verify_image_hash: {{.Display.VerifyImageHash}}  # Compare SHA-256 of copied images (sizes are always checked)
+  copy_workers: {{.Display.CopyWorkers}}  # Parallel image copies (0 = number of CPUs)
+  notes_subdir: {{quote .Display.NotesSubdir}}  # Directory for note pages ("" puts them at the site root)
+  max_parse_errors: {{.Display.MaxParseErrors}}  # Abort the build after this many failed notes (-1 = never)
+  date_format: {{quote .Display.DateFormat}}  # Go time layout for note dates and org timestamps
+  timeline: {{.Display.Timeline}}  # Generate timeline.html grouping notes by year and month
+  recent_window_days: {{.Display.RecentWindowDays}}  # Highlight graph nodes dated within this many days (0 = off)
+  respect_noexport: {{.Display.RespectNoExport}}  # Strip :noexport: subtrees and #+begin_private blocks
+  home_sort: {{list .Display.HomeSort}}  # Recent list order: pinned, date_desc, date_asc, title_asc, title_desc
+  strict_ids: {{.Display.StrictIDs}}  # Skip every node sharing a duplicate ID instead of keeping the first
+  backlink_exclude_tags: {{list .Display.BacklinkExcludeTags}}  # Hide backlinks from notes with these tags (e.g. "moc")
+  backlink_exclude_ids: {{list .Display.BacklinkExcludeIDs}}  # Hide backlinks from these node IDs
+  local_graph_max_nodes: {{.Display.LocalGraphMaxNodes}}  # Cap local graph size on note pages (0 = unlimited)
+  show_properties: {{list .Display.ShowProperties}}  # Note properties shown in a table on note pages, in order
+  emit_tag_json: {{.Display.EmitTagJSON}}  # Write tags.json and tags/<tag>.json
+  emit_gexf: {{.Display.EmitGEXF}}  # Write graph.gexf for Gephi
+  graph_seed: {{quote .Display.GraphSeed}}  # Write graph-component.json with this note's connected component
+  tag_colors: {{mapping .Display.TagColors}}  # Graph colors per tag (e.g. emacs: "#7f5ab6"); others are hashed
+  seed: {{quote .Display.Seed}}  # Seed for tag palette colors and graph layouts ("" = default picks)
+  statuses:{{if not .Display.Statuses}} []{{end}}  # Badges for the STATUS property: name, label and color
+{{- range .Display.Statuses}}
+    - {name: {{quote .Name}}, label: {{quote .Label}}, color: {{quote .Color}}}
+{{- end}}
+  graph_color_by_status: {{.Display.GraphColorByStatus}}  # Color graph nodes by their status color
+  empty_note_placeholder: {{quote .Display.EmptyNotePlaceholder}}  # Shown on notes without content
+  hide_empty_notes: {{.Display.HideEmptyNotes}}  # Leave empty notes out of the recent list and timeline
+  emit_backlink_json: {{.Display.EmitBacklinkJSON}}  # Write api/backlinks/<id>.json for each note
+  graph_hide_hubs_over: {{.Display.GraphHideHubsOver}}  # Hub notes with more links than this are left out of the graph (0 = off)
+  graph_hub_mode: {{quote .Display.GraphHubMode}}  # "hide" removes hubs from the graph, "dim" draws them faded
+  reading_progress: {{.Display.ReadingProgress}}  # Progress bar and heading mini-map on notes with 3+ headings
+  tag_sort: {{list .Display.TagSort}}  # Note order on tag pages (same keys as home_sort)
+  lazy_images: {{.Display.LazyImages}}  # Lazy-load images and add width/height for local ones
+  featured_id: {{quote .Display.FeaturedID}}  # Note shown above the recent list (else the first with a FEATURED property)
+  emit_csv: {{.Display.EmitCSV}}  # Write notes.csv with per-note tags, link counts, dates and files
+  slug_transliterate: {{.Display.SlugTransliterate}}  # Keep heading anchors ASCII
+  global_search: {{.Display.GlobalSearch}}  # Search box in the header of every page
+  graph_preview_nodes: {{.Display.GraphPreviewNodes}}  # Show only the N most-linked notes on graph.html (0 = all)
+  graph_directed: {{.Display.GraphDirected}}  # Draw graph links as arrows (false merges links between the same notes)
+  max_image_width: {{.Display.MaxImageWidth}}  # Downscale wider JPEG/PNG images, linking to the original (0 = off)
+  emit_llms_txt: {{.Display.EmitLLMsTxt}}  # Write llms.txt listing note titles, URLs and excerpts
+  emit_llms_full_txt: {{.Display.EmitLLMsFullTxt}}  # With emit_llms_txt, also write llms-full.txt with every note's text
+  tag_feeds: {{.Display.TagFeeds}}  # Write an RSS feed per tag (tags/<tag>.xml)
+  batch_size: {{.Display.BatchSize}}  # Render notes in batches of this size, freeing memory between them (0 = unbatched)
+  workers: {{.Display.Workers}}  # Goroutines rendering note pages (0 = one per CPU, 1 = sequential)
+  setup_files: {{.Display.SetupFiles}}  # Apply keywords from #+SETUPFILE files the note doesn't set
+  compact_search_json: {{.Display.CompactSearchJSON}}  # Write search.json without indentation
+  detect_language: {{.Display.DetectLanguage}}  # Guess a note's language from its script when it has no LANGUAGE property
+  title_strip_pattern: {{quote .Display.TitleStripPattern}}  # Regexp removed from titles in link lists, previews and the graph
+  folder_sections: {{.Display.FolderSections}}  # Generate sections/<folder>.html for each subfolder of roam_dir
+  search_alias_display: {{quote .Display.SearchAliasDisplay}}  # Show the title ("canonical") or matched alias ("matched") for alias hits
+  search_alias_weight: {{.Display.SearchAliasWeight}}  # Score of an alias match relative to a title match (0 ignores aliases)
+  changes_page: {{.Display.ChangesPage}}  # Generate changes.html listing the most recent notes
+  changes_count: {{.Display.ChangesCount}}  # Number of notes on changes.html
+  tag_breadcrumbs: {{.Display.TagBreadcrumbs}}  # Add BreadcrumbList JSON-LD to tag pages ("a/b" is a child of "a")
+  tasks_page: {{.Display.TasksPage}}  # Generate tasks.html listing open TODO headlines
+  dedupe_backlinks: {{.Display.DedupeBacklinks}}  # List a note linking several times once in backlinks, with a count
+  maintenance:  # maintenance.html lists notes that need tending
+    enabled: {{.Display.Maintenance.Enabled}}  # Generate maintenance.html (not linked from the nav)
+    min_links: {{.Display.Maintenance.MinLinks}}  # Orphans: fewer links to or from other notes
+    min_words: {{.Display.Maintenance.MinWords}}  # Stubs: fewer words of text
+  feed_count: {{.Display.FeedCount}}  # Notes in feed.xml (0 = no feed; needs an absolute base_url)
+  generate_sitemap: {{.Display.GenerateSitemap}}  # Write sitemap.xml (needs an absolute base_url)
+  include_heading_nodes: {{quote .Display.IncludeHeadingNodes}}  # Publish headlines with an ID: "anchor", "page" or "" (left out)
+  excerpt_length: {{.Display.ExcerptLength}}  # Maximum length of note excerpts in previews, feeds and search results
+  export_dot: {{.Display.ExportDOT}}  # Write graph.dot for GraphViz
+  emit_graphml: {{.Display.EmitGraphML}}  # Write graph.graphml for Gephi or Cytoscape
+  broken_links_report: {{.Display.BrokenLinksReport}}  # Write broken-links.json listing id: links to missing or excluded notes
+  syntax_theme: {{quote .Display.SyntaxTheme}}  # Highlight source blocks with this chroma style ("" = off)
+  math: {{.Display.Math}}  # Render LaTeX math with KaTeX
+  url_style: {{quote .Display.URLStyle}}  # Note URLs: "id" (notes/<id>.html) or "slug" (notes/<title-slug>/)
+  precompress:  # Write .gz and .br copies of .html, .json, .css and .js files
+    enabled: {{.Display.Precompress.Enabled}}  # For nginx gzip_static / brotli_static; originals are kept, serve ignores it
+    min_size: {{.Display.Precompress.MinSize}}  # Skip files smaller than this many bytes
+    gzip_level: {{.Display.Precompress.GzipLevel}}  # 1 (fastest) to 9 (smallest)
+    brotli_level: {{.Display.Precompress.BrotliLevel}}  # 0 (fastest) to 11 (smallest)
+`))
+
+// Sample returns cfg as a commented YAML config file
+func Sample(cfg *Config) []byte {
+	var buf bytes.Buffer
+	if err := sampleTemplate.Execute(&buf, cfg); err != nil {
+		// The template only reads plain fields of cfg
+		panic(err)
+	}
+	return buf.Bytes()
+}
+
+// yamlList formats values as a YAML flow sequence of quoted strings
+func yamlList(values []string) string {
+	quoted := make([]string, len(values))
+	for i, v := range values {
+		quoted[i] = strconv.Quote(v)
+	}
+	return "[" + strings.Join(quoted, ", ") + "]"
+}
+
+// yamlMapping formats m as a YAML flow mapping of quoted strings, ordered
+// by key
+func yamlMapping(m map[string]string) string {
+	pairs := make([]string, 0, len(m))
+	for _, k := range slices.Sorted(maps.Keys(m)) {
+		pairs = append(pairs, strconv.Quote(k)+": "+strconv.Quote(m[k]))
+	}
+	return "{" + strings.Join(pairs, ", ") + "}"
+}
@@ -0,0 +1,136 @@
+package config
+
+import (
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+// yamlKeys returns the dotted paths of the options of a config struct type
+func yamlKeys(typ reflect.Type, prefix string) []string {
+	var keys []string
+	for i := range typ.NumField() {
+		f := typ.Field(i)
+		name, _, _ := strings.Cut(f.Tag.Get("yaml"), ",")
+		if name == "" || name == "-" {
+			continue
+		}
+		keys = append(keys, prefix+name)
+		if f.Type.Kind() == reflect.Struct {
+			keys = append(keys, yamlKeys(f.Type, prefix+name+".")...)
+		}
+	}
+	return keys
+}
+
+// mappingKeys returns the dotted paths of the keys of decoded YAML
+func mappingKeys(m map[string]any, prefix string) []string {
+	var keys []string
+	for k, v := range m {
+		keys = append(keys, prefix+k)
+		if sub, ok := v.(map[string]any); ok && k != "tag_colors" {
+			keys = append(keys, mappingKeys(sub, prefix+k+".")...)
+		}
+	}
+	return keys
+}
+
+func TestSampleDocumentsEveryOption(t *testing.T) {
+	var doc map[string]any
+	if err := yaml.Unmarshal(Sample(DefaultConfig()), &doc); err != nil {
+		t.Fatal(err)
+	}
+	want := yamlKeys(reflect.TypeFor[Config](), "")
+	got := mappingKeys(doc, "")
+	slices.Sort(want)
+	slices.Sort(got)
+	if !slices.Equal(got, want) {
+		t.Errorf("sample options\n%v\nwant\n%v", got, want)
+	}
+
+	// Every option has a comment
+	for _, line := range strings.Split(string(Sample(DefaultConfig())), "\n") {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") || strings.HasPrefix(trimmed, "- ") {
+			continue
+		}
+		indent := len(line) - len(strings.TrimLeft(line, " "))
+		if indent > 0 && !strings.Contains(line, "  # ") {
+			t.Errorf("option without a comment: %s", line)
+		}
+	}
+}
+
+func TestSampleRoundTrip(t *testing.T) {
+	custom := DefaultConfig()
+	custom.Site.Title = `Notes "and" \\ things`
+	custom.Site.EditURLTemplate = "https://github.com/me/notes/edit/main/{path}"
+	custom.Paths.Bibliography = "refs.bib"
+	custom.Exclude.Links = []string{"a1->b2", "*<->c3"}
+	custom.Graph.ExcludeTags = []string{"journal"}
+	custom.Display.Seed = "2024"
+	custom.Display.GraphSeed = "a1"
+	custom.Display.TagColors = map[string]string{"emacs": "#7f5ab6", "go": "#00add8"}
+	custom.Display.Statuses = []StatusConfig{
+		{Name: "seedling", Label: "Seedling", Color: "#59a14f"},
+		{Name: "evergreen", Color: "#4e79a7"},
+	}
+	custom.Display.SearchAliasWeight = 1.5
+	custom.Display.Maintenance.Enabled = true
+	custom.Display.Precompress.Enabled = true
+
+	tests := []struct {
+		name string
+		cfg  *Config
+	}{
+		{"defaults", DefaultConfig()},
+		{"custom", custom},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sample := Sample(tt.cfg)
+			var got Config
+			if err := yaml.Unmarshal(sample, &got); err != nil {
+				t.Fatalf("invalid sample: %v\n%s", err, sample)
+			}
+			// Compared as YAML, where nil and empty lists are the same
+			want, err := yaml.Marshal(tt.cfg)
+			if err != nil {
+				t.Fatal(err)
+			}
+			gotYAML, err := yaml.Marshal(&got)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(gotYAML) != string(want) {
+				t.Errorf("sample loads as\n%s\nwant\n%s", gotYAML, want)
+			}
+			if err := got.Validate(); err != nil {
+				t.Errorf("sample doesn't validate: %v", err)
+			}
+		})
+	}
+}
+
+func TestExpandPath(t *testing.T) {
+	t.Setenv("HOME", "/home/jane")
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"~/org-roam", "/home/jane/org-roam"},
+		{"~", "/home/jane"},
+		{"~/", "/home/jane"},
+		{"/srv/notes", "/srv/notes"},
+		{"notes/~draft", "notes/~draft"},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := ExpandPath(tt.path); got != tt.want {
+			t.Errorf("ExpandPath(%q) = %q, want %q", tt.path, got, tt.want)
+		}
+	}
+}
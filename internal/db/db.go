@@ -2,9 +2,12 @@ package db
 
 import (
 	"database/sql"
+	"errors"
 	"fmt"
+	"os"
 	"regexp"
 	"strings"
+	"unicode"
 
 	_ "github.com/mattn/go-sqlite3"
 )
@@ -15,11 +18,27 @@ type Node struct {
 	File       string
 	Level      int
 	Pos        int
+	Olp        []string // Outline path of a heading node, outermost first
 	Title      string
 	Tags       []string
 	Properties map[string]string
 }
 
+// Ref is a ROAM_REFS entry of a node
+type Ref struct {
+	Type string // Link type: "cite", "https", "doi", ...
+	Path string // Cite key, or link path without the type (e.g. "//example.com")
+}
+
+// String returns the ref as written in ROAM_REFS: @key for a cite key,
+// type:path for anything else
+func (r Ref) String() string {
+	if r.Type == "cite" {
+		return "@" + r.Path
+	}
+	return r.Type + ":" + r.Path
+}
+
 // Link represents a link between nodes
 type Link struct {
 	Source string
@@ -27,6 +46,17 @@ type Link struct {
 	Type   string
 }
 
+var (
+	// ErrDBNotFound is returned by Open when the database file doesn't exist
+	ErrDBNotFound = errors.New("database not found")
+	// ErrSchemaUnsupported is returned by Open when the database lacks the
+	// org-roam tables this package reads
+	ErrSchemaUnsupported = errors.New("unsupported database schema")
+)
+
+// requiredTables are the org-roam tables read by the loaders
+var requiredTables = []string{"nodes", "tags", "links"}
+
 // DB wraps the org-roam SQLite database
 type DB struct {
 	db *sql.DB
@@ -34,6 +64,10 @@ type DB struct {
 
 // Open opens the org-roam database
 func Open(path string) (*DB, error) {
+	if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("%w: %s", ErrDBNotFound, path)
+	}
+
 	db, err := sql.Open("sqlite3", path+"?mode=ro")
 	if err != nil {
 		return nil, fmt.Errorf("failed to open database: %w", err)
@@ -44,22 +78,43 @@ func Open(path string) (*DB, error) {
 		return nil, fmt.Errorf("failed to connect to database: %w", err)
 	}
 
+	if err := checkSchema(db); err != nil {
+		db.Close()
+		return nil, err
+	}
+
 	return &DB{db: db}, nil
 }
 
+// checkSchema verifies that the org-roam tables exist
+func checkSchema(db *sql.DB) error {
+	for _, table := range requiredTables {
+		var name string
+		err := db.QueryRow(`SELECT name FROM sqlite_master WHERE type = 'table' AND name = ?`, table).Scan(&name)
+		if errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("%w: missing table %s", ErrSchemaUnsupported, table)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read database schema: %w", err)
+		}
+	}
+	return nil
+}
+
 // Close closes the database connection
 func (d *DB) Close() error {
 	return d.db.Close()
 }
 
-// LoadNodes loads all nodes from the database
-func (d *DB) LoadNodes() ([]Node, error) {
+// LoadNodes loads the file nodes from the database, along with the heading
+// nodes (level > 0) if headings is set
+func (d *DB) LoadNodes(headings bool) ([]Node, error) {
 	rows, err := d.db.Query(`
-		SELECT n.id, n.file, n.level, n.pos, n.title, n.properties
+		SELECT n.id, n.file, n.level, n.pos, n.title, n.properties, n.olp
 		FROM nodes n
-		WHERE n.level = 0
-		ORDER BY n.file DESC
-	`)
+		WHERE n.level = 0 OR ?
+		ORDER BY n.file DESC, n.pos
+	`, headings)
 	if err != nil {
 		return nil, fmt.Errorf("failed to query nodes: %w", err)
 	}
@@ -70,14 +125,15 @@ func (d *DB) LoadNodes() ([]Node, error) {
 		var n Node
 		var propsStr sql.NullString
 		var titleStr sql.NullString
+		var olpStr sql.NullString
 		var fileStr string
 
-		if err := rows.Scan(&n.ID, &fileStr, &n.Level, &n.Pos, &titleStr, &propsStr); err != nil {
+		if err := rows.Scan(&n.ID, &fileStr, &n.Level, &n.Pos, &titleStr, &propsStr, &olpStr); err != nil {
 			return nil, fmt.Errorf("failed to scan node: %w", err)
 		}
 
 		// Clean ID and file path (remove quotes)
-		n.ID = trimQuotes(n.ID)
+		n.ID = cleanID(n.ID)
 		n.File = trimQuotes(fileStr)
 
 		if titleStr.Valid {
@@ -89,12 +145,36 @@ func (d *DB) LoadNodes() ([]Node, error) {
 			n.Properties = parseElispProps(propsStr.String)
 		}
 
+		if olpStr.Valid {
+			n.Olp = parseElispStrings(olpStr.String)
+		}
+
 		nodes = append(nodes, n)
 	}
 
 	return nodes, rows.Err()
 }
 
+// LoadNodeIDs loads the IDs of all nodes, headings included
+func (d *DB) LoadNodeIDs() (map[string]bool, error) {
+	rows, err := d.db.Query(`SELECT id FROM nodes`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query node IDs: %w", err)
+	}
+	defer rows.Close()
+
+	ids := make(map[string]bool)
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan node ID: %w", err)
+		}
+		ids[cleanID(id)] = true
+	}
+
+	return ids, rows.Err()
+}
+
 // LoadTags loads all tags for nodes
 func (d *DB) LoadTags() (map[string][]string, error) {
 	rows, err := d.db.Query(`SELECT node_id, tag FROM tags`)
@@ -110,7 +190,7 @@ func (d *DB) LoadTags() (map[string][]string, error) {
 			return nil, fmt.Errorf("failed to scan tag: %w", err)
 		}
 		// Clean nodeID and tag strings (remove quotes)
-		nodeID = trimQuotes(nodeID)
+		nodeID = cleanID(nodeID)
 		tag = trimQuotes(tag)
 		tags[nodeID] = append(tags[nodeID], tag)
 	}
@@ -118,6 +198,57 @@ func (d *DB) LoadTags() (map[string][]string, error) {
 	return tags, rows.Err()
 }
 
+// LoadAliases loads the ROAM_ALIASES of all nodes
+func (d *DB) LoadAliases() (map[string][]string, error) {
+	rows, err := d.db.Query(`SELECT node_id, alias FROM aliases`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query aliases: %w", err)
+	}
+	defer rows.Close()
+
+	aliases := make(map[string][]string)
+	for rows.Next() {
+		var nodeID, alias string
+		if err := rows.Scan(&nodeID, &alias); err != nil {
+			return nil, fmt.Errorf("failed to scan alias: %w", err)
+		}
+		nodeID = cleanID(nodeID)
+		if alias = cleanTitle(alias); alias != "" {
+			aliases[nodeID] = append(aliases[nodeID], alias)
+		}
+	}
+
+	return aliases, rows.Err()
+}
+
+// LoadRefs loads the ROAM_REFS of all nodes: cite keys, URLs and other
+// links
+func (d *DB) LoadRefs() (map[string][]Ref, error) {
+	rows, err := d.db.Query(`SELECT node_id, ref, type FROM refs`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query refs: %w", err)
+	}
+	defer rows.Close()
+
+	refs := make(map[string][]Ref)
+	for rows.Next() {
+		var nodeID, ref, refType string
+		if err := rows.Scan(&nodeID, &ref, &refType); err != nil {
+			return nil, fmt.Errorf("failed to scan ref: %w", err)
+		}
+		nodeID = cleanID(nodeID)
+		r := Ref{Type: trimQuotes(refType), Path: trimQuotes(ref)}
+		if r.Type == "cite" {
+			r.Path = strings.TrimPrefix(r.Path, "@")
+		}
+		if r.Path != "" {
+			refs[nodeID] = append(refs[nodeID], r)
+		}
+	}
+
+	return refs, rows.Err()
+}
+
 // LoadLinks loads all links between nodes
 func (d *DB) LoadLinks() ([]Link, error) {
 	rows, err := d.db.Query(`
@@ -139,8 +270,8 @@ func (d *DB) LoadLinks() ([]Link, error) {
 		}
 		l.Type = trimQuotes(linkType)
 		// Clean IDs (remove quotes)
-		l.Source = trimQuotes(l.Source)
-		l.Target = trimQuotes(l.Target)
+		l.Source = cleanID(l.Source)
+		l.Target = cleanID(l.Target)
 		links = append(links, l)
 	}
 
@@ -173,12 +304,38 @@ func trimQuotes(s string) string {
 	return strings.Trim(s, "\"")
 }
 
+// cleanID removes quotes, whitespace and control characters around an ID,
+// so IDs stored with a stray newline still match the links to them
+func cleanID(s string) string {
+	trim := func(r rune) bool { return unicode.IsSpace(r) || unicode.IsControl(r) }
+	s = strings.TrimFunc(s, trim)
+	return strings.TrimFunc(trimQuotes(s), trim)
+}
+
 // cleanTitle removes quotes and unescapes Lisp-style escapes from title
 func cleanTitle(s string) string {
-	s = trimQuotes(s)
+	// Only the outer quotes, as the string may end in an escaped one
+	if len(s) >= 2 && s[0] == '"' && s[len(s)-1] == '"' {
+		s = s[1 : len(s)-1]
+	}
 	// Unescape Lisp-style backslash escapes (e.g., \\pi -> \pi)
-	s = strings.ReplaceAll(s, "\\\\", "\\")
-	return s
+	return elispUnescaper.Replace(s)
+}
+
+// elispUnescaper undoes the backslash escapes of an elisp string
+var elispUnescaper = strings.NewReplacer(`\\`, `\`, `\"`, `"`)
+
+// elispStringRe matches a double-quoted elisp string
+var elispStringRe = regexp.MustCompile(`"((?:[^"\\]|\\.)*)"`)
+
+// parseElispStrings parses an elisp list of strings
+// Example: ("Projects" "Web")
+func parseElispStrings(s string) []string {
+	var list []string
+	for _, m := range elispStringRe.FindAllStringSubmatch(s, -1) {
+		list = append(list, cleanTitle(`"`+m[1]+`"`))
+	}
+	return list
 }
 
 // parseElispProps parses elisp property list format
@@ -0,0 +1,229 @@
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"maps"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestOpenErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		tables  []string // Created before opening, nil for no database file
+		wantErr error
+	}{
+		{"missing", nil, ErrDBNotFound},
+		{"empty", []string{}, ErrSchemaUnsupported},
+		{"no links table", []string{"nodes", "tags"}, ErrSchemaUnsupported},
+		{"org-roam", []string{"nodes", "tags", "links", "files"}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "org-roam.db")
+			if tt.tables != nil {
+				db, err := sql.Open("sqlite3", path)
+				if err != nil {
+					t.Fatal(err)
+				}
+				// Touch the file even with no tables
+				if _, err := db.Exec(`PRAGMA user_version = 1`); err != nil {
+					t.Fatal(err)
+				}
+				for _, table := range tt.tables {
+					if _, err := db.Exec(`CREATE TABLE ` + table + ` (id)`); err != nil {
+						t.Fatal(err)
+					}
+				}
+				db.Close()
+			}
+
+			d, err := Open(path)
+			if err == nil {
+				d.Close()
+			}
+			if tt.wantErr == nil {
+				if err != nil {
+					t.Errorf("Open: %v", err)
+				}
+				return
+			}
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("Open error = %v, want %v", err, tt.wantErr)
+			}
+			for _, other := range []error{ErrDBNotFound, ErrSchemaUnsupported} {
+				if other != tt.wantErr && errors.Is(err, other) {
+					t.Errorf("Open error %v also matches %v", err, other)
+				}
+			}
+		})
+	}
+}
+
+func TestCleanID(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{`"a1"`, "a1"},
+		{`"a1 "`, "a1"},
+		{"\"a1\"\n", "a1"},
+		{"\"\ta1\r\n\"", "a1"},
+		{" \"a1\"\x00", "a1"},
+		{"a1", "a1"},
+		{`"a 1"`, "a 1"},
+		{`""`, ""},
+	}
+	for _, tt := range tests {
+		if got := cleanID(tt.in); got != tt.want {
+			t.Errorf("cleanID(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestCleanTitle(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{`"Alpha"`, "Alpha"},
+		{`Alpha`, "Alpha"},
+		{`"$\\pi$"`, `$\pi$`},
+		{`"Say \"hi\""`, `Say "hi"`},
+		{`"C:\\"`, `C:\`},
+		{`""`, ""},
+	}
+	for _, tt := range tests {
+		if got := cleanTitle(tt.in); got != tt.want {
+			t.Errorf("cleanTitle(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestLoadCleansIDs(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "org-roam.db")
+	sqlDB, err := sql.Open("sqlite3", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// IDs stored with stray whitespace, as some databases have them
+	for _, stmt := range []string{
+		`CREATE TABLE nodes (id, file, level, pos, title, properties, olp)`,
+		`CREATE TABLE tags (node_id, tag)`,
+		`CREATE TABLE links (pos, source, dest, type, properties)`,
+		`INSERT INTO nodes VALUES ('"a1 "', '"/roam/a.org"', 0, 1, '"Alpha"', NULL, NULL)`,
+		`INSERT INTO nodes VALUES ('"b2"' || char(10), '"/roam/b.org"', 0, 1, '"Beta"', NULL, NULL)`,
+		`INSERT INTO tags VALUES (' "a1"', '"go"')`,
+		`INSERT INTO tags VALUES ('"b2"' || char(13, 10), '"web"')`,
+		`INSERT INTO links VALUES (1, '"b2 "', '"a1"' || char(10), '"id"', '()')`,
+		`INSERT INTO links VALUES (1, '"a1"', char(9) || '"b2"', '"id"', '()')`,
+	} {
+		if _, err := sqlDB.Exec(stmt); err != nil {
+			t.Fatalf("%s: %v", stmt, err)
+		}
+	}
+	sqlDB.Close()
+
+	d, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer d.Close()
+
+	nodes, err := d.LoadNodes(false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ids []string
+	for _, n := range nodes {
+		ids = append(ids, n.ID)
+	}
+	slices.Sort(ids)
+	if !slices.Equal(ids, []string{"a1", "b2"}) {
+		t.Errorf("node IDs = %q", ids)
+	}
+
+	tags, err := d.LoadTags()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := map[string][]string{"a1": {"go"}, "b2": {"web"}}; !maps.EqualFunc(tags, want, slices.Equal) {
+		t.Errorf("tags = %q, want %q", tags, want)
+	}
+
+	links, err := d.LoadLinks()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Every link resolves to a node once cleaned
+	for _, l := range links {
+		if !slices.Contains(ids, l.Source) || !slices.Contains(ids, l.Target) {
+			t.Errorf("link %q -> %q doesn't resolve", l.Source, l.Target)
+		}
+	}
+	if len(links) != 2 {
+		t.Errorf("loaded %d links, want 2", len(links))
+	}
+}
+
+func TestLoadNodesHeadings(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "org-roam.db")
+	sqlDB, err := sql.Open("sqlite3", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, stmt := range []string{
+		`CREATE TABLE nodes (id, file, level, pos, title, properties, olp)`,
+		`CREATE TABLE tags (node_id, tag)`,
+		`CREATE TABLE links (pos, source, dest, type, properties)`,
+		`INSERT INTO nodes VALUES ('"h2"', '"/roam/a.org"', 2, 120, '"Inner"', NULL, '("Outer")')`,
+		`INSERT INTO nodes VALUES ('"a1"', '"/roam/a.org"', 0, 1, '"Alpha"', NULL, NULL)`,
+		`INSERT INTO nodes VALUES ('"h1"', '"/roam/a.org"', 1, 40, '"Outer"', NULL, 'nil')`,
+		`INSERT INTO nodes VALUES ('"h3"', '"/roam/a.org"', 3, 200, '"Deep"', NULL, '("Outer" "Inner \"quoted\"")')`,
+	} {
+		if _, err := sqlDB.Exec(stmt); err != nil {
+			t.Fatalf("%s: %v", stmt, err)
+		}
+	}
+	sqlDB.Close()
+
+	d, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer d.Close()
+
+	type node struct {
+		ID    string
+		Level int
+		Pos   int
+		Olp   []string
+	}
+	tests := []struct {
+		headings bool
+		want     []node // In file position order
+	}{
+		{false, []node{{"a1", 0, 1, nil}}},
+		{true, []node{
+			{"a1", 0, 1, nil},
+			{"h1", 1, 40, nil},
+			{"h2", 2, 120, []string{"Outer"}},
+			{"h3", 3, 200, []string{"Outer", `Inner "quoted"`}},
+		}},
+	}
+	for _, tt := range tests {
+		nodes, err := d.LoadNodes(tt.headings)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got []node
+		for _, n := range nodes {
+			got = append(got, node{n.ID, n.Level, n.Pos, n.Olp})
+		}
+		if !slices.EqualFunc(got, tt.want, func(a, b node) bool {
+			return a.ID == b.ID && a.Level == b.Level && a.Pos == b.Pos && slices.Equal(a.Olp, b.Olp)
+		}) {
+			t.Errorf("LoadNodes(%v) = %+v, want %+v", tt.headings, got, tt.want)
+		}
+	}
+}
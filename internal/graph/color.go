@@ -0,0 +1,42 @@
+package graph
+
+import (
+	"hash/fnv"
+)
+
+// TagPalette is the palette tags are colored from (d3's Tableau10)
+var TagPalette = []string{
+	"#4e79a7", "#f28e2c", "#e15759", "#76b7b2", "#59a14f",
+	"#edc949", "#af7aa1", "#ff9da7", "#9c755f", "#bab0ab",
+}
+
+// TagColor returns the palette color for a tag, derived from a hash of its
+// name so it is the same on every build. A non-empty seed (display.seed)
+// is hashed along with the name, picking another color for each tag.
+func TagColor(tag, seed string) string {
+	h := fnv.New32a()
+	if seed != "" {
+		h.Write([]byte(seed))
+		h.Write([]byte{0})
+	}
+	h.Write([]byte(tag))
+	return TagPalette[h.Sum32()%uint32(len(TagPalette))]
+}
+
+// AssignTagColors sets a color for every tag used by the graph's nodes,
+// taking it from overrides when present and from TagColor otherwise
+func (g *Graph) AssignTagColors(overrides map[string]string, seed string) {
+	g.TagColors = make(map[string]string)
+	for _, n := range g.Nodes {
+		for _, tag := range n.Tags {
+			if _, ok := g.TagColors[tag]; ok {
+				continue
+			}
+			if c, ok := overrides[tag]; ok && c != "" {
+				g.TagColors[tag] = c
+			} else {
+				g.TagColors[tag] = TagColor(tag, seed)
+			}
+		}
+	}
+}
@@ -0,0 +1,88 @@
+package graph
+
+import (
+	"maps"
+	"slices"
+	"testing"
+
+	"github.com/nicehiro/org-roam-web/internal/db"
+)
+
+func TestTagColor(t *testing.T) {
+	for _, seed := range []string{"", "42", "spring"} {
+		for _, tag := range []string{"go", "web", "private", "", "日本語"} {
+			got := TagColor(tag, seed)
+			if !slices.Contains(TagPalette, got) {
+				t.Errorf("TagColor(%q, %q) = %q, not in the palette", tag, seed, got)
+			}
+			for range 3 {
+				if again := TagColor(tag, seed); again != got {
+					t.Errorf("TagColor(%q, %q) = %q, then %q", tag, seed, got, again)
+				}
+			}
+		}
+	}
+}
+
+func TestTagColorSeed(t *testing.T) {
+	tags := []string{"go", "web", "math", "lang", "private", "draft", "ideas", "books"}
+	colors := func(seed string) []string {
+		var cs []string
+		for _, tag := range tags {
+			cs = append(cs, TagColor(tag, seed))
+		}
+		return cs
+	}
+
+	// The palette without a seed is the one from before seeds existed
+	if got := TagColor("go", ""); got != TagPalette[7] {
+		t.Errorf("unseeded TagColor(go) = %s, want %s", got, TagPalette[7])
+	}
+	if slices.Equal(colors(""), colors("42")) {
+		t.Error("seed 42 colors every tag as without a seed")
+	}
+	if !slices.Equal(colors("42"), colors("42")) {
+		t.Error("the same seed gives different colors")
+	}
+}
+
+func TestAssignTagColors(t *testing.T) {
+	nodes, links := testGraph("a-b", "b-c")
+	tags := map[string][]string{"a": {"go"}, "b": {"go", "web"}, "c": {"math"}}
+
+	tests := []struct {
+		name      string
+		overrides map[string]string
+		want      map[string]string
+	}{
+		{
+			"hashed",
+			nil,
+			map[string]string{"go": TagColor("go", ""), "web": TagColor("web", ""), "math": TagColor("math", "")},
+		},
+		{
+			"override",
+			map[string]string{"web": "#000000", "unused": "#ffffff"},
+			map[string]string{"go": TagColor("go", ""), "web": "#000000", "math": TagColor("math", "")},
+		},
+		{
+			"empty override",
+			map[string]string{"go": ""},
+			map[string]string{"go": TagColor("go", ""), "web": TagColor("web", ""), "math": TagColor("math", "")},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reversed := slices.Clone(nodes)
+			slices.Reverse(reversed)
+			// Node order mustn't change the colors
+			for _, ns := range [][]db.Node{nodes, reversed} {
+				g := BuildGraph(ns, links, tags)
+				g.AssignTagColors(tt.overrides, "")
+				if !maps.Equal(g.TagColors, tt.want) {
+					t.Errorf("tag colors = %v, want %v", g.TagColors, tt.want)
+				}
+			}
+		})
+	}
+}
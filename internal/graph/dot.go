@@ -0,0 +1,30 @@
+package graph
+
+import (
+	"strings"
+)
+
+// dotEscaper escapes a string for use inside a double-quoted DOT ID.
+// Backslashes are doubled so that GraphViz doesn't read "\n", "\l" or
+// LaTeX such as "\pi" as escape sequences.
+var dotEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "\r", "")
+
+// dotQuote returns s as a double-quoted DOT ID
+func dotQuote(s string) string {
+	return `"` + dotEscaper.Replace(s) + `"`
+}
+
+// ToDOT converts the graph to a GraphViz digraph. Nodes are identified by
+// their ID and labelled with their title; each link is one edge.
+func (g *Graph) ToDOT() []byte {
+	var b strings.Builder
+	b.WriteString("digraph \"org-roam\" {\n")
+	for _, n := range g.Nodes {
+		b.WriteString("  " + dotQuote(n.ID) + " [label=" + dotQuote(n.Title) + "];\n")
+	}
+	for _, l := range g.Links {
+		b.WriteString("  " + dotQuote(l.Source) + " -> " + dotQuote(l.Target) + ";\n")
+	}
+	b.WriteString("}\n")
+	return []byte(b.String())
+}
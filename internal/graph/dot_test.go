@@ -0,0 +1,64 @@
+package graph
+
+import "testing"
+
+func TestDotQuote(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"Alpha", `"Alpha"`},
+		{`a "b" \c`, `"a \"b\" \\c"`},
+		{`$\alpha$`, `"$\\alpha$"`},
+		{`\n is not a newline`, `"\\n is not a newline"`},
+		{"two\nlines", `"two\nlines"`},
+		{"windows\r\nline", `"windows\nline"`},
+		{`ends in \`, `"ends in \\"`},
+		{`"`, `"\""`},
+		{"", `""`},
+	}
+	for _, tt := range tests {
+		if got := dotQuote(tt.in); got != tt.want {
+			t.Errorf("dotQuote(%q) = %s, want %s", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestToDOT(t *testing.T) {
+	tests := []struct {
+		name  string
+		graph *Graph
+		want  string
+	}{
+		{
+			name:  "empty",
+			graph: &Graph{},
+			want:  "digraph \"org-roam\" {\n}\n",
+		},
+		{
+			name: "quoted titles and IDs",
+			graph: &Graph{
+				Nodes: []GraphNode{
+					{ID: "a1", Title: `a "b" \c`},
+					{ID: `b"2`, Title: `$\alpha$`},
+					{ID: "c3", Title: "line\nbreak"},
+				},
+				Links: []GraphLink{{Source: "a1", Target: `b"2`}, {Source: `b"2`, Target: "c3"}},
+			},
+			want: `digraph "org-roam" {
+  "a1" [label="a \"b\" \\c"];
+  "b\"2" [label="$\\alpha$"];
+  "c3" [label="line\nbreak"];
+  "a1" -> "b\"2";
+  "b\"2" -> "c3";
+}
+`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := string(tt.graph.ToDOT()); got != tt.want {
+				t.Errorf("ToDOT() =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,91 @@
+package graph
+
+import (
+	"encoding/xml"
+	"testing"
+	"time"
+)
+
+func TestToGEXF(t *testing.T) {
+	nodes, links := testGraph("a-b", "b-a", "b-c", "d")
+	tags := map[string][]string{"a": {"go", "web"}, "b": {"go"}}
+	date := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name  string
+		dates map[string]time.Time
+		mode  string
+	}{
+		{"static", nil, "static"},
+		{"dynamic", map[string]time.Time{"a": date, "c": date.AddDate(0, 1, 0)}, "dynamic"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := BuildGraph(nodes, links, tags)
+			if tt.dates != nil {
+				g.SetDates(func(id string) time.Time { return tt.dates[id] })
+			}
+			data, err := g.ToGEXF()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var doc gexfDoc
+			if err := xml.Unmarshal(data, &doc); err != nil {
+				t.Fatalf("invalid GEXF: %v\n%s", err, data)
+			}
+			if doc.XMLName.Space != "http://gexf.net/1.3" || doc.Version != "1.3" {
+				t.Errorf("gexf namespace %q version %q", doc.XMLName.Space, doc.Version)
+			}
+			if len(doc.Graph.Nodes) != 4 || len(doc.Graph.Edges) != 3 {
+				t.Errorf("%d nodes and %d edges, want 4 and 3", len(doc.Graph.Nodes), len(doc.Graph.Edges))
+			}
+			if doc.Graph.Mode != tt.mode {
+				t.Errorf("mode %q, want %q", doc.Graph.Mode, tt.mode)
+			}
+			if (doc.Graph.TimeFormat == "date") != (tt.dates != nil) {
+				t.Errorf("timeformat %q for dates %v", doc.Graph.TimeFormat, tt.dates)
+			}
+
+			byID := make(map[string]gexfNode)
+			for _, n := range doc.Graph.Nodes {
+				byID[n.ID] = n
+			}
+			attrs := func(id string) map[string]string {
+				m := make(map[string]string)
+				for _, v := range byID[id].AttValues {
+					m[v.For] = v.Value
+				}
+				return m
+			}
+			if a := attrs("a"); a[gexfAttrTitle] != "A" || a[gexfAttrTags] != "go|web" || a[gexfAttrLinkCount] != "2" {
+				t.Errorf("attributes of a = %v", a)
+			}
+			if b := attrs("b"); b[gexfAttrLinkCount] != "3" {
+				t.Errorf("link count of b = %s, want 3", b[gexfAttrLinkCount])
+			}
+			if byID["a"].Label != "A" {
+				t.Errorf("label of a = %q", byID["a"].Label)
+			}
+			for id, want := range map[string]string{"a": "2024-03-01", "b": "", "c": "2024-04-01"} {
+				if tt.dates == nil {
+					want = ""
+				}
+				if byID[id].Start != want {
+					t.Errorf("start of %s = %q, want %q", id, byID[id].Start, want)
+				}
+			}
+
+			ids := make(map[string]bool)
+			for _, e := range doc.Graph.Edges {
+				if ids[e.ID] {
+					t.Errorf("duplicate edge ID %s", e.ID)
+				}
+				ids[e.ID] = true
+				if byID[e.Source].ID == "" || byID[e.Target].ID == "" {
+					t.Errorf("edge %s-%s to a missing node", e.Source, e.Target)
+				}
+			}
+		})
+	}
+}
@@ -2,28 +2,46 @@ package graph
 
 import (
 	"encoding/json"
+	"sort"
+	"time"
 
 	"github.com/nicehiro/org-roam-web/internal/db"
 )
 
 // Graph represents the note graph
 type Graph struct {
-	Nodes []GraphNode `json:"nodes"`
-	Links []GraphLink `json:"links"`
+	Nodes     []GraphNode `json:"nodes"`
+	Links     []GraphLink `json:"links"`
+	Truncated bool        `json:"truncated,omitempty"` // Local graph hit its node cap
+
+	TagColors map[string]string `json:"tagColors,omitempty"` // Tag -> CSS color
+
+	StatusColors map[string]string `json:"statusColors,omitempty"` // Status -> CSS color
+
+	Directed bool `json:"directed"` // Draw links as arrows from source to target
 }
 
 // GraphNode represents a node in the graph
 type GraphNode struct {
-	ID       string   `json:"id"`
-	Title    string   `json:"title"`
-	Tags     []string `json:"tags"`
-	LinkCount int     `json:"linkCount"`
+	ID        string   `json:"id"`
+	Title     string   `json:"title"`
+	Tags      []string `json:"tags"`
+	LinkCount int      `json:"linkCount"`
+	URL       string   `json:"url,omitempty"`
+	Recent    bool     `json:"recent,omitempty"`
+	Status    string   `json:"status,omitempty"`
+	Hub       bool     `json:"hub,omitempty"` // Over the hub threshold, drawn dimmed
+	Aliases   []string `json:"aliases,omitempty"`
+
+	date time.Time // Set by SetDates for GEXF export
 }
 
 // GraphLink represents a link in the graph
 type GraphLink struct {
 	Source string `json:"source"`
 	Target string `json:"target"`
+
+	Bidirectional bool `json:"bidirectional,omitempty"` // Merged with the reverse link
 }
 
 // BuildGraph creates a graph from nodes and links
@@ -76,13 +94,218 @@ func BuildGraph(nodes []db.Node, links []db.Link, nodeTags map[string][]string)
 	return g
 }
 
+// SetURLs fills in each node's page URL using urlFor
+func (g *Graph) SetURLs(urlFor func(id string) string) {
+	for i := range g.Nodes {
+		g.Nodes[i].URL = urlFor(g.Nodes[i].ID)
+	}
+}
+
+// MarkRecent flags the nodes for which isRecent returns true
+func (g *Graph) MarkRecent(isRecent func(id string) bool) {
+	for i := range g.Nodes {
+		g.Nodes[i].Recent = isRecent(g.Nodes[i].ID)
+	}
+}
+
+// RemoveHubs drops nodes with more than maxLinks links, along with their
+// links, and recounts the links of the remaining nodes
+func (g *Graph) RemoveHubs(maxLinks int) {
+	hubs := make(map[string]bool)
+	nodes := g.Nodes[:0]
+	for _, n := range g.Nodes {
+		if n.LinkCount > maxLinks {
+			hubs[n.ID] = true
+			continue
+		}
+		nodes = append(nodes, n)
+	}
+	g.Nodes = nodes
+
+	linkCount := make(map[string]int)
+	links := g.Links[:0]
+	for _, l := range g.Links {
+		if hubs[l.Source] || hubs[l.Target] {
+			continue
+		}
+		links = append(links, l)
+		linkCount[l.Source]++
+		linkCount[l.Target]++
+	}
+	g.Links = links
+
+	for i := range g.Nodes {
+		g.Nodes[i].LinkCount = linkCount[g.Nodes[i].ID]
+	}
+}
+
+// Preview returns a copy of the graph reduced to its n most-linked nodes
+// (ties broken by ID) and the links among them. Link counts keep their
+// values from the full graph.
+func (g *Graph) Preview(n int) *Graph {
+	nodes := append([]GraphNode(nil), g.Nodes...)
+	sort.SliceStable(nodes, func(i, j int) bool {
+		if nodes[i].LinkCount != nodes[j].LinkCount {
+			return nodes[i].LinkCount > nodes[j].LinkCount
+		}
+		return nodes[i].ID < nodes[j].ID
+	})
+	if n < len(nodes) {
+		nodes = nodes[:n]
+	}
+
+	kept := make(map[string]bool, len(nodes))
+	for _, node := range nodes {
+		kept[node.ID] = true
+	}
+	links := make([]GraphLink, 0)
+	for _, l := range g.Links {
+		if kept[l.Source] && kept[l.Target] {
+			links = append(links, l)
+		}
+	}
+
+	return &Graph{
+		Nodes:        nodes,
+		Links:        links,
+		Truncated:    len(nodes) < len(g.Nodes),
+		TagColors:    g.TagColors,
+		StatusColors: g.StatusColors,
+		Directed:     g.Directed,
+	}
+}
+
+// MergeReciprocal collapses links between the same two notes, in either
+// direction, into one. Links that had a reverse counterpart are flagged as
+// bidirectional. Link counts are left as they are.
+func (g *Graph) MergeReciprocal() {
+	type pair struct{ a, b string }
+	index := make(map[pair]int)
+	links := make([]GraphLink, 0, len(g.Links))
+	for _, l := range g.Links {
+		if i, ok := index[pair{l.Source, l.Target}]; ok {
+			if links[i].Source != l.Source {
+				links[i].Bidirectional = true
+			}
+			continue
+		}
+		index[pair{l.Source, l.Target}] = len(links)
+		index[pair{l.Target, l.Source}] = len(links)
+		links = append(links, l)
+	}
+	g.Links = links
+}
+
+// MarkHubs flags nodes with more than maxLinks links as hubs
+func (g *Graph) MarkHubs(maxLinks int) {
+	for i := range g.Nodes {
+		g.Nodes[i].Hub = g.Nodes[i].LinkCount > maxLinks
+	}
+}
+
+// SetStatuses fills in each node's status using statusOf
+func (g *Graph) SetStatuses(statusOf func(id string) string) {
+	for i := range g.Nodes {
+		g.Nodes[i].Status = statusOf(g.Nodes[i].ID)
+	}
+}
+
+// SetAliases fills in each node's ROAM_ALIASES using aliasesOf
+func (g *Graph) SetAliases(aliasesOf func(id string) []string) {
+	for i := range g.Nodes {
+		g.Nodes[i].Aliases = aliasesOf(g.Nodes[i].ID)
+	}
+}
+
 // ToJSON converts the graph to JSON
 func (g *Graph) ToJSON() ([]byte, error) {
 	return json.MarshalIndent(g, "", "  ")
 }
 
-// LocalGraph creates a subgraph around a specific node
-func LocalGraph(nodeID string, depth int, nodes []db.Node, links []db.Link, nodeTags map[string][]string) *Graph {
+// ComponentOf returns the IDs in the connected component containing seedID,
+// following links in either direction. A seed without links forms a
+// component of its own.
+func ComponentOf(seedID string, links []db.Link) map[string]bool {
+	adjacency := make(map[string][]string)
+	for _, l := range links {
+		adjacency[l.Source] = append(adjacency[l.Source], l.Target)
+		adjacency[l.Target] = append(adjacency[l.Target], l.Source)
+	}
+
+	component := map[string]bool{seedID: true}
+	stack := []string{seedID}
+	for len(stack) > 0 {
+		id := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		for _, neighbor := range adjacency[id] {
+			if !component[neighbor] {
+				component[neighbor] = true
+				stack = append(stack, neighbor)
+			}
+		}
+	}
+	return component
+}
+
+// Orphans returns the IDs of the nodes with no links to or from another of
+// the nodes, in node order. Links to nodes outside the set don't count.
+func Orphans(nodes []db.Node, links []db.Link) []string {
+	ids := make(map[string]bool, len(nodes))
+	for _, n := range nodes {
+		ids[n.ID] = true
+	}
+	linked := make(map[string]bool)
+	for _, l := range links {
+		if ids[l.Source] && ids[l.Target] && l.Source != l.Target {
+			linked[l.Source] = true
+			linked[l.Target] = true
+		}
+	}
+
+	var orphans []string
+	for _, n := range nodes {
+		if !linked[n.ID] {
+			orphans = append(orphans, n.ID)
+		}
+	}
+	return orphans
+}
+
+// ToCompactJSON converts the graph to unindented JSON for embedding in
+// pages. Link counts are dropped and empty tag lists omitted, as the
+// embedded views don't use them.
+func (g *Graph) ToCompactJSON() ([]byte, error) {
+	type compactNode struct {
+		ID     string   `json:"id"`
+		Title  string   `json:"title"`
+		Tags   []string `json:"tags,omitempty"`
+		URL    string   `json:"url,omitempty"`
+		Recent bool     `json:"recent,omitempty"`
+	}
+
+	nodes := make([]compactNode, 0, len(g.Nodes))
+	for _, n := range g.Nodes {
+		nodes = append(nodes, compactNode{
+			ID:     n.ID,
+			Title:  n.Title,
+			Tags:   n.Tags,
+			URL:    n.URL,
+			Recent: n.Recent,
+		})
+	}
+
+	return json.Marshal(struct {
+		Nodes     []compactNode `json:"nodes"`
+		Links     []GraphLink   `json:"links"`
+		Truncated bool          `json:"truncated,omitempty"`
+	}{nodes, g.Links, g.Truncated})
+}
+
+// LocalGraph creates a subgraph around a specific node. When maxNodes is
+// positive the subgraph holds at most that many nodes, preferring closer
+// and then better-connected neighbors, and is marked Truncated if any
+// reachable node was left out.
+func LocalGraph(nodeID string, depth, maxNodes int, nodes []db.Node, links []db.Link, nodeTags map[string][]string) *Graph {
 	// Build adjacency list
 	adjacency := make(map[string][]string)
 	for _, l := range links {
@@ -90,6 +313,16 @@ func LocalGraph(nodeID string, depth int, nodes []db.Node, links []db.Link, node
 		adjacency[l.Target] = append(adjacency[l.Target], l.Source)
 	}
 
+	// Expand high-degree neighbors first so a cap keeps the hubs
+	for id, neighbors := range adjacency {
+		sort.SliceStable(neighbors, func(i, j int) bool {
+			return len(adjacency[neighbors[i]]) > len(adjacency[neighbors[j]])
+		})
+		adjacency[id] = neighbors
+	}
+
+	truncated := false
+
 	// BFS to find nodes within depth
 	visited := make(map[string]bool)
 	queue := []struct {
@@ -108,6 +341,10 @@ func LocalGraph(nodeID string, depth int, nodes []db.Node, links []db.Link, node
 
 		for _, neighbor := range adjacency[curr.id] {
 			if !visited[neighbor] {
+				if maxNodes > 0 && len(visited) >= maxNodes {
+					truncated = true
+					break
+				}
 				visited[neighbor] = true
 				queue = append(queue, struct {
 					id    string
@@ -117,16 +354,11 @@ func LocalGraph(nodeID string, depth int, nodes []db.Node, links []db.Link, node
 		}
 	}
 
-	// Build node map for quick lookup
-	nodeMap := make(map[string]db.Node)
-	for _, n := range nodes {
-		nodeMap[n.ID] = n
-	}
-
 	// Create subgraph
 	g := &Graph{
-		Nodes: make([]GraphNode, 0),
-		Links: make([]GraphLink, 0),
+		Nodes:     make([]GraphNode, 0),
+		Links:     make([]GraphLink, 0),
+		Truncated: truncated,
 	}
 
 	// Count links for local nodes
@@ -138,10 +370,10 @@ func LocalGraph(nodeID string, depth int, nodes []db.Node, links []db.Link, node
 		}
 	}
 
-	// Add visited nodes
-	for id := range visited {
-		if n, ok := nodeMap[id]; ok {
-			tags := nodeTags[id]
+	// Add visited nodes, in input order so builds are reproducible
+	for _, n := range nodes {
+		if visited[n.ID] {
+			tags := nodeTags[n.ID]
 			if tags == nil {
 				tags = []string{}
 			}
@@ -149,7 +381,7 @@ func LocalGraph(nodeID string, depth int, nodes []db.Node, links []db.Link, node
 				ID:        n.ID,
 				Title:     n.Title,
 				Tags:      tags,
-				LinkCount: linkCount[id],
+				LinkCount: linkCount[n.ID],
 			})
 		}
 	}
@@ -0,0 +1,353 @@
+package graph
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/nicehiro/org-roam-web/internal/db"
+)
+
+// testGraph returns the nodes and links of a graph given as "a-b" pairs,
+// with nodes in order of first mention
+func testGraph(pairs ...string) ([]db.Node, []db.Link) {
+	var nodes []db.Node
+	var links []db.Link
+	seen := make(map[string]bool)
+	for _, pair := range pairs {
+		source, target, _ := strings.Cut(pair, "-")
+		for _, id := range []string{source, target} {
+			if id != "" && !seen[id] {
+				seen[id] = true
+				nodes = append(nodes, db.Node{ID: id, Title: strings.ToUpper(id)})
+			}
+		}
+		if target != "" {
+			links = append(links, db.Link{Source: source, Target: target, Type: "id"})
+		}
+	}
+	return nodes, links
+}
+
+// nodeIDs returns the sorted IDs of a graph's nodes
+func nodeIDs(g *Graph) []string {
+	var ids []string
+	for _, n := range g.Nodes {
+		ids = append(ids, n.ID)
+	}
+	slices.Sort(ids)
+	return ids
+}
+
+func TestLocalGraphMaxNodes(t *testing.T) {
+	// a is the best-connected neighbor of h, then b, then c; o is an orphan
+	nodes, links := testGraph("h-a", "h-b", "h-c", "a-x", "a-y", "a-z", "b-x", "o")
+
+	tests := []struct {
+		name      string
+		root      string
+		depth     int
+		maxNodes  int
+		want      []string
+		truncated bool
+	}{
+		{"uncapped", "h", 1, 0, []string{"a", "b", "c", "h"}, false},
+		{"cap equal to size", "h", 1, 4, []string{"a", "b", "c", "h"}, false},
+		{"cap keeps best connected", "h", 1, 3, []string{"a", "b", "h"}, true},
+		{"uncapped depth 2", "h", 2, 0, []string{"a", "b", "c", "h", "x", "y", "z"}, false},
+		{"cap keeps closer nodes", "h", 2, 5, []string{"a", "b", "c", "h", "x"}, true},
+		{"cap of one", "h", 2, 1, []string{"h"}, true},
+		{"orphan", "o", 2, 1, []string{"o"}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := LocalGraph(tt.root, tt.depth, tt.maxNodes, nodes, links, nil)
+			if got := nodeIDs(g); !slices.Equal(got, tt.want) {
+				t.Errorf("nodes = %v, want %v", got, tt.want)
+			}
+			if tt.maxNodes > 0 && len(g.Nodes) > tt.maxNodes {
+				t.Errorf("%d nodes over the cap of %d", len(g.Nodes), tt.maxNodes)
+			}
+			if g.Truncated != tt.truncated {
+				t.Errorf("Truncated = %t, want %t", g.Truncated, tt.truncated)
+			}
+			for _, l := range g.Links {
+				if !slices.Contains(tt.want, l.Source) || !slices.Contains(tt.want, l.Target) {
+					t.Errorf("link %s-%s leaves the local graph", l.Source, l.Target)
+				}
+			}
+		})
+	}
+}
+
+func TestComponentOf(t *testing.T) {
+	// Two components, a-b-c (linked in both directions) and d-e, plus an
+	// orphan o
+	_, links := testGraph("a-b", "c-b", "d-e", "e-d")
+
+	tests := []struct {
+		seed string
+		want []string
+	}{
+		{"a", []string{"a", "b", "c"}},
+		{"c", []string{"a", "b", "c"}},
+		{"e", []string{"d", "e"}},
+		{"o", []string{"o"}},
+	}
+	for _, tt := range tests {
+		var got []string
+		for id := range ComponentOf(tt.seed, links) {
+			got = append(got, id)
+		}
+		slices.Sort(got)
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("ComponentOf(%s) = %v, want %v", tt.seed, got, tt.want)
+		}
+	}
+}
+
+func TestToCompactJSON(t *testing.T) {
+	nodes, links := testGraph("a-b", "b-c", "c-a", "d-a", "o")
+	tags := map[string][]string{"a": {"go"}, "b": {"go", "web"}}
+	tests := []struct {
+		name string
+		g    *Graph
+	}{
+		{"global", BuildGraph(nodes, links, tags)},
+		{"local", LocalGraph("a", 1, 0, nodes, links, tags)},
+		{"truncated", LocalGraph("a", 1, 2, nodes, links, tags)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			standalone, err := tt.g.ToJSON()
+			if err != nil {
+				t.Fatal(err)
+			}
+			embedded, err := tt.g.ToCompactJSON()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(embedded) >= len(standalone) {
+				t.Errorf("embedded JSON is %d bytes, standalone %d", len(embedded), len(standalone))
+			}
+			if strings.Contains(string(embedded), "\n") || strings.Contains(string(embedded), "  ") {
+				t.Errorf("embedded JSON is indented: %s", embedded)
+			}
+
+			var full, compact struct {
+				Nodes     []map[string]any `json:"nodes"`
+				Links     []GraphLink      `json:"links"`
+				Truncated bool             `json:"truncated"`
+			}
+			if err := json.Unmarshal(standalone, &full); err != nil {
+				t.Fatalf("standalone JSON: %v", err)
+			}
+			if err := json.Unmarshal(embedded, &compact); err != nil {
+				t.Fatalf("embedded JSON: %v", err)
+			}
+			if len(compact.Nodes) != len(full.Nodes) || !slices.Equal(compact.Links, full.Links) || compact.Truncated != tt.g.Truncated {
+				t.Errorf("embedded graph %s differs from standalone %s", embedded, standalone)
+			}
+			for i, n := range compact.Nodes {
+				if n["id"] != full.Nodes[i]["id"] || n["title"] != full.Nodes[i]["title"] {
+					t.Errorf("embedded node %v, standalone %v", n, full.Nodes[i])
+				}
+				if _, ok := n["linkCount"]; ok {
+					t.Errorf("embedded node %v has a link count", n)
+				}
+				if tags, ok := n["tags"]; ok && len(tags.([]any)) == 0 {
+					t.Errorf("embedded node %v has empty tags", n)
+				}
+				if _, ok := full.Nodes[i]["tags"]; !ok {
+					t.Errorf("standalone node %v lacks tags", full.Nodes[i])
+				}
+			}
+		})
+	}
+}
+
+func TestHubs(t *testing.T) {
+	// h is linked to everything, a and b to each other too
+	nodes, links := testGraph("h-a", "h-b", "h-c", "a-b")
+	tests := []struct {
+		name      string
+		max       int
+		dim       bool
+		wantNodes []string
+		wantLinks int
+		wantHubs  []string // Flagged when dimming
+	}{
+		{"remove", 2, false, []string{"a", "b", "c"}, 1, nil},
+		{"remove none", 3, false, []string{"a", "b", "c", "h"}, 4, nil},
+		{"remove all linked", 0, false, nil, 0, nil},
+		{"dim", 2, true, []string{"a", "b", "c", "h"}, 4, []string{"h"}},
+		{"dim more", 1, true, []string{"a", "b", "c", "h"}, 4, []string{"a", "b", "h"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := BuildGraph(nodes, links, nil)
+			if tt.dim {
+				g.MarkHubs(tt.max)
+			} else {
+				g.RemoveHubs(tt.max)
+			}
+
+			if got := nodeIDs(g); !slices.Equal(got, tt.wantNodes) {
+				t.Errorf("nodes = %v, want %v", got, tt.wantNodes)
+			}
+			if len(g.Links) != tt.wantLinks {
+				t.Errorf("%d links, want %d", len(g.Links), tt.wantLinks)
+			}
+			present := make(map[string]bool)
+			counts := make(map[string]int)
+			for _, n := range g.Nodes {
+				present[n.ID] = true
+			}
+			for _, l := range g.Links {
+				if !present[l.Source] || !present[l.Target] {
+					t.Errorf("link %s-%s to a removed node", l.Source, l.Target)
+				}
+				counts[l.Source]++
+				counts[l.Target]++
+			}
+			var hubs []string
+			for _, n := range g.Nodes {
+				if n.LinkCount != counts[n.ID] {
+					t.Errorf("%s: linkCount %d, has %d links", n.ID, n.LinkCount, counts[n.ID])
+				}
+				if n.Hub {
+					hubs = append(hubs, n.ID)
+				}
+			}
+			slices.Sort(hubs)
+			if !slices.Equal(hubs, tt.wantHubs) {
+				t.Errorf("hubs = %v, want %v", hubs, tt.wantHubs)
+			}
+		})
+	}
+}
+
+func BenchmarkBuildGraph(b *testing.B) {
+	for _, size := range []int{1000, 10000, 100000} {
+		b.Run(fmt.Sprint(size), func(b *testing.B) {
+			nodes := make([]db.Node, size)
+			tags := make(map[string][]string, size)
+			for i := range nodes {
+				id := fmt.Sprintf("n%d", i)
+				nodes[i] = db.Node{ID: id, Title: "Note " + id}
+				tags[id] = []string{fmt.Sprintf("tag%d", i%20)}
+			}
+			// Five links per note, a few to hubs
+			links := make([]db.Link, 0, 5*size)
+			for i := range nodes {
+				for j := 1; j <= 4; j++ {
+					links = append(links, db.Link{Source: nodes[i].ID, Target: nodes[(i*7+j)%size].ID, Type: "id"})
+				}
+				links = append(links, db.Link{Source: nodes[i].ID, Target: nodes[i%10].ID, Type: "id"})
+			}
+
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				BuildGraph(nodes, links, tags)
+			}
+		})
+	}
+}
+
+func TestPreview(t *testing.T) {
+	// Link counts: h 4, a 3, b 3, c 2, d 1, e 1
+	nodes, links := testGraph("h-a", "h-b", "h-c", "h-d", "a-b", "a-c", "b-e")
+	tests := []struct {
+		n         int
+		wantNodes []string // In order
+		wantLinks []string
+	}{
+		{1, []string{"h"}, nil},
+		{3, []string{"h", "a", "b"}, []string{"h-a", "h-b", "a-b"}},
+		{4, []string{"h", "a", "b", "c"}, []string{"h-a", "h-b", "h-c", "a-b", "a-c"}},
+		{10, []string{"h", "a", "b", "c", "d", "e"}, []string{"h-a", "h-b", "h-c", "h-d", "a-b", "a-c", "b-e"}},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprint(tt.n), func(t *testing.T) {
+			g := BuildGraph(nodes, links, nil)
+			g.Directed = true
+			g.TagColors = map[string]string{"go": "#000000"}
+			p := g.Preview(tt.n)
+
+			var ids []string
+			for _, n := range p.Nodes {
+				ids = append(ids, n.ID)
+			}
+			if !slices.Equal(ids, tt.wantNodes) {
+				t.Errorf("nodes = %v, want %v", ids, tt.wantNodes)
+			}
+			var got []string
+			for _, l := range p.Links {
+				got = append(got, l.Source+"-"+l.Target)
+			}
+			if !slices.Equal(got, tt.wantLinks) {
+				t.Errorf("links = %v, want %v", got, tt.wantLinks)
+			}
+			if p.Truncated != (tt.n < len(nodes)) {
+				t.Errorf("truncated = %t", p.Truncated)
+			}
+			if !p.Directed || p.TagColors["go"] == "" {
+				t.Errorf("preview lost graph settings: directed %t, tag colors %v", p.Directed, p.TagColors)
+			}
+			if p.Nodes[0].LinkCount != 4 {
+				t.Errorf("h has link count %d in the preview, want 4 as in the full graph", p.Nodes[0].LinkCount)
+			}
+			if len(g.Nodes) != len(nodes) || len(g.Links) != len(links) {
+				t.Error("Preview changed the full graph")
+			}
+		})
+	}
+}
+
+func TestMergeReciprocal(t *testing.T) {
+	nodes, links := testGraph("a-b", "b-a", "b-c", "a-b", "c-d", "d-c", "d-c")
+	g := BuildGraph(nodes, links, nil)
+	g.MergeReciprocal()
+
+	var got []string
+	for _, l := range g.Links {
+		s := l.Source + "-" + l.Target
+		if l.Bidirectional {
+			s += " both"
+		}
+		got = append(got, s)
+	}
+	want := []string{"a-b both", "b-c", "c-d both"}
+	if !slices.Equal(got, want) {
+		t.Errorf("merged links = %v, want %v", got, want)
+	}
+}
+
+func TestOrphans(t *testing.T) {
+	tests := []struct {
+		name  string
+		pairs []string
+		drop  []string // Nodes filtered out before the check
+		want  []string
+	}{
+		{"none", []string{"a-b", "b-c"}, nil, nil},
+		{"lone nodes", []string{"a-b", "c", "d"}, nil, []string{"c", "d"}},
+		{"incoming only", []string{"a-b", "c-a"}, nil, nil},
+		{"self link", []string{"a-a", "b-c"}, nil, []string{"a"}},
+		{"link to a filtered node", []string{"a-b", "c-d"}, []string{"d"}, []string{"c"}},
+		{"filtered orphan", []string{"a-b", "c"}, []string{"c"}, nil},
+		{"empty", nil, nil, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			nodes, links := testGraph(tt.pairs...)
+			nodes = slices.DeleteFunc(nodes, func(n db.Node) bool { return slices.Contains(tt.drop, n.ID) })
+			if got := Orphans(nodes, links); !slices.Equal(got, tt.want) {
+				t.Errorf("Orphans = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
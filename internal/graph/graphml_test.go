@@ -0,0 +1,162 @@
+package graph
+
+import (
+	"encoding/xml"
+	"strings"
+	"testing"
+)
+
+const graphMLNS = "http://graphml.graphdrawing.org/xmlns"
+
+// graphMLChildren returns the names of the elements directly under the root
+// of a GraphML document, in order
+func graphMLChildren(t *testing.T, data []byte) []string {
+	t.Helper()
+	dec := xml.NewDecoder(strings.NewReader(string(data)))
+	var names []string
+	depth := 0
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			break
+		}
+		switch el := tok.(type) {
+		case xml.StartElement:
+			if el.Name.Space != graphMLNS {
+				t.Errorf("element %s outside the GraphML namespace", el.Name.Local)
+			}
+			if depth == 1 {
+				names = append(names, el.Name.Local)
+			}
+			depth++
+		case xml.EndElement:
+			depth--
+		}
+	}
+	return names
+}
+
+func TestToGraphML(t *testing.T) {
+	tests := []struct {
+		name      string
+		pairs     []string
+		tags      map[string][]string
+		titles    map[string]string // Replacing the default titles
+		wantNodes int
+		wantEdges int
+		wantData  map[string]map[string]string // Node -> key -> value
+	}{
+		{
+			name:      "tagged",
+			pairs:     []string{"a-b", "b-a", "b-c", "d"},
+			tags:      map[string][]string{"a": {"go", "web"}, "b": {"go"}},
+			wantNodes: 4,
+			wantEdges: 3,
+			wantData: map[string]map[string]string{
+				"a": {"title": "A", "tags": "go,web", "linkCount": "2"},
+				"b": {"title": "B", "tags": "go", "linkCount": "3"},
+				"d": {"title": "D", "tags": "", "linkCount": "0"},
+			},
+		},
+		{
+			name:      "no tags anywhere",
+			pairs:     []string{"a-b"},
+			wantNodes: 2,
+			wantEdges: 1,
+			wantData: map[string]map[string]string{
+				"a": {"title": "A", "tags": "", "linkCount": "1"},
+			},
+		},
+		{
+			name:      "special characters",
+			pairs:     []string{"a-b"},
+			titles:    map[string]string{"a": `$\pi_0$ & <"policies">`},
+			wantNodes: 2,
+			wantEdges: 1,
+			wantData: map[string]map[string]string{
+				"a": {"title": `$\pi_0$ & <"policies">`},
+			},
+		},
+		{
+			name:  "empty",
+			pairs: nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			nodes, links := testGraph(tt.pairs...)
+			for i, n := range nodes {
+				if title, ok := tt.titles[n.ID]; ok {
+					nodes[i].Title = title
+				}
+			}
+			data, err := BuildGraph(nodes, links, tt.tags).ToGraphML()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var doc graphMLDoc
+			if err := xml.Unmarshal(data, &doc); err != nil {
+				t.Fatalf("invalid GraphML: %v\n%s", err, data)
+			}
+			if doc.XMLName.Space != graphMLNS || doc.XMLName.Local != "graphml" {
+				t.Errorf("root element %v", doc.XMLName)
+			}
+
+			// The schema wants every key before the graph
+			children := graphMLChildren(t, data)
+			if len(children) == 0 || children[len(children)-1] != "graph" || strings.Count(strings.Join(children, " "), "graph") != 1 {
+				t.Errorf("root children %v, want keys then one graph", children)
+			}
+			keys := make(map[string]bool)
+			for _, k := range doc.Keys {
+				if k.For != "node" || k.AttrName == "" || k.AttrType == "" {
+					t.Errorf("incomplete key %+v", k)
+				}
+				keys[k.ID] = true
+			}
+			if doc.Graph.EdgeDefault != "directed" {
+				t.Errorf("edgedefault %q", doc.Graph.EdgeDefault)
+			}
+
+			if len(doc.Graph.Nodes) != tt.wantNodes || len(doc.Graph.Edges) != tt.wantEdges {
+				t.Errorf("%d nodes and %d edges, want %d and %d",
+					len(doc.Graph.Nodes), len(doc.Graph.Edges), tt.wantNodes, tt.wantEdges)
+			}
+			got := make(map[string]map[string]string)
+			for _, n := range doc.Graph.Nodes {
+				if got[n.ID] != nil {
+					t.Errorf("node %s listed twice", n.ID)
+				}
+				got[n.ID] = make(map[string]string)
+				for _, d := range n.Data {
+					if !keys[d.Key] {
+						t.Errorf("node %s has data for undeclared key %q", n.ID, d.Key)
+					}
+					got[n.ID][d.Key] = d.Value
+				}
+				if len(n.Data) != len(doc.Keys) {
+					t.Errorf("node %s has %d data elements, want %d", n.ID, len(n.Data), len(doc.Keys))
+				}
+			}
+			for id, want := range tt.wantData {
+				for key, value := range want {
+					if got[id][key] != value {
+						t.Errorf("%s of %s = %q, want %q", key, id, got[id][key], value)
+					}
+				}
+			}
+
+			edgeIDs := make(map[string]bool)
+			for _, e := range doc.Graph.Edges {
+				if edgeIDs[e.ID] {
+					t.Errorf("edge ID %s used twice", e.ID)
+				}
+				edgeIDs[e.ID] = true
+				if got[e.Source] == nil || got[e.Target] == nil {
+					t.Errorf("edge %s joins unknown nodes %s and %s", e.ID, e.Source, e.Target)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,173 @@
+package linkcheck
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Result is the outcome of checking one URL
+type Result struct {
+	URL       string    `json:"url"`
+	Status    int       `json:"status"` // 0 when the URL was unreachable
+	Error     string    `json:"error,omitempty"`
+	CheckedAt time.Time `json:"checkedAt"`
+}
+
+// OK reports whether the URL answered with a 2xx status
+func (r Result) OK() bool {
+	return r.Error == "" && r.Status >= 200 && r.Status < 300
+}
+
+// Checker checks external URLs concurrently
+type Checker struct {
+	Client      *http.Client
+	Concurrency int
+	Skip        []string // URL prefixes that are never checked
+
+	// Successful results newer than CacheTTL are reused instead of
+	// requesting the URL again
+	Cache    map[string]Result
+	CacheTTL time.Duration
+
+	mu sync.Mutex
+}
+
+// NewChecker creates a checker with the given per-request timeout and
+// number of parallel requests
+func NewChecker(timeout time.Duration, concurrency int) *Checker {
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+	return &Checker{
+		Client:      &http.Client{Timeout: timeout},
+		Concurrency: concurrency,
+		Cache:       make(map[string]Result),
+		CacheTTL:    24 * time.Hour,
+	}
+}
+
+// Skipped reports whether url matches the skip list
+func (c *Checker) Skipped(url string) bool {
+	for _, prefix := range c.Skip {
+		if prefix != "" && strings.HasPrefix(url, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// Check checks every URL not on the skip list and returns the results
+// sorted by URL. Fresh results are stored in the cache.
+func (c *Checker) Check(urls []string) []Result {
+	jobs := make(chan string)
+	var (
+		wg      sync.WaitGroup
+		results []Result
+	)
+	for i := 0; i < c.Concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for u := range jobs {
+				res := c.checkCached(u)
+				c.mu.Lock()
+				results = append(results, res)
+				c.mu.Unlock()
+			}
+		}()
+	}
+
+	for _, u := range urls {
+		if !c.Skipped(u) {
+			jobs <- u
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	sort.Slice(results, func(i, j int) bool {
+		return results[i].URL < results[j].URL
+	})
+	return results
+}
+
+// checkCached returns a fresh cached result for url or checks it
+func (c *Checker) checkCached(url string) Result {
+	c.mu.Lock()
+	cached, ok := c.Cache[url]
+	c.mu.Unlock()
+	if ok && cached.OK() && time.Since(cached.CheckedAt) < c.CacheTTL {
+		return cached
+	}
+
+	res := c.checkURL(url)
+	c.mu.Lock()
+	c.Cache[url] = res
+	c.mu.Unlock()
+	return res
+}
+
+// checkURL requests url with HEAD, falling back to GET for servers that
+// reject or mishandle HEAD
+func (c *Checker) checkURL(url string) Result {
+	res := Result{URL: url, CheckedAt: time.Now()}
+
+	status, err := c.request(http.MethodHead, url)
+	if err != nil || status >= 400 {
+		status, err = c.request(http.MethodGet, url)
+	}
+	if err != nil {
+		res.Error = err.Error()
+		return res
+	}
+	res.Status = status
+	return res
+}
+
+// request performs a single request and returns the response status
+func (c *Checker) request(method, url string) (int, error) {
+	req, err := http.NewRequest(method, url, nil)
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("User-Agent", "org-roam-web link checker")
+
+	resp, err := c.Client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	resp.Body.Close()
+	return resp.StatusCode, nil
+}
+
+// LoadCache reads cached results from path. A missing file yields an
+// empty cache.
+func LoadCache(path string) (map[string]Result, error) {
+	cache := make(map[string]Result)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return cache, nil
+		}
+		return nil, fmt.Errorf("failed to read link cache: %w", err)
+	}
+	if err := json.Unmarshal(data, &cache); err != nil {
+		return nil, fmt.Errorf("failed to parse link cache: %w", err)
+	}
+	return cache, nil
+}
+
+// SaveCache writes cached results to path
+func SaveCache(path string, cache map[string]Result) error {
+	data, err := json.MarshalIndent(cache, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
@@ -0,0 +1,175 @@
+package linkcheck
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+// testServer answers by path, counting the requests for each
+type testServer struct {
+	*httptest.Server
+	mu       sync.Mutex
+	requests map[string]int
+}
+
+func newTestServer(t *testing.T) *testServer {
+	s := &testServer{requests: make(map[string]int)}
+	mux := http.NewServeMux()
+	ok := func(w http.ResponseWriter, req *http.Request) {}
+	mux.HandleFunc("/ok", ok)
+	mux.HandleFunc("/moved-here", ok)
+	mux.HandleFunc("/missing", func(w http.ResponseWriter, req *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	})
+	mux.HandleFunc("/error", func(w http.ResponseWriter, req *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	})
+	mux.HandleFunc("/no-head", func(w http.ResponseWriter, req *http.Request) {
+		if req.Method == http.MethodHead {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}
+	})
+	mux.HandleFunc("/moved", func(w http.ResponseWriter, req *http.Request) {
+		http.Redirect(w, req, "/moved-here", http.StatusMovedPermanently)
+	})
+	mux.HandleFunc("/slow", func(w http.ResponseWriter, req *http.Request) {
+		time.Sleep(200 * time.Millisecond)
+	})
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		s.mu.Lock()
+		s.requests[req.URL.Path]++
+		s.mu.Unlock()
+		mux.ServeHTTP(w, req)
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+// count returns the number of requests made for path
+func (s *testServer) count(path string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.requests[path]
+}
+
+func TestCheck(t *testing.T) {
+	srv := newTestServer(t)
+	closed := httptest.NewServer(http.NotFoundHandler())
+	closed.Close()
+
+	c := NewChecker(100*time.Millisecond, 4)
+	c.Skip = []string{srv.URL + "/skipped"}
+	results := c.Check([]string{
+		srv.URL + "/ok",
+		srv.URL + "/missing",
+		srv.URL + "/error",
+		srv.URL + "/no-head",
+		srv.URL + "/moved",
+		srv.URL + "/slow",
+		srv.URL + "/skipped/page",
+		closed.URL + "/gone",
+	})
+
+	tests := []struct {
+		url    string
+		status int
+		ok     bool
+		err    bool
+	}{
+		{closed.URL + "/gone", 0, false, true},
+		{srv.URL + "/error", 500, false, false},
+		{srv.URL + "/missing", 404, false, false},
+		{srv.URL + "/moved", 200, true, false},
+		{srv.URL + "/no-head", 200, true, false},
+		{srv.URL + "/ok", 200, true, false},
+		{srv.URL + "/slow", 0, false, true},
+	}
+	byURL := make(map[string]Result)
+	for _, res := range results {
+		byURL[res.URL] = res
+	}
+	if len(results) != len(tests) {
+		t.Errorf("got %d results, want %d: %v", len(results), len(tests), results)
+	}
+	for _, tt := range tests {
+		res, ok := byURL[tt.url]
+		if !ok {
+			t.Errorf("%s: not checked", tt.url)
+			continue
+		}
+		if res.Status != tt.status || res.OK() != tt.ok || (res.Error != "") != tt.err {
+			t.Errorf("%s: status %d, ok %t, error %q; want status %d, ok %t, error %t",
+				tt.url, res.Status, res.OK(), res.Error, tt.status, tt.ok, tt.err)
+		}
+	}
+	for i := 1; i < len(results); i++ {
+		if results[i-1].URL > results[i].URL {
+			t.Errorf("results not sorted by URL: %s before %s", results[i-1].URL, results[i].URL)
+		}
+	}
+	if n := srv.count("/skipped/page"); n != 0 {
+		t.Errorf("skipped URL requested %d times", n)
+	}
+	// HEAD is tried first and GET only after it fails
+	if n := srv.count("/ok"); n != 1 {
+		t.Errorf("/ok requested %d times, want 1", n)
+	}
+	if n := srv.count("/no-head"); n != 2 {
+		t.Errorf("/no-head requested %d times, want 2", n)
+	}
+}
+
+func TestCheckCache(t *testing.T) {
+	srv := newTestServer(t)
+	urls := []string{srv.URL + "/ok", srv.URL + "/missing"}
+	path := filepath.Join(t.TempDir(), "linkcheck.json")
+
+	c := NewChecker(time.Second, 2)
+	c.Check(urls)
+	if err := SaveCache(path, c.Cache); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		ttl     time.Duration
+		okCount int // Requests for /ok after the second run
+	}{
+		// Successful results are reused; failures are checked again
+		{"fresh", time.Hour, 1},
+		{"expired", time.Nanosecond, 2},
+	}
+	missing := srv.count("/missing")
+	for _, tt := range tests {
+		before := srv.count("/ok")
+		cache, err := LoadCache(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		c := NewChecker(time.Second, 2)
+		c.Cache = cache
+		c.CacheTTL = tt.ttl
+		results := c.Check(urls)
+
+		if got := srv.count("/ok") - before; got != tt.okCount-1 {
+			t.Errorf("%s: /ok requested %d more times, want %d", tt.name, got, tt.okCount-1)
+		}
+		if got := srv.count("/missing"); got <= missing {
+			t.Errorf("%s: broken URL not checked again", tt.name)
+		}
+		missing = srv.count("/missing")
+		if len(results) != 2 || !results[1].OK() {
+			t.Errorf("%s: results %v", tt.name, results)
+		}
+	}
+
+	// A missing cache file is an empty cache
+	cache, err := LoadCache(filepath.Join(t.TempDir(), "none.json"))
+	if err != nil || len(cache) != 0 {
+		t.Errorf("LoadCache of a missing file = %v, %v", cache, err)
+	}
+}
@@ -0,0 +1,149 @@
+package parser
+
+import (
+	"regexp"
+	"slices"
+	"strings"
+	"unicode/utf8"
+)
+
+var (
+	excerptLinkRe    = regexp.MustCompile(`\[\[([^\]]+)\](?:\[([^\]]*)\])?\]`)
+	excerptHeadingRe = regexp.MustCompile(`^\*+\s`)
+	headlineStarsRe  = regexp.MustCompile(`^(\*+)\s`)
+	headlineTagsRe   = regexp.MustCompile(`\s:((?:[\w@#%]+:)+)\s*$`)
+)
+
+// StripNoExport removes :noexport: subtrees and #+begin_private blocks from
+// org content, as Parse leaves them out of the page when RespectNoExport is
+// set, so that excerpts and word counts only cover what is published
+func StripNoExport(content string) string {
+	var kept []string
+	skipLevel := 0 // Stars of the :noexport: headline being skipped, or 0
+	inPrivate := false
+	for _, line := range strings.Split(content, "\n") {
+		lower := strings.ToLower(strings.TrimSpace(line))
+		if inPrivate {
+			if strings.HasPrefix(lower, "#+end_private") {
+				inPrivate = false
+			}
+			continue
+		}
+
+		if m := headlineStarsRe.FindStringSubmatch(line); m != nil {
+			level := len(m[1])
+			if skipLevel > 0 && level > skipLevel {
+				continue
+			}
+			skipLevel = 0
+			if t := headlineTagsRe.FindStringSubmatch(line); t != nil && slices.Contains(strings.Split(t[1], ":"), "noexport") {
+				skipLevel = level
+				continue
+			}
+		} else if skipLevel > 0 {
+			continue
+		}
+
+		if strings.HasPrefix(lower, "#+begin_private") {
+			inPrivate = true
+			continue
+		}
+		kept = append(kept, line)
+	}
+	return strings.Join(kept, "\n")
+}
+
+// Excerpt returns the first paragraph of org content as plain text,
+// shortened to at most maxRunes runes at a word boundary, with runs of
+// whitespace collapsed. Keywords, drawers, headings and blocks before it
+// are skipped.
+func Excerpt(content string, maxRunes int) string {
+	var para []string
+	inDrawer, inBlock := false, false
+	for _, line := range strings.Split(content, "\n") {
+		trimmed := strings.TrimSpace(line)
+		lower := strings.ToLower(trimmed)
+		switch {
+		case inDrawer:
+			if strings.EqualFold(trimmed, ":END:") {
+				inDrawer = false
+			}
+			continue
+		case inBlock:
+			if strings.HasPrefix(lower, "#+end_") {
+				inBlock = false
+			}
+			continue
+		case strings.HasPrefix(lower, "#+begin_"):
+			inBlock = true
+		case strings.HasPrefix(trimmed, ":") && strings.HasSuffix(trimmed, ":") && len(trimmed) > 1:
+			inDrawer = true
+		case trimmed == "", strings.HasPrefix(trimmed, "#+"), excerptHeadingRe.MatchString(line):
+			// Skipped before the paragraph, ends it after
+		default:
+			para = append(para, trimmed)
+			continue
+		}
+		if len(para) > 0 {
+			break
+		}
+	}
+
+	text := strings.Join(strings.Fields(strings.Join(para, " ")), " ")
+	text = excerptLinkRe.ReplaceAllStringFunc(text, func(m string) string {
+		sub := excerptLinkRe.FindStringSubmatch(m)
+		if sub[2] != "" {
+			return sub[2]
+		}
+		return strings.TrimPrefix(sub[1], "id:")
+	})
+	return truncateWords(text, maxRunes)
+}
+
+// truncateWords shortens s to at most max runes, cutting at the last space
+// and appending an ellipsis. Text without spaces, such as CJK, is cut at max
+// runes.
+func truncateWords(s string, max int) string {
+	if max <= 0 || utf8.RuneCountInString(s) <= max {
+		return s
+	}
+	runes := []rune(s)
+	cut := string(runes[:max])
+	if runes[max] != ' ' {
+		if i := strings.LastIndex(cut, " "); i > 0 {
+			cut = cut[:i]
+		}
+	}
+	return strings.TrimRight(cut, " ,.;:") + "…"
+}
+
+// WordCount returns the number of words in the text of org content.
+// Keywords, drawers and block delimiters are not counted, and links count
+// as their description.
+func WordCount(content string) int {
+	count := 0
+	inDrawer := false
+	for _, line := range strings.Split(content, "\n") {
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case inDrawer:
+			if strings.EqualFold(trimmed, ":END:") {
+				inDrawer = false
+			}
+		case strings.HasPrefix(trimmed, ":") && strings.HasSuffix(trimmed, ":") && len(trimmed) > 1:
+			inDrawer = true
+		case strings.HasPrefix(trimmed, "#+"):
+		default:
+			text := excerptHeadingRe.ReplaceAllString(trimmed, "")
+			text = excerptLinkRe.ReplaceAllStringFunc(text, func(m string) string {
+				sub := excerptLinkRe.FindStringSubmatch(m)
+				if sub[2] != "" {
+					return sub[2]
+				}
+				return "link"
+			})
+			count += len(strings.Fields(text))
+		}
+	}
+	return count
+}
@@ -0,0 +1,141 @@
+package parser
+
+import (
+	"testing"
+	"unicode/utf8"
+)
+
+func TestStripNoExport(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    string
+	}{
+		{
+			"noexport subtree",
+			"Intro.\n* Secret :noexport:\nHidden.\n** Child\nAlso hidden.\n* Public\nShown.",
+			"Intro.\n* Public\nShown.",
+		},
+		{
+			"noexport among tags",
+			"* Draft   :wip:noexport:idea:\nHidden.\n* Done :wip:\nShown.",
+			"* Done :wip:\nShown.",
+		},
+		{
+			"nested noexport ends at a sibling",
+			"* Parent\n** Secret :noexport:\n*** Deeper\nHidden.\n** Sibling\nShown.",
+			"* Parent\n** Sibling\nShown.",
+		},
+		{
+			"private block",
+			"Before.\n#+begin_private\nHidden.\n#+end_private\nAfter.",
+			"Before.\nAfter.",
+		},
+		{
+			"private block upper case",
+			"#+BEGIN_PRIVATE\nHidden.\n#+END_PRIVATE\nAfter.",
+			"After.",
+		},
+		{
+			"tag in the title only",
+			"* Why :noexport: matters\nShown.",
+			"* Why :noexport: matters\nShown.",
+		},
+		{"nothing to strip", "* Heading :tag:\nText.", "* Heading :tag:\nText."},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := StripNoExport(tt.content); got != tt.want {
+				t.Errorf("StripNoExport(%q)\n got %q\nwant %q", tt.content, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExcerptSkipsNoExport(t *testing.T) {
+	content := `:PROPERTIES:
+:ID: a1
+:END:
+#+title: Alpha
+
+* Draft :noexport:
+Secret plans for the launch.
+
+#+begin_private
+Private aside.
+#+end_private
+
+* Summary
+The public summary.
+`
+	stripped := StripNoExport(content)
+	if got, want := Excerpt(stripped, 160), "The public summary."; got != want {
+		t.Errorf("Excerpt = %q, want %q", got, want)
+	}
+	// "Summary" and "The public summary."
+	if got, want := WordCount(stripped), 4; got != want {
+		t.Errorf("WordCount = %d, want %d", got, want)
+	}
+	if got := Excerpt(content, 160); got != "Secret plans for the launch." {
+		t.Errorf("Excerpt without stripping = %q", got)
+	}
+}
+
+func TestTruncateWords(t *testing.T) {
+	tests := []struct {
+		name string
+		s    string
+		max  int
+		want string
+	}{
+		{"short", "Hello world", 20, "Hello world"},
+		{"exact length", "Hello world", 11, "Hello world"},
+		{"no limit", "Hello world", 0, "Hello world"},
+		{"mid-word", "Hello wonderful world", 12, "Hello…"},
+		{"at a word end", "Hello wonderful world", 15, "Hello wonderful…"},
+		{"at a space", "Hello wonderful world", 16, "Hello wonderful…"},
+		{"trailing punctuation", "One, two; three", 9, "One, two…"},
+		{"one long word", "Supercalifragilistic", 5, "Super…"},
+		{"accents", "Café crème brûlée", 12, "Café crème…"},
+		{"accent at the cut", "naïve résumé", 9, "naïve…"},
+		{"CJK without spaces", "日本語のテキストです", 4, "日本語の…"},
+		{"CJK words", "日本語 テキスト です", 6, "日本語…"},
+		{"emoji", "🎉🎉 party 🎉", 4, "🎉🎉…"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := truncateWords(tt.s, tt.max)
+			if got != tt.want {
+				t.Errorf("truncateWords(%q, %d) = %q, want %q", tt.s, tt.max, got, tt.want)
+			}
+			if !utf8.ValidString(got) {
+				t.Errorf("truncateWords(%q, %d) = %q, not valid UTF-8", tt.s, tt.max, got)
+			}
+		})
+	}
+}
+
+func TestExcerpt(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		max     int
+		want    string
+	}{
+		{"collapses whitespace", "First   line\n\tsecond  line", 160, "First line second line"},
+		{"first paragraph only", "One.\n\nTwo.", 160, "One."},
+		{"skips keywords and drawers", "#+title: T\n:PROPERTIES:\n:ID: x\n:END:\nBody text.", 160, "Body text."},
+		{"skips headings and blocks", "* Heading\n#+begin_src go\ncode\n#+end_src\nText.", 160, "Text."},
+		{"links as descriptions", "See [[https://example.org][the site]] and [[id:abc]].", 160, "See the site and abc."},
+		{"truncated at a word", "Alpha beta gamma delta", 14, "Alpha beta…"},
+		{"multibyte", "Ünïcödé wörds everywhere", 13, "Ünïcödé wörds…"},
+		{"empty", "#+title: Only keywords\n", 160, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Excerpt(tt.content, tt.max); got != tt.want {
+				t.Errorf("Excerpt(%q, %d) = %q, want %q", tt.content, tt.max, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,70 @@
+package parser
+
+import (
+	"bytes"
+	"fmt"
+	"html"
+	"strings"
+
+	"github.com/alecthomas/chroma/v2"
+	chromahtml "github.com/alecthomas/chroma/v2/formatters/html"
+	"github.com/alecthomas/chroma/v2/lexers"
+	"github.com/alecthomas/chroma/v2/styles"
+)
+
+// highlightFormatter marks tokens with CSS classes; the colors come from
+// the stylesheet written by SyntaxCSS
+var highlightFormatter = chromahtml.New(chromahtml.WithClasses(true))
+
+// highlightCode renders source as highlighted HTML for lang. It reports
+// false for blocks without a language (which go-org calls "text") or in
+// one it doesn't know, which are left to go-org's plain rendering.
+func highlightCode(source, lang string, inline bool) (string, bool) {
+	if lang == "" || lang == "text" {
+		return "", false
+	}
+	lexer := lexers.Get(lang)
+	if lexer == nil {
+		return "", false
+	}
+	tokens, err := chroma.Coalesce(lexer).Tokenise(nil, source)
+	if err != nil {
+		return "", false
+	}
+	var buf strings.Builder
+	if err := highlightFormatter.Format(&buf, styles.Fallback, tokens); err != nil {
+		return "", false
+	}
+
+	class := "highlight"
+	if inline {
+		class = "highlight-inline"
+	}
+	return fmt.Sprintf("<div class=\"%s\">\n%s\n</div>", class, buf.String()), true
+}
+
+// mermaidDiagram renders a mermaid source block for the mermaid runtime,
+// which reads the diagram from the element's text
+func mermaidDiagram(source string) string {
+	return fmt.Sprintf("<div class=\"mermaid\">\n%s\n</div>", html.EscapeString(source))
+}
+
+// SyntaxThemeExists reports whether theme names a known highlighting style
+func SyntaxThemeExists(theme string) bool {
+	_, ok := styles.Registry[theme]
+	return ok
+}
+
+// SyntaxCSS returns the stylesheet coloring highlighted source blocks in
+// the given theme
+func SyntaxCSS(theme string) ([]byte, error) {
+	style, ok := styles.Registry[theme]
+	if !ok {
+		return nil, fmt.Errorf("unknown syntax theme %q", theme)
+	}
+	var buf bytes.Buffer
+	if err := highlightFormatter.WriteCSS(&buf, style); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -1,16 +1,36 @@
 package parser
 
 import (
+	"errors"
 	"fmt"
+	"html"
 	"os"
 	"path/filepath"
 	"regexp"
-	"strconv"
 	"strings"
+	"unicode"
 
 	"github.com/niklasfasching/go-org/org"
 )
 
+// ErrParse matches any *ParseError with errors.Is
+var ErrParse = errors.New("failed to parse note")
+
+// ParseError reports a note file that could not be read or converted
+type ParseError struct {
+	File string
+	Err  error
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("%s: %v", e.File, e.Err)
+}
+
+func (e *ParseError) Unwrap() error { return e.Err }
+
+// Is makes errors.Is(err, ErrParse) match parse errors
+func (e *ParseError) Is(target error) bool { return target == ErrParse }
+
 // ToCEntry represents a table of contents entry
 type ToCEntry struct {
 	Level int
@@ -20,11 +40,28 @@ type ToCEntry struct {
 
 // ParsedNote contains the parsed content of an org file
 type ParsedNote struct {
-	Title   string
-	Content string // HTML content
-	Links   []InternalLink
-	Images  []string
-	ToC     []ToCEntry
+	Title    string
+	Author   string // #+AUTHOR, or the default from a setup file
+	Content  string // HTML content
+	Links    []InternalLink
+	Images   []string
+	ToC      []ToCEntry
+	Headings []ToCEntry // All headings in document order, with their anchor IDs
+
+	ExternalLinks []string // http(s) URLs linked from the note, deduplicated
+
+	Tasks []Task // Headlines with a TODO keyword, in document order
+
+	HasMermaid bool // Content has mermaid diagrams
+}
+
+// Task is a headline with a TODO keyword
+type Task struct {
+	Keyword  string // e.g. TODO, NEXT or DONE
+	Priority string // e.g. A, or "" if the headline has none
+	Title    string
+	ID       string // Anchor ID of the headline
+	Done     bool   // Keyword is a done state (listed after "|" in #+TODO)
 }
 
 // InternalLink represents an internal link to another note
@@ -35,17 +72,38 @@ type InternalLink struct {
 
 // Parser handles org file parsing
 type Parser struct {
+	// DateFormat is the Go time layout used for org timestamps
+	DateFormat string
+	// RespectNoExport strips :noexport: subtrees and #+begin_private blocks
+	RespectNoExport bool
+	// TransliterateSlugs makes heading anchors ASCII (see TransliterateSlug)
+	TransliterateSlugs bool
+	// ResolveSetupFiles applies keywords from #+SETUPFILE files (see LoadSetup)
+	ResolveSetupFiles bool
+	// IDAnchors adds an anchor named by the ID property of each headline,
+	// so id: links to heading nodes can point into the page
+	IDAnchors bool
+	// SyntaxHighlight marks up source blocks in known languages with the
+	// CSS classes styled by SyntaxCSS
+	SyntaxHighlight bool
+
 	roamDir string
 	nodeMap map[string]string // ID -> Title mapping
 	baseURL string
+	noteURL func(id string) string
 }
 
-// NewParser creates a new org parser
-func NewParser(roamDir string, nodeMap map[string]string, baseURL string) *Parser {
+// NewParser creates a new org parser. noteURL maps a node ID to the URL of
+// its page and is used when rendering id: links.
+func NewParser(roamDir string, nodeMap map[string]string, baseURL string, noteURL func(id string) string) *Parser {
 	return &Parser{
-		roamDir: roamDir,
-		nodeMap: nodeMap,
-		baseURL: baseURL,
+		DateFormat:        "Jan 2, 2006",
+		RespectNoExport:   true,
+		ResolveSetupFiles: true,
+		roamDir:           roamDir,
+		nodeMap:           nodeMap,
+		baseURL:           baseURL,
+		noteURL:           noteURL,
 	}
 }
 
@@ -53,7 +111,7 @@ func NewParser(roamDir string, nodeMap map[string]string, baseURL string) *Parse
 func (p *Parser) ParseFile(filePath string) (*ParsedNote, error) {
 	content, err := os.ReadFile(filePath)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read file: %w", err)
+		return nil, &ParseError{File: filePath, Err: fmt.Errorf("failed to read file: %w", err)}
 	}
 
 	return p.Parse(string(content), filePath)
@@ -63,6 +121,22 @@ func (p *Parser) ParseFile(filePath string) (*ParsedNote, error) {
 func (p *Parser) Parse(content string, filePath string) (*ParsedNote, error) {
 	// Extract title from #+title: line
 	title := extractTitle(content)
+	author := extractAuthor(content)
+
+	// Setup files provide defaults for what the note doesn't set itself
+	if p.ResolveSetupFiles && setupFileRe.MatchString(content) {
+		setup := LoadSetup(content, filePath)
+		for _, w := range setup.Warnings {
+			fmt.Printf("Warning: %s\n", w)
+		}
+		if title == "Untitled" && setup.Title != "" {
+			title = setup.Title
+		}
+		if author == "" {
+			author = setup.Author
+		}
+		content = spliceSetup(content, setup)
+	}
 
 	// Find all internal links before conversion
 	links := p.extractInternalLinks(content)
@@ -73,14 +147,37 @@ func (p *Parser) Parse(content string, filePath string) (*ParsedNote, error) {
 	// Convert LaTeX environments for KaTeX compatibility
 	content = convertLatexForKaTeX(content)
 
+	// Format org timestamps
+	content = convertTimestamps(content, p.DateFormat)
+
 	// Convert org to HTML
-	doc := org.New().Parse(strings.NewReader(content), filePath)
+	conf := org.New()
+	if !p.RespectNoExport {
+		conf.DefaultSettings["EXCLUDE_TAGS"] = ""
+	}
+	doc := conf.Parse(strings.NewReader(content), filePath)
 
 	// Use custom HTML writer
-	writer := newCustomHTMLWriter(p.nodeMap, p.roamDir, p.baseURL)
+	writer := newCustomHTMLWriter(p.nodeMap, p.roamDir, p.baseURL, p.noteURL)
+	writer.respectNoExport = p.RespectNoExport
+	writer.transliterate = p.TransliterateSlugs
+	writer.idAnchors = p.IDAnchors
+	plain := writer.HighlightCodeBlock
+	writer.HighlightCodeBlock = func(source, lang string, inline bool, params map[string]string) string {
+		if lang == "mermaid" && !inline {
+			writer.hasMermaid = true
+			return mermaidDiagram(source)
+		}
+		if p.SyntaxHighlight {
+			if code, ok := highlightCode(source, lang, inline); ok {
+				return code
+			}
+		}
+		return plain(source, lang, inline, params)
+	}
 	html, err := doc.Write(writer)
 	if err != nil {
-		return nil, fmt.Errorf("failed to convert to HTML: %w", err)
+		return nil, &ParseError{File: filePath, Err: fmt.Errorf("failed to convert to HTML: %w", err)}
 	}
 
 	// Extract just the body content (remove html/head/body tags)
@@ -89,40 +186,107 @@ func (p *Parser) Parse(content string, filePath string) (*ParsedNote, error) {
 	// Remove go-org generated title and ToC from body (we render our own)
 	html = stripOrgTitleAndToC(html)
 
-	// Extract table of contents (h2 and h3 only)
-	toc := extractToC(html)
+	// Build table of contents (h2 and h3 only) from the rendered headings
+	var toc []ToCEntry
+	for _, h := range writer.headings {
+		if h.Level == 2 || h.Level == 3 {
+			toc = append(toc, h)
+		}
+	}
 
 	return &ParsedNote{
-		Title:   title,
-		Content: html,
-		Links:   links,
-		Images:  images,
-		ToC:     toc,
+		Title:    title,
+		Author:   author,
+		Content:  html,
+		Links:    links,
+		Images:   images,
+		ToC:      toc,
+		Headings: writer.headings,
+
+		ExternalLinks: writer.externalLinks,
+
+		Tasks: writer.tasks,
+
+		HasMermaid: writer.hasMermaid,
 	}, nil
 }
 
-// convertLatexForKaTeX converts unsupported LaTeX environments to KaTeX-compatible ones
+// IsEmptyOrg reports whether org content has nothing besides keyword lines
+// such as #+title and property drawers
+func IsEmptyOrg(content string) bool {
+	inDrawer := false
+	for _, line := range strings.Split(content, "\n") {
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case strings.EqualFold(trimmed, ":PROPERTIES:"):
+			inDrawer = true
+		case inDrawer:
+			if strings.EqualFold(trimmed, ":END:") {
+				inDrawer = false
+			}
+		case trimmed == "", strings.HasPrefix(trimmed, "#+"):
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// IsEmptyHTML reports whether rendered content has no visible text or images
+func IsEmptyHTML(html string) bool {
+	if strings.Contains(html, "<img") {
+		return false
+	}
+	return strings.TrimSpace(htmlTagRe.ReplaceAllString(html, "")) == ""
+}
+
+var htmlTagRe = regexp.MustCompile(`<[^>]*>`)
+
+// convertLatexForKaTeX converts unsupported LaTeX environments to
+// KaTeX-compatible ones. The contents of blocks such as #+begin_src are
+// left untouched.
 func convertLatexForKaTeX(content string) string {
+	lines := strings.Split(content, "\n")
+	inBlock := false
+	for i, line := range lines {
+		trimmed := strings.ToLower(strings.TrimSpace(line))
+		switch {
+		case strings.HasPrefix(trimmed, "#+begin_"):
+			inBlock = true
+			continue
+		case strings.HasPrefix(trimmed, "#+end_"):
+			inBlock = false
+			continue
+		case inBlock:
+			continue
+		}
+		lines[i] = convertLatexLine(line)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// convertLatexLine rewrites starred environments in a single line
+func convertLatexLine(content string) string {
 	// Convert \begin{align*}...\end{align*} to $$\begin{aligned}...\end{aligned}$$
 	re1 := regexp.MustCompile(`\\begin\{align\*\}`)
-	content = re1.ReplaceAllString(content, `$$\begin{aligned}`)
+	content = re1.ReplaceAllLiteralString(content, `$$\begin{aligned}`)
 
 	re2 := regexp.MustCompile(`\\end\{align\*\}`)
-	content = re2.ReplaceAllString(content, `\end{aligned}$$`)
+	content = re2.ReplaceAllLiteralString(content, `\end{aligned}$$`)
 
 	// Convert \begin{equation*}...\end{equation*} to $$...$$
 	re3 := regexp.MustCompile(`\\begin\{equation\*\}`)
-	content = re3.ReplaceAllString(content, `$$`)
+	content = re3.ReplaceAllLiteralString(content, `$$`)
 
 	re4 := regexp.MustCompile(`\\end\{equation\*\}`)
-	content = re4.ReplaceAllString(content, `$$`)
+	content = re4.ReplaceAllLiteralString(content, `$$`)
 
 	// Convert \begin{gather*}...\end{gather*} to $$\begin{gathered}...\end{gathered}$$
 	re5 := regexp.MustCompile(`\\begin\{gather\*\}`)
-	content = re5.ReplaceAllString(content, `$$\begin{gathered}`)
+	content = re5.ReplaceAllLiteralString(content, `$$\begin{gathered}`)
 
 	re6 := regexp.MustCompile(`\\end\{gather\*\}`)
-	content = re6.ReplaceAllString(content, `\end{gathered}$$`)
+	content = re6.ReplaceAllLiteralString(content, `\end{gathered}$$`)
 
 	return content
 }
@@ -141,29 +305,6 @@ func stripOrgTitleAndToC(html string) string {
 	return html
 }
 
-// extractToC extracts h2 and h3 headings from HTML content for table of contents
-func extractToC(html string) []ToCEntry {
-	var toc []ToCEntry
-
-	// Match headline elements generated by go-org
-	// Format: <h2 id="headline-1">Title</h2> or <h3 id="headline-2">Title</h3>
-	re := regexp.MustCompile(`<h([23])\s+id="(headline-\d+)"[^>]*>([^<]+)</h[23]>`)
-	matches := re.FindAllStringSubmatch(html, -1)
-
-	for _, m := range matches {
-		if len(m) >= 4 {
-			level, _ := strconv.Atoi(m[1])
-			toc = append(toc, ToCEntry{
-				Level: level,
-				Title: strings.TrimSpace(m[3]),
-				ID:    m[2],
-			})
-		}
-	}
-
-	return toc
-}
-
 // extractTitle extracts the title from #+title: line
 func extractTitle(content string) string {
 	re := regexp.MustCompile(`(?i)#\+title:\s*(.+)`)
@@ -184,7 +325,7 @@ func (p *Parser) extractInternalLinks(content string) []InternalLink {
 	matches := re.FindAllStringSubmatch(content, -1)
 
 	for _, m := range matches {
-		id := m[1]
+		id := strings.TrimSpace(m[1])
 		if seen[id] {
 			continue
 		}
@@ -253,12 +394,23 @@ func extractBodyContent(html string) string {
 // customHTMLWriter extends the default org HTML writer
 type customHTMLWriter struct {
 	*org.HTMLWriter
-	nodeMap map[string]string
-	roamDir string
-	baseURL string
+	nodeMap         map[string]string
+	roamDir         string
+	baseURL         string
+	noteURL         func(id string) string
+	doc             *org.Document
+	respectNoExport bool
+	transliterate   bool
+	idAnchors       bool
+	headings        []ToCEntry
+	anchors         map[string]int // anchor ID -> times used, for deduplication
+	externalLinks   []string
+	seenExternal    map[string]bool
+	tasks           []Task
+	hasMermaid      bool
 }
 
-func newCustomHTMLWriter(nodeMap map[string]string, roamDir string, baseURL string) *customHTMLWriter {
+func newCustomHTMLWriter(nodeMap map[string]string, roamDir string, baseURL string, noteURL func(id string) string) *customHTMLWriter {
 	w := org.NewHTMLWriter()
 
 	cw := &customHTMLWriter{
@@ -266,6 +418,10 @@ func newCustomHTMLWriter(nodeMap map[string]string, roamDir string, baseURL stri
 		nodeMap:    nodeMap,
 		roamDir:    roamDir,
 		baseURL:    baseURL,
+		noteURL:    noteURL,
+		anchors:    make(map[string]int),
+
+		seenExternal: make(map[string]bool),
 	}
 
 	// Set self as extending writer to override link rendering
@@ -274,6 +430,135 @@ func newCustomHTMLWriter(nodeMap map[string]string, roamDir string, baseURL stri
 	return cw
 }
 
+// Before keeps a reference to the document being written
+func (w *customHTMLWriter) Before(d *org.Document) {
+	w.doc = d
+	w.HTMLWriter.Before(d)
+}
+
+// WriteHeadline renders a headline with a readable, page-unique anchor ID
+// and a permalink, and records it for the table of contents
+func (w *customHTMLWriter) WriteHeadline(h org.Headline) {
+	if h.IsExcluded(w.doc) {
+		return
+	}
+
+	level := (h.Lvl - 1) + w.TopLevelHLevel
+	title := strings.TrimSpace(w.getDescriptionText(h.Title))
+	id := html.EscapeString(w.headingID(h, title))
+	w.headings = append(w.headings, ToCEntry{Level: level, Title: title, ID: id})
+	if h.Status != "" {
+		w.tasks = append(w.tasks, Task{
+			Keyword:  h.Status,
+			Priority: h.Priority,
+			Title:    title,
+			ID:       id,
+			Done:     isDoneKeyword(w.doc.Get("TODO"), h.Status),
+		})
+	}
+
+	w.WriteString(fmt.Sprintf(`<div id="outline-container-%s" class="outline-%d">`, id, level) + "\n")
+	if orgID, ok := h.Properties.Get("ID"); ok && w.idAnchors {
+		w.WriteString(fmt.Sprintf(`<a id="%s"></a>`, html.EscapeString(orgID)) + "\n")
+	}
+	w.WriteString(fmt.Sprintf(`<h%d id="%s">`, level, id) + "\n")
+	if w.doc.GetOption("todo") != "nil" && h.Status != "" {
+		w.WriteString(fmt.Sprintf(`<span class="todo status-%s">%s</span>`, strings.ToLower(h.Status), h.Status) + "\n")
+	}
+	if w.doc.GetOption("pri") != "nil" && h.Priority != "" {
+		w.WriteString(fmt.Sprintf(`<span class="priority priority-%s">[%s]</span>`, strings.ToLower(h.Priority), h.Priority) + "\n")
+	}
+
+	org.WriteNodes(w, h.Title...)
+	if w.doc.GetOption("tags") != "nil" && len(h.Tags) != 0 {
+		tags := make([]string, len(h.Tags))
+		for i, tag := range h.Tags {
+			tags[i] = fmt.Sprintf(`<span class="tag-%s">%s</span>`, strings.ToLower(tag), tag)
+		}
+		w.WriteString("&#xa0;&#xa0;&#xa0;")
+		w.WriteString(fmt.Sprintf(`<span class="tags">%s</span>`, strings.Join(tags, "&#xa0;")))
+	}
+	w.WriteString(fmt.Sprintf(` <a href="#%s" class="heading-anchor" aria-label="Permalink to this section">¶</a>`, id))
+	w.WriteString(fmt.Sprintf("\n</h%d>\n", level))
+
+	if content := w.WriteNodesAsString(h.Children...); content != "" {
+		w.WriteString(fmt.Sprintf(`<div id="outline-text-%s" class="outline-text-%d">`, id, level) + "\n" + content + "</div>\n")
+	}
+	w.WriteString("</div>\n")
+}
+
+// isDoneKeyword reports whether keyword is a done state of a #+TODO
+// sequence: one after the "|", or the last one when there is no "|"
+func isDoneKeyword(sequence, keyword string) bool {
+	active, done, found := strings.Cut(sequence, "|")
+	if !found {
+		fields := strings.Fields(active)
+		if len(fields) == 0 {
+			return false
+		}
+		done = fields[len(fields)-1]
+	}
+	for _, k := range strings.Fields(done) {
+		// Drop fast-access keys such as DONE(d)
+		if name, _, _ := strings.Cut(k, "("); name == keyword {
+			return true
+		}
+	}
+	return false
+}
+
+// WriteBlock drops #+begin_private blocks when noexport handling is on
+func (w *customHTMLWriter) WriteBlock(b org.Block) {
+	if w.respectNoExport && strings.EqualFold(b.Name, "PRIVATE") {
+		return
+	}
+	w.HTMLWriter.WriteBlock(b)
+}
+
+// headingID returns the anchor for a headline: its CUSTOM_ID property if
+// set, otherwise a slug of the title. Repeated IDs get a numeric suffix.
+func (w *customHTMLWriter) headingID(h org.Headline, title string) string {
+	id, ok := h.Properties.Get("CUSTOM_ID")
+	if !ok {
+		if w.transliterate {
+			id = TransliterateSlug(title)
+		} else {
+			id = Slugify(title)
+		}
+		if id == "" {
+			id = "section"
+		}
+	}
+
+	base := id
+	for w.anchors[id] > 0 {
+		id = fmt.Sprintf("%s-%d", base, w.anchors[base])
+		w.anchors[base]++
+	}
+	w.anchors[id]++
+
+	return id
+}
+
+// Slugify converts text to a lowercase, hyphen-separated identifier,
+// keeping letters and digits from any script
+func Slugify(s string) string {
+	var b strings.Builder
+	hyphen := false
+	for _, r := range strings.ToLower(s) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			if hyphen && b.Len() > 0 {
+				b.WriteByte('-')
+			}
+			b.WriteRune(r)
+			hyphen = false
+		} else {
+			hyphen = true
+		}
+	}
+	return b.String()
+}
+
 // WriteRegularLink handles link rendering
 func (w *customHTMLWriter) WriteRegularLink(l org.RegularLink) {
 	url := l.URL
@@ -281,7 +566,7 @@ func (w *customHTMLWriter) WriteRegularLink(l org.RegularLink) {
 
 	// Handle id: links
 	if strings.HasPrefix(url, "id:") {
-		id := strings.TrimPrefix(url, "id:")
+		id := strings.TrimSpace(strings.TrimPrefix(url, "id:"))
 		title := ""
 		if len(desc) > 0 {
 			title = w.getDescriptionText(desc)
@@ -295,7 +580,7 @@ func (w *customHTMLWriter) WriteRegularLink(l org.RegularLink) {
 		}
 
 		// Write internal link with # prefix
-		w.WriteString(fmt.Sprintf(`<a href="%s/notes/%s.html" class="internal-link"><span class="link-marker">#</span> %s</a>`, w.baseURL, id, title))
+		w.WriteString(fmt.Sprintf(`<a href="%s" class="internal-link"><span class="link-marker">#</span> %s</a>`, w.noteURL(id), title))
 		return
 	}
 
@@ -318,6 +603,10 @@ func (w *customHTMLWriter) WriteRegularLink(l org.RegularLink) {
 	}
 
 	// Default: external link
+	if (strings.HasPrefix(url, "http://") || strings.HasPrefix(url, "https://")) && !w.seenExternal[url] {
+		w.seenExternal[url] = true
+		w.externalLinks = append(w.externalLinks, url)
+	}
 	descStr := url
 	if len(desc) > 0 {
 		descStr = w.getDescriptionText(desc)
@@ -325,6 +614,15 @@ func (w *customHTMLWriter) WriteRegularLink(l org.RegularLink) {
 	w.WriteString(fmt.Sprintf(`<a href="%s" class="external-link" target="_blank" rel="noopener">%s</a>`, url, descStr))
 }
 
+// WriteLatexBlock wraps LaTeX environments such as align or equation in
+// display math delimiters so KaTeX renders them. go-org only recognizes
+// environments outside of blocks, so those in source blocks stay code.
+func (w *customHTMLWriter) WriteLatexBlock(b org.LatexBlock) {
+	w.WriteString(`<div class="math-display">\[`)
+	org.WriteNodes(w, b.Content...)
+	w.WriteString("\\]</div>\n")
+}
+
 // getDescriptionText extracts text from description nodes
 func (w *customHTMLWriter) getDescriptionText(desc []org.Node) string {
 	var result strings.Builder
@@ -346,6 +644,12 @@ func (w *customHTMLWriter) extractText(result *strings.Builder, nodes []org.Node
 		case org.Emphasis:
 			// Extract content from bold, italic, etc.
 			w.extractText(result, v.Content)
+		case org.RegularLink:
+			if len(v.Description) > 0 {
+				w.extractText(result, v.Description)
+			} else {
+				result.WriteString(v.URL)
+			}
 		}
 	}
 }
@@ -362,13 +666,23 @@ func isImage(path string) bool {
 
 // rewriteImagePath converts org image path to web path
 func (w *customHTMLWriter) rewriteImagePath(path string) string {
+	return ImageURL(w.baseURL, path)
+}
+
+// ImageURL converts an org image path to its URL on the site. Images are
+// served from /img/, where the build copies the roam directory's img/.
+func ImageURL(baseURL, path string) string {
+	// Leave remote images where they are
+	if strings.HasPrefix(path, "http://") || strings.HasPrefix(path, "https://") {
+		return path
+	}
 	// Remove file: prefix if present
 	path = strings.TrimPrefix(path, "file:")
 	// Remove leading ./ if present
 	path = strings.TrimPrefix(path, "./")
 	// Ensure it starts with /img/ or similar
 	if strings.HasPrefix(path, "img/") {
-		return w.baseURL + "/" + path
+		return baseURL + "/" + path
 	}
-	return w.baseURL + "/img/" + filepath.Base(path)
+	return baseURL + "/img/" + filepath.Base(path)
 }
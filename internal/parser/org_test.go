@@ -0,0 +1,269 @@
+package parser
+
+import (
+	"errors"
+	"io/fs"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+// newTestParser returns a parser linking notes at /notes/<id>.html
+func newTestParser() *Parser {
+	nodeMap := map[string]string{"a1": "Alpha", "b2": "Beta"}
+	return NewParser("/roam", nodeMap, "", func(id string) string {
+		return "/notes/" + id + ".html"
+	})
+}
+
+// mustParse parses org content, failing the test on error
+func mustParse(t *testing.T, p *Parser, content string) *ParsedNote {
+	t.Helper()
+	parsed, err := p.Parse(content, "/roam/note.org")
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	return parsed
+}
+
+var headingIDRe = regexp.MustCompile(`<h\d id="([^"]*)">`)
+
+func TestHeadingAnchors(t *testing.T) {
+	content := `#+title: Anchors
+
+* Setup
+** Install
+** Install
+* Setup
+* Usage & Tips
+** Setup
+*** Deep heading
+`
+	parsed := mustParse(t, newTestParser(), content)
+
+	wantIDs := []string{"setup", "install", "install-1", "setup-1", "usage-tips", "setup-2", "deep-heading"}
+	ids := headingIDRe.FindAllStringSubmatch(parsed.Content, -1)
+	if len(ids) != len(wantIDs) {
+		t.Fatalf("found %d headings, want %d", len(ids), len(wantIDs))
+	}
+	for i, m := range ids {
+		if m[1] != wantIDs[i] {
+			t.Errorf("heading %d has id %q, want %q", i, m[1], wantIDs[i])
+		}
+	}
+	seen := make(map[string]bool)
+	for _, m := range ids {
+		id := m[1]
+		if id == "" || seen[id] {
+			t.Errorf("heading id %q is empty or repeated", id)
+		}
+		seen[id] = true
+		if !regexp.MustCompile(`href="#` + regexp.QuoteMeta(id) + `" class="heading-anchor"`).MatchString(parsed.Content) {
+			t.Errorf("heading %q has no permalink", id)
+		}
+	}
+
+	// The ToC (h2 and h3) points at the same anchors, in document order
+	var want []string
+	for i, h := range parsed.Headings {
+		if h.ID != ids[i][1] {
+			t.Errorf("heading %d has id %q, rendered as %q", i, h.ID, ids[i][1])
+		}
+		if h.Level == 2 || h.Level == 3 {
+			want = append(want, h.ID)
+		}
+	}
+	if len(parsed.ToC) != len(want) {
+		t.Fatalf("ToC has %d entries, want %d", len(parsed.ToC), len(want))
+	}
+	for i, e := range parsed.ToC {
+		if e.ID != want[i] {
+			t.Errorf("ToC entry %d links to %q, want %q", i, e.ID, want[i])
+		}
+	}
+}
+
+func TestRespectNoExport(t *testing.T) {
+	content := `#+title: Mixed
+
+Public intro.
+
+* Draft :noexport:
+Secret plans.
+
+#+begin_private
+Private aside.
+#+end_private
+
+* Summary
+Public summary.
+`
+	tests := []struct {
+		respect bool
+		hidden  bool
+	}{
+		{true, true},
+		{false, false},
+	}
+	for _, tt := range tests {
+		p := newTestParser()
+		p.RespectNoExport = tt.respect
+		parsed := mustParse(t, p, content)
+		for _, text := range []string{"Secret plans", "Private aside", "Draft"} {
+			if strings.Contains(parsed.Content, text) == tt.hidden {
+				t.Errorf("respect_noexport %t: %q shown = %t", tt.respect, text, !tt.hidden)
+			}
+		}
+		for _, text := range []string{"Public intro", "Public summary"} {
+			if !strings.Contains(parsed.Content, text) {
+				t.Errorf("respect_noexport %t: %q missing", tt.respect, text)
+			}
+		}
+	}
+}
+
+func TestLatexEnvironments(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    string
+		reject  string // Must not appear
+	}{
+		{
+			"align",
+			"Text\n\n\\begin{align}\na &= b \\\\\nc &= d\n\\end{align}\n",
+			"<div class=\"math-display\">\\[\\begin{align}\na &amp;= b \\\\\nc &amp;= d\n\\end{align}\\]</div>",
+			"",
+		},
+		{
+			"equation",
+			"\\begin{equation}\nE = mc^2\n\\end{equation}\n",
+			"<div class=\"math-display\">\\[\\begin{equation}\nE = mc^2\n\\end{equation}\\]</div>",
+			"",
+		},
+		{
+			"starred align",
+			"\\begin{align*}\na &= b\n\\end{align*}\n",
+			"$$\\begin{aligned}\na &amp;= b\n\\end{aligned}$$",
+			"align*",
+		},
+		{
+			"starred equation",
+			"\\begin{equation*}\nx = 1\n\\end{equation*}\n",
+			"$$\nx = 1\n$$",
+			"equation*",
+		},
+		{
+			"source block",
+			"#+begin_src latex\n\\begin{align*}\nx\n\\end{align*}\n#+end_src\n",
+			"\\begin{align*}\nx\n\\end{align*}",
+			"math-display",
+		},
+		{
+			"example block",
+			"#+begin_example\n\\begin{equation}\nx\n\\end{equation}\n#+end_example\n",
+			"\\begin{equation}\nx\n\\end{equation}",
+			"math-display",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			content := mustParse(t, newTestParser(), tt.content).Content
+			if !strings.Contains(content, tt.want) {
+				t.Errorf("content lacks %q:\n%s", tt.want, content)
+			}
+			if tt.reject != "" && strings.Contains(content, tt.reject) {
+				t.Errorf("content has %q:\n%s", tt.reject, content)
+			}
+		})
+	}
+}
+
+func TestParseError(t *testing.T) {
+	dir := t.TempDir()
+	tests := []struct {
+		name    string
+		path    string
+		wantErr error // Also matched by the error besides ErrParse
+	}{
+		{"missing file", filepath.Join(dir, "missing.org"), fs.ErrNotExist},
+		{"directory", dir, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := newTestParser().ParseFile(tt.path)
+			if !errors.Is(err, ErrParse) {
+				t.Fatalf("ParseFile error = %v, want ErrParse", err)
+			}
+			var pe *ParseError
+			if !errors.As(err, &pe) || pe.File != tt.path {
+				t.Errorf("ParseFile error = %#v, want a ParseError for %s", err, tt.path)
+			}
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Errorf("ParseFile error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestSyntaxHighlight(t *testing.T) {
+	plain := func(lang, source string) string {
+		return "<div class=\"src src-" + lang + "\">\n<div class=\"highlight\">\n<pre>\n" + source + "\n</pre>\n</div>\n</div>"
+	}
+	tests := []struct {
+		name      string
+		block     string
+		highlight bool
+		want      string // Expected in the content
+		wantPlain bool   // Rendered as go-org's plain <pre>
+	}{
+		{"known language", "#+begin_src go\nfunc main() {}\n#+end_src", true, `<span class="kd">func</span>`, false},
+		{"known language, highlighting off", "#+begin_src go\nfunc main() {}\n#+end_src", false, plain("go", "func main() {}"), true},
+		{"unknown language", "#+begin_src nosuchlang\nfoo <b>\n#+end_src", true, plain("nosuchlang", "foo &lt;b&gt;"), true},
+		{"no language", "#+begin_src\nfoo <b>\n#+end_src", true, plain("text", "foo &lt;b&gt;"), true},
+		{"escaped", "#+begin_src go\nx := \"<a>\"\n#+end_src", true, `&#34;&lt;a&gt;&#34;`, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := newTestParser()
+			p.SyntaxHighlight = tt.highlight
+			parsed, err := p.Parse("#+title: Code\n\n"+tt.block+"\n", "/roam/code.org")
+			if err != nil {
+				t.Fatalf("Parse: %v", err)
+			}
+			if !strings.Contains(parsed.Content, tt.want) {
+				t.Errorf("content lacks %q:\n%s", tt.want, parsed.Content)
+			}
+			if chroma := strings.Contains(parsed.Content, `class="chroma"`); chroma == tt.wantPlain {
+				t.Errorf("highlighted: %v, want %v", chroma, !tt.wantPlain)
+			}
+		})
+	}
+}
+
+func TestSyntaxCSS(t *testing.T) {
+	tests := []struct {
+		theme   string
+		wantErr bool
+	}{
+		{"github", false},
+		{"monokai", false},
+		{"nosuchtheme", true},
+		{"", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.theme, func(t *testing.T) {
+			if exists := SyntaxThemeExists(tt.theme); exists == tt.wantErr {
+				t.Errorf("SyntaxThemeExists(%q) = %v", tt.theme, exists)
+			}
+			css, err := SyntaxCSS(tt.theme)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("SyntaxCSS(%q) error = %v, want error %v", tt.theme, err, tt.wantErr)
+			}
+			if !tt.wantErr && !strings.Contains(string(css), ".chroma .kd") {
+				t.Errorf("SyntaxCSS(%q) has no keyword color:\n%s", tt.theme, css)
+			}
+		})
+	}
+}
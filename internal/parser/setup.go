@@ -0,0 +1,120 @@
+package parser
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// maxSetupDepth limits how deeply #+SETUPFILE files may include each other
+const maxSetupDepth = 5
+
+var (
+	setupFileRe   = regexp.MustCompile(`(?im)^[ \t]*#\+setupfile:[ \t]*(.*?)[ \t]*$`)
+	setupKeyRe    = regexp.MustCompile(`(?i)^[ \t]*#\+([a-z_]+):[ \t]*(.*?)[ \t]*$`)
+	authorRe      = regexp.MustCompile(`(?im)^[ \t]*#\+author:[ \t]*(.+?)[ \t]*$`)
+	setupBlockKey = map[string]bool{"SETUPFILE": true, "INCLUDE": true, "TITLE": true}
+)
+
+// Setup holds the defaults a note takes from its #+SETUPFILE files
+type Setup struct {
+	Title    string
+	Author   string
+	FileTags []string
+	Keywords []string // Other keyword lines, such as #+MACRO and #+OPTIONS
+	Warnings []string // Setup files that were skipped, and why
+}
+
+// LoadSetup resolves the #+SETUPFILE keywords in content, relative to the
+// note at path. Setup files may reference further setup files; cycles and
+// chains deeper than maxSetupDepth are skipped and reported in Warnings.
+// Later files override earlier ones.
+func LoadSetup(content, path string) Setup {
+	var s Setup
+	visited := map[string]bool{filepath.Clean(path): true}
+	s.load(content, path, visited, 0)
+	return s
+}
+
+func (s *Setup) load(content, path string, visited map[string]bool, depth int) {
+	for _, m := range setupFileRe.FindAllStringSubmatch(content, -1) {
+		ref := strings.Trim(m[1], `"`)
+		if ref == "" || strings.Contains(ref, "://") {
+			continue
+		}
+		if !filepath.IsAbs(ref) {
+			ref = filepath.Join(filepath.Dir(path), ref)
+		}
+		ref = filepath.Clean(ref)
+
+		if visited[ref] {
+			s.Warnings = append(s.Warnings, fmt.Sprintf("skipping setup file %s: included in a cycle from %s", ref, filepath.Base(path)))
+			continue
+		}
+		if depth >= maxSetupDepth {
+			s.Warnings = append(s.Warnings, fmt.Sprintf("skipping setup file %s: nested more than %d deep", ref, maxSetupDepth))
+			continue
+		}
+
+		data, err := os.ReadFile(ref)
+		if err != nil {
+			s.Warnings = append(s.Warnings, fmt.Sprintf("skipping setup file %s: %v", ref, err))
+			continue
+		}
+
+		visited[ref] = true
+		setup := string(data)
+		s.load(setup, ref, visited, depth+1)
+		s.apply(setup)
+		delete(visited, ref)
+	}
+}
+
+// apply takes the keywords of one setup file
+func (s *Setup) apply(content string) {
+	for _, line := range strings.Split(content, "\n") {
+		m := setupKeyRe.FindStringSubmatch(line)
+		if m == nil {
+			continue
+		}
+		key, value := strings.ToUpper(m[1]), m[2]
+		switch key {
+		case "TITLE":
+			s.Title = value
+		case "AUTHOR":
+			s.Author = value
+		case "FILETAGS":
+			for _, tag := range strings.Split(value, ":") {
+				if tag = strings.TrimSpace(tag); tag != "" {
+					s.FileTags = append(s.FileTags, tag)
+				}
+			}
+		}
+		if !setupBlockKey[key] {
+			s.Keywords = append(s.Keywords, strings.TrimSpace(line))
+		}
+	}
+}
+
+// spliceSetup replaces the note's #+SETUPFILE lines with the keywords they
+// provide, so go-org applies them without reading the files itself
+func spliceSetup(content string, s Setup) string {
+	first := true
+	return setupFileRe.ReplaceAllStringFunc(content, func(string) string {
+		if !first {
+			return ""
+		}
+		first = false
+		return strings.Join(s.Keywords, "\n")
+	})
+}
+
+// extractAuthor returns the note's #+AUTHOR, or "" if it has none
+func extractAuthor(content string) string {
+	if m := authorRe.FindStringSubmatch(content); m != nil {
+		return m[1]
+	}
+	return ""
+}
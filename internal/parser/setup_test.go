@@ -0,0 +1,167 @@
+package parser
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// writeFiles writes files relative to dir
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestSetupFile(t *testing.T) {
+	tests := []struct {
+		name       string
+		files      map[string]string // Relative to the note's directory
+		note       string
+		wantTitle  string
+		wantAuthor string
+		wantTags   []string
+		wantWarn   string // Expected in a warning, "" for none
+	}{
+		{
+			name:       "default author",
+			files:      map[string]string{"setup.org": "#+AUTHOR: Jane Doe\n"},
+			note:       "#+SETUPFILE: setup.org\n#+title: Note\n",
+			wantTitle:  "Note",
+			wantAuthor: "Jane Doe",
+		},
+		{
+			name:       "note author wins",
+			files:      map[string]string{"setup.org": "#+AUTHOR: Jane Doe\n"},
+			note:       "#+SETUPFILE: setup.org\n#+title: Note\n#+author: John Roe\n",
+			wantTitle:  "Note",
+			wantAuthor: "John Roe",
+		},
+		{
+			name:      "default title",
+			files:     map[string]string{"setup.org": "#+TITLE: Shared\n"},
+			note:      "#+SETUPFILE: setup.org\n",
+			wantTitle: "Shared",
+		},
+		{
+			name:      "note title wins",
+			files:     map[string]string{"setup.org": "#+TITLE: Shared\n"},
+			note:      "#+SETUPFILE: setup.org\n#+title: Note\n",
+			wantTitle: "Note",
+		},
+		{
+			name:      "filetags",
+			files:     map[string]string{"setup.org": "#+FILETAGS: :go:web:\n"},
+			note:      "#+SETUPFILE: setup.org\n#+title: Note\n",
+			wantTitle: "Note",
+			wantTags:  []string{"go", "web"},
+		},
+		{
+			name:       "quoted path in a parent directory",
+			files:      map[string]string{"../shared/setup.org": "#+AUTHOR: Jane Doe\n"},
+			note:       "#+SETUPFILE: \"../shared/setup.org\"\n#+title: Note\n",
+			wantTitle:  "Note",
+			wantAuthor: "Jane Doe",
+		},
+		{
+			name: "nested, later file wins",
+			files: map[string]string{
+				"setup.org": "#+SETUPFILE: base.org\n#+AUTHOR: Jane Doe\n",
+				"base.org":  "#+AUTHOR: Base Author\n#+FILETAGS: :base:\n",
+			},
+			note:       "#+SETUPFILE: setup.org\n#+title: Note\n",
+			wantTitle:  "Note",
+			wantAuthor: "Jane Doe",
+			wantTags:   []string{"base"},
+		},
+		{
+			name: "cycle",
+			files: map[string]string{
+				"a.org": "#+SETUPFILE: b.org\n#+AUTHOR: Jane Doe\n",
+				"b.org": "#+SETUPFILE: a.org\n",
+			},
+			note:       "#+SETUPFILE: a.org\n#+title: Note\n",
+			wantTitle:  "Note",
+			wantAuthor: "Jane Doe",
+			wantWarn:   "cycle",
+		},
+		{
+			name: "too deep",
+			files: map[string]string{
+				"s1.org": "#+SETUPFILE: s2.org\n", "s2.org": "#+SETUPFILE: s3.org\n",
+				"s3.org": "#+SETUPFILE: s4.org\n", "s4.org": "#+SETUPFILE: s5.org\n",
+				"s5.org": "#+SETUPFILE: s6.org\n", "s6.org": "#+AUTHOR: Too Deep\n",
+			},
+			note:      "#+SETUPFILE: s1.org\n#+title: Note\n",
+			wantTitle: "Note",
+			wantWarn:  "nested more than",
+		},
+		{
+			name:      "missing file",
+			note:      "#+SETUPFILE: missing.org\n#+title: Note\n",
+			wantTitle: "Note",
+			wantWarn:  "missing.org",
+		},
+		{
+			name:      "remote file ignored",
+			note:      "#+SETUPFILE: https://example.org/setup.org\n#+title: Note\n",
+			wantTitle: "Note",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := filepath.Join(t.TempDir(), "notes")
+			writeFiles(t, dir, tt.files)
+			path := filepath.Join(dir, "note.org")
+
+			setup := LoadSetup(tt.note, path)
+			if !slices.Equal(setup.FileTags, tt.wantTags) {
+				t.Errorf("FileTags = %v, want %v", setup.FileTags, tt.wantTags)
+			}
+			warnings := strings.Join(setup.Warnings, "\n")
+			if tt.wantWarn == "" && warnings != "" {
+				t.Errorf("unexpected warnings: %s", warnings)
+			}
+			if !strings.Contains(warnings, tt.wantWarn) {
+				t.Errorf("warnings %q lack %q", warnings, tt.wantWarn)
+			}
+
+			p := newTestParser()
+			p.ResolveSetupFiles = true
+			parsed, err := p.Parse(tt.note, path)
+			if err != nil {
+				t.Fatalf("Parse: %v", err)
+			}
+			if parsed.Title != tt.wantTitle {
+				t.Errorf("Title = %q, want %q", parsed.Title, tt.wantTitle)
+			}
+			if parsed.Author != tt.wantAuthor {
+				t.Errorf("Author = %q, want %q", parsed.Author, tt.wantAuthor)
+			}
+		})
+	}
+}
+
+func TestSetupFileDisabled(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{"setup.org": "#+AUTHOR: Jane Doe\n#+TITLE: Shared\n"})
+
+	p := newTestParser()
+	p.ResolveSetupFiles = false
+	parsed, err := p.Parse("#+SETUPFILE: setup.org\n", filepath.Join(dir, "note.org"))
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	if parsed.Author != "" || parsed.Title != "Untitled" {
+		t.Errorf("with setup files disabled got title %q, author %q", parsed.Title, parsed.Author)
+	}
+}
@@ -0,0 +1,66 @@
+package parser
+
+import (
+	"fmt"
+	"hash/fnv"
+	"strings"
+	"unicode"
+)
+
+// latinFold maps accented Latin letters to ASCII
+var latinFold = map[rune]string{
+	'à': "a", 'á': "a", 'â': "a", 'ã': "a", 'ä': "a", 'å': "a", 'ā': "a", 'ă': "a", 'ą': "a",
+	'æ': "ae", 'ç': "c", 'ć': "c", 'č': "c", 'ď': "d", 'đ': "d", 'ð': "d",
+	'è': "e", 'é': "e", 'ê': "e", 'ë': "e", 'ē': "e", 'ę': "e", 'ě': "e",
+	'ì': "i", 'í': "i", 'î': "i", 'ï': "i", 'ī': "i", 'ı': "i",
+	'ł': "l", 'ñ': "n", 'ń': "n", 'ň': "n",
+	'ò': "o", 'ó': "o", 'ô': "o", 'õ': "o", 'ö': "o", 'ø': "o", 'ō': "o", 'ő': "o", 'œ': "oe",
+	'ř': "r", 'ś': "s", 'š': "s", 'ş': "s", 'ß': "ss", 'ť': "t", 'ţ': "t", 'þ': "th",
+	'ù': "u", 'ú': "u", 'û': "u", 'ü': "u", 'ū': "u", 'ů': "u", 'ű': "u",
+	'ý': "y", 'ÿ': "y", 'ź': "z", 'ż': "z", 'ž': "z",
+}
+
+// cyrillicLatin transliterates Russian and Ukrainian letters
+var cyrillicLatin = map[rune]string{
+	'а': "a", 'б': "b", 'в': "v", 'г': "g", 'ґ': "g", 'д': "d", 'е': "e", 'ё': "yo",
+	'є': "ye", 'ж': "zh", 'з': "z", 'и': "i", 'і': "i", 'ї': "yi", 'й': "y", 'к': "k",
+	'л': "l", 'м': "m", 'н': "n", 'о': "o", 'п': "p", 'р': "r", 'с': "s", 'т': "t",
+	'у': "u", 'ф': "f", 'х': "kh", 'ц': "ts", 'ч': "ch", 'ш': "sh", 'щ': "shch",
+	'ъ': "", 'ы': "y", 'ь': "", 'э': "e", 'ю': "yu", 'я': "ya",
+}
+
+// TransliterateSlug is like ASCIISlug, but when nothing is left (e.g. a
+// Chinese or emoji-only title) it returns a slug derived from a hash of s,
+// so the same text always gets the same slug.
+func TransliterateSlug(s string) string {
+	if slug := ASCIISlug(s); slug != "" {
+		return slug
+	}
+	if strings.TrimSpace(s) == "" {
+		return ""
+	}
+	h := fnv.New32a()
+	h.Write([]byte(s))
+	return fmt.Sprintf("h-%08x", h.Sum32())
+}
+
+// ASCIISlug is like Slugify but keeps the result ASCII: accented Latin
+// letters are folded and Cyrillic is transliterated, while letters from
+// other scripts are dropped. It returns "" when nothing is left.
+func ASCIISlug(s string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(s) {
+		switch {
+		case r < unicode.MaxASCII:
+			b.WriteRune(r)
+		case latinFold[r] != "":
+			b.WriteString(latinFold[r])
+		case cyrillicLatin[r] != "":
+			b.WriteString(cyrillicLatin[r])
+		default:
+			b.WriteByte(' ')
+		}
+	}
+
+	return Slugify(b.String())
+}
@@ -0,0 +1,68 @@
+package parser
+
+import (
+	"regexp"
+	"slices"
+	"testing"
+)
+
+var hashSlugRe = regexp.MustCompile(`^h-[0-9a-f]{8}$`)
+
+func TestTransliterateSlug(t *testing.T) {
+	tests := []struct {
+		name  string
+		title string
+		want  string // "hash" for a hash-based slug
+	}{
+		{"ascii", "Hello, World!", "hello-world"},
+		{"accented latin", "Café Über Straße", "cafe-uber-strasse"},
+		{"cyrillic", "Привет, мир", "privet-mir"},
+		{"ukrainian", "Їжак і щука", "yizhak-i-shchuka"},
+		{"mixed", "Go 并发 patterns", "go-patterns"},
+		{"chinese", "你好世界", "hash"},
+		{"emoji", "🎉🚀", "hash"},
+		{"blank", "  ", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := TransliterateSlug(tt.title)
+			if tt.want != "hash" {
+				if got != tt.want {
+					t.Errorf("TransliterateSlug(%q) = %q, want %q", tt.title, got, tt.want)
+				}
+				return
+			}
+			if !hashSlugRe.MatchString(got) {
+				t.Errorf("TransliterateSlug(%q) = %q, want a hash slug", tt.title, got)
+			}
+			if again := TransliterateSlug(tt.title); again != got {
+				t.Errorf("TransliterateSlug(%q) = %q, then %q", tt.title, got, again)
+			}
+			if other := TransliterateSlug(tt.title + "!"); other == got {
+				t.Errorf("TransliterateSlug(%q) and of %q are both %q", tt.title, tt.title+"!", got)
+			}
+		})
+	}
+}
+
+func TestTransliteratedAnchors(t *testing.T) {
+	content := "* Привет\n* 你好\n* 你好\n* 🎉\n"
+	tests := []struct {
+		transliterate bool
+		want          []string
+	}{
+		{false, []string{"привет", "你好", "你好-1", "section"}},
+		{true, []string{"privet", TransliterateSlug("你好"), TransliterateSlug("你好") + "-1", TransliterateSlug("🎉")}},
+	}
+	for _, tt := range tests {
+		p := newTestParser()
+		p.TransliterateSlugs = tt.transliterate
+		var got []string
+		for _, m := range headingIDRe.FindAllStringSubmatch(mustParse(t, p, content).Content, -1) {
+			got = append(got, m[1])
+		}
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("transliterate %t: anchors %q, want %q", tt.transliterate, got, tt.want)
+		}
+	}
+}
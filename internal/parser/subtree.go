@@ -0,0 +1,121 @@
+package parser
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+var (
+	subtreeHeadingRe = regexp.MustCompile(`^(\*+)\s`)
+	subtreeIDRe      = regexp.MustCompile(`(?i)^\s*:ID:\s+(\S+)\s*$`)
+	subtreeKeywordRe = regexp.MustCompile(`^\s*#\+(\w+):`)
+)
+
+// Subtree returns the org content of the headline whose property drawer has
+// the given ID, as a document of its own: the file's keywords (except
+// #+TITLE) followed by the headline's body, with its subheadings promoted
+// to top level. It reports false if no headline has the ID.
+func Subtree(content, id string) (string, bool) {
+	lines := strings.Split(content, "\n")
+
+	start, level := -1, 0
+	heading, headingLevel := -1, 0
+	for i, line := range lines {
+		if m := subtreeHeadingRe.FindStringSubmatch(line); m != nil {
+			heading, headingLevel = i, len(m[1])
+			continue
+		}
+		if m := subtreeIDRe.FindStringSubmatch(line); m != nil && m[1] == id && heading >= 0 {
+			start, level = heading, headingLevel
+			break
+		}
+	}
+	if start < 0 {
+		return "", false
+	}
+
+	var b strings.Builder
+	for _, line := range lines {
+		if subtreeHeadingRe.MatchString(line) {
+			break
+		}
+		if m := subtreeKeywordRe.FindStringSubmatch(line); m != nil && !strings.EqualFold(m[1], "title") {
+			b.WriteString(line + "\n")
+		}
+	}
+
+	for _, line := range lines[start+1:] {
+		if m := subtreeHeadingRe.FindStringSubmatch(line); m != nil {
+			if len(m[1]) <= level {
+				break
+			}
+			line = line[level:]
+		}
+		b.WriteString(line + "\n")
+	}
+	return b.String(), true
+}
+
+// LinkSubtrees replaces each headline whose property drawer has an ID in
+// titles, along with everything under it, by a headline of the same level
+// linking to that ID, for headings published as pages of their own
+func LinkSubtrees(content string, titles map[string]string) string {
+	if len(titles) == 0 {
+		return content
+	}
+	lines := strings.Split(content, "\n")
+
+	// The range of each linked subtree, as its first line and level
+	starts := make(map[int]int)
+	heading, headingLevel := -1, 0
+	for i, line := range lines {
+		if m := subtreeHeadingRe.FindStringSubmatch(line); m != nil {
+			heading, headingLevel = i, len(m[1])
+			continue
+		}
+		if m := subtreeIDRe.FindStringSubmatch(line); m != nil && heading >= 0 {
+			if _, ok := titles[m[1]]; ok {
+				starts[heading] = headingLevel
+			}
+		}
+	}
+	if len(starts) == 0 {
+		return content
+	}
+
+	var kept []string
+	skipLevel := 0
+	for i, line := range lines {
+		m := subtreeHeadingRe.FindStringSubmatch(line)
+		if skipLevel > 0 {
+			if m == nil || len(m[1]) > skipLevel {
+				continue
+			}
+			skipLevel = 0
+		}
+		level, ok := starts[i]
+		if !ok {
+			kept = append(kept, line)
+			continue
+		}
+		id := subtreeID(lines[i+1:])
+		kept = append(kept, fmt.Sprintf("%s [[id:%s][%s]]", strings.Repeat("*", level), id, titles[id]))
+		skipLevel = level
+	}
+	return strings.Join(kept, "\n")
+}
+
+// subtreeID returns the ID in the property drawer at the start of lines,
+// which follow a headline
+func subtreeID(lines []string) string {
+	for _, line := range lines {
+		if subtreeHeadingRe.MatchString(line) {
+			break
+		}
+		if m := subtreeIDRe.FindStringSubmatch(line); m != nil {
+			return m[1]
+		}
+	}
+	return ""
+}
@@ -0,0 +1,111 @@
+package parser
+
+import "testing"
+
+// subtreeFile has heading nodes at several levels
+const subtreeFile = `#+title: File
+#+filetags: :go:
+
+Intro.
+
+* Outer
+:PROPERTIES:
+:ID: h1
+:END:
+Outer text.
+** Inner
+:PROPERTIES:
+:ID: h2
+:END:
+Inner text.
+*** Deep
+Deep text.
+** Sibling
+Sibling text.
+* Next
+:PROPERTIES:
+:ID: h3
+:END:
+Next text.`
+
+func TestSubtree(t *testing.T) {
+	tests := []struct {
+		name   string
+		id     string
+		want   string
+		wantOK bool
+	}{
+		{
+			name:   "nested headings promoted",
+			id:     "h1",
+			want:   "#+filetags: :go:\n:PROPERTIES:\n:ID: h1\n:END:\nOuter text.\n* Inner\n:PROPERTIES:\n:ID: h2\n:END:\nInner text.\n** Deep\nDeep text.\n* Sibling\nSibling text.\n",
+			wantOK: true,
+		},
+		{
+			name:   "ends at a sibling",
+			id:     "h2",
+			want:   "#+filetags: :go:\n:PROPERTIES:\n:ID: h2\n:END:\nInner text.\n* Deep\nDeep text.\n",
+			wantOK: true,
+		},
+		{
+			name:   "last heading",
+			id:     "h3",
+			want:   "#+filetags: :go:\n:PROPERTIES:\n:ID: h3\n:END:\nNext text.\n",
+			wantOK: true,
+		},
+		{name: "missing ID", id: "nope"},
+		{name: "ID prefix", id: "h"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := Subtree(subtreeFile, tt.id)
+			if ok != tt.wantOK {
+				t.Fatalf("Subtree(%q) ok = %v, want %v", tt.id, ok, tt.wantOK)
+			}
+			if got != tt.want {
+				t.Errorf("Subtree(%q) =\n%s\nwant\n%s", tt.id, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLinkSubtrees(t *testing.T) {
+	tests := []struct {
+		name   string
+		titles map[string]string
+		want   string
+	}{
+		{
+			name:   "none",
+			titles: nil,
+			want:   subtreeFile,
+		},
+		{
+			name:   "unknown ID",
+			titles: map[string]string{"nope": "Nope"},
+			want:   subtreeFile,
+		},
+		{
+			name:   "nested heading",
+			titles: map[string]string{"h2": "Inner"},
+			want:   "#+title: File\n#+filetags: :go:\n\nIntro.\n\n* Outer\n:PROPERTIES:\n:ID: h1\n:END:\nOuter text.\n** [[id:h2][Inner]]\n** Sibling\nSibling text.\n* Next\n:PROPERTIES:\n:ID: h3\n:END:\nNext text.",
+		},
+		{
+			name:   "outer heading covers inner",
+			titles: map[string]string{"h1": "Outer", "h2": "Inner"},
+			want:   "#+title: File\n#+filetags: :go:\n\nIntro.\n\n* [[id:h1][Outer]]\n* Next\n:PROPERTIES:\n:ID: h3\n:END:\nNext text.",
+		},
+		{
+			name:   "last heading",
+			titles: map[string]string{"h3": "Next"},
+			want:   "#+title: File\n#+filetags: :go:\n\nIntro.\n\n* Outer\n:PROPERTIES:\n:ID: h1\n:END:\nOuter text.\n** Inner\n:PROPERTIES:\n:ID: h2\n:END:\nInner text.\n*** Deep\nDeep text.\n** Sibling\nSibling text.\n* [[id:h3][Next]]",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := LinkSubtrees(subtreeFile, tt.titles); got != tt.want {
+				t.Errorf("LinkSubtrees =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,111 @@
+package parser
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+// timestampBody matches the inside of an org timestamp: a date, optional
+// day name, optional time or time range, and optional repeater/warning
+// cookies (which are dropped for display)
+const timestampBody = `(\d{4}-\d{2}-\d{2})(?: [^\s\d\]>]+)?(?: (\d{1,2}:\d{2})(?:-(\d{1,2}:\d{2}))?)?(?: [.+-]{1,2}\d+[hdwmy])*`
+
+var (
+	activeRangeRe   = regexp.MustCompile(`<` + timestampBody + `>--<` + timestampBody + `>`)
+	inactiveRangeRe = regexp.MustCompile(`\[` + timestampBody + `\]--\[` + timestampBody + `\]`)
+	activeRe        = regexp.MustCompile(`<` + timestampBody + `>`)
+	inactiveRe      = regexp.MustCompile(`\[` + timestampBody + `\]`)
+
+	// inlineCodeRe matches inline code (~code~) and verbatim (=verbatim=)
+	inlineCodeRe = regexp.MustCompile(`~[^\s~](?:[^~]*[^\s~])?~|=[^\s=](?:[^=]*[^\s=])?=`)
+)
+
+// convertTimestamps rewrites active (<2024-01-01 Mon>) and inactive
+// ([2024-01-01 Mon]) timestamps and ranges into formatted <time> elements,
+// passed through go-org as inline HTML export snippets. Keyword lines, the
+// contents of blocks and inline code and verbatim are left untouched.
+func convertTimestamps(content string, dateFormat string) string {
+	lines := strings.Split(content, "\n")
+	inBlock := false
+	for i, line := range lines {
+		trimmed := strings.ToLower(strings.TrimSpace(line))
+		switch {
+		case strings.HasPrefix(trimmed, "#+begin_"):
+			inBlock = true
+			continue
+		case strings.HasPrefix(trimmed, "#+end_"):
+			inBlock = false
+			continue
+		case inBlock, strings.HasPrefix(trimmed, "#+"), strings.HasPrefix(trimmed, ":"):
+			continue
+		}
+		lines[i] = convertTimestampLine(line, dateFormat)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// convertTimestampLine converts the timestamps in a single line, outside
+// inline code and verbatim
+func convertTimestampLine(line string, dateFormat string) string {
+	var b strings.Builder
+	last := 0
+	for _, loc := range inlineCodeRe.FindAllStringIndex(line, -1) {
+		// Markup starts a word, so a=b= isn't verbatim
+		if r, _ := utf8.DecodeLastRuneInString(line[:loc[0]]); unicode.IsLetter(r) || unicode.IsDigit(r) {
+			continue
+		}
+		b.WriteString(convertTimestampText(line[last:loc[0]], dateFormat))
+		b.WriteString(line[loc[0]:loc[1]])
+		last = loc[1]
+	}
+	b.WriteString(convertTimestampText(line[last:], dateFormat))
+	return b.String()
+}
+
+// convertTimestampText converts the timestamps in text
+func convertTimestampText(line string, dateFormat string) string {
+	rangeFunc := func(re *regexp.Regexp, class string) func(string) string {
+		return func(s string) string {
+			m := re.FindStringSubmatch(s)
+			return fmt.Sprintf(`@@html:<span class="timestamp-range %s">%s – %s</span>@@`, class,
+				timeElement(m[1], m[2], m[3], dateFormat), timeElement(m[4], m[5], m[6], dateFormat))
+		}
+	}
+	singleFunc := func(re *regexp.Regexp, class string) func(string) string {
+		return func(s string) string {
+			m := re.FindStringSubmatch(s)
+			return fmt.Sprintf(`@@html:<span class="timestamp %s">%s</span>@@`, class, timeElement(m[1], m[2], m[3], dateFormat))
+		}
+	}
+
+	line = activeRangeRe.ReplaceAllStringFunc(line, rangeFunc(activeRangeRe, "active"))
+	line = inactiveRangeRe.ReplaceAllStringFunc(line, rangeFunc(inactiveRangeRe, "inactive"))
+	line = activeRe.ReplaceAllStringFunc(line, singleFunc(activeRe, "active"))
+	line = inactiveRe.ReplaceAllStringFunc(line, singleFunc(inactiveRe, "inactive"))
+	return line
+}
+
+// timeElement formats a date with optional start and end times as a
+// <time> element. Unparseable dates are returned as written.
+func timeElement(date, start, end, dateFormat string) string {
+	t, err := time.Parse("2006-01-02", date)
+	if err != nil {
+		return date
+	}
+
+	text := t.Format(dateFormat)
+	datetime := date
+	if start != "" {
+		text += " " + start
+		datetime += "T" + fmt.Sprintf("%05s", start)
+		if end != "" {
+			text += "–" + end
+		}
+	}
+
+	return fmt.Sprintf(`<time datetime="%s">%s</time>`, datetime, text)
+}
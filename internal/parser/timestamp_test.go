@@ -0,0 +1,104 @@
+package parser
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestConvertTimestamps(t *testing.T) {
+	tests := []struct {
+		name   string
+		in     string
+		format string
+		want   string
+	}{
+		{
+			"active", "Due <2024-01-05 Fri>.", "Jan 2, 2006",
+			`Due @@html:<span class="timestamp active"><time datetime="2024-01-05">Jan 5, 2024</time></span>@@.`,
+		},
+		{
+			"inactive", "[2024-02-01 Thu]", "Jan 2, 2006",
+			`@@html:<span class="timestamp inactive"><time datetime="2024-02-01">Feb 1, 2024</time></span>@@`,
+		},
+		{
+			"repeater dropped", "<2024-01-05 Fri 10:00 +1w>", "2006-01-02",
+			`@@html:<span class="timestamp active"><time datetime="2024-01-05T10:00">2024-01-05 10:00</time></span>@@`,
+		},
+		{
+			"repeater and warning", "<2024-01-05 Fri .+2d -3d>", "02.01.2006",
+			`@@html:<span class="timestamp active"><time datetime="2024-01-05">05.01.2024</time></span>@@`,
+		},
+		{
+			"time range", "<2024-01-05 Fri 9:00-10:30>", "Jan 2",
+			`@@html:<span class="timestamp active"><time datetime="2024-01-05T09:00">Jan 5 9:00–10:30</time></span>@@`,
+		},
+		{
+			"active date range", "<2024-03-01 Fri>--<2024-03-03 Sun>", "Jan 2, 2006",
+			`@@html:<span class="timestamp-range active"><time datetime="2024-03-01">Mar 1, 2024</time> – <time datetime="2024-03-03">Mar 3, 2024</time></span>@@`,
+		},
+		{
+			"inactive date range", "[2024-03-01]--[2024-03-03 Sun +1m]", "2006-01-02",
+			`@@html:<span class="timestamp-range inactive"><time datetime="2024-03-01">2024-03-01</time> – <time datetime="2024-03-03">2024-03-03</time></span>@@`,
+		},
+		{
+			"invalid date", "<2024-13-45 Mon>", "Jan 2, 2006",
+			`@@html:<span class="timestamp active">2024-13-45</span>@@`,
+		},
+		{"keyword line", "#+date: <2024-01-05 Fri>", "Jan 2, 2006", "#+date: <2024-01-05 Fri>"},
+		{"property drawer", ":CREATED: [2024-01-05 Fri]", "Jan 2, 2006", ":CREATED: [2024-01-05 Fri]"},
+		{
+			"block", "#+begin_example\n<2024-01-05 Fri>\n#+end_example", "Jan 2, 2006",
+			"#+begin_example\n<2024-01-05 Fri>\n#+end_example",
+		},
+		{"not a timestamp", "<not a date> [1/3]", "Jan 2, 2006", "<not a date> [1/3]"},
+		{"inline code", "Write ~<2024-01-01 Mon>~ for a date", "Jan 2, 2006", "Write ~<2024-01-01 Mon>~ for a date"},
+		{"verbatim", "Or =[2024-01-01 Mon 10:00]= and =<2024-03-01>--<2024-03-03>=", "Jan 2, 2006", "Or =[2024-01-01 Mon 10:00]= and =<2024-03-01>--<2024-03-03>="},
+		{
+			"code next to a timestamp", "~<2024-01-01 Mon>~ is due <2024-01-05 Fri>", "Jan 2, 2006",
+			`~<2024-01-01 Mon>~ is due @@html:<span class="timestamp active"><time datetime="2024-01-05">Jan 5, 2024</time></span>@@`,
+		},
+		{
+			"equals inside a word", "x=<2024-01-05 Fri>=", "Jan 2, 2006",
+			`x=@@html:<span class="timestamp active"><time datetime="2024-01-05">Jan 5, 2024</time></span>@@=`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := convertTimestamps(tt.in, tt.format); got != tt.want {
+				t.Errorf("convertTimestamps(%q)\n got %s\nwant %s", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseTimestamps(t *testing.T) {
+	p := newTestParser()
+	p.DateFormat = "2 Jan 2006"
+	parsed := mustParse(t, p, "Meeting <2024-01-05 Fri 10:00 +1w> after [2024-01-01 Mon].\n")
+
+	for _, want := range []string{
+		`<span class="timestamp active"><time datetime="2024-01-05T10:00">5 Jan 2024 10:00</time></span>`,
+		`<span class="timestamp inactive"><time datetime="2024-01-01">1 Jan 2024</time></span>`,
+	} {
+		if !strings.Contains(parsed.Content, want) {
+			t.Errorf("content lacks %s:\n%s", want, parsed.Content)
+		}
+	}
+	if strings.Contains(parsed.Content, "+1w") {
+		t.Error("repeater cookie left in content")
+	}
+}
+
+func TestParseTimestampsInCode(t *testing.T) {
+	p := newTestParser()
+	parsed := mustParse(t, p, "Active timestamps look like ~<2024-01-01 Mon>~ or =[2024-01-01 Mon]=.\n")
+
+	for _, want := range []string{`<code>&lt;2024-01-01 Mon&gt;</code>`, `<code class="verbatim">[2024-01-01 Mon]</code>`} {
+		if !strings.Contains(parsed.Content, want) {
+			t.Errorf("content lacks %s:\n%s", want, parsed.Content)
+		}
+	}
+	if strings.Contains(parsed.Content, "<time") {
+		t.Errorf("timestamp in code converted:\n%s", parsed.Content)
+	}
+}
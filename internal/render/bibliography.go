@@ -0,0 +1,116 @@
+package render
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/nicehiro/org-roam-web/internal/db"
+)
+
+// Citation is a ROAM_REFS cite key resolved against paths.bibliography
+type Citation struct {
+	Key     string
+	Text    string // Formatted citation, empty if Missing
+	URL     string // DOI or URL of the work, if known
+	Missing bool   // The key isn't in the bibliography
+}
+
+// NoteRef is a ROAM_REFS entry shown on a note page
+type NoteRef struct {
+	Text string
+	URL  string // Link to the ref, empty if it isn't a web link
+}
+
+// BibliographyData holds data for the bibliography page
+type BibliographyData struct {
+	Site    SiteData
+	Entries []BibliographyEntry
+}
+
+// BibliographyEntry is a cited work with the notes citing it
+type BibliographyEntry struct {
+	Citation
+	Notes []LinkData
+}
+
+// noteCitations resolves the cite keys of a note, in ROAM_REFS order
+func (r *Renderer) noteCitations(n db.Node) []Citation {
+	if r.bibliography == nil {
+		return nil
+	}
+	var cites []Citation
+	for _, key := range r.citeKeys[n.ID] {
+		cites = append(cites, r.citation(key))
+	}
+	return cites
+}
+
+// noteRefs returns the refs of a note other than the cite keys resolved
+// by noteCitations, in ROAM_REFS order. http(s) and doi refs link to the
+// work.
+func (r *Renderer) noteRefs(n db.Node) []NoteRef {
+	var refs []NoteRef
+	for _, ref := range r.refs[n.ID] {
+		switch ref.Type {
+		case "cite":
+			if r.bibliography == nil {
+				refs = append(refs, NoteRef{Text: ref.String()})
+			}
+		case "http", "https":
+			refs = append(refs, NoteRef{Text: ref.String(), URL: ref.String()})
+		case "doi":
+			refs = append(refs, NoteRef{Text: ref.String(), URL: "https://doi.org/" + ref.Path})
+		default:
+			refs = append(refs, NoteRef{Text: ref.String()})
+		}
+	}
+	return refs
+}
+
+// citation resolves one cite key
+func (r *Renderer) citation(key string) Citation {
+	e, ok := r.bibliography[key]
+	if !ok {
+		return Citation{Key: key, Missing: true}
+	}
+	return Citation{Key: key, Text: e.Citation(), URL: e.Link()}
+}
+
+// generateBibliography generates bibliography.html, listing every work
+// cited by a published note, sorted by citation with missing keys last.
+// Missing keys are reported once each.
+func (r *Renderer) generateBibliography() error {
+	byKey := make(map[string]*BibliographyEntry)
+	for _, n := range r.sortedNodes() {
+		for _, key := range r.citeKeys[n.ID] {
+			e, ok := byKey[key]
+			if !ok {
+				e = &BibliographyEntry{Citation: r.citation(key)}
+				byKey[key] = e
+				if e.Missing {
+					fmt.Printf("Warning: cite key %s of note %s is not in the bibliography\n", key, n.Title)
+				}
+			}
+			e.Notes = append(e.Notes, LinkData{ID: n.ID, Title: r.nodeMap[n.ID], URL: r.noteURL(n.ID)})
+		}
+	}
+
+	data := BibliographyData{Site: r.siteData()}
+	for _, e := range byKey {
+		data.Entries = append(data.Entries, *e)
+	}
+	sort.Slice(data.Entries, func(i, j int) bool {
+		a, b := data.Entries[i], data.Entries[j]
+		if a.Missing != b.Missing {
+			return !a.Missing
+		}
+		if ta, tb := strings.ToLower(a.Text), strings.ToLower(b.Text); ta != tb {
+			return ta < tb
+		}
+		return a.Key < b.Key
+	})
+
+	return r.renderPage("bibliography.html", filepath.Join(r.cfg.Paths.OutputDir, "bibliography.html"), data)
+}
@@ -0,0 +1,72 @@
+package render
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// addRef records a ROAM_REFS entry of a note in the fixture database
+func (v *testVault) addRef(id, ref, refType string) {
+	v.t.Helper()
+	v.exec(`INSERT INTO refs (node_id, ref, type) VALUES (?, ?, ?)`, quote(id), quote(ref), quote(refType))
+}
+
+func TestBibliography(t *testing.T) {
+	const citation = "Dijkstra (1968). Go To Statement Considered Harmful. Communications of the ACM."
+	tests := []struct {
+		name string
+		file string
+	}{
+		{"BibTeX", "refs.bib"},
+		{"CSL-JSON", "refs.json"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := newTestVault(t)
+			v.cfg.Paths.Bibliography = filepath.Join("..", "bib", "testdata", tt.file)
+			v.add(testNote{ID: "l1", Title: "Literature"})
+			v.addRef("l1", "dijkstra68", "cite")
+			v.addRef("l1", "@missing99", "cite")
+			v.addRef("l1", "//example.org/page", "https")
+			v.add(testNote{ID: "l2", Title: "Another reading"})
+			v.addRef("l2", "dijkstra68", "cite")
+			out := captureStdout(t, func() { v.build() })
+
+			refs := between(v.read("notes/l1.html"), "<h2>References</h2>", "</section>")
+			for _, want := range []string{
+				`<a href="/bibliography.html#dijkstra68">` + citation + `</a>`,
+				`<a href="https://doi.org/10.1145/362929.362947" class="external-link"`,
+				`<li class="missing-ref">@missing99 (not in the bibliography)</li>`,
+				`<a href="https://example.org/page" class="external-link"`,
+			} {
+				if !strings.Contains(refs, want) {
+					t.Errorf("references lack %s:\n%s", want, refs)
+				}
+			}
+
+			page := v.read("bibliography.html")
+			if !strings.Contains(page, "2 works cited") {
+				t.Errorf("bibliography count: %s", between(page, `<p class="bibliography-count">`, "</p>"))
+			}
+			entry := between(page, `id="dijkstra68">`, "</li>")
+			if !strings.Contains(entry, citation) || !strings.Contains(entry, ">Literature</a>") || !strings.Contains(entry, ">Another reading</a>") {
+				t.Errorf("dijkstra68 entry lacks its citation or citing notes:\n%s", entry)
+			}
+			if !strings.Contains(between(page, `id="missing99">`, "</li>"), "@missing99 (not in the bibliography)") {
+				t.Error("missing key not listed as missing")
+			}
+			if n := strings.Count(out, "cite key missing99 of note Literature is not in the bibliography"); n != 1 {
+				t.Errorf("missing key reported %d times:\n%s", n, out)
+			}
+		})
+	}
+}
+
+func TestBibliographyMissingFile(t *testing.T) {
+	v := newTestVault(t)
+	v.cfg.Paths.Bibliography = filepath.Join(v.cfg.Paths.RoamDir, "none.bib")
+	if _, err := v.tryBuild(); err == nil || !strings.Contains(err.Error(), "failed to read bibliography") {
+		t.Errorf("Build with a missing bibliography: err = %v", err)
+	}
+}
@@ -0,0 +1,53 @@
+package render
+
+import (
+	"encoding/json"
+	"html/template"
+	"strings"
+)
+
+// tagSeparator splits hierarchical tag names such as "lang/go"
+const tagSeparator = "/"
+
+// breadcrumbItem is a schema.org ListItem of a BreadcrumbList
+type breadcrumbItem struct {
+	Type     string `json:"@type"`
+	Position int    `json:"position"`
+	Name     string `json:"name"`
+	Item     string `json:"item,omitempty"` // Omitted for the current page
+}
+
+// tagBreadcrumbs returns the trail Home › parent › tag of a tag page.
+// Parents are the leading parts of a hierarchical tag name; those without
+// a page of their own are skipped.
+func (r *Renderer) tagBreadcrumbs(tag string, hasPage func(tag string) bool) []breadcrumbItem {
+	items := []breadcrumbItem{{Name: r.cfg.Site.Title, Item: r.cfg.Site.AbsURL("")}}
+
+	parts := strings.Split(tag, tagSeparator)
+	for i := 1; i < len(parts); i++ {
+		parent := strings.Join(parts[:i], tagSeparator)
+		if hasPage(parent) {
+			items = append(items, breadcrumbItem{Name: "#" + parent, Item: r.cfg.Site.AbsURL("tags/" + parent + ".html")})
+		}
+	}
+	items = append(items, breadcrumbItem{Name: "#" + tag})
+
+	for i := range items {
+		items[i].Type = "ListItem"
+		items[i].Position = i + 1
+	}
+	return items
+}
+
+// breadcrumbJSONLD renders a BreadcrumbList as JSON-LD for a script element
+func breadcrumbJSONLD(items []breadcrumbItem) (template.JS, error) {
+	data, err := json.Marshal(struct {
+		Context string           `json:"@context"`
+		Type    string           `json:"@type"`
+		Items   []breadcrumbItem `json:"itemListElement"`
+	}{"https://schema.org", "BreadcrumbList", items})
+	if err != nil {
+		return "", err
+	}
+	return scriptJSON(data), nil
+}
@@ -0,0 +1,82 @@
+package render
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// breadcrumbList is the JSON-LD on a tag page
+type breadcrumbList struct {
+	Context string           `json:"@context"`
+	Type    string           `json:"@type"`
+	Items   []breadcrumbItem `json:"itemListElement"`
+}
+
+func TestTagBreadcrumbs(t *testing.T) {
+	v := newTestVault(t)
+	v.cfg.Site.BaseURL = "https://example.com/wiki"
+	v.cfg.Display.TagBreadcrumbs = true
+	v.add(testNote{ID: "t1", Title: "Web", Tags: []string{"lang/go/web"}})
+	v.add(testNote{ID: "t2", Title: "Languages", Tags: []string{"lang"}})
+	captureStdout(t, func() { v.build() })
+
+	home := breadcrumbItem{Type: "ListItem", Position: 1, Name: "My Notes", Item: "https://example.com/wiki/"}
+	tests := []struct {
+		tag  string
+		want []breadcrumbItem
+	}{
+		{"go", []breadcrumbItem{home, {Type: "ListItem", Position: 2, Name: "#go"}}},
+		{"lang", []breadcrumbItem{home, {Type: "ListItem", Position: 2, Name: "#lang"}}},
+		{
+			// lang/go has no notes of its own, so no page to link
+			"lang/go/web", []breadcrumbItem{
+				home,
+				{Type: "ListItem", Position: 2, Name: "#lang", Item: "https://example.com/wiki/tags/lang.html"},
+				{Type: "ListItem", Position: 3, Name: "#lang/go/web"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.tag, func(t *testing.T) {
+			page := v.read("tags/" + tt.tag + ".html")
+			raw := between(page, `<script type="application/ld+json">`, "</script>")
+			var list breadcrumbList
+			if err := json.Unmarshal([]byte(raw), &list); err != nil {
+				t.Fatalf("breadcrumb JSON-LD %q: %v", raw, err)
+			}
+			if list.Context != "https://schema.org" || list.Type != "BreadcrumbList" {
+				t.Errorf("JSON-LD is a %s of %s", list.Type, list.Context)
+			}
+			if !reflect.DeepEqual(list.Items, tt.want) {
+				t.Errorf("breadcrumbs %+v, want %+v", list.Items, tt.want)
+			}
+		})
+	}
+}
+
+func TestTagBreadcrumbsDisabled(t *testing.T) {
+	v := newTestVault(t)
+	v.build()
+	if raw := between(v.read("tags/go.html"), `<script type="application/ld+json">`, "</script>"); raw != "" {
+		t.Errorf("breadcrumbs on a tag page with tag_breadcrumbs off: %s", raw)
+	}
+}
+
+func TestBreadcrumbJSONLDEscaping(t *testing.T) {
+	js, err := breadcrumbJSONLD([]breadcrumbItem{{Type: "ListItem", Position: 1, Name: "</script><b>"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var list breadcrumbList
+	if err := json.Unmarshal([]byte(js), &list); err != nil {
+		t.Fatal(err)
+	}
+	if list.Items[0].Name != "</script><b>" {
+		t.Errorf("breadcrumbJSONLD = %s, decodes to name %q", js, list.Items[0].Name)
+	}
+	if strings.ContainsAny(string(js), "<>") {
+		t.Errorf("breadcrumbJSONLD output %s is not escaped for a script element", js)
+	}
+}
@@ -0,0 +1,110 @@
+package render
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/nicehiro/org-roam-web/internal/db"
+	"github.com/nicehiro/org-roam-web/internal/parser"
+)
+
+// Reasons an id: link has no page to point to
+const (
+	BrokenLinkExcluded = "excluded" // The target note exists but isn't published
+	BrokenLinkMissing  = "missing"  // No note has the target ID
+)
+
+// BrokenLink is an id: link in a note whose target isn't published
+type BrokenLink struct {
+	Source string `json:"source"` // ID of the linking note
+	File   string `json:"file"`   // Its file, relative to roam_dir
+	Target string `json:"target"`
+	Reason string `json:"reason"`
+}
+
+// noteBrokenLinks returns the id: links of a parsed note that have no
+// published target, once per target
+func (r *Renderer) noteBrokenLinks(n db.Node, links []parser.InternalLink) []BrokenLink {
+	var broken []BrokenLink
+	seen := make(map[string]bool)
+	for _, l := range links {
+		if _, ok := r.nodeMap[l.ID]; ok || seen[l.ID] {
+			continue
+		}
+		seen[l.ID] = true
+		reason := BrokenLinkMissing
+		if r.knownIDs[l.ID] {
+			reason = BrokenLinkExcluded
+		}
+		broken = append(broken, BrokenLink{
+			Source: n.ID,
+			File:   filepath.ToSlash(r.relNotePath(n.File)),
+			Target: l.ID,
+			Reason: reason,
+		})
+	}
+	return broken
+}
+
+// BrokenLinks returns the broken id: links found by Build, ordered by file
+// and target
+func (r *Renderer) BrokenLinks() []BrokenLink {
+	r.noteTextMu.Lock()
+	defer r.noteTextMu.Unlock()
+	var all []BrokenLink
+	for _, links := range r.brokenLinks {
+		all = append(all, links...)
+	}
+	sort.Slice(all, func(i, j int) bool {
+		if all[i].File != all[j].File {
+			return all[i].File < all[j].File
+		}
+		return all[i].Target < all[j].Target
+	})
+	return all
+}
+
+// MissingLinks counts the broken links to IDs no note has. Links to
+// excluded notes aren't counted.
+func (r *Renderer) MissingLinks() int {
+	missing := 0
+	for _, l := range r.BrokenLinks() {
+		if l.Reason == BrokenLinkMissing {
+			missing++
+		}
+	}
+	return missing
+}
+
+// reportBrokenLinks warns about each link to a missing note, counts the
+// links to excluded notes, and writes broken-links.json with
+// display.broken_links_report
+func (r *Renderer) reportBrokenLinks() error {
+	broken := r.BrokenLinks()
+	excluded := 0
+	for _, l := range broken {
+		if l.Reason == BrokenLinkMissing {
+			fmt.Printf("Warning: broken link in %s: no note with ID %s\n", l.File, l.Target)
+		} else {
+			excluded++
+		}
+	}
+	if excluded > 0 {
+		fmt.Printf("Skipped %d links to excluded notes\n", excluded)
+	}
+
+	if !r.cfg.Display.BrokenLinksReport {
+		return nil
+	}
+	if broken == nil {
+		broken = []BrokenLink{}
+	}
+	data, err := json.MarshalIndent(broken, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to serialize broken links: %w", err)
+	}
+	return os.WriteFile(filepath.Join(r.cfg.Paths.OutputDir, "broken-links.json"), data, 0644)
+}
@@ -0,0 +1,77 @@
+package render
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestBrokenLinks(t *testing.T) {
+	want := []BrokenLink{
+		{Source: "x1", File: "x1.org", Target: "d4", Reason: BrokenLinkExcluded},
+		{Source: "x1", File: "x1.org", Target: "zz", Reason: BrokenLinkMissing},
+	}
+	tests := []struct {
+		name   string
+		report bool
+	}{
+		{"without report", false},
+		{"with report", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := newTestVault(t)
+			v.cfg.Display.BrokenLinksReport = tt.report
+			// d4 is excluded as private, no note has zz
+			v.add(testNote{ID: "x1", Title: "Linker", Links: []string{"zz", "d4", "zz"}})
+			var r *Renderer
+			out := captureStdout(t, func() { r = v.build() })
+
+			if got := r.BrokenLinks(); !slices.Equal(got, want) {
+				t.Errorf("BrokenLinks() = %+v, want %+v", got, want)
+			}
+			if got := r.MissingLinks(); got != 1 {
+				t.Errorf("MissingLinks() = %d, want 1", got)
+			}
+
+			if got := strings.Count(out, "Warning: broken link"); got != 1 {
+				t.Errorf("%d broken link warnings, want 1:\n%s", got, out)
+			}
+			if !strings.Contains(out, "Warning: broken link in x1.org: no note with ID zz") {
+				t.Errorf("no warning for zz:\n%s", out)
+			}
+			if !strings.Contains(out, "Skipped 1 links to excluded notes") {
+				t.Errorf("excluded link not counted:\n%s", out)
+			}
+
+			if !tt.report {
+				if v.exists("broken-links.json") {
+					t.Error("broken-links.json written without display.broken_links_report")
+				}
+				return
+			}
+			var report []BrokenLink
+			v.readJSON("broken-links.json", &report)
+			if !slices.Equal(report, want) {
+				t.Errorf("broken-links.json = %+v, want %+v", report, want)
+			}
+		})
+	}
+}
+
+func TestBrokenLinksNone(t *testing.T) {
+	v := newTestVault(t)
+	v.cfg.Display.BrokenLinksReport = true
+	var r *Renderer
+	out := captureStdout(t, func() { r = v.build() })
+
+	if got := r.MissingLinks(); got != 0 {
+		t.Errorf("MissingLinks() = %d, want 0", got)
+	}
+	if strings.Contains(out, "broken link") || strings.Contains(out, "excluded notes") {
+		t.Errorf("unexpected report:\n%s", out)
+	}
+	if got := strings.TrimSpace(v.read("broken-links.json")); got != "[]" {
+		t.Errorf("broken-links.json = %s, want []", got)
+	}
+}
@@ -0,0 +1,170 @@
+package render
+
+import (
+	"encoding/base64"
+	"fmt"
+	"html/template"
+	"io/fs"
+	"mime"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/nicehiro/org-roam-web/internal/db"
+	"github.com/nicehiro/org-roam-web/internal/graph"
+	"github.com/nicehiro/org-roam-web/internal/parser"
+)
+
+// BundleData holds data for a single-file export of several notes
+type BundleData struct {
+	Title  string
+	Styles template.CSS
+	Notes  []BundleNote
+}
+
+// BundleNote is one note inside a bundle
+type BundleNote struct {
+	ID      string
+	Title   string
+	Tags    []string
+	ModTime time.Time
+	Content template.HTML
+}
+
+var (
+	styleRe     = regexp.MustCompile(`(?s)<style>(.*?)</style>`)
+	bundleImgRe = regexp.MustCompile(`src="/img/([^"]+)"`)
+)
+
+// ExportBundle writes the note rootID and every note within depth links of
+// it to outPath as one self-contained HTML file. Styles and images are
+// inlined, and links between bundled notes become in-page anchors.
+func (r *Renderer) ExportBundle(rootID string, depth int, outPath string) error {
+	if err := r.loadData(); err != nil {
+		return err
+	}
+
+	rootID = r.resolveRedirect(rootID)
+	if _, ok := r.nodeMap[rootID]; !ok {
+		return fmt.Errorf("note %s not found", rootID)
+	}
+
+	// The bundle holds the root's local graph, root first then by title
+	g := graph.LocalGraph(rootID, depth, 0, r.nodes, r.links, r.nodeTags)
+	inBundle := make(map[string]bool)
+	for _, gn := range g.Nodes {
+		inBundle[gn.ID] = true
+	}
+	var members []db.Node
+	for _, n := range r.nodes {
+		if inBundle[n.ID] {
+			members = append(members, n)
+		}
+	}
+	sort.SliceStable(members, func(i, j int) bool {
+		if (members[i].ID == rootID) != (members[j].ID == rootID) {
+			return members[i].ID == rootID
+		}
+		return members[i].Title < members[j].Title
+	})
+
+	noteURL := func(id string) string {
+		id = r.resolveRedirect(id)
+		if inBundle[id] {
+			return "#note-" + id
+		}
+		return r.noteURL(id)
+	}
+
+	// Parse with an empty base URL so image paths can be matched and inlined
+	p := parser.NewParser(r.cfg.Paths.RoamDir, r.nodeMap, "", noteURL)
+	p.DateFormat = r.cfg.Display.DateFormat
+	p.RespectNoExport = r.cfg.Display.RespectNoExport
+	p.TransliterateSlugs = r.cfg.Display.SlugTransliterate
+	p.ResolveSetupFiles = r.cfg.Display.SetupFiles
+	p.SyntaxHighlight = r.cfg.Display.SyntaxTheme != ""
+
+	styles, err := bundleStyles()
+	if err != nil {
+		return err
+	}
+	if theme := r.cfg.Display.SyntaxTheme; theme != "" {
+		css, err := parser.SyntaxCSS(theme)
+		if err != nil {
+			return err
+		}
+		styles += "\n" + string(css)
+	}
+
+	data := BundleData{
+		Title:  r.nodeMap[rootID],
+		Styles: template.CSS(styles),
+	}
+	for _, n := range members {
+		parsed, err := r.parseNote(p, n)
+		if err != nil {
+			return fmt.Errorf("failed to parse note %s: %w", n.Title, err)
+		}
+		data.Notes = append(data.Notes, BundleNote{
+			ID:      n.ID,
+			Title:   parsed.Title,
+			Tags:    r.nodeTags[n.ID],
+			ModTime: r.noteDate(n),
+			Content: template.HTML(r.inlineImages(parsed.Content)),
+		})
+	}
+
+	tmpl, err := template.New("").Funcs(templateFuncs(r.cfg.Display.DateFormat)).ParseFS(templatesFS, "templates/bundle.html")
+	if err != nil {
+		return fmt.Errorf("failed to parse template bundle.html: %w", err)
+	}
+
+	f, err := os.Create(outPath)
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %w", outPath, err)
+	}
+	defer f.Close()
+
+	if err := tmpl.ExecuteTemplate(f, "bundle", data); err != nil {
+		return fmt.Errorf("failed to execute template bundle.html: %w", err)
+	}
+
+	return nil
+}
+
+// bundleStyles collects the site and note page stylesheets so a bundle
+// looks like the published pages
+func bundleStyles() (string, error) {
+	var styles []string
+	for _, name := range []string{"templates/base.html", "templates/partials.html", "templates/note.html"} {
+		src, err := fs.ReadFile(templatesFS, name)
+		if err != nil {
+			return "", fmt.Errorf("failed to read %s: %w", name, err)
+		}
+		if m := styleRe.FindSubmatch(src); m != nil {
+			styles = append(styles, string(m[1]))
+		}
+	}
+	return strings.Join(styles, "\n"), nil
+}
+
+// inlineImages replaces site image references in content with data URIs.
+// Images that can't be read are reported and left as they are.
+func (r *Renderer) inlineImages(content string) string {
+	return bundleImgRe.ReplaceAllStringFunc(content, func(m string) string {
+		rel := bundleImgRe.FindStringSubmatch(m)[1]
+		img, err := os.ReadFile(filepath.Join(r.cfg.Paths.RoamDir, "img", filepath.FromSlash(rel)))
+		if err != nil {
+			fmt.Printf("Warning: image %s not inlined: %v\n", rel, err)
+			return m
+		}
+		mimeType := mime.TypeByExtension(filepath.Ext(rel))
+		if mimeType == "" {
+			mimeType = "application/octet-stream"
+		}
+		return `src="data:` + mimeType + `;base64,` + base64.StdEncoding.EncodeToString(img) + `"`
+	})
+}
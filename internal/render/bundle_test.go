@@ -0,0 +1,97 @@
+package render
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"testing"
+)
+
+var bundleNoteRe = regexp.MustCompile(`<article class="bundle-note" id="note-([^"]+)"`)
+
+func TestExportBundle(t *testing.T) {
+	tests := []struct {
+		depth int
+		want  []string // Bundled notes, in order
+	}{
+		{0, []string{"f6"}},
+		{1, []string{"f6", "g7"}},
+		{2, []string{"f6", "g7", "h8"}},
+		{5, []string{"f6", "g7", "h8"}},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("depth %d", tt.depth), func(t *testing.T) {
+			v := newTestVault(t)
+			v.add(testNote{ID: "f6", Title: "Zeta", Body: "[[file:img/pic.png]]\n\n[[file:img/missing.png]]", Links: []string{"g7"}})
+			v.add(testNote{ID: "g7", Title: "Eta", Links: []string{"h8"}})
+			v.add(testNote{ID: "h8", Title: "Theta"})
+			v.writeFile("img/pic.png", "\x89PNG\r\n\x1a\n")
+
+			r, err := NewRenderer(v.cfg)
+			if err != nil {
+				t.Fatal(err)
+			}
+			outPath := filepath.Join(t.TempDir(), "f6-bundle.html")
+			out := captureStdout(t, func() {
+				if err := r.ExportBundle("f6", tt.depth, outPath); err != nil {
+					t.Fatal(err)
+				}
+			})
+			data, err := os.ReadFile(outPath)
+			if err != nil {
+				t.Fatal(err)
+			}
+			bundle := string(data)
+
+			var got []string
+			for _, m := range bundleNoteRe.FindAllStringSubmatch(bundle, -1) {
+				got = append(got, m[1])
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("bundled notes = %v, want %v", got, tt.want)
+			}
+
+			// Styles are inlined, with no stylesheet or script to fetch
+			if !strings.Contains(bundle, ".note-content") || !strings.Contains(bundle, "--border") {
+				t.Error("bundle lacks the inlined page styles")
+			}
+			if strings.Contains(bundle, `rel="stylesheet"`) || strings.Contains(bundle, "<script src=") {
+				t.Error("bundle references external assets")
+			}
+
+			// Found images become data URIs; a missing one is kept with a warning
+			if !strings.Contains(bundle, `src="data:image/png;base64,iVBORw0KGgo="`) {
+				t.Error("img/pic.png not inlined")
+			}
+			if !strings.Contains(bundle, `src="/img/missing.png"`) || !strings.Contains(out, "Warning: image missing.png not inlined") {
+				t.Errorf("missing image not left in place with a warning: %q", out)
+			}
+
+			// Links within the bundle are anchors, others go to the site
+			for _, id := range []string{"g7", "h8"} {
+				inBundle := slices.Contains(tt.want, id)
+				if strings.Contains(bundle, `href="#note-`+id+`"`) != inBundle {
+					t.Errorf("anchor link to %s = %t, want %t", id, !inBundle, inBundle)
+				}
+			}
+			if tt.depth == 1 && !strings.Contains(bundle, `href="/notes/h8.html"`) {
+				t.Error("link to h8, outside the bundle, doesn't go to its page")
+			}
+		})
+	}
+
+	t.Run("unknown note", func(t *testing.T) {
+		v := newTestVault(t)
+		r, err := NewRenderer(v.cfg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = r.ExportBundle("zz", 1, filepath.Join(t.TempDir(), "zz-bundle.html"))
+		if err == nil || !strings.Contains(err.Error(), "note zz not found") {
+			t.Errorf("error = %v, want note zz not found", err)
+		}
+	})
+}
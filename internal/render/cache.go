@@ -0,0 +1,123 @@
+package render
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/nicehiro/org-roam-web/internal/config"
+)
+
+// buildCacheFile is the name of the build cache inside the output directory
+const buildCacheFile = ".build-cache.json"
+
+// buildCache records, for generated files that are costly to rebuild, a
+// hash of the inputs they were generated from. A file whose inputs hash
+// the same on the next build is left as it is.
+type buildCache struct {
+	path   string
+	Hashes map[string]string `json:"hashes"` // Output file name -> input hash
+}
+
+// loadBuildCache reads the build cache from outputDir. A missing or
+// unreadable cache yields an empty one, so every file is regenerated.
+func loadBuildCache(outputDir string) *buildCache {
+	c := &buildCache{
+		path:   filepath.Join(outputDir, buildCacheFile),
+		Hashes: make(map[string]string),
+	}
+	data, err := os.ReadFile(c.path)
+	if err != nil {
+		return c
+	}
+	if err := json.Unmarshal(data, c); err != nil || c.Hashes == nil {
+		fmt.Printf("Warning: ignoring unreadable build cache %s\n", c.path)
+		c.Hashes = make(map[string]string)
+	}
+	return c
+}
+
+// fresh reports whether name was generated from inputs hashing to hash and
+// is still present in the output directory
+func (c *buildCache) fresh(name, hash string) bool {
+	if c.Hashes[name] != hash {
+		return false
+	}
+	_, err := os.Stat(filepath.Join(filepath.Dir(c.path), name))
+	return err == nil
+}
+
+// record stores the input hash name was generated from
+func (c *buildCache) record(name, hash string) {
+	c.Hashes[name] = hash
+}
+
+// save writes the build cache
+func (c *buildCache) save() error {
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(c.path, data, 0644)
+}
+
+// inputHash hashes what the search index and graph are built from: each
+// note's ID, title, tags and URL, the site, display, exclude and graph
+// configuration, plus the given extra inputs. The configuration is hashed
+// whole rather than option by option, so a new option can never leave a
+// stale file behind.
+func (r *Renderer) inputHash(extra ...string) string {
+	h := sha256.New()
+	options := struct {
+		Site    config.SiteConfig
+		Display config.DisplayConfig
+		Exclude config.ExcludeConfig
+		Graph   config.GraphConfig
+	}{r.cfg.Site, r.cfg.Display, r.cfg.Exclude, r.cfg.Graph}
+	if data, err := json.Marshal(options); err == nil {
+		h.Write(data)
+	} else {
+		// Only a NaN float fails to marshal; fmt prints maps sorted too
+		fmt.Fprintf(h, "%#v", options)
+	}
+	h.Write([]byte("\n"))
+
+	for _, n := range r.nodes {
+		tags := append([]string(nil), r.nodeTags[n.ID]...)
+		sort.Strings(tags)
+		fmt.Fprintf(h, "node\x00%s\x00%s\x00%s\x00%s\x00%s\n", n.ID, n.Title, strings.Join(tags, "\x00"), r.noteURL(n.ID), n.Properties["STATUS"])
+	}
+	for _, e := range extra {
+		fmt.Fprintf(h, "extra\x00%s\n", e)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// graphInputHash extends inputHash with the links, the notes shown and
+// their aliases, and the notes currently flagged as recent
+func (r *Renderer) graphInputHash() string {
+	var extra []string
+	for _, l := range r.graphLinks {
+		extra = append(extra, "link\x00"+l.Source+"\x00"+l.Target)
+	}
+	for _, n := range r.graphNodes {
+		extra = append(extra, "shown\x00"+n.ID)
+		if aliases := r.aliases[n.ID]; len(aliases) > 0 {
+			extra = append(extra, "alias\x00"+n.ID+"\x00"+strings.Join(aliases, "\x00"))
+		}
+	}
+	sort.Strings(extra)
+	if days := r.cfg.Display.RecentWindowDays; days > 0 {
+		for _, n := range r.nodes {
+			if isRecent(r.dates[n.ID], days, buildTime()) {
+				extra = append(extra, "recent\x00"+n.ID)
+			}
+		}
+	}
+	return r.inputHash(extra...)
+}
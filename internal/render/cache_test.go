@@ -0,0 +1,72 @@
+package render
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestBuildCache(t *testing.T) {
+	tests := []struct {
+		name   string
+		change func(v *testVault)
+		graph  bool // graph.json regenerated
+		search bool // search.json regenerated
+	}{
+		{"unchanged", func(v *testVault) {}, false, false},
+		{"title", func(v *testVault) {
+			v.exec(`UPDATE nodes SET title = ? WHERE id = ?`, quote("Beta Two"), quote("b2"))
+		}, true, true},
+		{"tag", func(v *testVault) {
+			v.exec(`INSERT INTO tags (node_id, tag) VALUES (?, ?)`, quote("c3"), quote("rust"))
+		}, true, true},
+		{"link", func(v *testVault) { v.link("c3", "b2") }, true, false},
+		{"note added", func(v *testVault) { v.add(testNote{ID: "f6", Title: "Zeta"}) }, true, true},
+		{"excerpt", func(v *testVault) {
+			v.writeFile("20230301120000-gamma.org", ":PROPERTIES:\n:ID: c3\n:END:\n#+title: Gamma\n\nNew text.\n")
+		}, false, true},
+		{"display option", func(v *testVault) { v.cfg.Display.GraphDirected = true }, true, true},
+		{"display map option", func(v *testVault) { v.cfg.Display.TagColors = map[string]string{"go": "#00add8"} }, true, true},
+		{"site option", func(v *testVault) { v.cfg.Site.Title = "Other Notes" }, true, true},
+		{"graph exclusion", func(v *testVault) { v.cfg.Graph.ExcludeIDs = []string{"c3"} }, true, true},
+		{"output removed", func(v *testVault) {
+			os.Remove(filepath.Join(v.cfg.Paths.OutputDir, "graph.json"))
+		}, true, false},
+		{"unreadable cache", func(v *testVault) {
+			os.WriteFile(filepath.Join(v.cfg.Paths.OutputDir, buildCacheFile), []byte("{"), 0644)
+		}, true, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := newTestVault(t)
+			v.build()
+
+			// Mark the outputs so a rebuild that skips them leaves the mark
+			const mark = `{"stale": true}`
+			for _, name := range []string{"graph.json", "search.json"} {
+				if err := os.WriteFile(filepath.Join(v.cfg.Paths.OutputDir, name), []byte(mark), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			tt.change(v)
+			captureStdout(t, func() { v.build() })
+
+			for _, out := range []struct {
+				name  string
+				regen bool
+			}{{"graph.json", tt.graph}, {"search.json", tt.search}} {
+				data := mark
+				if v.exists(out.name) {
+					data = v.read(out.name)
+				}
+				if regen := data != mark; regen != out.regen {
+					t.Errorf("%s regenerated = %t, want %t", out.name, regen, out.regen)
+				}
+				if out.regen && !strings.Contains(data, `"nodes"`) && !strings.Contains(data, `"entries"`) {
+					t.Errorf("%s regenerated as %q", out.name, data)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,63 @@
+package render
+
+import (
+	"encoding/csv"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/nicehiro/org-roam-web/internal/db"
+)
+
+// generateCSV writes notes.csv with one row per published note for
+// auditing in a spreadsheet. Link counts only include published notes.
+func (r *Renderer) generateCSV() error {
+	published := make(map[string]bool, len(r.nodes))
+	for _, n := range r.nodes {
+		published[n.ID] = true
+	}
+
+	outgoing := make(map[string]int)
+	incoming := make(map[string]int)
+	for _, l := range r.links {
+		if published[l.Source] && published[l.Target] {
+			outgoing[l.Source]++
+			incoming[l.Target]++
+		}
+	}
+
+	nodes := append([]db.Node(nil), r.nodes...)
+	sort.SliceStable(nodes, func(i, j int) bool { return nodes[i].ID < nodes[j].ID })
+
+	f, err := os.Create(filepath.Join(r.cfg.Paths.OutputDir, "notes.csv"))
+	if err != nil {
+		return fmt.Errorf("failed to create notes.csv: %w", err)
+	}
+	defer f.Close()
+
+	w := csv.NewWriter(f)
+	w.Write([]string{"id", "title", "tags", "link_count", "backlink_count", "date", "file"})
+	for _, n := range nodes {
+		date := ""
+		if t := r.noteDate(n); !t.IsZero() {
+			date = t.Format("2006-01-02")
+		}
+		w.Write([]string{
+			n.ID,
+			n.Title,
+			strings.Join(r.nodeTags[n.ID], ";"),
+			strconv.Itoa(outgoing[n.ID]),
+			strconv.Itoa(incoming[n.ID]),
+			date,
+			filepath.Base(n.File),
+		})
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return fmt.Errorf("failed to write notes.csv: %w", err)
+	}
+	return nil
+}
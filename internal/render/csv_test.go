@@ -0,0 +1,49 @@
+package render
+
+import (
+	"encoding/csv"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestNotesCSV(t *testing.T) {
+	v := newTestVault(t)
+	v.cfg.Display.EmitCSV = true
+	v.add(testNote{ID: "q1", Title: "Hello, World", File: "20240301120000-hello.org", Tags: []string{"go", "web"}, Links: []string{"a1"}})
+	v.build()
+
+	raw := v.read("notes.csv")
+	if !strings.Contains(raw, "\nq1,\"Hello, World\",") {
+		t.Errorf("title with a comma not quoted:\n%s", raw)
+	}
+	rows, err := csv.NewReader(strings.NewReader(raw)).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := [][]string{
+		{"id", "title", "tags", "link_count", "backlink_count", "date", "file"},
+		{"a1", "Alpha", "go", "1", "3", "2024-01-01", "20240101120000-alpha.org"},
+		{"b2", "Beta", "go;web", "2", "1", "2024-02-01", "20240201120000-beta.org"},
+		{"c3", "Gamma", "", "1", "1", "2023-03-01", "20230301120000-gamma.org"},
+		{"e5", "Epsilon", "", "0", "0", "2021-05-10", "epsilon.org"},
+		{"q1", "Hello, World", "go;web", "1", "0", "2024-03-01", "20240301120000-hello.org"},
+	}
+	if len(rows) != len(want) {
+		t.Fatalf("notes.csv has %d rows, want %d:\n%s", len(rows), len(want), raw)
+	}
+	for i := range want {
+		if !slices.Equal(rows[i], want[i]) {
+			t.Errorf("row %d = %q, want %q", i, rows[i], want[i])
+		}
+	}
+}
+
+func TestNotesCSVDisabled(t *testing.T) {
+	v := newTestVault(t)
+	v.build()
+	if v.exists("notes.csv") {
+		t.Error("notes.csv written without display.emit_csv")
+	}
+}
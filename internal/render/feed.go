@@ -0,0 +1,167 @@
+package render
+
+import (
+	"encoding/xml"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// TagFeed is the RSS feed of one tag, for discovery links
+type TagFeed struct {
+	Tag string
+	URL string
+}
+
+type rssFeed struct {
+	XMLName xml.Name   `xml:"rss"`
+	Version string     `xml:"version,attr"`
+	Channel rssChannel `xml:"channel"`
+}
+
+type rssChannel struct {
+	Title       string    `xml:"title"`
+	Link        string    `xml:"link"`
+	Description string    `xml:"description"`
+	Items       []rssItem `xml:"item"`
+}
+
+type rssItem struct {
+	Title       string `xml:"title"`
+	Link        string `xml:"link"`
+	GUID        string `xml:"guid"`
+	PubDate     string `xml:"pubDate,omitempty"`
+	Description string `xml:"description,omitempty"`
+}
+
+type atomFeed struct {
+	XMLName xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	Title   string      `xml:"title"`
+	ID      string      `xml:"id"`
+	Updated string      `xml:"updated"`
+	Links   []atomLink  `xml:"link"`
+	Author  atomAuthor  `xml:"author"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+type atomLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr,omitempty"`
+}
+
+type atomAuthor struct {
+	Name string `xml:"name"`
+}
+
+type atomEntry struct {
+	Title     string   `xml:"title"`
+	ID        string   `xml:"id"`
+	Link      atomLink `xml:"link"`
+	Published string   `xml:"published"`
+	Updated   string   `xml:"updated"`
+	Summary   string   `xml:"summary,omitempty"`
+}
+
+// generateFeed writes feed.xml, an Atom feed of the first display.feed_count
+// notes in display.home_sort order, so it lists what the home page does.
+// Notes without a date are left out.
+func (r *Renderer) generateFeed() error {
+	site := r.cfg.Site
+	feed := atomFeed{
+		Title: site.Title,
+		ID:    site.AbsURL("feed.xml"),
+		Links: []atomLink{
+			{Href: site.AbsURL("feed.xml"), Rel: "self"},
+			{Href: site.AbsURL("")},
+		},
+		Author: atomAuthor{Name: site.Title},
+	}
+
+	var updated time.Time
+	for _, n := range r.sortedNodes() {
+		if len(feed.Entries) == r.cfg.Display.FeedCount {
+			break
+		}
+		date := r.noteDate(n)
+		if date.IsZero() {
+			continue
+		}
+		if date.After(updated) {
+			updated = date
+		}
+		link := r.noteURL(n.ID)
+		feed.Entries = append(feed.Entries, atomEntry{
+			Title:     r.nodeMap[n.ID],
+			ID:        link,
+			Link:      atomLink{Href: link},
+			Published: date.Format(time.RFC3339),
+			Updated:   date.Format(time.RFC3339),
+			Summary:   r.noteExcerpt(n),
+		})
+	}
+	if updated.IsZero() {
+		updated = buildTime()
+	}
+	feed.Updated = updated.Format(time.RFC3339)
+
+	data, err := xml.MarshalIndent(feed, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to serialize feed: %w", err)
+	}
+	data = append([]byte(xml.Header), data...)
+	return os.WriteFile(filepath.Join(r.cfg.Paths.OutputDir, "feed.xml"), data, 0644)
+}
+
+// tagFeedURL returns the URL of a tag's RSS feed
+func (r *Renderer) tagFeedURL(tag string) string {
+	return r.cfg.Site.AbsURL("tags/" + tag + ".xml")
+}
+
+// tagFeeds returns the feeds of those tags that have published notes, or
+// nil if display.tag_feeds is off
+func (r *Renderer) tagFeeds(tags []string) []TagFeed {
+	if !r.cfg.Display.TagFeeds {
+		return nil
+	}
+	published := make(map[string]bool)
+	for _, n := range r.nodes {
+		for _, tag := range r.nodeTags[n.ID] {
+			published[tag] = true
+		}
+	}
+
+	feeds := make([]TagFeed, 0, len(tags))
+	for _, tag := range tags {
+		if published[tag] {
+			feeds = append(feeds, TagFeed{Tag: tag, URL: r.tagFeedURL(tag)})
+		}
+	}
+	return feeds
+}
+
+// writeTagFeed writes tags/<tag>.xml, an RSS feed of the notes on a tag page
+func (r *Renderer) writeTagFeed(tagsDir, tag string, notes []NotePreview) error {
+	feed := rssFeed{
+		Version: "2.0",
+		Channel: rssChannel{
+			Title:       fmt.Sprintf("#%s | %s", tag, r.cfg.Site.Title),
+			Link:        r.cfg.Site.AbsURL("tags/" + tag + ".html"),
+			Description: fmt.Sprintf("Notes tagged %s", tag),
+		},
+	}
+	for _, n := range notes {
+		item := rssItem{Title: n.Title, Link: n.URL, GUID: n.URL, Description: n.Excerpt}
+		if !n.ModTime.IsZero() {
+			item.PubDate = n.ModTime.Format(time.RFC1123Z)
+		}
+		feed.Channel.Items = append(feed.Channel.Items, item)
+	}
+
+	data, err := xml.MarshalIndent(feed, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to serialize feed for tag %s: %w", tag, err)
+	}
+	data = append([]byte(xml.Header), data...)
+	return os.WriteFile(filepath.Join(tagsDir, tag+".xml"), data, 0644)
+}
@@ -0,0 +1,78 @@
+package render
+
+import (
+	"encoding/xml"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// feedTitles returns the entry titles of feed.xml, in order
+func (v *testVault) feedTitles() []string {
+	v.t.Helper()
+	var feed atomFeed
+	if err := xml.Unmarshal([]byte(v.read("feed.xml")), &feed); err != nil {
+		v.t.Fatalf("feed.xml: %v", err)
+	}
+	var titles []string
+	for _, e := range feed.Entries {
+		titles = append(titles, e.Title)
+	}
+	return titles
+}
+
+func TestFeedOrder(t *testing.T) {
+	tests := []struct {
+		name      string
+		homeSort  []string
+		feedCount int
+		want      []string
+	}{
+		{"newest first", []string{"date_desc"}, 20, []string{"Beta", "Alpha", "Gamma", "Epsilon"}},
+		{"oldest first", []string{"date_asc"}, 20, []string{"Epsilon", "Gamma", "Alpha", "Beta"}},
+		{"by title", []string{"title_asc"}, 20, []string{"Alpha", "Beta", "Epsilon", "Gamma"}},
+		{"by title, limited", []string{"title_desc"}, 2, []string{"Gamma", "Epsilon"}},
+		{"limited", []string{"date_desc"}, 3, []string{"Beta", "Alpha", "Gamma"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := newTestVault(t)
+			v.cfg.Site.BaseURL = "https://example.com"
+			v.cfg.Display.HomeSort = tt.homeSort
+			v.cfg.Display.FeedCount = tt.feedCount
+			v.build()
+
+			if got := v.feedTitles(); !slices.Equal(got, tt.want) {
+				t.Errorf("feed entries %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFeedTitleEscaping(t *testing.T) {
+	tests := []struct {
+		title   string
+		wantRaw string // Escaped form in feed.xml
+	}{
+		{`$\pi_0$ policies`, `<title>$\pi_0$ policies</title>`},
+		{`$a < b$ & $c > d$`, `<title>$a &lt; b$ &amp; $c &gt; d$</title>`},
+		{`Don't $\frac{1}{2}$`, `<title>Don&#39;t $\frac{1}{2}$</title>`},
+		{`<script>alert(1)</script>`, `<title>&lt;script&gt;alert(1)&lt;/script&gt;</title>`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.title, func(t *testing.T) {
+			v := newTestVault(t)
+			v.cfg.Site.BaseURL = "https://example.com"
+			v.cfg.Display.FeedCount = 1
+			v.add(testNote{ID: "x1", Title: tt.title})
+			v.build()
+
+			if raw := v.read("feed.xml"); !strings.Contains(raw, tt.wantRaw) {
+				t.Errorf("feed.xml lacks %s:\n%s", tt.wantRaw, raw)
+			}
+			if got := v.feedTitles(); !slices.Equal(got, []string{tt.title}) {
+				t.Errorf("feed entries %q, want [%q]", got, tt.title)
+			}
+		})
+	}
+}
@@ -0,0 +1,113 @@
+package render
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/nicehiro/org-roam-web/internal/db"
+	"github.com/nicehiro/org-roam-web/internal/parser"
+)
+
+// noteFile caches what is looked up per note file. Heading nodes share the
+// file of the note they belong to, so each file is dated and checked for a
+// sidecar once however many nodes it holds. Dates come from the file name
+// and os.Stat, so the content isn't read or kept for them.
+type noteFile struct {
+	date     time.Time
+	hasDate  bool
+	extra    map[string]any
+	hasExtra bool
+}
+
+// fileEntry returns the cache entry of a resolved note path. Callers hold
+// r.filesMu.
+func (r *Renderer) fileEntry(path string) *noteFile {
+	f, ok := r.files[path]
+	if !ok {
+		f = &noteFile{}
+		r.files[path] = f
+	}
+	return f
+}
+
+// readNoteFile returns the content of the file holding n, or for a heading
+// node the content of its subtree (see parser.Subtree). Subtrees of other
+// heading nodes published as pages are replaced by links to them.
+func (r *Renderer) readNoteFile(n db.Node) (string, error) {
+	path := r.resolveFilePath(n.File)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	content := string(data)
+	if n.Level > 0 {
+		var ok bool
+		if content, ok = parser.Subtree(content, n.ID); !ok {
+			return "", &parser.ParseError{File: path, Err: fmt.Errorf("no headline with ID %s", n.ID)}
+		}
+	}
+	if pages := r.headingPages[n.File]; len(pages) > 0 {
+		others := make(map[string]string, len(pages))
+		for id, title := range pages {
+			if id != n.ID {
+				others[id] = title
+			}
+		}
+		content = parser.LinkSubtrees(content, others)
+	}
+	return content, nil
+}
+
+// publishedContent returns the org content of n that its page shows,
+// without :noexport: subtrees and private blocks unless
+// display.respect_noexport is off
+func (r *Renderer) publishedContent(n db.Node) (string, error) {
+	content, err := r.readNoteFile(n)
+	if err != nil || !r.cfg.Display.RespectNoExport {
+		return content, err
+	}
+	return parser.StripNoExport(content), nil
+}
+
+// parseNote parses the org content of n. A heading node is parsed from its
+// subtree and titled by its headline.
+func (r *Renderer) parseNote(p *parser.Parser, n db.Node) (*parser.ParsedNote, error) {
+	// Resolve file path (database stores absolute paths from original machine)
+	filePath := r.resolveFilePath(n.File)
+	if n.Level == 0 && len(r.headingPages[n.File]) == 0 {
+		return p.ParseFile(filePath)
+	}
+
+	content, err := r.readNoteFile(n)
+	if err != nil {
+		var parseErr *parser.ParseError
+		if errors.As(err, &parseErr) {
+			return nil, err
+		}
+		return nil, &parser.ParseError{File: filePath, Err: fmt.Errorf("failed to read file: %w", err)}
+	}
+	parsed, err := p.Parse(content, filePath)
+	if err != nil {
+		return nil, err
+	}
+	if n.Level > 0 {
+		parsed.Title = n.Title
+	}
+	return parsed, nil
+}
+
+// fileDate returns the date of the file holding n, from its name or else
+// its modification time
+func (r *Renderer) fileDate(n db.Node) time.Time {
+	r.filesMu.Lock()
+	defer r.filesMu.Unlock()
+	path := r.resolveFilePath(n.File)
+	f := r.fileEntry(path)
+	if !f.hasDate {
+		f.date = extractDateFromFilename(path)
+		f.hasDate = true
+	}
+	return f.date
+}
@@ -0,0 +1,171 @@
+package render
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nicehiro/org-roam-web/internal/db"
+	"github.com/nicehiro/org-roam-web/internal/parser"
+)
+
+// multiNote is a note file holding a file node and a heading node
+const multiNote = `:PROPERTIES:
+:ID:       m1
+:END:
+#+title: Multi
+
+Intro.
+
+* Part
+:PROPERTIES:
+:ID:       m2
+:END:
+Part text.
+`
+
+func TestHeadingNodeFiles(t *testing.T) {
+	mtime := time.Date(2022, 3, 4, 5, 6, 7, 0, time.UTC)
+	tests := []struct {
+		name     string
+		file     string
+		wantDate time.Time
+	}{
+		{"dated file name", "20240102030405-multi.org", time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)},
+		{"dated by mtime", "multi.org", mtime},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := newTestVault(t)
+			v.cfg.Display.IncludeHeadingNodes = "page"
+			v.writeFile(tt.file, multiNote)
+			v.writeFile(strings.TrimSuffix(tt.file, ".org")+".meta.yaml", "header: Shared\n")
+			path := filepath.Join(v.cfg.Paths.RoamDir, tt.file)
+			if err := os.Chtimes(path, mtime, mtime); err != nil {
+				t.Fatal(err)
+			}
+			dbFile := quote(fixtureRoot + "/" + tt.file)
+			v.exec(`INSERT INTO nodes (id, file, level, pos, title, properties) VALUES ('"m1"', ?, 0, 1, '"Multi"', '(("ID" . "m1"))')`, dbFile)
+			v.exec(`INSERT INTO nodes (id, file, level, pos, title, properties) VALUES ('"m2"', ?, 1, 50, '"Part"', '(("ID" . "m2"))')`, dbFile)
+			r := v.build()
+
+			if !r.dates["m1"].Equal(tt.wantDate) || !r.dates["m2"].Equal(tt.wantDate) {
+				t.Errorf("dates m1 %v, m2 %v, want both %v", r.dates["m1"], r.dates["m2"], tt.wantDate)
+			}
+			if len(r.files) != len(r.nodes)-1 {
+				t.Errorf("%d file entries for %d nodes in %d files", len(r.files), len(r.nodes), len(r.nodes)-1)
+			}
+
+			m1 := db.Node{ID: "m1", File: fixtureRoot + "/" + tt.file}
+			m2 := db.Node{ID: "m2", File: m1.File, Level: 1}
+			e1, e2 := r.noteExtra(m1), r.noteExtra(m2)
+			if e1["header"] != "Shared" || reflect.ValueOf(e1).Pointer() != reflect.ValueOf(e2).Pointer() {
+				t.Errorf("sidecar of m1 %v and m2 %v not shared", e1, e2)
+			}
+			for _, id := range []string{"m1", "m2"} {
+				if !v.exists("notes/" + id + ".html") {
+					t.Errorf("no page for %s", id)
+				}
+			}
+
+			// Content isn't cached: a later read sees the file as it is now
+			v.writeFile(tt.file, strings.Replace(multiNote, "Part text.", "Changed text.", 1))
+			content, err := r.readNoteFile(m2)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !strings.Contains(content, "Changed text.") || strings.Contains(content, "Intro.") {
+				t.Errorf("heading node content %q, want the current subtree", content)
+			}
+		})
+	}
+}
+
+// addMultiNote adds multiNote to the vault with its file and heading nodes,
+// plus l1 linking to the heading node
+func addMultiNote(v *testVault) {
+	v.t.Helper()
+	v.writeFile("multi.org", multiNote)
+	dbFile := quote(fixtureRoot + "/multi.org")
+	v.exec(`INSERT INTO nodes (id, file, level, pos, title, properties) VALUES ('"m1"', ?, 0, 1, '"Multi"', '(("ID" . "m1"))')`, dbFile)
+	v.exec(`INSERT INTO nodes (id, file, level, pos, title, properties, olp) VALUES ('"m2"', ?, 1, 50, '"Part"', '(("ID" . "m2"))', 'nil')`, dbFile)
+	v.add(testNote{ID: "l1", Title: "Linker", Links: []string{"m2"}})
+}
+
+func TestHeadingNodeModes(t *testing.T) {
+	tests := []struct {
+		mode       string
+		wantPage   bool   // notes/m2.html is written
+		wantURL    string // noteURL("m2")
+		wantInline bool   // m1's page shows the subtree
+		wantAnchor bool   // m1's page has an anchor for m2
+	}{
+		{mode: "", wantURL: "/notes/m2.html", wantInline: true},
+		{mode: "anchor", wantURL: "/notes/m1.html#m2", wantInline: true, wantAnchor: true},
+		{mode: "page", wantPage: true, wantURL: "/notes/m2.html"},
+	}
+	for _, tt := range tests {
+		t.Run("mode "+quote(tt.mode), func(t *testing.T) {
+			v := newTestVault(t)
+			v.cfg.Display.IncludeHeadingNodes = tt.mode
+			addMultiNote(v)
+			r := v.build()
+
+			if got := r.noteURL("m2"); got != tt.wantURL {
+				t.Errorf("noteURL(m2) = %q, want %q", got, tt.wantURL)
+			}
+			if v.exists("notes/m2.html") != tt.wantPage {
+				t.Errorf("notes/m2.html written: %v, want %v", !tt.wantPage, tt.wantPage)
+			}
+			m1 := v.read("notes/m1.html")
+			if got := strings.Contains(m1, "Part text."); got != tt.wantInline {
+				t.Errorf("m1 shows the subtree: %v, want %v", got, tt.wantInline)
+			}
+			if got := strings.Contains(m1, `<a id="m2"></a>`); got != tt.wantAnchor {
+				t.Errorf("m1 has an anchor for m2: %v, want %v", got, tt.wantAnchor)
+			}
+			if tt.mode != "" && !strings.Contains(v.read("notes/l1.html"), `href="`+tt.wantURL+`"`) {
+				t.Errorf("l1 doesn't link to %s", tt.wantURL)
+			}
+
+			if !tt.wantPage {
+				return
+			}
+			// The file's page links to the heading's page in its place
+			if !slices.Contains(noteHrefs(m1), "m2") {
+				t.Errorf("m1 links to %v, want m2 among them", noteHrefs(m1))
+			}
+			m2 := v.read("notes/m2.html")
+			if !strings.Contains(m2, "Part text.") || strings.Contains(m2, "Intro.") {
+				t.Error("m2 page doesn't show just its subtree")
+			}
+			if !strings.Contains(m2, "<title>Part") {
+				t.Error("m2 page isn't titled by its headline")
+			}
+		})
+	}
+}
+
+func TestHeadingNodeMissing(t *testing.T) {
+	v := newTestVault(t)
+	v.cfg.Display.IncludeHeadingNodes = "page"
+	v.cfg.Display.MaxParseErrors = 0
+	addMultiNote(v)
+	v.exec(`INSERT INTO nodes (id, file, level, pos, title, properties) VALUES ('"m3"', ?, 1, 90, '"Gone"', '(("ID" . "m3"))')`,
+		quote(fixtureRoot+"/multi.org"))
+
+	var err error
+	captureStdout(t, func() { _, err = v.tryBuild() })
+	var pe *parser.ParseError
+	if !errors.As(err, &pe) {
+		t.Fatalf("Build error = %v, want a ParseError", err)
+	}
+	if filepath.Base(pe.File) != "multi.org" || !strings.Contains(err.Error(), "m3") {
+		t.Errorf("ParseError %v, want one for m3 in multi.org", err)
+	}
+}
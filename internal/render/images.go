@@ -0,0 +1,187 @@
+package render
+
+import (
+	"fmt"
+	"image"
+	_ "image/gif"
+	"image/jpeg"
+	"image/png"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"golang.org/x/image/draw"
+)
+
+var (
+	imgTagRe  = regexp.MustCompile(`<img\b[^>]*>`)
+	imgSrcRe  = regexp.MustCompile(`\bsrc="([^"]*)"`)
+	imgAttrRe = regexp.MustCompile(`\b(loading|decoding|width|height)=`)
+)
+
+// lazyImages adds loading="lazy" and decoding="async" to the images in
+// content and, for local images whose size can be read, width and height
+// so the page doesn't shift as they load
+func (r *Renderer) lazyImages(content string) string {
+	return imgTagRe.ReplaceAllStringFunc(content, func(tag string) string {
+		present := make(map[string]bool)
+		for _, m := range imgAttrRe.FindAllStringSubmatch(tag, -1) {
+			present[m[1]] = true
+		}
+
+		var attrs []string
+		if !present["loading"] {
+			attrs = append(attrs, `loading="lazy"`)
+		}
+		if !present["decoding"] {
+			attrs = append(attrs, `decoding="async"`)
+		}
+		if !present["width"] && !present["height"] {
+			if m := imgSrcRe.FindStringSubmatch(tag); m != nil {
+				if w, h, ok := r.localImageSize(m[1]); ok {
+					attrs = append(attrs, fmt.Sprintf(`width="%d" height="%d"`, w, h))
+				}
+			}
+		}
+		if len(attrs) == 0 {
+			return tag
+		}
+
+		end := strings.TrimSuffix(strings.TrimSuffix(tag, ">"), "/")
+		closing := ">"
+		if strings.HasSuffix(tag, "/>") {
+			closing = " />"
+		}
+		return strings.TrimRight(end, " ") + " " + strings.Join(attrs, " ") + closing
+	})
+}
+
+// localImageSize returns the pixel size of an image served from the site's
+// /img/ directory. Remote images and formats the standard library can't
+// decode (such as SVG and WebP) report ok = false.
+func (r *Renderer) localImageSize(src string) (width, height int, ok bool) {
+	prefix := r.cfg.Site.BaseURL + "/img/"
+	if !strings.HasPrefix(src, prefix) {
+		return 0, 0, false
+	}
+	rel := filepath.FromSlash(strings.TrimPrefix(src, prefix))
+
+	f, err := os.Open(filepath.Join(r.cfg.Paths.RoamDir, "img", rel))
+	if err != nil {
+		return 0, 0, false
+	}
+	defer f.Close()
+
+	cfg, _, err := image.DecodeConfig(f)
+	if err != nil {
+		return 0, 0, false
+	}
+
+	// Report the size of the downscaled copy that is actually served
+	if max := r.cfg.Display.MaxImageWidth; max > 0 && cfg.Width > max && isResizable(rel) {
+		return max, scaledHeight(cfg.Width, cfg.Height, max), true
+	}
+	return cfg.Width, cfg.Height, true
+}
+
+// fullImagePath returns where the original of a downscaled image is kept
+// (foo.png -> foo.full.png)
+func fullImagePath(path string) string {
+	ext := filepath.Ext(path)
+	return strings.TrimSuffix(path, ext) + ".full" + ext
+}
+
+// isResizable reports whether the image at path is a JPEG or PNG
+func isResizable(path string) bool {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".jpg", ".jpeg", ".png":
+		return true
+	}
+	return false
+}
+
+// scaledHeight returns the height of a width x height image scaled to
+// newWidth, keeping its aspect ratio
+func scaledHeight(width, height, newWidth int) int {
+	h := (height*newWidth + width/2) / width
+	if h < 1 {
+		h = 1
+	}
+	return h
+}
+
+// resizeImage writes a copy of the JPEG or PNG at src to dst, scaled down
+// to maxWidth, and the original to fullImagePath(dst). It reports false,
+// writing nothing, when the image is narrow enough to be copied as-is.
+func resizeImage(src, dst string, maxWidth int) (bool, error) {
+	f, err := os.Open(src)
+	if err != nil {
+		return false, err
+	}
+	defer f.Close()
+
+	cfg, format, err := image.DecodeConfig(f)
+	if err != nil || cfg.Width <= maxWidth {
+		// Leave images we can't decode to the plain copy
+		return false, nil
+	}
+	if _, err := f.Seek(0, 0); err != nil {
+		return false, err
+	}
+	img, _, err := image.Decode(f)
+	if err != nil {
+		return false, nil
+	}
+
+	scaled := image.NewNRGBA(image.Rect(0, 0, maxWidth, scaledHeight(cfg.Width, cfg.Height, maxWidth)))
+	draw.CatmullRom.Scale(scaled, scaled.Bounds(), img, img.Bounds(), draw.Src, nil)
+
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return false, err
+	}
+	out, err := os.Create(dst)
+	if err != nil {
+		return false, err
+	}
+	if format == "jpeg" {
+		err = jpeg.Encode(out, scaled, &jpeg.Options{Quality: 85})
+	} else {
+		err = png.Encode(out, scaled)
+	}
+	if cerr := out.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to write resized %s: %w", dst, err)
+	}
+
+	if err := copyFile(src, fullImagePath(dst)); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// linkFullImages wraps downscaled images in a link to their original
+func (r *Renderer) linkFullImages(content string) string {
+	prefix := r.cfg.Site.BaseURL + "/img/"
+	return imgTagRe.ReplaceAllStringFunc(content, func(tag string) string {
+		m := imgSrcRe.FindStringSubmatch(tag)
+		if m == nil || !strings.HasPrefix(m[1], prefix) || !isResizable(m[1]) {
+			return tag
+		}
+
+		rel := filepath.FromSlash(strings.TrimPrefix(m[1], prefix))
+		f, err := os.Open(filepath.Join(r.cfg.Paths.RoamDir, "img", rel))
+		if err != nil {
+			return tag
+		}
+		defer f.Close()
+		cfg, _, err := image.DecodeConfig(f)
+		if err != nil || cfg.Width <= r.cfg.Display.MaxImageWidth {
+			return tag
+		}
+
+		return fmt.Sprintf(`<a href="%s" class="image-full" target="_blank">%s</a>`, fullImagePath(m[1]), tag)
+	})
+}
@@ -0,0 +1,178 @@
+package render
+
+import (
+	"bytes"
+	"image"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+// writeImage writes a blank width x height image, encoded by the
+// extension of path (PNG, JPEG or GIF)
+func writeImage(t *testing.T, path string, width, height int) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	switch filepath.Ext(path) {
+	case ".jpg":
+		err = jpeg.Encode(f, img, nil)
+	case ".gif":
+		err = gif.Encode(f, img, nil)
+	default:
+		err = png.Encode(f, img)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+var imgTagSrcRe = regexp.MustCompile(`<img[^>]*src="([^"]*)"[^>]*>`)
+
+// imgTags returns the img tags of page by src
+func imgTags(page string) map[string]string {
+	tags := make(map[string]string)
+	for _, m := range imgTagSrcRe.FindAllStringSubmatch(page, -1) {
+		tags[m[1]] = m[0]
+	}
+	return tags
+}
+
+func TestLazyImages(t *testing.T) {
+	const (
+		local  = "/img/photo.png"
+		remote = "https://example.org/remote.png"
+		svg    = "/img/diagram.svg"
+	)
+	tests := []struct {
+		name string
+		lazy bool
+		want map[string]string // src -> img tag
+	}{
+		{"enabled", true, map[string]string{
+			local:  `<img src="/img/photo.png" alt="photo.png" loading="lazy" decoding="async" width="40" height="30" />`,
+			remote: `<img src="https://example.org/remote.png" alt="remote.png" loading="lazy" decoding="async" />`,
+			svg:    `<img src="/img/diagram.svg" alt="diagram.svg" loading="lazy" decoding="async" />`,
+		}},
+		// The parser marks images lazy by itself
+		{"disabled", false, map[string]string{
+			local:  `<img src="/img/photo.png" alt="photo.png" loading="lazy" />`,
+			remote: `<img src="https://example.org/remote.png" alt="remote.png" loading="lazy" />`,
+			svg:    `<img src="/img/diagram.svg" alt="diagram.svg" loading="lazy" />`,
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := newTestVault(t)
+			v.cfg.Display.LazyImages = tt.lazy
+			writeImage(t, filepath.Join(v.cfg.Paths.RoamDir, "img", "photo.png"), 40, 30)
+			v.writeFile("img/diagram.svg", `<svg xmlns="http://www.w3.org/2000/svg" width="10" height="10"/>`)
+			v.add(testNote{ID: "i1", Title: "Images", Body: "[[file:img/photo.png]]\n\n[[" + remote + "]]\n\n[[file:img/diagram.svg]]"})
+			v.build()
+
+			got := imgTags(between(v.read("notes/i1.html"), `<article class="note-main">`, "</article>"))
+			for src, want := range tt.want {
+				if got[src] != want {
+					t.Errorf("img %s:\n got %s\nwant %s", src, got[src], want)
+				}
+			}
+		})
+	}
+}
+
+func TestLazyImagesKeepAttributes(t *testing.T) {
+	v := newTestVault(t)
+	writeImage(t, filepath.Join(v.cfg.Paths.RoamDir, "img", "photo.png"), 40, 30)
+	r, err := NewRenderer(v.cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		in, want string
+	}{
+		{`<img src="/img/photo.png">`, `<img src="/img/photo.png" loading="lazy" decoding="async" width="40" height="30">`},
+		{`<img src="/img/photo.png" loading="eager"/>`, `<img src="/img/photo.png" loading="eager" decoding="async" width="40" height="30" />`},
+		{`<img src="/img/photo.png" width="20" />`, `<img src="/img/photo.png" width="20" loading="lazy" decoding="async" />`},
+		{`<img src="/img/missing.png" />`, `<img src="/img/missing.png" loading="lazy" decoding="async" />`},
+		{`<img src="/img/photo.png" loading="lazy" decoding="sync" height="9" />`, `<img src="/img/photo.png" loading="lazy" decoding="sync" height="9" />`},
+	}
+	for _, tt := range tests {
+		if got := r.lazyImages(tt.in); got != tt.want {
+			t.Errorf("lazyImages(%s)\n got %s\nwant %s", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestMaxImageWidth(t *testing.T) {
+	tests := []struct {
+		file          string
+		width, height int
+		wantW, wantH  int // Size served
+		resized       bool
+	}{
+		{"wide.png", 400, 300, 200, 150, true},
+		{"wide.jpg", 400, 201, 200, 101, true},
+		{"nested/tall.png", 300, 900, 200, 600, true},
+		{"small.png", 120, 80, 120, 80, false},
+		{"edge.png", 200, 10, 200, 10, false},
+		{"wide.gif", 400, 100, 400, 100, false},
+	}
+	v := newTestVault(t)
+	v.cfg.Display.MaxImageWidth = 200
+	var body strings.Builder
+	for _, tt := range tests {
+		writeImage(t, filepath.Join(v.cfg.Paths.RoamDir, "img", tt.file), tt.width, tt.height)
+		body.WriteString("[[file:img/" + tt.file + "]]\n\n")
+	}
+	v.add(testNote{ID: "i1", Title: "Images", Body: body.String()})
+	v.build()
+	page := v.read("notes/i1.html")
+
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			src, err := os.ReadFile(filepath.Join(v.cfg.Paths.RoamDir, "img", tt.file))
+			if err != nil {
+				t.Fatal(err)
+			}
+			served := v.read("img/" + tt.file)
+			cfg, _, err := image.DecodeConfig(strings.NewReader(served))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if cfg.Width != tt.wantW || cfg.Height != tt.wantH {
+				t.Errorf("served %dx%d, want %dx%d", cfg.Width, cfg.Height, tt.wantW, tt.wantH)
+			}
+
+			full := "img/" + fullImagePath(tt.file)
+			if v.exists(full) != tt.resized {
+				t.Errorf("%s written: %t, want %t", full, v.exists(full), tt.resized)
+			}
+			original := served
+			if tt.resized {
+				original = v.read(full)
+			}
+			if !bytes.Equal([]byte(original), src) {
+				t.Error("original image not kept byte for byte")
+			}
+
+			link := `<a href="/` + full + `" class="image-full" target="_blank"><img src="/img/` + tt.file + `"`
+			if strings.Contains(page, link) != tt.resized {
+				t.Errorf("page links the full image: %t, want %t", !tt.resized, tt.resized)
+			}
+		})
+	}
+}
@@ -0,0 +1,64 @@
+package render
+
+import (
+	"strings"
+	"unicode"
+
+	"github.com/nicehiro/org-roam-web/internal/db"
+)
+
+// minScriptShare is the fraction of letters a non-Latin script needs before
+// a note is considered written in it
+const minScriptShare = 0.3
+
+// noteLanguage returns the lang attribute of a note page: the LANGUAGE
+// property, else the language guessed from its text, else site.language
+func (r *Renderer) noteLanguage(n db.Node, content string) string {
+	if lang := strings.TrimSpace(n.Properties["LANGUAGE"]); lang != "" {
+		return lang
+	}
+	if r.cfg.Display.DetectLanguage {
+		if lang := detectLanguage(htmlToText(content)); lang != "" {
+			return lang
+		}
+	}
+	return r.cfg.Site.Language
+}
+
+// detectLanguage guesses the language of text from the scripts its letters
+// are written in. Latin text is ambiguous, so it returns "" for it.
+func detectLanguage(text string) string {
+	var letters, han, kana, hangul, cyrillic int
+	for _, c := range text {
+		if !unicode.IsLetter(c) {
+			continue
+		}
+		letters++
+		switch {
+		case unicode.Is(unicode.Han, c):
+			han++
+		case unicode.In(c, unicode.Hiragana, unicode.Katakana):
+			kana++
+		case unicode.Is(unicode.Hangul, c):
+			hangul++
+		case unicode.Is(unicode.Cyrillic, c):
+			cyrillic++
+		}
+	}
+	if letters == 0 {
+		return ""
+	}
+
+	share := func(n int) bool { return float64(n) >= minScriptShare*float64(letters) }
+	switch {
+	case kana > 0 && share(kana+han):
+		return "ja"
+	case share(han):
+		return "zh"
+	case share(hangul):
+		return "ko"
+	case share(cyrillic):
+		return "ru"
+	}
+	return ""
+}
@@ -0,0 +1,65 @@
+package render
+
+import "testing"
+
+func TestDetectLanguage(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want string
+	}{
+		{"chinese", "知识管理是一种方法。", "zh"},
+		{"chinese with english terms", "我们用 Go 写了一个静态网站生成器", "zh"},
+		{"japanese", "これは日本語の文章です。", "ja"},
+		{"japanese with kanji", "日本語で書かれたノート", "ja"},
+		{"korean", "이것은 한국어 문장입니다", "ko"},
+		{"russian", "Это заметка на русском языке", "ru"},
+		{"english", "A note written in English.", ""},
+		{"mostly english", "Notes on the word 知识 and how it is used in context", ""},
+		{"no letters", "1234 !?", ""},
+		{"empty", "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := detectLanguage(tt.text); got != tt.want {
+				t.Errorf("detectLanguage(%q) = %q, want %q", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNoteLanguage(t *testing.T) {
+	tests := []struct {
+		name     string
+		language string // LANGUAGE property
+		body     string
+		detect   bool
+		want     string
+	}{
+		{"explicit property", "de", "Ein Text.", true, "de"},
+		{"property wins over detection", "en", "知识管理是一种方法。", true, "en"},
+		{"detected from CJK content", "", "知识管理是一种方法。", true, "zh"},
+		{"detection disabled", "", "知识管理是一种方法。", false, "fr"},
+		{"latin content", "", "Un texte.", true, "fr"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := newTestVault(t)
+			v.cfg.Site.Language = "fr"
+			v.cfg.Display.DetectLanguage = tt.detect
+			note := testNote{ID: "l1", Title: "Language", Body: tt.body}
+			if tt.language != "" {
+				note.Props = map[string]string{"LANGUAGE": tt.language}
+			}
+			v.add(note)
+			v.build()
+
+			if got := between(v.read("notes/l1.html"), `<html lang="`, `"`); got != tt.want {
+				t.Errorf("note page lang = %q, want %q", got, tt.want)
+			}
+			if got := between(v.read("index.html"), `<html lang="`, `"`); got != "fr" {
+				t.Errorf("home page lang = %q, want site.language", got)
+			}
+		})
+	}
+}
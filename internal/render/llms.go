@@ -0,0 +1,98 @@
+package render
+
+import (
+	"fmt"
+	"html"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/nicehiro/org-roam-web/internal/db"
+)
+
+var (
+	textChromeRe  = regexp.MustCompile(`<span class="link-marker">#</span>\s*|<a [^>]*class="heading-anchor"[^>]*>[^<]*</a>`)
+	textHeadingRe = regexp.MustCompile(`(?is)<h([1-6])[^>]*>(.*?)</h[1-6]>`)
+	textBreakRe   = regexp.MustCompile(`(?i)<br\s*/?>|</(p|div|pre|h[1-6]|tr|blockquote)>`)
+	textItemRe    = regexp.MustCompile(`(?i)\s*<li[^>]*>\s*(<p[^>]*>)?`)
+	textTagRe     = regexp.MustCompile(`<[^>]*>`)
+	textBlankRe   = regexp.MustCompile(`\n\s*\n\s*\n+`)
+)
+
+// htmlToText converts rendered note content to plain text with markdown
+// style headings and list items
+func htmlToText(s string) string {
+	s = textChromeRe.ReplaceAllString(s, "")
+	s = textHeadingRe.ReplaceAllStringFunc(s, func(m string) string {
+		sub := textHeadingRe.FindStringSubmatch(m)
+		title := strings.Join(strings.Fields(textTagRe.ReplaceAllString(sub[2], "")), " ")
+		return "\n\n" + strings.Repeat("#", int(sub[1][0]-'0')) + " " + title + "\n"
+	})
+	s = textItemRe.ReplaceAllString(s, "\n- ")
+	s = textBreakRe.ReplaceAllString(s, "\n")
+	s = textTagRe.ReplaceAllString(s, "")
+	s = html.UnescapeString(s)
+
+	lines := strings.Split(s, "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimRight(line, " \t")
+	}
+	// Items of a list, including nested ones, go on consecutive lines
+	kept := lines[:0]
+	for i, line := range lines {
+		if line == "" && len(kept) > 0 && strings.HasPrefix(kept[len(kept)-1], "- ") &&
+			i+1 < len(lines) && (lines[i+1] == "" || strings.HasPrefix(lines[i+1], "- ")) {
+			continue
+		}
+		kept = append(kept, line)
+	}
+	s = strings.Join(kept, "\n")
+	return strings.TrimSpace(textBlankRe.ReplaceAllString(s, "\n\n"))
+}
+
+// generateLLMsTxt writes llms.txt, an index of the published notes for
+// language model tools, and with display.emit_llms_full_txt also
+// llms-full.txt holding the text of every note
+func (r *Renderer) generateLLMsTxt() error {
+	nodes := append([]db.Node(nil), r.listedNodes()...)
+	sort.SliceStable(nodes, func(i, j int) bool {
+		return strings.ToLower(nodes[i].Title) < strings.ToLower(nodes[j].Title)
+	})
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "# %s\n\n## Notes\n\n", r.cfg.Site.Title)
+	for _, n := range nodes {
+		fmt.Fprintf(&b, "- [%s](%s)", n.Title, r.noteURL(n.ID))
+		if excerpt := r.noteExcerpt(n); excerpt != "" {
+			fmt.Fprintf(&b, ": %s", excerpt)
+		}
+		b.WriteString("\n")
+	}
+	if err := os.WriteFile(filepath.Join(r.cfg.Paths.OutputDir, "llms.txt"), []byte(b.String()), 0644); err != nil {
+		return fmt.Errorf("failed to write llms.txt: %w", err)
+	}
+
+	if !r.cfg.Display.EmitLLMsFullTxt {
+		return nil
+	}
+
+	b.Reset()
+	fmt.Fprintf(&b, "# %s\n", r.cfg.Site.Title)
+	for _, n := range nodes {
+		text, ok := r.noteText[n.ID]
+		if !ok {
+			// The page failed to render
+			continue
+		}
+		fmt.Fprintf(&b, "\n---\n\n# %s\n\nURL: %s\n", n.Title, r.noteURL(n.ID))
+		if text != "" {
+			fmt.Fprintf(&b, "\n%s\n", text)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(r.cfg.Paths.OutputDir, "llms-full.txt"), []byte(b.String()), 0644); err != nil {
+		return fmt.Errorf("failed to write llms-full.txt: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,85 @@
+package render
+
+import (
+	"regexp"
+	"slices"
+	"strings"
+	"testing"
+)
+
+var llmsEntryRe = regexp.MustCompile(`(?m)^- \[([^\]]+)\]\(([^)]+)\)(: .*)?$`)
+
+func TestLLMsTxt(t *testing.T) {
+	v := newTestVault(t)
+	v.cfg.Site.BaseURL = "https://example.com"
+	v.cfg.Display.EmitLLMsTxt = true
+	v.cfg.Display.EmitLLMsFullTxt = true
+	v.build()
+
+	index := v.read("llms.txt")
+	if !strings.HasPrefix(index, "# "+v.cfg.Site.Title+"\n\n## Notes\n\n") {
+		t.Errorf("llms.txt header:\n%s", index)
+	}
+	var titles, urls []string
+	for _, m := range llmsEntryRe.FindAllStringSubmatch(index, -1) {
+		titles = append(titles, m[1])
+		urls = append(urls, m[2])
+		if m[3] == "" {
+			t.Errorf("%s has no description", m[1])
+		}
+	}
+	if want := []string{"Alpha", "Beta", "Epsilon", "Gamma"}; !slices.Equal(titles, want) {
+		t.Errorf("llms.txt lists %v, want %v", titles, want)
+	}
+	wantURLs := []string{
+		"https://example.com/notes/a1.html",
+		"https://example.com/notes/b2.html",
+		"https://example.com/notes/e5.html",
+		"https://example.com/notes/c3.html",
+	}
+	if !slices.Equal(urls, wantURLs) {
+		t.Errorf("llms.txt URLs %v, want %v", urls, wantURLs)
+	}
+
+	full := v.read("llms-full.txt")
+	for _, want := range []string{"\n# Alpha\n\nURL: https://example.com/notes/a1.html\n", "Alpha is the first note.", "## First heading"} {
+		if !strings.Contains(full, want) {
+			t.Errorf("llms-full.txt lacks %q", want)
+		}
+	}
+	for name, content := range map[string]string{"llms.txt": index, "llms-full.txt": full} {
+		if strings.Contains(content, "Delta") || strings.Contains(content, "d4") {
+			t.Errorf("%s includes the excluded note", name)
+		}
+	}
+}
+
+func TestLLMsTxtDisabled(t *testing.T) {
+	v := newTestVault(t)
+	v.build()
+	for _, name := range []string{"llms.txt", "llms-full.txt"} {
+		if v.exists(name) {
+			t.Errorf("%s written without display.emit_llms_txt", name)
+		}
+	}
+}
+
+func TestHTMLToText(t *testing.T) {
+	tests := []struct {
+		html, want string
+	}{
+		{"<p>One &amp; two</p>", "One & two"},
+		{`<h2 id="x"><span class="link-marker">#</span> Title <em>here</em></h2><p>Text</p>`, "## Title here\nText"},
+		{"<ul><li>a</li><li>b</li></ul>", "- a\n- b"},
+		// As go-org renders a nested list
+		{"<ul>\n<li>a</li>\n<li>\n<p>b</p>\n<ul>\n<li>c</li>\n</ul>\n</li>\n</ul>\n<p>Text after.</p>", "- a\n- b\n- c\n\nText after."},
+		{"<p>Intro</p>\n<ol>\n<li>one</li>\n<li>two</li>\n</ol>", "Intro\n\n- one\n- two"},
+		{"<p>a</p>\n\n\n\n<p>b</p>", "a\n\nb"},
+		{"line<br/>break", "line\nbreak"},
+	}
+	for _, tt := range tests {
+		if got := htmlToText(tt.html); got != tt.want {
+			t.Errorf("htmlToText(%q) = %q, want %q", tt.html, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,82 @@
+package render
+
+import (
+	"path/filepath"
+	"sort"
+
+	"github.com/nicehiro/org-roam-web/internal/parser"
+)
+
+// MaintenanceData holds data for the maintenance page
+type MaintenanceData struct {
+	Site     SiteData
+	MinLinks int
+	MinWords int
+	Orphans  []MaintenanceNote
+	Stubs    []MaintenanceNote
+	Untagged []MaintenanceNote
+}
+
+// MaintenanceNote is a note listed on the maintenance page
+type MaintenanceNote struct {
+	Title string
+	URL   string
+	Links int // Links to or from other notes
+	Words int
+}
+
+// generateMaintenance generates maintenance.html, listing the notes with
+// fewer than display.maintenance.min_links links (orphans), fewer than
+// min_words words (stubs), and no tags. A note may appear in several
+// sections; each is ordered by title.
+func (r *Renderer) generateMaintenance() error {
+	cfg := r.cfg.Display.Maintenance
+	data := MaintenanceData{
+		Site:     r.siteData(),
+		MinLinks: cfg.MinLinks,
+		MinWords: cfg.MinWords,
+	}
+
+	// Count links the way the graph does, between published notes
+	published := make(map[string]bool, len(r.nodes))
+	for _, n := range r.nodes {
+		published[n.ID] = true
+	}
+	linkCount := make(map[string]int)
+	for _, l := range r.links {
+		if published[l.Source] && published[l.Target] {
+			linkCount[l.Source]++
+			linkCount[l.Target]++
+		}
+	}
+
+	compare := newNoteCompare([]string{"title_asc"}, r.noteDate)
+	sorted := r.listedNodes()
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return compare(sorted[i], sorted[j]) < 0
+	})
+
+	for _, n := range sorted {
+		words := 0
+		if content, err := r.publishedContent(n); err == nil {
+			words = parser.WordCount(content)
+		}
+		note := MaintenanceNote{
+			Title: r.nodeMap[n.ID],
+			URL:   r.noteURL(n.ID),
+			Links: linkCount[n.ID],
+			Words: words,
+		}
+		if note.Links < cfg.MinLinks {
+			data.Orphans = append(data.Orphans, note)
+		}
+		if note.Words < cfg.MinWords {
+			data.Stubs = append(data.Stubs, note)
+		}
+		if len(r.nodeTags[n.ID]) == 0 {
+			data.Untagged = append(data.Untagged, note)
+		}
+	}
+
+	return r.renderPage("maintenance.html", filepath.Join(r.cfg.Paths.OutputDir, "maintenance.html"), data)
+}
@@ -0,0 +1,93 @@
+package render
+
+import (
+	"regexp"
+	"slices"
+	"strings"
+	"testing"
+)
+
+var maintenanceTitleRe = regexp.MustCompile(`class="note-title">([^<]*)</a>`)
+
+// maintenanceSection returns the titles listed under a heading of the
+// maintenance page, in order
+func maintenanceSection(page, heading string) []string {
+	var titles []string
+	section := between(page, "<h2>"+heading+" (", "</section>")
+	for _, m := range maintenanceTitleRe.FindAllStringSubmatch(section, -1) {
+		titles = append(titles, m[1])
+	}
+	return titles
+}
+
+func TestMaintenancePage(t *testing.T) {
+	// Besides the fixture, a tagged note of 60 words linking to a1
+	wordy := strings.TrimSpace(strings.Repeat("word ", 60))
+
+	tests := []struct {
+		name         string
+		minLinks     int
+		minWords     int
+		wantOrphans  []string
+		wantStubs    []string
+		wantUntagged []string
+	}{
+		{
+			name:         "defaults",
+			minLinks:     1,
+			minWords:     50,
+			wantOrphans:  []string{"Epsilon"},
+			wantStubs:    []string{"Alpha", "Beta", "Epsilon", "Gamma"},
+			wantUntagged: []string{"Epsilon", "Gamma"},
+		},
+		{
+			name:         "more links required",
+			minLinks:     3,
+			minWords:     0,
+			wantOrphans:  []string{"Epsilon", "Gamma", "Wordy"},
+			wantUntagged: []string{"Epsilon", "Gamma"},
+		},
+		{
+			name:         "more words required",
+			minLinks:     0,
+			minWords:     100,
+			wantStubs:    []string{"Alpha", "Beta", "Epsilon", "Gamma", "Wordy"},
+			wantUntagged: []string{"Epsilon", "Gamma"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := newTestVault(t)
+			v.cfg.Display.Maintenance.Enabled = true
+			v.cfg.Display.Maintenance.MinLinks = tt.minLinks
+			v.cfg.Display.Maintenance.MinWords = tt.minWords
+			v.add(testNote{ID: "w1", Title: "Wordy", Tags: []string{"go"}, Body: wordy, Links: []string{"a1"}})
+			v.build()
+			page := v.read("maintenance.html")
+
+			for _, s := range []struct {
+				heading string
+				want    []string
+			}{
+				{"Orphans", tt.wantOrphans},
+				{"Stubs", tt.wantStubs},
+				{"Untagged", tt.wantUntagged},
+			} {
+				if got := maintenanceSection(page, s.heading); !slices.Equal(got, s.want) {
+					t.Errorf("%s = %v, want %v", s.heading, got, s.want)
+				}
+			}
+			if strings.Contains(page, "Delta") {
+				t.Error("maintenance page lists the private note")
+			}
+		})
+	}
+}
+
+func TestMaintenancePageDisabled(t *testing.T) {
+	v := newTestVault(t)
+	v.build()
+	if v.exists("maintenance.html") {
+		t.Error("maintenance.html written with the page disabled")
+	}
+}
@@ -0,0 +1,147 @@
+package render
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/andybalholm/brotli"
+)
+
+// precompressExts are the output files given .gz and .br siblings
+var precompressExts = map[string]bool{".html": true, ".json": true, ".css": true, ".js": true}
+
+// precompressMinGain is the fraction of a file compression must save for
+// the compressed copy to be kept
+const precompressMinGain = 0.1
+
+// precompress writes gzip and brotli copies next to the text files in the
+// output directory, for web servers serving them as they are (nginx
+// gzip_static and brotli_static). A copy that would save too little is
+// not written, and a stale one from an earlier build is removed. A file
+// whose content and compression level match the build cache keeps the
+// copy of the earlier build. Copies without an original, or every copy
+// when display.precompress is off, are removed.
+func (r *Renderer) precompress() error {
+	pc := r.cfg.Display.Precompress
+	encoders := []struct {
+		ext   string
+		level int
+		new   func(io.Writer) (io.WriteCloser, error)
+	}{
+		{".gz", pc.GzipLevel, func(w io.Writer) (io.WriteCloser, error) { return gzip.NewWriterLevel(w, pc.GzipLevel) }},
+		{".br", pc.BrotliLevel, func(w io.Writer) (io.WriteCloser, error) { return brotli.NewWriterLevel(w, pc.BrotliLevel), nil }},
+	}
+
+	err := filepath.WalkDir(r.cfg.Paths.OutputDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		if ext := filepath.Ext(path); ext == ".gz" || ext == ".br" {
+			orig := strings.TrimSuffix(path, ext)
+			if !precompressExts[filepath.Ext(orig)] {
+				return nil
+			}
+			if pc.Enabled {
+				if _, err := os.Stat(orig); err == nil {
+					return nil
+				}
+			}
+			return r.removeCompressed(path)
+		}
+		// The build cache isn't served
+		if !pc.Enabled || !precompressExts[filepath.Ext(path)] || d.Name() == buildCacheFile {
+			return nil
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		sum := sha256.Sum256(data)
+
+		for _, enc := range encoders {
+			out := path + enc.ext
+			if len(data) < pc.MinSize {
+				if err := r.removeCompressed(out); err != nil {
+					return err
+				}
+				continue
+			}
+
+			name, err := r.outputName(out)
+			if err != nil {
+				return err
+			}
+			hash := fmt.Sprintf("%s:%d", hex.EncodeToString(sum[:]), enc.level)
+			if r.cache.fresh(name, hash) {
+				continue
+			}
+
+			var buf bytes.Buffer
+			w, err := enc.new(&buf)
+			if err != nil {
+				return err
+			}
+			if _, err := w.Write(data); err != nil {
+				return err
+			}
+			if err := w.Close(); err != nil {
+				return err
+			}
+
+			if float64(buf.Len()) > float64(len(data))*(1-precompressMinGain) {
+				if err := r.removeCompressed(out); err != nil {
+					return err
+				}
+				continue
+			}
+			if err := os.WriteFile(out, buf.Bytes(), 0644); err != nil {
+				return err
+			}
+			r.cache.record(name, hash)
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to precompress output: %w", err)
+	}
+	return nil
+}
+
+// outputName returns path relative to the output directory, the way the
+// build cache names files
+func (r *Renderer) outputName(path string) (string, error) {
+	rel, err := filepath.Rel(r.cfg.Paths.OutputDir, path)
+	if err != nil {
+		return "", err
+	}
+	return filepath.ToSlash(rel), nil
+}
+
+// removeCompressed removes a compressed copy and forgets it in the build
+// cache
+func (r *Renderer) removeCompressed(path string) error {
+	if name, err := r.outputName(path); err == nil {
+		delete(r.cache.Hashes, name)
+	}
+	return removeStale(path)
+}
+
+// removeStale removes a compressed copy left by an earlier build
+func removeStale(path string) error {
+	if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return nil
+}
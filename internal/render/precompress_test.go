@@ -0,0 +1,177 @@
+package render
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/andybalholm/brotli"
+)
+
+// compressedFiles returns the .gz and .br files in the output directory
+func compressedFiles(t *testing.T, dir string) []string {
+	t.Helper()
+	var files []string
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if ext := filepath.Ext(path); !d.IsDir() && (ext == ".gz" || ext == ".br") {
+			rel, _ := filepath.Rel(dir, path)
+			files = append(files, filepath.ToSlash(rel))
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return files
+}
+
+func TestPrecompress(t *testing.T) {
+	v := newTestVault(t)
+	v.cfg.Display.Precompress.Enabled = true
+	v.cfg.Display.Precompress.MinSize = 1024
+	v.build()
+
+	page := v.read("index.html")
+	tests := []struct {
+		name   string
+		decode func(io.Reader) (io.Reader, error)
+	}{
+		{"index.html.gz", func(r io.Reader) (io.Reader, error) { return gzip.NewReader(r) }},
+		{"index.html.br", func(r io.Reader) (io.Reader, error) { return brotli.NewReader(r), nil }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := tt.decode(strings.NewReader(v.read(tt.name)))
+			if err != nil {
+				t.Fatal(err)
+			}
+			data, err := io.ReadAll(r)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(data, []byte(page)) {
+				t.Errorf("%s doesn't decompress to index.html", tt.name)
+			}
+		})
+	}
+
+	for _, name := range compressedFiles(t, v.cfg.Paths.OutputDir) {
+		orig := strings.TrimSuffix(strings.TrimSuffix(name, ".gz"), ".br")
+		data := v.read(orig)
+		if len(data) < 1024 {
+			t.Errorf("%s written for %s of %d bytes", name, orig, len(data))
+		}
+		if strings.HasPrefix(orig, buildCacheFile) {
+			t.Errorf("build cache compressed as %s", name)
+		}
+	}
+}
+
+func TestPrecompressRebuild(t *testing.T) {
+	old := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name     string
+		change   func(v *testVault) // Before the second build
+		want     []string           // Among the copies after it
+		unwanted []string           // Missing after it
+		kept     bool               // index.html.gz isn't rewritten
+	}{
+		{
+			name:   "unchanged",
+			change: func(v *testVault) {},
+			want:   []string{"index.html.gz", "index.html.br"},
+			kept:   true,
+		},
+		{
+			name:   "level changed",
+			change: func(v *testVault) { v.cfg.Display.Precompress.GzipLevel = 1 },
+			want:   []string{"index.html.gz", "index.html.br"},
+		},
+		{
+			name: "orphaned copies",
+			change: func(v *testVault) {
+				for _, name := range []string{"gone.html.gz", "notes/gone.html.br"} {
+					if err := os.WriteFile(filepath.Join(v.cfg.Paths.OutputDir, name), []byte("stale"), 0644); err != nil {
+						v.t.Fatal(err)
+					}
+				}
+			},
+			want:     []string{"index.html.gz"},
+			unwanted: []string{"gone.html.gz", "notes/gone.html.br"},
+			kept:     true,
+		},
+		{
+			name: "other archives",
+			change: func(v *testVault) {
+				if err := os.WriteFile(filepath.Join(v.cfg.Paths.OutputDir, "backup.tar.gz"), []byte("archive"), 0644); err != nil {
+					v.t.Fatal(err)
+				}
+			},
+			want: []string{"index.html.gz", "backup.tar.gz"},
+			kept: true,
+		},
+		{
+			name:     "grown min size",
+			change:   func(v *testVault) { v.cfg.Display.Precompress.MinSize = 1 << 30 },
+			unwanted: []string{"index.html.gz", "index.html.br"},
+		},
+		{
+			name:     "disabled",
+			change:   func(v *testVault) { v.cfg.Display.Precompress.Enabled = false },
+			unwanted: []string{"index.html.gz", "index.html.br"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := newTestVault(t)
+			v.cfg.Display.Precompress.Enabled = true
+			v.build()
+			gz := filepath.Join(v.cfg.Paths.OutputDir, "index.html.gz")
+			if err := os.Chtimes(gz, old, old); err != nil {
+				t.Fatal(err)
+			}
+
+			tt.change(v)
+			v.build()
+			files := compressedFiles(t, v.cfg.Paths.OutputDir)
+			for _, name := range tt.want {
+				if !slices.Contains(files, name) {
+					t.Errorf("%s missing, have %v", name, files)
+				}
+			}
+			for _, name := range tt.unwanted {
+				if slices.Contains(files, name) {
+					t.Errorf("%s left behind", name)
+				}
+			}
+			if !v.cfg.Display.Precompress.Enabled {
+				for _, name := range files {
+					if name != "backup.tar.gz" {
+						t.Errorf("%s left with precompression off", name)
+					}
+				}
+			}
+
+			if len(tt.want) == 0 {
+				return
+			}
+			info, err := os.Stat(gz)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if kept := info.ModTime().Equal(old); kept != tt.kept {
+				t.Errorf("index.html.gz kept = %v, want %v", kept, tt.kept)
+			}
+		})
+	}
+}
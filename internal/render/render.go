@@ -1,19 +1,30 @@
 package render
 
 import (
+	"bytes"
+	"crypto/sha256"
 	"embed"
+	"encoding/json"
 	"fmt"
+	"hash/fnv"
 	"html/template"
 	"io"
 	"io/fs"
+	"net/url"
 	"os"
 	"path/filepath"
 	"regexp"
+	"runtime"
+	"runtime/debug"
+	"slices"
 	"sort"
 	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/nicehiro/org-roam-web/internal/bib"
 	"github.com/nicehiro/org-roam-web/internal/config"
 	"github.com/nicehiro/org-roam-web/internal/db"
 	"github.com/nicehiro/org-roam-web/internal/graph"
@@ -24,6 +35,32 @@ import (
 //go:embed templates/*
 var templatesFS embed.FS
 
+// rootOutputs are the files and directories generated at the site root.
+// When display.notes_subdir is empty, note pages share the root and must
+// not replace any of them; every new root output belongs in this list.
+var rootOutputs = []string{
+	"index.html", "graph.html", "timeline.html", "changes.html", "tasks.html",
+	"bibliography.html", "maintenance.html",
+	"feed.xml", "sitemap.xml",
+	"search.json", "graph.json", "graph-component.json", "tags.json", "broken-links.json",
+	"graph.gexf", "graph.graphml", "graph.dot", "notes.csv",
+	"llms.txt", "llms-full.txt", "syntax.css", buildCacheFile,
+	"tags", "sections", "img", "api",
+}
+
+// isRootOutput reports whether name, relative to the output directory, is
+// one of rootOutputs
+func isRootOutput(name string) bool {
+	return slices.Contains(rootOutputs, name)
+}
+
+// minimapMinHeadings is how many ToC headings a note needs before
+// display.reading_progress adds the mini-map
+const minimapMinHeadings = 3
+
+// untaggedTag is the name of the pseudo-tag page listing notes without tags
+const untaggedTag = "untagged"
+
 // NoteData holds data for rendering a note page
 type NoteData struct {
 	Site       SiteData
@@ -35,79 +72,245 @@ type NoteData struct {
 	Backlinks  []LinkData
 	LocalGraph template.JS
 	HasGraph   bool
+	Truncated  bool // Local graph was capped by local_graph_max_nodes
 	ToC        []parser.ToCEntry
 	ModTime    time.Time
+	Properties []NoteProperty
+	SeriesNav  *SeriesNav
+	Status     *NoteStatus
+	Empty      bool   // Content has no visible text
+	EmptyText  string // Placeholder shown for empty notes
+	Minimap    bool   // Show the reading progress bar and mini-map
+	HasMermaid bool   // Load the mermaid runtime for diagrams in Content
+	Banner     string // Hero image URL from the BANNER property
+	OGImage    string // Banner, or else the note's first image
+
+	Extra map[string]any // Data from the note's .meta.yaml sidecar, if any
+
+	Author string // #+AUTHOR, or the default from a setup file
+
+	Citations []Citation // ROAM_REFS cite keys, with paths.bibliography
+	Refs      []NoteRef  // Other ROAM_REFS, and cite keys without a bibliography
+
+	EditURL string // From site.edit_url_template
+}
+
+// NoteStatus is the maturity badge of a note
+type NoteStatus struct {
+	Name  string
+	Label string
+	Color string
+	Known bool // Listed in display.statuses
+}
+
+// NoteProperty is a property shown in a note's metadata table
+type NoteProperty struct {
+	Name  string
+	Value string
 }
 
 // LinkData represents a link to another note
 type LinkData struct {
-	ID    string
-	Title string
+	ID    string `json:"id"`
+	Title string `json:"title"`
+	URL   string `json:"url"`
+	Count int    `json:"count,omitempty"` // Links merged by display.dedupe_backlinks
 }
 
 // HomeData holds data for rendering the home page
 type HomeData struct {
 	Site        SiteData
+	Featured    *NotePreview // Left out of RecentNotes
 	RecentNotes []NotePreview
 }
 
 // GraphPageData holds data for the graph page
 type GraphPageData struct {
-	Site      SiteData
-	GraphJSON template.JS
-	AllTags   []string
-	TopTags   []string
+	Site         SiteData
+	GraphJSON    template.JS
+	AllTags      []string
+	TopTags      []string
+	ShowUntagged bool
+	TotalNodes   int       // Set when the page shows a preview of the graph
+	TagFeeds     []TagFeed // Feeds of the top tags, for discovery links
 }
 
 // TagPageData holds data for a tag page
 type TagPageData struct {
-	Site  SiteData
-	Tag   string
-	Notes []NotePreview
+	Site    SiteData
+	Tag     string
+	Notes   []NotePreview
+	FeedURL string // Set when display.tag_feeds is on
+
+	Breadcrumbs template.JS // BreadcrumbList JSON-LD, with display.tag_breadcrumbs
 }
 
 // NotePreview is a short preview of a note
 type NotePreview struct {
 	ID      string
 	Title   string
+	URL     string
 	Tags    []string
 	ModTime time.Time
+	Excerpt string // Start of the first paragraph, on tag pages
 }
 
 // SiteData holds global site information
 type SiteData struct {
-	Title   string
-	BaseURL string
+	Title        string
+	BaseURL      string
+	Timeline     bool
+	Changes      bool // Link to changes.html
+	Tasks        bool // Link to tasks.html
+	Feed         bool // Link to the Atom feed.xml
+	SyntaxCSS    bool // Link to syntax.css for highlighted source blocks
+	Math         bool // Load KaTeX to render LaTeX math
+	GlobalSearch bool // Search box in the header of every page
+
+	Language string        // lang attribute of the page
+	Sections []SectionLink // Top-level folder sections linked from the nav
+
+	LayoutSeed float64 // display.seed as a d3.randomLcg seed in (0, 1), 0 for none
+}
+
+// RedirectData holds data for a redirect stub page
+type RedirectData struct {
+	Site  SiteData
+	Title string
+	URL   string
+}
+
+// TagIndexEntry summarizes one tag in tags.json
+type TagIndexEntry struct {
+	Name  string   `json:"name"`
+	Count int      `json:"count"`
+	Notes []string `json:"notes"` // Note IDs
+}
+
+// TagNoteEntry is a note listed in tags/<tag>.json
+type TagNoteEntry struct {
+	ID    string   `json:"id"`
+	Title string   `json:"title"`
+	URL   string   `json:"url"`
+	Tags  []string `json:"tags"`
+}
+
+// TimelineData holds data for the timeline page
+type TimelineData struct {
+	Site    SiteData
+	Years   []TimelineYear
+	Undated []NotePreview
+}
+
+// ChangesData holds data for the recent changes page
+type ChangesData struct {
+	Site  SiteData
+	Notes []NotePreview // Newest first
+}
+
+// TimelineYear groups a year's notes by month, newest first
+type TimelineYear struct {
+	Year   int
+	Count  int
+	Months []TimelineMonth
+}
+
+// TimelineMonth lists the notes of one month, newest first
+type TimelineMonth struct {
+	Month time.Month
+	Notes []NotePreview
 }
 
 // Renderer handles site generation
 type Renderer struct {
-	cfg       *config.Config
-	nodes     []db.Node
-	links     []db.Link
-	nodeTags  map[string][]string
-	nodeMap   map[string]string   // ID -> Title
-	backlinks map[string][]string // ID -> []SourceID
+	cfg           *config.Config
+	nodes         []db.Node
+	links         []db.Link
+	nodeTags      map[string][]string
+	nodeMap       map[string]string   // ID -> display title
+	backlinks     map[string][]string // ID -> []SourceID
+	dates         map[string]time.Time
+	redirects     map[string]string // Redirecting ID -> target ID
+	redirectNodes []db.Node
+	headingNodes  []db.Node
+	headingParent map[string]string            // Heading node ID -> file node ID, with include_heading_nodes: anchor
+	headingPages  map[string]map[string]string // File -> heading node ID -> title, with include_heading_nodes: page
+	slugs         map[string]string            // ID -> page directory, with url_style: slug
+	series        map[string][]db.Node         // SERIES name -> ordered parts
+	cache         *buildCache
+	nodeProps     map[string]map[string]string // ID -> properties
+	emptyNotes    map[string]bool              // Hidden by display.hide_empty_notes
+	siteGraph     *graph.Graph                 // Built once by globalGraph
+	noteText      map[string]string            // Plain text of rendered notes, for llms-full.txt
+	noteTextMu    sync.Mutex                   // Guards noteText, noteTasks and brokenLinks during batched builds
+	noteTasks     map[string][]parser.Task     // Open tasks of rendered notes, for tasks.html
+	brokenLinks   map[string][]BrokenLink      // Unpublished id: link targets of rendered notes
+	knownIDs      map[string]bool              // Every node ID in the database
+	titleStrip    *regexp.Regexp               // display.title_strip_pattern, if set
+	dbRoot        string                       // Directory shared by the note paths in the database
+	sections      []SectionLink                // Top-level folders, for display.folder_sections
+	graphNodes    []db.Node                    // Notes shown in graphs, without graph.exclude_*
+	graphLinks    []db.Link                    // Links among graphNodes
+	files         map[string]*noteFile         // Resolved path -> its date and sidecar
+	aliases       map[string][]string          // ID -> ROAM_ALIASES
+	refs          map[string][]db.Ref          // ID -> ROAM_REFS
+	citeKeys      map[string][]string          // ID -> cite keys in ROAM_REFS
+	bibliography  map[string]bib.Entry         // paths.bibliography by cite key, if set
+	filesMu       sync.Mutex                   // Guards files during batched builds
+	excerpts      map[string]string            // ID -> excerpt, computed once per build
+	excerptsMu    sync.Mutex
+
+	// Incremental state carried over from a previous build (serve mode)
+	searchIndex  *search.SearchIndex
+	changedFiles map[string]bool
 }
 
 // NewRenderer creates a new site renderer
 func NewRenderer(cfg *config.Config) (*Renderer, error) {
+	var titleStrip *regexp.Regexp
+	if pattern := cfg.Display.TitleStripPattern; pattern != "" {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid display.title_strip_pattern: %w", err)
+		}
+		titleStrip = re
+	}
+	if theme := cfg.Display.SyntaxTheme; theme != "" && !parser.SyntaxThemeExists(theme) {
+		return nil, fmt.Errorf("invalid display.syntax_theme: unknown theme %q", theme)
+	}
+
 	return &Renderer{
-		cfg:       cfg,
-		nodeMap:   make(map[string]string),
-		backlinks: make(map[string][]string),
+		cfg:        cfg,
+		nodeMap:    make(map[string]string),
+		backlinks:  make(map[string][]string),
+		dates:      make(map[string]time.Time),
+		redirects:  make(map[string]string),
+		files:      make(map[string]*noteFile),
+		titleStrip: titleStrip,
 	}, nil
 }
 
+// displayTitle applies display.title_strip_pattern to a title. A title
+// the pattern would empty is kept as is.
+func (r *Renderer) displayTitle(title string) string {
+	if r.titleStrip == nil {
+		return title
+	}
+	if stripped := strings.TrimSpace(r.titleStrip.ReplaceAllString(title, "")); stripped != "" {
+		return stripped
+	}
+	return title
+}
+
 // templateFuncs returns the template function map
-func templateFuncs() template.FuncMap {
+func templateFuncs(dateFormat string) template.FuncMap {
 	return template.FuncMap{
 		"join": strings.Join,
 		"formatDate": func(t time.Time) string {
 			if t.IsZero() {
 				return ""
 			}
-			return t.Format("Jan 2, 2006")
+			return t.Format(dateFormat)
 		},
 		// safeHTML marks a string as safe HTML (won't be escaped)
 		// Used for titles containing LaTeX like $\pi_0$
@@ -117,23 +320,34 @@ func templateFuncs() template.FuncMap {
 	}
 }
 
-// parseTemplate parses a specific template with the base template
-func parseTemplate(name string) (*template.Template, error) {
-	return template.New("").Funcs(templateFuncs()).ParseFS(templatesFS, "templates/base.html", "templates/"+name)
+// parseTemplate parses a specific template with the base template and the
+// partials shared between note layouts
+func parseTemplate(name string, dateFormat string) (*template.Template, error) {
+	return template.New("").Funcs(templateFuncs(dateFormat)).ParseFS(templatesFS, "templates/base.html", "templates/partials.html", "templates/"+name)
 }
 
 // Build generates the static site
 func (r *Renderer) Build() error {
+	// Command line flags may have moved the paths since the config was loaded
+	if err := r.cfg.Paths.CheckNesting(); err != nil {
+		return err
+	}
+
 	// Load data from database
 	if err := r.loadData(); err != nil {
 		return err
 	}
+	if r.searchIndex != nil {
+		r.reuseExcerpts(r.searchIndex)
+	}
 
 	// Create output directory
 	if err := os.MkdirAll(r.cfg.Paths.OutputDir, 0755); err != nil {
 		return fmt.Errorf("failed to create output directory: %w", err)
 	}
 
+	r.cache = loadBuildCache(r.cfg.Paths.OutputDir)
+
 	// Generate pages
 	if err := r.generateHome(); err != nil {
 		return err
@@ -143,6 +357,14 @@ func (r *Renderer) Build() error {
 		return err
 	}
 
+	if err := r.reportBrokenLinks(); err != nil {
+		return err
+	}
+
+	if err := r.generateRedirects(); err != nil {
+		return err
+	}
+
 	if err := r.generateGraph(); err != nil {
 		return err
 	}
@@ -151,6 +373,42 @@ func (r *Renderer) Build() error {
 		return err
 	}
 
+	if r.cfg.Display.Timeline {
+		if err := r.generateTimeline(); err != nil {
+			return err
+		}
+	}
+
+	if r.cfg.Display.ChangesPage {
+		if err := r.generateChanges(); err != nil {
+			return err
+		}
+	}
+
+	if r.cfg.Display.TasksPage {
+		if err := r.generateTasks(); err != nil {
+			return err
+		}
+	}
+
+	if r.cfg.Display.Maintenance.Enabled {
+		if err := r.generateMaintenance(); err != nil {
+			return err
+		}
+	}
+
+	if r.bibliography != nil {
+		if err := r.generateBibliography(); err != nil {
+			return err
+		}
+	}
+
+	if r.cfg.Display.FolderSections {
+		if err := r.generateSections(); err != nil {
+			return err
+		}
+	}
+
 	// Copy images
 	if err := r.copyImages(); err != nil {
 		return err
@@ -161,14 +419,173 @@ func (r *Renderer) Build() error {
 		return err
 	}
 
+	// Feed readers and search engines need absolute URLs
+	if r.cfg.Display.FeedCount > 0 {
+		if !r.cfg.Site.HasAbsBaseURL() {
+			fmt.Printf("Warning: skipping feed.xml: site.base_url %q is not an absolute URL\n", r.cfg.Site.BaseURL)
+		} else if err := r.generateFeed(); err != nil {
+			return err
+		}
+	}
+
+	if r.cfg.Display.GenerateSitemap {
+		if !r.cfg.Site.HasAbsBaseURL() {
+			fmt.Printf("Warning: skipping sitemap.xml: site.base_url %q is not an absolute URL\n", r.cfg.Site.BaseURL)
+		} else if err := r.generateSitemap(); err != nil {
+			return err
+		}
+	}
+
+	if r.cfg.Display.SyntaxTheme != "" {
+		if err := r.generateSyntaxCSS(); err != nil {
+			return err
+		}
+	}
+
 	// Generate graph JSON
 	if err := r.generateGraphJSON(); err != nil {
 		return err
 	}
 
+	if r.cfg.Display.GraphSeed != "" {
+		if err := r.generateComponentJSON(); err != nil {
+			return err
+		}
+	}
+
+	if r.cfg.Display.EmitGEXF {
+		if err := r.generateGEXF(); err != nil {
+			return err
+		}
+	}
+
+	if r.cfg.Display.EmitGraphML {
+		if err := r.generateGraphML(); err != nil {
+			return err
+		}
+	}
+
+	if r.cfg.Display.ExportDOT {
+		if err := r.generateDOT(); err != nil {
+			return err
+		}
+	}
+
+	if r.cfg.Display.EmitCSV {
+		if err := r.generateCSV(); err != nil {
+			return err
+		}
+	}
+
+	if r.cfg.Display.EmitLLMsTxt {
+		if err := r.generateLLMsTxt(); err != nil {
+			return err
+		}
+	}
+
+	// Last, so every generated file is covered. It runs with
+	// precompression off too, to remove the copies of earlier builds.
+	if err := r.precompress(); err != nil {
+		return err
+	}
+
+	if err := r.cache.save(); err != nil {
+		return fmt.Errorf("failed to save build cache: %w", err)
+	}
+
 	return nil
 }
 
+// SearchIndex loads the notes and builds the search index without
+// generating any output
+func (r *Renderer) SearchIndex() (*search.SearchIndex, error) {
+	if err := r.loadData(); err != nil {
+		return nil, err
+	}
+	index := search.BuildIndex(r.nodes, r.nodeTags)
+	r.applySearchOptions(index)
+	return index, nil
+}
+
+// applySearchOptions adds the aliases, refs, excerpts and alias settings
+// to a search index
+func (r *Renderer) applySearchOptions(index *search.SearchIndex) {
+	index.SetAliases(r.aliases)
+	refs := make(map[string][]string, len(r.refs))
+	for id, nodeRefs := range r.refs {
+		for _, ref := range nodeRefs {
+			refs[id] = append(refs[id], ref.String())
+		}
+	}
+	index.SetRefs(refs)
+	excerpts := make(map[string]string, len(r.nodes))
+	for _, n := range r.nodes {
+		excerpts[n.ID] = r.noteExcerpt(n)
+	}
+	index.SetExcerpts(excerpts)
+	index.AliasDisplay = r.cfg.Display.SearchAliasDisplay
+	index.AliasWeight = r.cfg.Display.SearchAliasWeight
+}
+
+// Orphans returns the published notes with no links to or from another
+// published note, ordered by title. It uses the data loaded by Build.
+func (r *Renderer) Orphans() []db.Node {
+	byID := make(map[string]db.Node, len(r.nodes))
+	for _, n := range r.nodes {
+		byID[n.ID] = n
+	}
+	var orphans []db.Node
+	for _, id := range graph.Orphans(r.nodes, r.links) {
+		orphans = append(orphans, byID[id])
+	}
+	sort.SliceStable(orphans, func(i, j int) bool {
+		return r.nodeMap[orphans[i].ID] < r.nodeMap[orphans[j].ID]
+	})
+	return orphans
+}
+
+// ExternalLinks parses every published note and returns the http(s) URLs
+// they link to, mapped to the titles of the notes linking to each
+func (r *Renderer) ExternalLinks() (map[string][]string, error) {
+	if err := r.loadData(); err != nil {
+		return nil, err
+	}
+
+	p := parser.NewParser(r.cfg.Paths.RoamDir, r.nodeMap, r.cfg.Site.BaseURL, r.noteURL)
+	p.RespectNoExport = r.cfg.Display.RespectNoExport
+	p.TransliterateSlugs = r.cfg.Display.SlugTransliterate
+	p.ResolveSetupFiles = r.cfg.Display.SetupFiles
+
+	urls := make(map[string][]string)
+	for _, n := range r.nodes {
+		parsed, err := r.parseNote(p, n)
+		if err != nil {
+			fmt.Printf("Warning: failed to parse note %s: %v\n", n.Title, err)
+			continue
+		}
+		for _, u := range parsed.ExternalLinks {
+			urls[u] = append(urls[u], n.Title)
+		}
+	}
+	return urls, nil
+}
+
+// UseIncremental makes Build update index in place for the notes whose
+// files are listed in changed (plus added and removed notes), instead of
+// rebuilding the search index from scratch
+func (r *Renderer) UseIncremental(index *search.SearchIndex, changed []string) {
+	r.searchIndex = index
+	r.changedFiles = make(map[string]bool)
+	for _, f := range changed {
+		r.changedFiles[filepath.Clean(f)] = true
+	}
+}
+
+// LastSearchIndex returns the search index written by the last Build
+func (r *Renderer) LastSearchIndex() *search.SearchIndex {
+	return r.searchIndex
+}
+
 // loadData loads all data from the database
 func (r *Renderer) loadData() error {
 	database, err := db.Open(r.cfg.Paths.DBPath)
@@ -177,46 +594,361 @@ func (r *Renderer) loadData() error {
 	}
 	defer database.Close()
 
+	r.siteGraph = nil
+	r.noteText = make(map[string]string)
+	r.noteTasks = make(map[string][]parser.Task)
+	r.brokenLinks = make(map[string][]BrokenLink)
+	r.files = make(map[string]*noteFile)
+	r.excerpts = make(map[string]string)
+	r.headingParent = make(map[string]string)
+	r.headingPages = nil
+
 	// Load nodes
-	nodes, err := database.LoadNodes()
+	nodes, err := database.LoadNodes(r.cfg.Display.IncludeHeadingNodes != "")
 	if err != nil {
 		return fmt.Errorf("failed to load nodes: %w", err)
 	}
 
+	r.knownIDs, err = database.LoadNodeIDs()
+	if err != nil {
+		return fmt.Errorf("failed to load node IDs: %w", err)
+	}
+
 	// Load tags
 	nodeTags, err := database.LoadTags()
 	if err != nil {
 		return fmt.Errorf("failed to load tags: %w", err)
 	}
 
+	aliases, err := database.LoadAliases()
+	if err != nil {
+		return fmt.Errorf("failed to load aliases: %w", err)
+	}
+	r.aliases = aliases
+
+	refs, err := database.LoadRefs()
+	if err != nil {
+		return fmt.Errorf("failed to load refs: %w", err)
+	}
+	r.refs = refs
+	r.citeKeys = make(map[string][]string)
+	for id, nodeRefs := range refs {
+		for _, ref := range nodeRefs {
+			if ref.Type == "cite" {
+				r.citeKeys[id] = append(r.citeKeys[id], ref.Path)
+			}
+		}
+	}
+	if path := r.cfg.Paths.Bibliography; path != "" {
+		entries, err := bib.Load(path)
+		if err != nil {
+			return err
+		}
+		r.bibliography = entries
+	}
+
 	// Load links
 	links, err := database.LoadLinks()
 	if err != nil {
 		return fmt.Errorf("failed to load links: %w", err)
 	}
 
+	// Drop duplicate IDs before anything is keyed by ID
+	nodes = r.dedupeNodes(nodes)
+
+	files := make([]string, len(nodes))
+	for i, n := range nodes {
+		files[i] = n.File
+	}
+	r.dbRoot = commonDir(files)
+
+	// Tags from setup files count like the note's own #+FILETAGS
+	if r.cfg.Display.SetupFiles {
+		r.addSetupTags(nodes, nodeTags)
+	}
+
 	// Filter excluded nodes
 	r.nodes = r.filterNodes(nodes, nodeTags)
 
 	// Filter out nodes whose files don't exist on disk
 	r.nodes = r.filterExistingFiles(r.nodes)
 
+	// Set aside notes that redirect to another note
+	r.nodes = r.splitRedirects(r.nodes)
+
+	// Anchored heading nodes are part of their file's page, while heading
+	// pages are linked from it in place of their subtree
+	switch r.cfg.Display.IncludeHeadingNodes {
+	case "anchor":
+		r.nodes = r.splitHeadings(r.nodes)
+	case "page":
+		r.headingPages = headingPages(r.nodes)
+	}
+
 	r.nodeTags = nodeTags
-	r.links = links
 
-	// Build node map
+	// Links to or from a redirecting note belong to its target, and those
+	// of an anchored heading to its file's note
+	rules := r.cfg.Exclude.LinkRules()
+	r.links = make([]db.Link, 0, len(links))
+	for _, l := range links {
+		l.Source = r.resolveRedirect(r.headingPage(l.Source))
+		l.Target = r.resolveRedirect(r.headingPage(l.Target))
+		if l.Source != l.Target && !excludedLink(rules, l) {
+			r.links = append(r.links, l)
+		}
+	}
+
+	// Build node map and resolve note dates
+	r.nodeProps = make(map[string]map[string]string)
 	for _, n := range r.nodes {
-		r.nodeMap[n.ID] = n.Title
+		r.nodeMap[n.ID] = r.displayTitle(n.Title)
+		r.nodeProps[n.ID] = n.Properties
+		r.dates[n.ID] = r.fileDate(n)
+	}
+	for id := range r.redirects {
+		r.nodeMap[id] = r.nodeMap[r.resolveRedirect(id)]
+	}
+	for _, n := range r.headingNodes {
+		r.nodeMap[n.ID] = r.displayTitle(n.Title)
 	}
+	r.assignSlugs()
 
 	// Build backlinks map
 	for _, l := range r.links {
 		r.backlinks[l.Target] = append(r.backlinks[l.Target], l.Source)
 	}
 
+	r.graphNodes, r.graphLinks = r.filterGraph(r.nodes, r.links)
+
+	r.series = r.buildSeries()
+
+	if r.cfg.Display.FolderSections {
+		r.sections = r.topSections()
+	}
+
+	if r.cfg.Display.HideEmptyNotes {
+		r.emptyNotes = r.findEmptyNotes()
+	}
+
 	return nil
 }
 
+// filterGraph drops the notes matching graph.exclude_tags or
+// graph.exclude_ids, and the links touching them
+func (r *Renderer) filterGraph(nodes []db.Node, links []db.Link) ([]db.Node, []db.Link) {
+	gc := r.cfg.Graph
+	if len(gc.ExcludeTags) == 0 && len(gc.ExcludeIDs) == 0 {
+		return nodes, links
+	}
+
+	hidden := make(map[string]bool)
+	var kept []db.Node
+	for _, n := range nodes {
+		if slices.Contains(gc.ExcludeIDs, n.ID) || slices.ContainsFunc(r.nodeTags[n.ID], func(t string) bool {
+			return slices.Contains(gc.ExcludeTags, t)
+		}) {
+			hidden[n.ID] = true
+			continue
+		}
+		kept = append(kept, n)
+	}
+
+	var keptLinks []db.Link
+	for _, l := range links {
+		if !hidden[l.Source] && !hidden[l.Target] {
+			keptLinks = append(keptLinks, l)
+		}
+	}
+	return kept, keptLinks
+}
+
+// findEmptyNotes returns the IDs of notes whose files have no published
+// content besides keywords and property drawers
+func (r *Renderer) findEmptyNotes() map[string]bool {
+	empty := make(map[string]bool)
+	for _, n := range r.nodes {
+		content, err := r.publishedContent(n)
+		if err == nil && parser.IsEmptyOrg(content) {
+			empty[n.ID] = true
+		}
+	}
+	return empty
+}
+
+// splitRedirects removes notes with a REDIRECT_TO property naming another
+// published note, recording them so a redirect stub is generated instead
+// of the page. Redirects to unknown notes, and notes redirecting in a
+// cycle, are ignored with a warning.
+func (r *Renderer) splitRedirects(nodes []db.Node) []db.Node {
+	ids := make(map[string]bool)
+	for _, n := range nodes {
+		ids[n.ID] = true
+	}
+
+	for _, n := range nodes {
+		target := strings.TrimPrefix(strings.TrimSpace(n.Properties["REDIRECT_TO"]), "id:")
+		if target == "" {
+			continue
+		}
+		if target == n.ID || !ids[target] {
+			fmt.Printf("Warning: note %s redirects to unknown note %s, rendering it normally\n", n.Title, target)
+			continue
+		}
+		r.redirects[n.ID] = target
+	}
+
+	// A cycle has no page to end at, so the notes in it are all kept.
+	// Cycles are found before any is removed, as removing one note's
+	// redirect would end the chain for the rest.
+	var cycle []db.Node
+	for _, n := range nodes {
+		if inRedirectCycle(r.redirects, n.ID) {
+			cycle = append(cycle, n)
+		}
+	}
+	for _, n := range cycle {
+		fmt.Printf("Warning: note %s is in a redirect cycle, rendering it normally\n", n.Title)
+		delete(r.redirects, n.ID)
+	}
+
+	var kept []db.Node
+	for _, n := range nodes {
+		if _, ok := r.redirects[n.ID]; ok {
+			r.redirectNodes = append(r.redirectNodes, n)
+		} else {
+			kept = append(kept, n)
+		}
+	}
+	return kept
+}
+
+// inRedirectCycle reports whether following redirects from id leads back
+// to it
+func inRedirectCycle(redirects map[string]string, id string) bool {
+	next := id
+	for i := 0; i < len(redirects); i++ {
+		target, ok := redirects[next]
+		if !ok {
+			return false
+		}
+		if target == id {
+			return true
+		}
+		next = target
+	}
+	return false
+}
+
+// splitHeadings removes heading nodes from nodes, recording each as an
+// anchor in the page of the file node it belongs to. Headings in a file
+// without a published file node are dropped.
+func (r *Renderer) splitHeadings(nodes []db.Node) []db.Node {
+	fileNodes := make(map[string]string)
+	for _, n := range nodes {
+		if n.Level == 0 {
+			fileNodes[n.File] = n.ID
+		}
+	}
+
+	r.headingNodes = nil
+	var kept []db.Node
+	for _, n := range nodes {
+		if n.Level == 0 {
+			kept = append(kept, n)
+			continue
+		}
+		if parent, ok := fileNodes[n.File]; ok {
+			r.headingParent[n.ID] = parent
+			r.headingNodes = append(r.headingNodes, n)
+		}
+	}
+	return kept
+}
+
+// headingPages returns the heading nodes among nodes by file, with their
+// titles
+func headingPages(nodes []db.Node) map[string]map[string]string {
+	pages := make(map[string]map[string]string)
+	for _, n := range nodes {
+		if n.Level == 0 {
+			continue
+		}
+		if pages[n.File] == nil {
+			pages[n.File] = make(map[string]string)
+		}
+		pages[n.File][n.ID] = n.Title
+	}
+	return pages
+}
+
+// headingPage returns the file node of an anchored heading node, or id
+// itself for any other node
+func (r *Renderer) headingPage(id string) string {
+	if parent, ok := r.headingParent[id]; ok {
+		return parent
+	}
+	return id
+}
+
+// resolveRedirect follows redirects from id to the note that is published.
+// splitRedirects has broken any cycle; the walk is bounded all the same.
+func (r *Renderer) resolveRedirect(id string) string {
+	for i := 0; i <= len(r.redirects); i++ {
+		target, ok := r.redirects[id]
+		if !ok {
+			break
+		}
+		id = target
+	}
+	return id
+}
+
+// dedupeNodes removes nodes sharing an ID, which would otherwise overwrite
+// each other's pages and map entries. The first node in database order is
+// kept; with display.strict_ids every node using the ID is dropped.
+func (r *Renderer) dedupeNodes(nodes []db.Node) []db.Node {
+	files := make(map[string][]string)
+	for _, n := range nodes {
+		files[n.ID] = append(files[n.ID], n.File)
+	}
+
+	var deduped []db.Node
+	seen := make(map[string]bool)
+	for _, n := range nodes {
+		if len(files[n.ID]) > 1 {
+			if seen[n.ID] {
+				continue
+			}
+			seen[n.ID] = true
+			if r.cfg.Display.StrictIDs {
+				fmt.Printf("Warning: duplicate ID %s in %s, skipping all\n", n.ID, strings.Join(files[n.ID], ", "))
+				continue
+			}
+			fmt.Printf("Warning: duplicate ID %s in %s, keeping %s\n", n.ID, strings.Join(files[n.ID], ", "), n.File)
+		}
+		deduped = append(deduped, n)
+	}
+
+	return deduped
+}
+
+// addSetupTags adds the FILETAGS of each note's #+SETUPFILE files to its
+// tags. Notes that can't be read are left to the later existence check.
+func (r *Renderer) addSetupTags(nodes []db.Node, nodeTags map[string][]string) {
+	for _, n := range nodes {
+		content, err := r.readNoteFile(n)
+		if err != nil || !strings.Contains(strings.ToLower(content), "#+setupfile:") {
+			continue
+		}
+		for _, tag := range parser.LoadSetup(content, r.resolveFilePath(n.File)).FileTags {
+			if !slices.Contains(nodeTags[n.ID], tag) {
+				nodeTags[n.ID] = append(nodeTags[n.ID], tag)
+			}
+		}
+	}
+}
+
 // filterNodes removes excluded nodes
 func (r *Renderer) filterNodes(nodes []db.Node, nodeTags map[string][]string) []db.Node {
 	excludeTags := make(map[string]bool)
@@ -265,6 +997,44 @@ func (r *Renderer) filterNodes(nodes []db.Node, nodeTags map[string][]string) []
 	return filtered
 }
 
+// noteDate returns the date of a note, resolved once in loadData. The zero
+// time means the note has no known date.
+func (r *Renderer) noteDate(n db.Node) time.Time {
+	return r.dates[n.ID]
+}
+
+// siteData returns the global site information for templates
+func (r *Renderer) siteData() SiteData {
+	return SiteData{
+		Title:    r.cfg.Site.Title,
+		BaseURL:  r.cfg.Site.BaseURL,
+		Timeline: r.cfg.Display.Timeline,
+		Changes:  r.cfg.Display.ChangesPage,
+		Tasks:    r.cfg.Display.TasksPage,
+		Feed:     r.cfg.Display.FeedCount > 0,
+
+		GlobalSearch: r.cfg.Display.GlobalSearch,
+		SyntaxCSS:    r.cfg.Display.SyntaxTheme != "",
+		Math:         r.cfg.Display.Math,
+
+		Language: r.cfg.Site.Language,
+		Sections: r.sections,
+
+		LayoutSeed: layoutSeed(r.cfg.Display.Seed),
+	}
+}
+
+// layoutSeed maps display.seed to a number in (0, 1) for seeding the
+// random source of the graph layouts, or 0 when no seed is set
+func layoutSeed(seed string) float64 {
+	if seed == "" {
+		return 0
+	}
+	h := fnv.New64a()
+	h.Write([]byte(seed))
+	return (float64(h.Sum64()>>12) + 0.5) / (1 << 52)
+}
+
 // extractDateFromFilename extracts date from org-roam filename
 // Formats supported:
 // - 20201031101403-title.org (org-roam format)
@@ -295,16 +1065,52 @@ func extractDateFromFilename(filename string) time.Time {
 	return time.Time{}
 }
 
+// listedNodes returns a copy of the nodes that appear in note listings,
+// leaving out empty notes when display.hide_empty_notes is set
+func (r *Renderer) listedNodes() []db.Node {
+	listed := make([]db.Node, 0, len(r.nodes))
+	for _, n := range r.nodes {
+		if !r.emptyNotes[n.ID] {
+			listed = append(listed, n)
+		}
+	}
+	return listed
+}
+
+// sortedNodes returns a copy of the nodes ordered by display.home_sort
+// (newest first by default)
+func (r *Renderer) sortedNodes() []db.Node {
+	sorted := r.listedNodes()
+	compare := newNoteCompare(r.cfg.Display.HomeSort, r.noteDate)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return compare(sorted[i], sorted[j]) < 0
+	})
+	return sorted
+}
+
 // generateHome generates the home page
 func (r *Renderer) generateHome() error {
-	// Sort nodes by date extracted from filename (descending - newest first)
-	sorted := make([]db.Node, len(r.nodes))
-	copy(sorted, r.nodes)
-	sort.Slice(sorted, func(i, j int) bool {
-		dateI := extractDateFromFilename(sorted[i].File)
-		dateJ := extractDateFromFilename(sorted[j].File)
-		return dateI.After(dateJ)
-	})
+	sorted := r.sortedNodes()
+
+	var featured *NotePreview
+	if n, ok := r.featuredNode(sorted); ok {
+		featured = &NotePreview{
+			ID:      n.ID,
+			Title:   r.nodeMap[n.ID],
+			URL:     r.noteURL(n.ID),
+			Tags:    r.nodeTags[n.ID],
+			ModTime: r.noteDate(n),
+			Excerpt: r.noteExcerpt(n),
+		}
+
+		rest := make([]db.Node, 0, len(sorted)-1)
+		for _, m := range sorted {
+			if m.ID != n.ID {
+				rest = append(rest, m)
+			}
+		}
+		sorted = rest
+	}
 
 	// Take recent notes
 	count := r.cfg.Display.RecentCount
@@ -317,99 +1123,504 @@ func (r *Renderer) generateHome() error {
 		n := sorted[i]
 		recentNotes[i] = NotePreview{
 			ID:      n.ID,
-			Title:   n.Title,
+			Title:   r.nodeMap[n.ID],
+			URL:     r.noteURL(n.ID),
 			Tags:    r.nodeTags[n.ID],
-			ModTime: extractDateFromFilename(n.File),
+			ModTime: r.noteDate(n),
 		}
 	}
 
 	data := HomeData{
-		Site: SiteData{
-			Title:   r.cfg.Site.Title,
-			BaseURL: r.cfg.Site.BaseURL,
-		},
+		Site:        r.siteData(),
+		Featured:    featured,
 		RecentNotes: recentNotes,
 	}
 
 	return r.renderPage("home.html", filepath.Join(r.cfg.Paths.OutputDir, "index.html"), data)
 }
 
+// featuredNode picks the home page's featured note from nodes: the one
+// named by display.featured_id, or else the first with a FEATURED property
+func (r *Renderer) featuredNode(nodes []db.Node) (db.Node, bool) {
+	if id := r.cfg.Display.FeaturedID; id != "" {
+		id = r.resolveRedirect(id)
+		for _, n := range nodes {
+			if n.ID == id {
+				return n, true
+			}
+		}
+		fmt.Printf("Warning: featured note %s not found or not published\n", r.cfg.Display.FeaturedID)
+	}
+
+	for _, n := range nodes {
+		if isTruthy(n.Properties["FEATURED"]) {
+			return n, true
+		}
+	}
+	return db.Node{}, false
+}
+
 // generateNotes generates all note pages
 func (r *Renderer) generateNotes() error {
-	notesDir := filepath.Join(r.cfg.Paths.OutputDir, "notes")
+	notesDir := filepath.Join(r.cfg.Paths.OutputDir, r.cfg.Display.NotesSubdir)
 	if err := os.MkdirAll(notesDir, 0755); err != nil {
 		return fmt.Errorf("failed to create notes directory: %w", err)
 	}
 
-	p := parser.NewParser(r.cfg.Paths.RoamDir, r.nodeMap, r.cfg.Site.BaseURL)
+	p := parser.NewParser(r.cfg.Paths.RoamDir, r.nodeMap, r.cfg.Site.BaseURL, r.noteURL)
+	p.DateFormat = r.cfg.Display.DateFormat
+	p.RespectNoExport = r.cfg.Display.RespectNoExport
+	p.TransliterateSlugs = r.cfg.Display.SlugTransliterate
+	p.ResolveSetupFiles = r.cfg.Display.SetupFiles
+	p.IDAnchors = r.cfg.Display.IncludeHeadingNodes == "anchor"
+	p.SyntaxHighlight = r.cfg.Display.SyntaxTheme != ""
 
+	var nodes []db.Node
 	for _, n := range r.nodes {
-		if err := r.generateNote(p, n, notesDir); err != nil {
-			fmt.Printf("Warning: failed to generate note %s: %v\n", n.Title, err)
+		// At the site root, a note page must not replace a generated page
+		if _, ok := r.slugs[n.ID]; !ok && r.cfg.Display.NotesSubdir == "" && isRootOutput(n.ID+".html") {
+			fmt.Printf("Warning: skipping note %s: %s.html is reserved at the site root\n", n.Title, n.ID)
+			continue
+		}
+		nodes = append(nodes, n)
+	}
+
+	var failed []string
+	fail := func(n db.Node, err error) error {
+		fmt.Printf("Warning: failed to generate note %s: %v\n", n.Title, err)
+		failed = append(failed, n.Title)
+		if max := r.cfg.Display.MaxParseErrors; max >= 0 && len(failed) > max {
+			return fmt.Errorf("too many failed notes (%d, max %d): %s: %w", len(failed), max, strings.Join(failed, ", "), err)
+		}
+		return nil
+	}
+
+	workers := r.cfg.Display.Workers
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+	size := r.cfg.Display.BatchSize
+
+	if workers == 1 && size <= 0 {
+		for _, n := range nodes {
+			if err := r.generateNote(p, n, notesDir); err != nil {
+				if err := fail(n, err); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}
+
+	// Without display.batch_size, all notes form one batch. With it, the
+	// memory of each batch is returned to the system before the next, to
+	// bound peak memory on large vaults. Failures are reported in note
+	// order, as in the sequential build.
+	if size <= 0 {
+		size = max(len(nodes), 1)
+	}
+	for start := 0; start < len(nodes); start += size {
+		batch := nodes[start:min(start+size, len(nodes))]
+		for i, err := range r.renderNotes(p, batch, notesDir, min(workers, len(batch))) {
+			if err != nil {
+				if err := fail(batch[i], err); err != nil {
+					return err
+				}
+			}
+		}
+		if r.cfg.Display.BatchSize > 0 {
+			debug.FreeOSMemory()
+		}
+	}
+
+	return nil
+}
+
+// renderNotes generates the pages of nodes across workers goroutines and
+// returns the error of each note at its index
+func (r *Renderer) renderNotes(p *parser.Parser, nodes []db.Node, notesDir string, workers int) []error {
+	errs := make([]error, len(nodes))
+	next := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range next {
+				errs[i] = r.generateNote(p, nodes[i], notesDir)
+			}
+		}()
+	}
+	for i := range nodes {
+		next <- i
+	}
+	close(next)
+	wg.Wait()
+	return errs
+}
+
+// generateRedirects writes a stub at the page of each redirecting note that
+// forwards to its target, and at the ID path of each note with a slug
+func (r *Renderer) generateRedirects() error {
+	notesDir := filepath.Join(r.cfg.Paths.OutputDir, r.cfg.Display.NotesSubdir)
+	for _, n := range r.redirectNodes {
+		data := RedirectData{
+			Site:  r.siteData(),
+			Title: r.nodeMap[n.ID],
+			URL:   r.noteURL(n.ID),
+		}
+		if err := r.renderPage("redirect.html", filepath.Join(notesDir, n.ID+".html"), data); err != nil {
+			return err
+		}
+	}
+	return r.generateSlugRedirects(notesDir)
+}
+
+// generateNote generates a single note page
+func (r *Renderer) generateNote(p *parser.Parser, n db.Node, notesDir string) error {
+	parsed, err := r.parseNote(p, n)
+	if err != nil {
+		return err
+	}
+
+	// Build links data
+	var links []LinkData
+	for _, l := range parsed.Links {
+		if title, ok := r.nodeMap[l.ID]; ok {
+			links = append(links, LinkData{ID: l.ID, Title: title, URL: r.noteURL(l.ID)})
+		}
+	}
+	broken := r.noteBrokenLinks(n, parsed.Links)
+	r.noteTextMu.Lock()
+	r.brokenLinks[n.ID] = broken
+	r.noteTextMu.Unlock()
+
+	// Build backlinks data. With dedupe_backlinks, a note linking here
+	// several times is listed once, at its first link, with a count.
+	var backlinks []LinkData
+	seen := make(map[string]int)
+	for _, sourceID := range r.backlinks[n.ID] {
+		if r.hiddenBacklinkSource(sourceID) {
+			continue
+		}
+		if i, ok := seen[sourceID]; ok && r.cfg.Display.DedupeBacklinks {
+			backlinks[i].Count++
+			continue
+		}
+		if title, ok := r.nodeMap[sourceID]; ok {
+			seen[sourceID] = len(backlinks)
+			backlinks = append(backlinks, LinkData{ID: sourceID, Title: title, URL: r.noteURL(sourceID), Count: 1})
+		}
+	}
+
+	if r.cfg.Display.EmitBacklinkJSON {
+		if err := r.writeBacklinkJSON(n.ID, backlinks); err != nil {
+			return err
+		}
+	}
+
+	// Generate local graph JSON
+	localG := graph.LocalGraph(n.ID, r.cfg.Display.LocalGraphDepth, r.cfg.Display.LocalGraphMaxNodes, r.graphNodes, r.graphLinks, r.nodeTags)
+	r.decorateGraph(localG)
+	localJSON, err := localG.ToCompactJSON()
+	if err != nil {
+		return fmt.Errorf("failed to serialize local graph: %w", err)
+	}
+
+	// Expand shortcodes such as {{< backlinks >}} in the rendered content
+	content := r.expandShortcodes(parsed.Content, shortcodeContext{
+		node:      n,
+		backlinks: backlinks,
+		toc:       parsed.ToC,
+	})
+	if r.cfg.Display.MaxImageWidth > 0 {
+		content = r.linkFullImages(content)
+	}
+	if r.cfg.Display.LazyImages {
+		content = r.lazyImages(content)
+	}
+	if r.cfg.Display.EmitLLMsFullTxt {
+		text := htmlToText(content)
+		r.noteTextMu.Lock()
+		r.noteText[n.ID] = text
+		r.noteTextMu.Unlock()
+	}
+	if r.cfg.Display.TasksPage {
+		var open []parser.Task
+		for _, t := range parsed.Tasks {
+			if !t.Done {
+				open = append(open, t)
+			}
+		}
+		r.noteTextMu.Lock()
+		r.noteTasks[n.ID] = open
+		r.noteTextMu.Unlock()
+	}
+
+	site := r.siteData()
+	site.Language = r.noteLanguage(n, content)
+
+	data := NoteData{
+		Site:       site,
+		ID:         n.ID,
+		Title:      parsed.Title,
+		Tags:       r.nodeTags[n.ID],
+		Content:    template.HTML(content),
+		Links:      links,
+		Backlinks:  backlinks,
+		LocalGraph: scriptJSON(localJSON),
+		HasGraph:   len(localG.Nodes) > 1,
+		Truncated:  localG.Truncated,
+		ToC:        parsed.ToC,
+		ModTime:    r.noteDate(n),
+		Properties: r.noteProperties(n),
+		SeriesNav:  r.seriesNav(n),
+		Status:     r.noteStatus(n),
+		Empty:      parser.IsEmptyHTML(content),
+		EmptyText:  r.cfg.Display.EmptyNotePlaceholder,
+		Minimap:    r.cfg.Display.ReadingProgress && len(parsed.ToC) >= minimapMinHeadings,
+		HasMermaid: parsed.HasMermaid,
+		Banner:     r.imageURL(n.Properties["BANNER"]),
+		Extra:      r.noteExtra(n),
+		Citations:  r.noteCitations(n),
+		Refs:       r.noteRefs(n),
+		Author:     parsed.Author,
+		EditURL:    r.editURL(n),
+	}
+	data.OGImage = data.Banner
+	if data.OGImage == "" && len(parsed.Images) > 0 {
+		data.OGImage = r.imageURL(parsed.Images[0])
+	}
+
+	outPath := r.notePagePath(notesDir, n.ID)
+	if err := os.MkdirAll(filepath.Dir(outPath), 0755); err != nil {
+		return fmt.Errorf("failed to create note directory: %w", err)
+	}
+	return r.renderPage(noteLayout(n), outPath, data)
+}
+
+// writeBacklinkJSON writes api/backlinks/<id>.json listing the notes
+// linking to a note, as shown on its page
+func (r *Renderer) writeBacklinkJSON(id string, backlinks []LinkData) error {
+	dir := filepath.Join(r.cfg.Paths.OutputDir, "api", "backlinks")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create backlinks directory: %w", err)
+	}
+
+	if backlinks == nil {
+		backlinks = []LinkData{}
+	}
+	data, err := json.MarshalIndent(struct {
+		ID        string     `json:"id"`
+		Backlinks []LinkData `json:"backlinks"`
+	}{id, backlinks}, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to serialize backlinks: %w", err)
+	}
+	return os.WriteFile(filepath.Join(dir, id+".json"), data, 0644)
+}
+
+// noteLayout returns the template selected by a node's LAYOUT property.
+// A layout "foo" maps to templates/note-foo.html; unset, "default" and
+// unknown layouts fall back to note.html.
+func noteLayout(n db.Node) string {
+	layout := strings.ToLower(strings.TrimSpace(n.Properties["LAYOUT"]))
+	if layout == "" || layout == "default" {
+		return "note.html"
+	}
+
+	name := "note-" + layout + ".html"
+	if _, err := fs.Stat(templatesFS, "templates/"+name); err != nil {
+		fmt.Printf("Warning: unknown layout '%s' for note %s, using default\n", layout, n.Title)
+		return "note.html"
+	}
+	return name
+}
+
+// noteExcerpt returns the start of a note's first paragraph. Each note's
+// file is read once however many pages show its excerpt.
+func (r *Renderer) noteExcerpt(n db.Node) string {
+	r.excerptsMu.Lock()
+	excerpt, ok := r.excerpts[n.ID]
+	r.excerptsMu.Unlock()
+	if ok {
+		return excerpt
+	}
+
+	if content, err := r.publishedContent(n); err == nil {
+		excerpt = parser.Excerpt(content, r.cfg.Display.ExcerptLength)
+	}
+	r.excerptsMu.Lock()
+	defer r.excerptsMu.Unlock()
+	if r.excerpts == nil {
+		r.excerpts = make(map[string]string)
+	}
+	r.excerpts[n.ID] = excerpt
+	return excerpt
+}
+
+// editURL returns the site.edit_url_template link for a note, or "" if
+// unset or the note has a NO_EDIT_LINK property. {path} is the note's
+// file path relative to roam_dir, slash-separated and escaped.
+func (r *Renderer) editURL(n db.Node) string {
+	tmpl := r.cfg.Site.EditURLTemplate
+	if tmpl == "" || isTruthy(n.Properties["NO_EDIT_LINK"]) {
+		return ""
+	}
+	parts := strings.Split(filepath.ToSlash(r.relNotePath(n.File)), "/")
+	for i, p := range parts {
+		parts[i] = url.PathEscape(p)
+	}
+	return strings.ReplaceAll(tmpl, "{path}", strings.Join(parts, "/"))
+}
+
+// noteStatus returns the badge for a note's STATUS property, or nil if it
+// has none. Statuses missing from display.statuses get a plain badge.
+func (r *Renderer) noteStatus(n db.Node) *NoteStatus {
+	name := strings.TrimSpace(n.Properties["STATUS"])
+	if name == "" {
+		return nil
+	}
+
+	s, ok := r.cfg.Display.FindStatus(name)
+	if !ok {
+		fmt.Printf("Warning: unknown status '%s' for note %s\n", name, n.Title)
+		return &NoteStatus{Name: name, Label: name}
+	}
+	label := s.Label
+	if label == "" {
+		label = s.Name
+	}
+	return &NoteStatus{Name: s.Name, Label: label, Color: s.Color, Known: true}
+}
+
+// imageURL returns the URL of an image referenced from a note or property.
+// Remote URLs are kept; local paths resolve like inline images.
+func (r *Renderer) imageURL(path string) string {
+	path = strings.TrimSpace(path)
+	if path == "" || strings.HasPrefix(path, "http://") || strings.HasPrefix(path, "https://") {
+		return path
+	}
+	return r.cfg.Site.AbsURL(parser.ImageURL("", path))
+}
+
+// internalProperties are the properties org-roam records for every node,
+// which are never shown on pages
+var internalProperties = map[string]bool{
+	"ID": true, "FILE": true, "CATEGORY": true, "BLOCKED": true, "ALLTAGS": true, "ITEM": true,
+}
+
+// noteProperties returns the properties listed in display.show_properties
+// that the note sets, in configured order. Internal properties such as ID
+// are left out.
+func (r *Renderer) noteProperties(n db.Node) []NoteProperty {
+	var props []NoteProperty
+	for _, name := range r.cfg.Display.ShowProperties {
+		// org-roam stores property keys upper-cased
+		key := strings.ToUpper(name)
+		value := strings.TrimSpace(n.Properties[key])
+		if value == "" || internalProperties[key] {
+			continue
+		}
+		props = append(props, NoteProperty{Name: name, Value: value})
+	}
+	return props
+}
+
+// hiddenBacklinkSource reports whether links from a note should be left out
+// of backlink lists per display.backlink_exclude_tags/_ids
+func (r *Renderer) hiddenBacklinkSource(id string) bool {
+	for _, excluded := range r.cfg.Display.BacklinkExcludeIDs {
+		if id == excluded {
+			return true
+		}
+	}
+	for _, tag := range r.nodeTags[id] {
+		for _, excluded := range r.cfg.Display.BacklinkExcludeTags {
+			if tag == excluded {
+				return true
+			}
 		}
 	}
-
-	return nil
+	return false
 }
 
-// generateNote generates a single note page
-func (r *Renderer) generateNote(p *parser.Parser, n db.Node, notesDir string) error {
-	// Resolve file path (database stores absolute paths from original machine)
-	filePath := r.resolveFilePath(n.File)
-
-	// Parse org file
-	parsed, err := p.ParseFile(filePath)
-	if err != nil {
-		return err
+// decorateGraph fills in the per-node fields the front-end relies on
+func (r *Renderer) decorateGraph(g *graph.Graph) {
+	g.Directed = r.cfg.Display.GraphDirected
+	if !g.Directed {
+		g.MergeReciprocal()
 	}
-
-	// Build links data
-	var links []LinkData
-	for _, l := range parsed.Links {
-		if title, ok := r.nodeMap[l.ID]; ok {
-			links = append(links, LinkData{ID: l.ID, Title: title})
+	g.SetURLs(r.noteURL)
+	if r.titleStrip != nil {
+		for i := range g.Nodes {
+			g.Nodes[i].Title = r.displayTitle(g.Nodes[i].Title)
 		}
 	}
-
-	// Build backlinks data
-	var backlinks []LinkData
-	for _, sourceID := range r.backlinks[n.ID] {
-		if title, ok := r.nodeMap[sourceID]; ok {
-			backlinks = append(backlinks, LinkData{ID: sourceID, Title: title})
+	g.AssignTagColors(r.cfg.Display.TagColors, r.cfg.Display.Seed)
+	if r.cfg.Display.GraphColorByStatus {
+		g.SetStatuses(func(id string) string {
+			return strings.TrimSpace(r.nodeProps[id]["STATUS"])
+		})
+		g.StatusColors = make(map[string]string)
+		for _, s := range r.cfg.Display.Statuses {
+			if s.Color != "" {
+				g.StatusColors[s.Name] = s.Color
+			}
 		}
 	}
+	if days := r.cfg.Display.RecentWindowDays; days > 0 {
+		g.MarkRecent(func(id string) bool {
+			return isRecent(r.dates[id], days, buildTime())
+		})
+	}
+}
 
-	// Generate local graph JSON
-	localG := graph.LocalGraph(n.ID, r.cfg.Display.LocalGraphDepth, r.nodes, r.links, r.nodeTags)
-	localJSON, err := localG.ToJSON()
-	if err != nil {
-		return fmt.Errorf("failed to serialize local graph: %w", err)
+// limitHubs applies display.graph_hide_hubs_over to the full graph
+func (r *Renderer) limitHubs(g *graph.Graph) {
+	max := r.cfg.Display.GraphHideHubsOver
+	if max <= 0 {
+		return
+	}
+	if r.cfg.Display.GraphHubMode == "dim" {
+		g.MarkHubs(max)
+	} else {
+		g.RemoveHubs(max)
 	}
+}
 
-	data := NoteData{
-		Site: SiteData{
-			Title:   r.cfg.Site.Title,
-			BaseURL: r.cfg.Site.BaseURL,
-		},
-		ID:         n.ID,
-		Title:      parsed.Title,
-		Tags:       r.nodeTags[n.ID],
-		Content:    template.HTML(parsed.Content),
-		Links:      links,
-		Backlinks:  backlinks,
-		LocalGraph: template.JS(localJSON),
-		HasGraph:   len(localG.Nodes) > 1,
-		ToC:        parsed.ToC,
-		ModTime:    extractDateFromFilename(n.File),
+// buildTime returns the time a build treats as now: SOURCE_DATE_EPOCH when
+// it is set, so output depending on the date can be reproduced later
+func buildTime() time.Time {
+	if epoch := os.Getenv("SOURCE_DATE_EPOCH"); epoch != "" {
+		if secs, err := strconv.ParseInt(epoch, 10, 64); err == nil {
+			return time.Unix(secs, 0).UTC()
+		}
+		fmt.Printf("Warning: ignoring invalid SOURCE_DATE_EPOCH %q\n", epoch)
 	}
+	return time.Now()
+}
 
-	outPath := filepath.Join(notesDir, n.ID+".html")
-	return r.renderPage("note.html", outPath, data)
+// isRecent reports whether date falls within the last days days before now
+func isRecent(date time.Time, days int, now time.Time) bool {
+	if date.IsZero() {
+		return false
+	}
+	return !date.Before(now.AddDate(0, 0, -days))
 }
 
 // generateGraph generates the graph page
 func (r *Renderer) generateGraph() error {
-	g := graph.BuildGraph(r.nodes, r.links, r.nodeTags)
+	g := r.globalGraph()
+
+	// Large graphs can be previewed by their best-connected notes, leaving
+	// the full graph to graph.json
+	totalNodes := 0
+	if max := r.cfg.Display.GraphPreviewNodes; max > 0 && len(g.Nodes) > max {
+		totalNodes = len(g.Nodes)
+		g = g.Preview(max)
+	}
+
 	graphJSON, err := g.ToJSON()
 	if err != nil {
 		return fmt.Errorf("failed to serialize graph: %w", err)
@@ -433,7 +1644,10 @@ func (r *Renderer) generateGraph() error {
 		tagList = append(tagList, tagCount{t, c})
 	}
 	sort.Slice(tagList, func(i, j int) bool {
-		return tagList[i].Count > tagList[j].Count
+		if tagList[i].Count != tagList[j].Count {
+			return tagList[i].Count > tagList[j].Count
+		}
+		return tagList[i].Tag < tagList[j].Tag
 	})
 
 	// Get top 10 tags
@@ -450,18 +1664,91 @@ func (r *Renderer) generateGraph() error {
 	sort.Strings(allTags)
 
 	data := GraphPageData{
-		Site: SiteData{
-			Title:   r.cfg.Site.Title,
-			BaseURL: r.cfg.Site.BaseURL,
-		},
-		GraphJSON: template.JS(graphJSON),
-		AllTags:   allTags,
-		TopTags:   topTags,
+		Site:         r.siteData(),
+		GraphJSON:    scriptJSON(graphJSON),
+		AllTags:      allTags,
+		TopTags:      topTags,
+		ShowUntagged: r.cfg.Display.ShowUntagged,
+		TotalNodes:   totalNodes,
+		TagFeeds:     r.tagFeeds(topTags),
 	}
 
 	return r.renderPage("graph.html", filepath.Join(r.cfg.Paths.OutputDir, "graph.html"), data)
 }
 
+// generateTimeline generates a page listing notes grouped by year and month
+func (r *Renderer) generateTimeline() error {
+	data := TimelineData{Site: r.siteData()}
+
+	compare := newNoteCompare([]string{"date_desc", "title_asc"}, r.noteDate)
+	sorted := r.listedNodes()
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return compare(sorted[i], sorted[j]) < 0
+	})
+
+	for _, n := range sorted {
+		date := r.noteDate(n)
+		preview := NotePreview{
+			ID:      n.ID,
+			Title:   r.nodeMap[n.ID],
+			URL:     r.noteURL(n.ID),
+			Tags:    r.nodeTags[n.ID],
+			ModTime: date,
+		}
+
+		if date.IsZero() {
+			data.Undated = append(data.Undated, preview)
+			continue
+		}
+
+		// Notes are sorted newest first, so a new year or month always
+		// starts a new group
+		if len(data.Years) == 0 || data.Years[len(data.Years)-1].Year != date.Year() {
+			data.Years = append(data.Years, TimelineYear{Year: date.Year()})
+		}
+		year := &data.Years[len(data.Years)-1]
+		if len(year.Months) == 0 || year.Months[len(year.Months)-1].Month != date.Month() {
+			year.Months = append(year.Months, TimelineMonth{Month: date.Month()})
+		}
+		month := &year.Months[len(year.Months)-1]
+		month.Notes = append(month.Notes, preview)
+		year.Count++
+	}
+
+	return r.renderPage("timeline.html", filepath.Join(r.cfg.Paths.OutputDir, "timeline.html"), data)
+}
+
+// generateChanges generates changes.html, the display.changes_count most
+// recent notes by date, newest first. Undated notes are left out.
+func (r *Renderer) generateChanges() error {
+	data := ChangesData{Site: r.siteData()}
+
+	compare := newNoteCompare([]string{"date_desc", "title_asc"}, r.noteDate)
+	sorted := r.listedNodes()
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return compare(sorted[i], sorted[j]) < 0
+	})
+
+	for _, n := range sorted {
+		if len(data.Notes) == r.cfg.Display.ChangesCount {
+			break
+		}
+		date := r.noteDate(n)
+		if date.IsZero() {
+			continue
+		}
+		data.Notes = append(data.Notes, NotePreview{
+			ID:      n.ID,
+			Title:   r.nodeMap[n.ID],
+			URL:     r.noteURL(n.ID),
+			Tags:    r.nodeTags[n.ID],
+			ModTime: date,
+		})
+	}
+
+	return r.renderPage("changes.html", filepath.Join(r.cfg.Paths.OutputDir, "changes.html"), data)
+}
+
 // generateTags generates tag listing pages
 func (r *Renderer) generateTags() error {
 	tagsDir := filepath.Join(r.cfg.Paths.OutputDir, "tags")
@@ -469,29 +1756,68 @@ func (r *Renderer) generateTags() error {
 		return fmt.Errorf("failed to create tags directory: %w", err)
 	}
 
-	// Group notes by tag
+	// Group notes by tag, in display.tag_sort order
+	sorted := make([]db.Node, len(r.nodes))
+	copy(sorted, r.nodes)
+	compare := newNoteCompare(r.cfg.Display.TagSort, r.noteDate)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return compare(sorted[i], sorted[j]) < 0
+	})
+
 	tagNotes := make(map[string][]NotePreview)
-	for _, n := range r.nodes {
+	var untagged []NotePreview
+	for _, n := range sorted {
 		preview := NotePreview{
-			ID:    n.ID,
-			Title: n.Title,
-			Tags:  r.nodeTags[n.ID],
+			ID:      n.ID,
+			Title:   r.nodeMap[n.ID],
+			URL:     r.noteURL(n.ID),
+			Tags:    r.nodeTags[n.ID],
+			ModTime: r.noteDate(n),
+			Excerpt: r.noteExcerpt(n),
+		}
+		if len(r.nodeTags[n.ID]) == 0 {
+			untagged = append(untagged, preview)
 		}
 		for _, tag := range r.nodeTags[n.ID] {
 			tagNotes[tag] = append(tagNotes[tag], preview)
 		}
 	}
 
-	// Generate a page for each tag
+	// Notes without tags get their own pseudo-tag page
+	if r.cfg.Display.ShowUntagged {
+		if _, ok := tagNotes[untaggedTag]; ok {
+			fmt.Printf("Warning: tag '%s' is in use, untagged notes are merged into it\n", untaggedTag)
+		}
+		tagNotes[untaggedTag] = append(tagNotes[untaggedTag], untagged...)
+	}
+
+	// Generate a page for each tag. Hierarchical tags ("a/b") go in
+	// subdirectories.
 	for tag, notes := range tagNotes {
+		if err := os.MkdirAll(filepath.Dir(filepath.Join(tagsDir, tag)), 0755); err != nil {
+			return fmt.Errorf("failed to create tags directory: %w", err)
+		}
 		data := TagPageData{
-			Site: SiteData{
-				Title:   r.cfg.Site.Title,
-				BaseURL: r.cfg.Site.BaseURL,
-			},
+			Site:  r.siteData(),
 			Tag:   tag,
 			Notes: notes,
 		}
+		if r.cfg.Display.TagBreadcrumbs {
+			crumbs, err := breadcrumbJSONLD(r.tagBreadcrumbs(tag, func(t string) bool {
+				_, ok := tagNotes[t]
+				return ok
+			}))
+			if err != nil {
+				return fmt.Errorf("failed to serialize breadcrumbs of tag %s: %w", tag, err)
+			}
+			data.Breadcrumbs = crumbs
+		}
+		if r.cfg.Display.TagFeeds {
+			data.FeedURL = r.tagFeedURL(tag)
+			if err := r.writeTagFeed(tagsDir, tag, notes); err != nil {
+				return err
+			}
+		}
 
 		outPath := filepath.Join(tagsDir, tag+".html")
 		if err := r.renderPage("tag.html", outPath, data); err != nil {
@@ -499,9 +1825,59 @@ func (r *Renderer) generateTags() error {
 		}
 	}
 
+	if r.cfg.Display.EmitTagJSON {
+		return r.generateTagJSON(tagsDir, tagNotes)
+	}
+
 	return nil
 }
 
+// generateTagJSON writes tags.json, listing every tag with its note IDs, and
+// a tags/<tag>.json per tag with the notes' details. Tags are sorted by
+// name; notes keep the order of the tag pages.
+func (r *Renderer) generateTagJSON(tagsDir string, tagNotes map[string][]NotePreview) error {
+	tags := make([]string, 0, len(tagNotes))
+	for tag := range tagNotes {
+		tags = append(tags, tag)
+	}
+	sort.Strings(tags)
+
+	index := make([]TagIndexEntry, 0, len(tags))
+	for _, tag := range tags {
+		entry := TagIndexEntry{Name: tag, Notes: make([]string, 0, len(tagNotes[tag]))}
+		notes := make([]TagNoteEntry, 0, len(tagNotes[tag]))
+		for _, n := range tagNotes[tag] {
+			entry.Notes = append(entry.Notes, n.ID)
+			noteTags := n.Tags
+			if noteTags == nil {
+				noteTags = []string{}
+			}
+			notes = append(notes, TagNoteEntry{ID: n.ID, Title: n.Title, URL: n.URL, Tags: noteTags})
+		}
+		entry.Count = len(entry.Notes)
+		index = append(index, entry)
+
+		data, err := json.MarshalIndent(struct {
+			Tag   string         `json:"tag"`
+			Notes []TagNoteEntry `json:"notes"`
+		}{tag, notes}, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to serialize tag %s: %w", tag, err)
+		}
+		if err := os.WriteFile(filepath.Join(tagsDir, tag+".json"), data, 0644); err != nil {
+			return err
+		}
+	}
+
+	data, err := json.MarshalIndent(struct {
+		Tags []TagIndexEntry `json:"tags"`
+	}{index}, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to serialize tag index: %w", err)
+	}
+	return os.WriteFile(filepath.Join(r.cfg.Paths.OutputDir, "tags.json"), data, 0644)
+}
+
 // copyImages copies images from roam directory to output
 func (r *Renderer) copyImages() error {
 	srcImgDir := filepath.Join(r.cfg.Paths.RoamDir, "img")
@@ -512,11 +1888,56 @@ func (r *Renderer) copyImages() error {
 		return nil // No images to copy
 	}
 
-	// Walk and copy all images
-	return filepath.WalkDir(srcImgDir, func(path string, d fs.DirEntry, err error) error {
+	workers := r.cfg.Display.CopyWorkers
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+
+	// Copy files on a bounded pool of workers. Directories are created by
+	// the walk itself, before any file inside them is queued.
+	type copyJob struct {
+		src, dst string
+	}
+	jobs := make(chan copyJob)
+	var (
+		wg       sync.WaitGroup
+		errOnce  sync.Once
+		firstErr error
+		failed   atomic.Bool
+	)
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for job := range jobs {
+				// Wide images are downscaled instead of copied
+				resized := false
+				var err error
+				if max := r.cfg.Display.MaxImageWidth; max > 0 && isResizable(job.src) {
+					resized, err = resizeImage(job.src, job.dst, max)
+				}
+				if !resized && err == nil {
+					err = copyFile(job.src, job.dst)
+					if err == nil {
+						err = verifyCopy(job.src, job.dst, r.cfg.Display.VerifyImageHash)
+					}
+				}
+				if err != nil {
+					errOnce.Do(func() { firstErr = err })
+					failed.Store(true)
+				}
+			}
+		}()
+	}
+
+	// Walk and queue all images
+	walkErr := filepath.WalkDir(srcImgDir, func(path string, d fs.DirEntry, err error) error {
 		if err != nil {
 			return err
 		}
+		if failed.Load() {
+			return filepath.SkipAll
+		}
 
 		// Get relative path
 		relPath, err := filepath.Rel(srcImgDir, path)
@@ -527,12 +1948,23 @@ func (r *Renderer) copyImages() error {
 		dstPath := filepath.Join(dstImgDir, relPath)
 
 		if d.IsDir() {
+			// Never copy the site's own output back into itself
+			if r.cfg.Paths.InOutputDir(path) {
+				return filepath.SkipDir
+			}
 			return os.MkdirAll(dstPath, 0755)
 		}
 
-		// Copy file
-		return copyFile(path, dstPath)
+		jobs <- copyJob{src: path, dst: dstPath}
+		return nil
 	})
+	close(jobs)
+	wg.Wait()
+
+	if walkErr != nil {
+		return walkErr
+	}
+	return firstErr
 }
 
 // copyFile copies a file from src to dst
@@ -548,24 +1980,159 @@ func copyFile(src, dst string) error {
 	}
 	defer srcFile.Close()
 
+	info, err := srcFile.Stat()
+	if err != nil {
+		return err
+	}
+
 	dstFile, err := os.Create(dst)
 	if err != nil {
 		return err
 	}
-	defer dstFile.Close()
 
-	_, err = io.Copy(dstFile, srcFile)
-	return err
+	if err := copyData(dstFile, srcFile, info.Size(), src); err != nil {
+		dstFile.Close()
+		return err
+	}
+
+	// Close explicitly so flush errors (e.g. disk full) are not lost
+	return dstFile.Close()
+}
+
+// copyData copies src, expected to hold size bytes, to dst and fails if
+// fewer bytes arrive
+func copyData(dst io.Writer, src io.Reader, size int64, name string) error {
+	n, err := io.Copy(dst, src)
+	if err != nil {
+		return fmt.Errorf("failed to copy %s: %w", name, err)
+	}
+	if n != size {
+		return fmt.Errorf("short write copying %s: wrote %d of %d bytes", name, n, size)
+	}
+	return nil
+}
+
+// verifyCopy checks that dst matches src in size and, if hash is set, in content
+func verifyCopy(src, dst string, hash bool) error {
+	srcInfo, err := os.Stat(src)
+	if err != nil {
+		return err
+	}
+	dstInfo, err := os.Stat(dst)
+	if err != nil {
+		return err
+	}
+	if srcInfo.Size() != dstInfo.Size() {
+		return fmt.Errorf("size mismatch copying %s: source %d bytes, destination %d bytes", src, srcInfo.Size(), dstInfo.Size())
+	}
+
+	if !hash {
+		return nil
+	}
+
+	srcSum, err := fileHash(src)
+	if err != nil {
+		return err
+	}
+	dstSum, err := fileHash(dst)
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(srcSum, dstSum) {
+		return fmt.Errorf("checksum mismatch copying %s", src)
+	}
+
+	return nil
+}
+
+// fileHash returns the SHA-256 digest of a file
+func fileHash(path string) ([]byte, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return nil, err
+	}
+	return h.Sum(nil), nil
+}
+
+// noteURL returns the site URL of a note page. Redirecting notes resolve to
+// their target's page, and anchored heading nodes to their place in their
+// file's page.
+func (r *Renderer) noteURL(id string) string {
+	if parent, ok := r.headingParent[id]; ok {
+		return r.noteURL(parent) + "#" + id
+	}
+	id = r.resolveRedirect(id)
+	if slug, ok := r.slugs[id]; ok {
+		if r.cfg.Display.NotesSubdir == "" {
+			return r.cfg.Site.BaseURL + "/" + slug + "/"
+		}
+		return r.cfg.Site.BaseURL + "/" + r.cfg.Display.NotesSubdir + "/" + slug + "/"
+	}
+	if r.cfg.Display.NotesSubdir == "" {
+		return r.cfg.Site.BaseURL + "/" + id + ".html"
+	}
+	return r.cfg.Site.BaseURL + "/" + r.cfg.Display.NotesSubdir + "/" + id + ".html"
 }
 
 // resolveFilePath converts the absolute file path from the database to a path
 // that works with the configured roam_dir. The database stores absolute paths
 // from the original machine, but we need to use the configured roam_dir.
 func (r *Renderer) resolveFilePath(dbPath string) string {
-	// Extract just the filename from the database path
-	filename := filepath.Base(dbPath)
-	// Resolve against the configured roam directory
-	return filepath.Join(r.cfg.Paths.RoamDir, filename)
+	return filepath.Join(r.cfg.Paths.RoamDir, r.relNotePath(dbPath))
+}
+
+// relNotePath returns the path of a note file relative to roam_dir. Paths
+// under roam_dir keep their subfolders, as do paths under the directory
+// the database's notes share when the same file exists in roam_dir (a
+// database from another machine). Anything else falls back to the bare
+// file name.
+func (r *Renderer) relNotePath(dbPath string) string {
+	if rel, ok := relWithin(r.cfg.Paths.RoamDir, dbPath); ok {
+		return rel
+	}
+	if r.dbRoot != "" {
+		if rel, ok := relWithin(r.dbRoot, dbPath); ok {
+			if _, err := os.Stat(filepath.Join(r.cfg.Paths.RoamDir, rel)); err == nil {
+				return rel
+			}
+		}
+	}
+	return filepath.Base(dbPath)
+}
+
+// relWithin returns path relative to dir when path is inside dir
+func relWithin(dir, path string) (string, bool) {
+	rel, err := filepath.Rel(dir, path)
+	if err != nil || rel == "." || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", false
+	}
+	return rel, true
+}
+
+// commonDir returns the deepest directory containing all the given files
+func commonDir(files []string) string {
+	var dir string
+	for i, f := range files {
+		d := filepath.Dir(filepath.Clean(f))
+		if i == 0 {
+			dir = d
+			continue
+		}
+		for dir != d && !strings.HasPrefix(d, dir+string(filepath.Separator)) {
+			parent := filepath.Dir(dir)
+			if parent == dir {
+				break
+			}
+			dir = parent
+		}
+	}
+	return dir
 }
 
 // fileExists checks if the org file for a node exists on disk
@@ -575,6 +2142,16 @@ func (r *Renderer) fileExists(n db.Node) bool {
 	return err == nil
 }
 
+// excludedLink reports whether l matches one of the exclude.links rules
+func excludedLink(rules []config.LinkRule, l db.Link) bool {
+	for _, rule := range rules {
+		if rule.Matches(l.Source, l.Target) {
+			return true
+		}
+	}
+	return false
+}
+
 // filterExistingFiles removes nodes whose org files don't exist on disk
 func (r *Renderer) filterExistingFiles(nodes []db.Node) []db.Node {
 	var existing []db.Node
@@ -590,43 +2167,260 @@ func (r *Renderer) filterExistingFiles(nodes []db.Node) []db.Node {
 
 // generateSearchIndex generates the search index JSON
 func (r *Renderer) generateSearchIndex() error {
-	index := search.BuildIndex(r.nodes, r.nodeTags)
-	data, err := index.ToJSON()
+	// Unchanged inputs leave the previous index in place
+	var extra []string
+	for _, n := range r.nodes {
+		extra = append(extra, "alias\x00"+n.ID+"\x00"+strings.Join(r.aliases[n.ID], "\x00"))
+		for _, ref := range r.refs[n.ID] {
+			extra = append(extra, "ref\x00"+n.ID+"\x00"+ref.String())
+		}
+		extra = append(extra, "excerpt\x00"+n.ID+"\x00"+r.noteExcerpt(n))
+	}
+	hash := r.inputHash(extra...)
+	if r.cache.fresh("search.json", hash) {
+		return nil
+	}
+
+	var index *search.SearchIndex
+	if r.searchIndex != nil {
+		index = r.searchIndex
+		r.updateSearchIndex(index)
+		index.Sort()
+	} else {
+		index = search.BuildIndex(r.nodes, r.nodeTags)
+		r.applySearchOptions(index)
+	}
+	// Slugs may have shifted with another note's title
+	index.SetURLs(r.noteURL)
+	r.searchIndex = index
+
+	toJSON := index.ToJSON
+	if r.cfg.Display.CompactSearchJSON {
+		toJSON = index.ToCompactJSON
+	}
+	data, err := toJSON()
 	if err != nil {
 		return err
 	}
 
-	return os.WriteFile(filepath.Join(r.cfg.Paths.OutputDir, "search.json"), data, 0644)
+	if err := os.WriteFile(filepath.Join(r.cfg.Paths.OutputDir, "search.json"), data, 0644); err != nil {
+		return err
+	}
+	r.cache.record("search.json", hash)
+	return nil
+}
+
+// updateSearchIndex refreshes the entries of notes in changed files, adds
+// notes missing from the index and removes notes that no longer exist.
+// Entries of other notes are kept as they are: their aliases and refs come
+// from the database, whose changes make serve rebuild in full.
+func (r *Renderer) updateSearchIndex(index *search.SearchIndex) {
+	current := make(map[string]bool)
+	for _, n := range r.nodes {
+		current[n.ID] = true
+	}
+
+	indexed := make(map[string]bool)
+	var stale []string
+	for _, e := range index.Entries {
+		indexed[e.ID] = true
+		if !current[e.ID] {
+			stale = append(stale, e.ID)
+		}
+	}
+	for _, id := range stale {
+		index.Remove(id)
+	}
+
+	var changed []db.Node
+	for _, n := range r.nodes {
+		if !indexed[n.ID] || r.fileChanged(n) {
+			changed = append(changed, n)
+		}
+	}
+	updates := search.BuildIndex(changed, r.nodeTags)
+	r.applySearchOptions(updates)
+	for _, e := range updates.Entries {
+		index.Upsert(e)
+	}
+}
+
+// fileChanged reports whether the file of n is among those UseIncremental
+// was given
+func (r *Renderer) fileChanged(n db.Node) bool {
+	return r.changedFiles[filepath.Clean(r.resolveFilePath(n.File))]
+}
+
+// reuseExcerpts takes the excerpts of notes whose files haven't changed
+// from the previous build's search index, so their files aren't read again
+func (r *Renderer) reuseExcerpts(index *search.SearchIndex) {
+	previous := make(map[string]string, len(index.Entries))
+	for _, e := range index.Entries {
+		previous[e.ID] = e.Excerpt
+	}
+	for _, n := range r.nodes {
+		if excerpt, ok := previous[n.ID]; ok && !r.fileChanged(n) {
+			r.excerpts[n.ID] = excerpt
+		}
+	}
 }
 
 // generateGraphJSON generates the full graph JSON
 func (r *Renderer) generateGraphJSON() error {
-	g := graph.BuildGraph(r.nodes, r.links, r.nodeTags)
+	// Unchanged inputs leave the previous graph in place
+	hash := r.graphInputHash()
+	if r.cache.fresh("graph.json", hash) {
+		return nil
+	}
+
+	data, err := r.globalGraph().ToJSON()
+	if err != nil {
+		return err
+	}
+
+	if err := os.WriteFile(filepath.Join(r.cfg.Paths.OutputDir, "graph.json"), data, 0644); err != nil {
+		return err
+	}
+	r.cache.record("graph.json", hash)
+	return nil
+}
+
+// globalGraph returns the site graph shared by graph.html and graph.json,
+// building it on first use
+func (r *Renderer) globalGraph() *graph.Graph {
+	if r.siteGraph == nil {
+		g := graph.BuildGraph(r.graphNodes, r.graphLinks, r.nodeTags)
+		r.limitHubs(g)
+		r.decorateGraph(g)
+		g.SetAliases(func(id string) []string { return r.aliases[id] })
+		r.siteGraph = g
+	}
+	return r.siteGraph
+}
+
+// generateComponentJSON generates graph-component.json, the graph
+// restricted to the connected component of display.graph_seed
+func (r *Renderer) generateComponentJSON() error {
+	seed := r.resolveRedirect(r.cfg.Display.GraphSeed)
+	if _, ok := r.nodeMap[seed]; !ok {
+		fmt.Printf("Warning: graph_seed %s is not a published note, skipping graph-component.json\n", seed)
+		return nil
+	}
+
+	component := graph.ComponentOf(seed, r.graphLinks)
+	var nodes []db.Node
+	for _, n := range r.graphNodes {
+		if component[n.ID] {
+			nodes = append(nodes, n)
+		}
+	}
+
+	g := graph.BuildGraph(nodes, r.graphLinks, r.nodeTags)
+	r.decorateGraph(g)
 	data, err := g.ToJSON()
 	if err != nil {
 		return err
 	}
 
-	return os.WriteFile(filepath.Join(r.cfg.Paths.OutputDir, "graph.json"), data, 0644)
+	return os.WriteFile(filepath.Join(r.cfg.Paths.OutputDir, "graph-component.json"), data, 0644)
+}
+
+// generateGEXF generates graph.gexf for Gephi
+func (r *Renderer) generateGEXF() error {
+	g := graph.BuildGraph(r.graphNodes, r.graphLinks, r.nodeTags)
+	g.SetDates(func(id string) time.Time { return r.dates[id] })
+	data, err := g.ToGEXF()
+	if err != nil {
+		return fmt.Errorf("failed to serialize GEXF: %w", err)
+	}
+
+	return os.WriteFile(filepath.Join(r.cfg.Paths.OutputDir, "graph.gexf"), data, 0644)
+}
+
+// generateGraphML generates graph.graphml for Gephi or Cytoscape
+func (r *Renderer) generateGraphML() error {
+	g := graph.BuildGraph(r.graphNodes, r.graphLinks, r.nodeTags)
+	data, err := g.ToGraphML()
+	if err != nil {
+		return fmt.Errorf("failed to serialize GraphML: %w", err)
+	}
+
+	return os.WriteFile(filepath.Join(r.cfg.Paths.OutputDir, "graph.graphml"), data, 0644)
+}
+
+// generateDOT generates graph.dot for GraphViz
+func (r *Renderer) generateDOT() error {
+	g := graph.BuildGraph(r.graphNodes, r.graphLinks, r.nodeTags)
+	return os.WriteFile(filepath.Join(r.cfg.Paths.OutputDir, "graph.dot"), g.ToDOT(), 0644)
+}
+
+// generateSyntaxCSS writes syntax.css, the colors of highlighted source
+// blocks in display.syntax_theme
+func (r *Renderer) generateSyntaxCSS() error {
+	data, err := parser.SyntaxCSS(r.cfg.Display.SyntaxTheme)
+	if err != nil {
+		return fmt.Errorf("failed to generate syntax.css: %w", err)
+	}
+
+	return os.WriteFile(filepath.Join(r.cfg.Paths.OutputDir, "syntax.css"), data, 0644)
+}
+
+// scriptJSON marks JSON for embedding in a <script> element. "<", ">" and
+// "&" are rewritten as \u escapes, which leaves the JSON equivalent but
+// means "</script>", "<!--" and "]]>" can't appear in it, whatever encoder
+// produced it.
+func scriptJSON(data []byte) template.JS {
+	var buf bytes.Buffer
+	json.HTMLEscape(&buf, data)
+	return template.JS(buf.String())
 }
 
 // renderPage renders a template to a file
 func (r *Renderer) renderPage(tmplName, outPath string, data interface{}) error {
 	// Parse template fresh each time to avoid name collisions
-	tmpl, err := parseTemplate(tmplName)
+	tmpl, err := parseTemplate(tmplName, r.cfg.Display.DateFormat)
 	if err != nil {
 		return fmt.Errorf("failed to parse template %s: %w", tmplName, err)
 	}
 
-	f, err := os.Create(outPath)
-	if err != nil {
-		return fmt.Errorf("failed to create %s: %w", outPath, err)
+	// Render into memory so a failed page leaves no truncated file behind
+	var buf bytes.Buffer
+	if err := tmpl.ExecuteTemplate(&buf, "base", data); err != nil {
+		return fmt.Errorf("failed to execute template %s for %s: %w", tmplName, pageLabel(data), err)
 	}
-	defer f.Close()
 
-	if err := tmpl.ExecuteTemplate(f, "base", data); err != nil {
-		return fmt.Errorf("failed to execute template %s: %w", tmplName, err)
+	if err := os.WriteFile(outPath, buf.Bytes(), 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", outPath, err)
 	}
 
 	return nil
 }
+
+// pageLabel describes the page rendered from data for error messages
+func pageLabel(data interface{}) string {
+	switch d := data.(type) {
+	case NoteData:
+		return fmt.Sprintf("note %s (%q)", d.ID, d.Title)
+	case TagPageData:
+		return fmt.Sprintf("tag %q", d.Tag)
+	case RedirectData:
+		return fmt.Sprintf("redirect %q", d.Title)
+	case HomeData:
+		return "home page"
+	case GraphPageData:
+		return "graph page"
+	case TimelineData:
+		return "timeline"
+	case ChangesData:
+		return "changes page"
+	case TasksData:
+		return "tasks page"
+	case MaintenanceData:
+		return "maintenance page"
+	case BibliographyData:
+		return "bibliography"
+	case SectionPageData:
+		return fmt.Sprintf("section %q", d.Folder)
+	}
+	return fmt.Sprintf("%T", data)
+}
@@ -0,0 +1,2834 @@
+package render
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"runtime"
+	"runtime/metrics"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nicehiro/org-roam-web/internal/config"
+	"github.com/nicehiro/org-roam-web/internal/db"
+	"github.com/nicehiro/org-roam-web/internal/graph"
+	"github.com/nicehiro/org-roam-web/internal/parser"
+	"github.com/nicehiro/org-roam-web/internal/search"
+)
+
+func TestUntaggedPage(t *testing.T) {
+	tests := []struct {
+		name         string
+		showUntagged bool
+		want         []string // Notes on tags/untagged.html, nil for no page
+	}{
+		{"enabled", true, []string{"c3", "e5"}},
+		{"disabled", false, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := newTestVault(t)
+			v.cfg.Display.ShowUntagged = tt.showUntagged
+			v.build()
+
+			linked := strings.Contains(v.read("graph.html"), `href="/tags/untagged.html"`)
+			if linked != tt.showUntagged {
+				t.Errorf("graph page links the untagged page: %t, want %t", linked, tt.showUntagged)
+			}
+			if tt.want == nil {
+				if v.exists("tags/untagged.html") {
+					t.Error("tags/untagged.html written with show_untagged off")
+				}
+				return
+			}
+			got := noteHrefs(v.read("tags/untagged.html"))
+			slices.Sort(got)
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("untagged page lists %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// shortWriter accepts at most limit bytes, reporting the rest as unwritten
+// the way a full disk can
+type shortWriter struct {
+	limit int
+	n     int
+}
+
+func (w *shortWriter) Write(p []byte) (int, error) {
+	n := min(len(p), w.limit-w.n)
+	w.n += n
+	return n, nil
+}
+
+func TestCopyDataSizeMismatch(t *testing.T) {
+	data := strings.Repeat("x", 4096)
+	tests := []struct {
+		name    string
+		dst     io.Writer
+		src     io.Reader
+		size    int64
+		wantErr bool
+	}{
+		{"complete", io.Discard, strings.NewReader(data), 4096, false},
+		{"short write", &shortWriter{limit: 1000}, strings.NewReader(data), 4096, true},
+		{"truncated source", io.Discard, strings.NewReader(data[:100]), 4096, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := copyData(tt.dst, tt.src, tt.size, "img/photo.png")
+			if (err != nil) != tt.wantErr {
+				t.Errorf("copyData error = %v, want error %t", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestVerifyCopy(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	src := write("src.png", "0123456789")
+
+	tests := []struct {
+		name    string
+		dst     string
+		hash    bool
+		wantErr string
+	}{
+		{"identical", "0123456789", true, ""},
+		{"truncated", "01234", false, "size mismatch"},
+		{"same size without hash", "9876543210", false, ""},
+		{"same size with hash", "9876543210", true, "checksum mismatch"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dst := write("dst.png", tt.dst)
+			err := verifyCopy(src, dst, tt.hash)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("verifyCopy: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("verifyCopy error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestGraphNodeURLs(t *testing.T) {
+	tests := []struct {
+		name        string
+		baseURL     string
+		notesSubdir string
+		urlStyle    string
+		want        map[string]string // Node ID -> url
+	}{
+		{"default", "", "notes", "id", map[string]string{"a1": "/notes/a1.html", "c3": "/notes/c3.html"}},
+		{"base url", "https://example.com/garden", "notes", "id", map[string]string{"a1": "https://example.com/garden/notes/a1.html"}},
+		{"custom subdir", "", "n", "id", map[string]string{"b2": "/n/b2.html"}},
+		{"root", "", "", "id", map[string]string{"a1": "/a1.html"}},
+		{"slug", "", "notes", "slug", map[string]string{"a1": "/notes/alpha/", "e5": "/notes/epsilon/"}},
+		{"root slug", "/garden", "", "slug", map[string]string{"b2": "/garden/beta/"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := newTestVault(t)
+			v.cfg.Site.BaseURL = tt.baseURL
+			v.cfg.Display.NotesSubdir = tt.notesSubdir
+			v.cfg.Display.URLStyle = tt.urlStyle
+			v.build()
+
+			var g graph.Graph
+			v.readJSON("graph.json", &g)
+			got := make(map[string]string)
+			for _, n := range g.Nodes {
+				got[n.ID] = n.URL
+			}
+			for id, url := range tt.want {
+				if got[id] != url {
+					t.Errorf("url of %s = %q, want %q", id, got[id], url)
+				}
+			}
+
+			// Every url leads to the page written for the note
+			for id, url := range got {
+				page := strings.TrimPrefix(strings.TrimPrefix(url, tt.baseURL), "/")
+				if strings.HasSuffix(page, "/") {
+					page += "index.html"
+				}
+				if !v.exists(page) {
+					t.Errorf("url %q of %s has no page at %s", url, id, page)
+				}
+			}
+		})
+	}
+}
+
+func TestSearchIndexQuery(t *testing.T) {
+	v := newTestVault(t)
+	v.add(testNote{ID: "go1", Title: "Go Concurrency", Tags: []string{"go"}})
+	v.add(testNote{ID: "go2", Title: "Gopher Notes"})
+	v.add(testNote{ID: "go3", Title: "Mango Season", Tags: []string{"web"}})
+	v.add(testNote{ID: "go4", Title: "Go Secrets", Tags: []string{"private"}})
+
+	r, err := NewRenderer(v.cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	index, err := r.SearchIndex()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		query string
+		tag   string
+		want  []string
+	}{
+		// Exact title word, title prefix, tag, title substring
+		{"go", "", []string{"go1", "go2", "a1", "b2", "go3"}},
+		{"go", "web", []string{"b2", "go3"}},
+		{"go concurrency", "", []string{"go1"}},
+		{"epsilon", "go", nil},
+		{"rust", "", nil},
+	}
+	for _, tt := range tests {
+		var got []string
+		for _, res := range index.Search(tt.query, tt.tag) {
+			got = append(got, res.Entry.ID)
+		}
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("Search(%q, tag %q) = %v, want %v", tt.query, tt.tag, got, tt.want)
+		}
+	}
+}
+
+func TestDuplicateIDs(t *testing.T) {
+	tests := []struct {
+		name      string
+		strict    bool
+		wantWarn  string
+		wantTitle string // Title of the a1 page, "" for no page
+	}{
+		{"keep first", false, "keeping " + fixtureRoot + "/zz-alpha-copy.org", "Alpha Copy"},
+		{"strict", true, "skipping all", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := newTestVault(t)
+			v.cfg.Display.StrictIDs = tt.strict
+			// A second file claiming a1, stored with stray whitespace
+			v.writeFile("zz-alpha-copy.org", ":PROPERTIES:\n:ID: a1\n:END:\n#+title: Alpha Copy\n\nA stale copy.\n")
+			v.exec(`INSERT INTO nodes (id, file, level, pos, title, properties) VALUES (?, ?, 0, 1, ?, '(("ID" . "a1"))')`,
+				quote("a1")+" \n", quote(fixtureRoot+"/zz-alpha-copy.org"), quote("Alpha Copy"))
+
+			out := captureStdout(t, func() { v.build() })
+			want := "Warning: duplicate ID a1 in " + fixtureRoot + "/zz-alpha-copy.org, " + fixtureRoot + "/20240101120000-alpha.org, " + tt.wantWarn
+			if !strings.Contains(out, want) {
+				t.Errorf("output %q lacks warning %q", out, want)
+			}
+
+			if tt.wantTitle == "" {
+				if v.exists("notes/a1.html") {
+					t.Error("notes/a1.html written for a duplicate ID in strict mode")
+				}
+			} else if page := v.read("notes/a1.html"); !strings.Contains(page, "<title>"+tt.wantTitle) {
+				t.Errorf("notes/a1.html is not titled %q", tt.wantTitle)
+			}
+
+			// The ID is kept once, or not at all
+			var index search.SearchIndex
+			v.readJSON("search.json", &index)
+			var g graph.Graph
+			v.readJSON("graph.json", &g)
+			count := 0
+			for _, e := range index.Entries {
+				if e.ID == "a1" {
+					count++
+				}
+			}
+			for _, n := range g.Nodes {
+				if n.ID == "a1" {
+					count++
+				}
+			}
+			wantCount := 2
+			if tt.strict {
+				wantCount = 0
+			}
+			if count != wantCount {
+				t.Errorf("a1 appears %d times in search.json and graph.json, want %d", count, wantCount)
+			}
+		})
+	}
+}
+
+func TestNoteLayout(t *testing.T) {
+	tests := []struct {
+		layout    string
+		wantIndex bool // Rendered with note-index.html
+		wantWarn  bool
+	}{
+		{"", false, false},
+		{"default", false, false},
+		{"index", true, false},
+		{" Index ", true, false},
+		{"fancy", false, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.layout, func(t *testing.T) {
+			v := newTestVault(t)
+			props := map[string]string{}
+			if tt.layout != "" {
+				props["LAYOUT"] = tt.layout
+			}
+			v.add(testNote{ID: "moc", Title: "Map", Props: props, Links: []string{"a1", "b2"}})
+
+			out := captureStdout(t, func() { v.build() })
+			page := v.read("notes/moc.html")
+			if got := strings.Contains(page, `class="index-page"`); got != tt.wantIndex {
+				t.Errorf("index layout used: %t, want %t", got, tt.wantIndex)
+			}
+			if got := strings.Contains(out, "unknown layout"); got != tt.wantWarn {
+				t.Errorf("unknown layout warning: %t, want %t (output %q)", got, tt.wantWarn, out)
+			}
+			// Either layout lists the linked notes
+			for _, id := range []string{"a1", "b2"} {
+				if !slices.Contains(noteHrefs(page), id) {
+					t.Errorf("page doesn't link to %s", id)
+				}
+			}
+		})
+	}
+}
+
+func TestBacklinkExclusion(t *testing.T) {
+	tests := []struct {
+		name        string
+		excludeTags []string
+		excludeIDs  []string
+		want        []string // Backlinks of a1
+	}{
+		{"none", nil, nil, []string{"b2", "c3", "moc"}},
+		{"hub tag", []string{"moc"}, nil, []string{"b2", "c3"}},
+		{"hub id", nil, []string{"moc", "c3"}, []string{"b2"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := newTestVault(t)
+			v.add(testNote{ID: "moc", Title: "Index", Tags: []string{"moc"}, Links: []string{"a1", "b2"}})
+			v.cfg.Display.BacklinkExcludeTags = tt.excludeTags
+			v.cfg.Display.BacklinkExcludeIDs = tt.excludeIDs
+			v.build()
+
+			got := backlinkIDs(v.read("notes/a1.html"))
+			slices.Sort(got)
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("backlinks of a1 = %v, want %v", got, tt.want)
+			}
+
+			// The graph keeps the hub's links
+			var g graph.Graph
+			v.readJSON("graph.json", &g)
+			if !slices.ContainsFunc(g.Links, func(l graph.GraphLink) bool {
+				return l.Source == "moc" && l.Target == "a1"
+			}) {
+				t.Error("graph.json lost the link from moc to a1")
+			}
+		})
+	}
+}
+
+// writeImageTree writes count files of distinct content into nested
+// folders under dir, returning their paths relative to dir
+func writeImageTree(tb testing.TB, dir string, count int) []string {
+	tb.Helper()
+	var files []string
+	for i := 0; i < count; i++ {
+		rel := filepath.Join(fmt.Sprintf("set%d", i%5), fmt.Sprintf("sub%d", i%3), fmt.Sprintf("img%03d.png", i))
+		path := filepath.Join(dir, rel)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			tb.Fatal(err)
+		}
+		data := bytes.Repeat([]byte{byte(i)}, 1024+i)
+		if err := os.WriteFile(path, data, 0644); err != nil {
+			tb.Fatal(err)
+		}
+		files = append(files, rel)
+	}
+	return files
+}
+
+func TestCopyImagesWorkers(t *testing.T) {
+	for _, workers := range []int{1, 2, 8, 0} {
+		t.Run(fmt.Sprint(workers), func(t *testing.T) {
+			v := newTestVault(t)
+			srcDir := filepath.Join(v.cfg.Paths.RoamDir, "img")
+			files := writeImageTree(t, srcDir, 60)
+			v.cfg.Display.CopyWorkers = workers
+			v.cfg.Display.VerifyImageHash = true
+			v.build()
+
+			for _, rel := range files {
+				want, err := os.ReadFile(filepath.Join(srcDir, rel))
+				if err != nil {
+					t.Fatal(err)
+				}
+				if got := v.read(filepath.Join("img", rel)); got != string(want) {
+					t.Errorf("img/%s differs from its source", rel)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkCopyImages(b *testing.B) {
+	src := b.TempDir()
+	writeImageTree(b, filepath.Join(src, "img"), 500)
+
+	for _, workers := range []int{1, 2, 4, 8} {
+		b.Run(fmt.Sprintf("workers=%d", workers), func(b *testing.B) {
+			cfg := config.DefaultConfig()
+			cfg.Paths.RoamDir = src
+			cfg.Display.CopyWorkers = workers
+			r, err := NewRenderer(cfg)
+			if err != nil {
+				b.Fatal(err)
+			}
+			for i := 0; i < b.N; i++ {
+				cfg.Paths.OutputDir = b.TempDir()
+				if err := r.copyImages(); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+var hrefRe = regexp.MustCompile(`href="(/[^"#]*)"`)
+
+func TestNotesSubdir(t *testing.T) {
+	tests := []struct {
+		subdir string
+		page   string // Page of a1
+	}{
+		{"notes", "notes/a1.html"},
+		{"pages/all", "pages/all/a1.html"},
+		{"", "a1.html"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.subdir, func(t *testing.T) {
+			v := newTestVault(t)
+			v.cfg.Display.NotesSubdir = tt.subdir
+			v.cfg.Display.Timeline = true
+			// At the root, these IDs would replace generated pages
+			v.add(testNote{ID: "index", Title: "Index Note", Links: []string{"a1"}})
+			v.add(testNote{ID: "graph", Title: "Graph Note"})
+			out := captureStdout(t, func() { v.build() })
+
+			// Every site link on the home page and a note page resolves
+			for _, name := range []string{"index.html", tt.page} {
+				for _, m := range hrefRe.FindAllStringSubmatch(v.read(name), -1) {
+					target := strings.TrimPrefix(m[1], "/")
+					if target == "" {
+						target = "index.html"
+					}
+					if strings.HasSuffix(target, ".html") && !v.exists(target) {
+						t.Errorf("%s links to missing %s", name, m[1])
+					}
+				}
+			}
+			if !strings.Contains(v.read(tt.page), `href="/`+strings.TrimSuffix(tt.page, "a1.html")+`b2.html"`) {
+				t.Errorf("%s doesn't link to b2 in the same directory", tt.page)
+			}
+
+			home := v.read("index.html")
+			graphPage := v.read("graph.html")
+			if tt.subdir == "" {
+				if !strings.Contains(home, "<title>My Notes") || !strings.Contains(graphPage, "<title>Graph") {
+					t.Error("a note page replaced index.html or graph.html")
+				}
+				if !strings.Contains(out, "index.html is reserved") || !strings.Contains(out, "graph.html is reserved") {
+					t.Errorf("no warning for the reserved note IDs in %q", out)
+				}
+			} else if !v.exists(filepath.Join(tt.subdir, "index.html")) {
+				t.Error("note index has no page under the subdir")
+			}
+		})
+	}
+}
+
+func TestMaxParseErrors(t *testing.T) {
+	tests := []struct {
+		max     int
+		wantErr bool
+	}{
+		{-1, false},
+		{3, false},
+		{2, true},
+		{0, true},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprint(tt.max), func(t *testing.T) {
+			v := newTestVault(t)
+			v.cfg.Display.MaxParseErrors = tt.max
+			v.cfg.Display.Workers = 1
+			// Notes whose file is a directory can't be read
+			for _, id := range []string{"bad1", "bad2", "bad3"} {
+				if err := os.Mkdir(filepath.Join(v.cfg.Paths.RoamDir, id+".org"), 0755); err != nil {
+					t.Fatal(err)
+				}
+				v.exec(`INSERT INTO nodes (id, file, level, pos, title) VALUES (?, ?, 0, 1, ?)`,
+					quote(id), quote(fixtureRoot+"/"+id+".org"), quote("Broken "+id))
+			}
+
+			var err error
+			out := captureStdout(t, func() { _, err = v.tryBuild() })
+			// The build stops at the first failure over the threshold
+			wantReported := 3
+			if tt.wantErr {
+				wantReported = tt.max + 1
+			}
+			if got := strings.Count(out, "Warning: failed to generate note Broken"); got != wantReported {
+				t.Errorf("%d failed notes reported, want %d", got, wantReported)
+			}
+			if !tt.wantErr {
+				if err != nil {
+					t.Fatalf("Build: %v", err)
+				}
+				if !v.exists("notes/a1.html") {
+					t.Error("notes/a1.html missing")
+				}
+				return
+			}
+			want := fmt.Sprintf("too many failed notes (%d, max %d)", tt.max+1, tt.max)
+			if err == nil || !strings.Contains(err.Error(), want) {
+				t.Errorf("Build error = %v, want %q", err, want)
+			}
+		})
+	}
+}
+
+// timelineRe matches the year headings, month headings and note links of
+// the timeline page
+var timelineRe = regexp.MustCompile(`<summary>([^<]+)<span class="year-count">(\d+) notes|<h3>(\w+)</h3>|/notes/(\w+)\.html"`)
+
+// timelineOutline flattens the timeline page into its headings and note IDs
+func timelineOutline(page string) []string {
+	var outline []string
+	main := between(page, `<main class="container timeline-page">`, "</main>")
+	for _, m := range timelineRe.FindAllStringSubmatch(main, -1) {
+		switch {
+		case m[1] != "":
+			outline = append(outline, m[1]+" ("+m[2]+")")
+		case m[3] != "":
+			outline = append(outline, m[3])
+		default:
+			outline = append(outline, m[4])
+		}
+	}
+	return outline
+}
+
+func TestTimeline(t *testing.T) {
+	tests := []struct {
+		name    string
+		exclude []string
+		notes   []testNote
+		want    []string
+	}{
+		{
+			name:    "fixture",
+			exclude: []string{"private"},
+			want:    []string{"2024 (2)", "February", "b2", "January", "a1", "2023 (1)", "March", "c3", "2021 (1)", "May", "e5"},
+		},
+		{
+			name:    "newest first within a month",
+			exclude: []string{"private"},
+			notes:   []testNote{{ID: "f6", Title: "Zeta", File: "20240115090000-zeta.org"}},
+			want:    []string{"2024 (3)", "February", "b2", "January", "f6", "a1", "2023 (1)", "March", "c3", "2021 (1)", "May", "e5"},
+		},
+		{
+			name: "excluded note shown when not excluded",
+			want: []string{"2024 (2)", "February", "b2", "January", "a1", "2023 (1)", "March", "c3", "2022 (1)", "April", "d4", "2021 (1)", "May", "e5"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := newTestVault(t)
+			v.cfg.Display.Timeline = true
+			v.cfg.Exclude.Tags = tt.exclude
+			for _, n := range tt.notes {
+				v.add(n)
+			}
+			v.build()
+
+			if got := timelineOutline(v.read("timeline.html")); !slices.Equal(got, tt.want) {
+				t.Errorf("timeline\n got %v\nwant %v", got, tt.want)
+			}
+		})
+	}
+
+	t.Run("undated", func(t *testing.T) {
+		v := newTestVault(t)
+		v.cfg.Display.Timeline = true
+		r := v.build()
+		// Every note on disk has at least an mtime, so unset a date the
+		// way an unreadable file would leave it
+		r.dates["e5"] = time.Time{}
+		if err := r.generateTimeline(); err != nil {
+			t.Fatal(err)
+		}
+
+		want := []string{"2024 (2)", "February", "b2", "January", "a1", "2023 (1)", "March", "c3", "Undated (1)", "e5"}
+		if got := timelineOutline(v.read("timeline.html")); !slices.Equal(got, want) {
+			t.Errorf("timeline\n got %v\nwant %v", got, want)
+		}
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		v := newTestVault(t)
+		v.build()
+		if v.exists("timeline.html") {
+			t.Error("timeline.html written with display.timeline off")
+		}
+		if strings.Contains(v.read("index.html"), "timeline.html") {
+			t.Error("home page links to the disabled timeline")
+		}
+	})
+}
+
+var xmlURLRe = regexp.MustCompile(`<(?:id|loc|link)>([^<]*)</|href="([^"]*)"`)
+
+func TestAbsoluteURLs(t *testing.T) {
+	tests := []struct {
+		baseURL string
+		want    string // Prefix of every feed and sitemap URL, "" for no files
+	}{
+		{"", ""},
+		{"/repo-name", ""},
+		{"https://example.com", "https://example.com/"},
+		{"https://example.com/notes", "https://example.com/notes/"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.baseURL, func(t *testing.T) {
+			v := newTestVault(t)
+			v.cfg.Site.BaseURL = tt.baseURL
+			v.cfg.Display.TagFeeds = true
+			out := captureStdout(t, func() { v.build() })
+
+			if tt.want == "" {
+				for _, name := range []string{"feed.xml", "sitemap.xml"} {
+					if v.exists(name) {
+						t.Errorf("%s written without an absolute base URL", name)
+					}
+					if !strings.Contains(out, "Warning: skipping "+name) {
+						t.Errorf("no warning for skipping %s in %q", name, out)
+					}
+				}
+				return
+			}
+
+			if strings.Contains(out, "Warning: skipping") {
+				t.Errorf("unexpected warning: %s", out)
+			}
+			for _, name := range []string{"feed.xml", "sitemap.xml", "tags/go.xml"} {
+				urls := 0
+				for _, m := range xmlURLRe.FindAllStringSubmatch(v.read(name), -1) {
+					u := m[1] + m[2]
+					if strings.HasPrefix(u, "http://www.w3.org/") || strings.HasPrefix(u, "http://www.sitemaps.org/") {
+						continue
+					}
+					urls++
+					if !strings.HasPrefix(u, tt.want) || strings.Contains(strings.TrimPrefix(u, "https://"), "//") {
+						t.Errorf("%s has URL %q, want it under %s", name, u, tt.want)
+					}
+				}
+				if urls == 0 {
+					t.Errorf("%s has no URLs", name)
+				}
+			}
+			if !strings.Contains(v.read("feed.xml"), "<id>"+tt.want+"feed.xml</id>") {
+				t.Errorf("feed id isn't %sfeed.xml", tt.want)
+			}
+		})
+	}
+}
+
+func TestRedirects(t *testing.T) {
+	redirect := func(id, target string) testNote {
+		return testNote{ID: id, Title: "Note " + id, Props: map[string]string{"REDIRECT_TO": target}}
+	}
+	tests := []struct {
+		name  string
+		notes []testNote
+		stubs map[string]string // Redirecting ID -> note the stub forwards to
+		warn  string
+	}{
+		{"redirect", []testNote{redirect("f6", "a1")}, map[string]string{"f6": "a1"}, ""},
+		{"id link", []testNote{redirect("f6", "id:a1")}, map[string]string{"f6": "a1"}, ""},
+		{
+			"chain",
+			[]testNote{redirect("f6", "g7"), redirect("g7", "a1")},
+			map[string]string{"f6": "a1", "g7": "a1"}, "",
+		},
+		{
+			"cycle",
+			[]testNote{redirect("f6", "g7"), redirect("g7", "h8"), redirect("h8", "f6")},
+			nil, "note Note f6 is in a redirect cycle",
+		},
+		{
+			"into a cycle",
+			[]testNote{redirect("i9", "f6"), redirect("f6", "g7"), redirect("g7", "f6")},
+			map[string]string{"i9": "f6"}, "note Note g7 is in a redirect cycle",
+		},
+		{"unknown target", []testNote{redirect("f6", "zz")}, nil, "note Note f6 redirects to unknown note zz"},
+		{"self", []testNote{redirect("f6", "f6")}, nil, "note Note f6 redirects to unknown note f6"},
+		{"excluded target", []testNote{redirect("f6", "d4")}, nil, "note Note f6 redirects to unknown note d4"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := newTestVault(t)
+			for _, n := range tt.notes {
+				v.add(n)
+			}
+			out := captureStdout(t, func() { v.build() })
+			if tt.warn != "" && !strings.Contains(out, tt.warn) {
+				t.Errorf("output %q lacks warning %q", out, tt.warn)
+			}
+
+			var index search.SearchIndex
+			v.readJSON("search.json", &index)
+			searched := make(map[string]bool)
+			for _, e := range index.Entries {
+				searched[e.ID] = true
+			}
+			var g graph.Graph
+			v.readJSON("graph.json", &g)
+			graphed := make(map[string]bool)
+			for _, n := range g.Nodes {
+				graphed[n.ID] = true
+			}
+
+			for _, n := range tt.notes {
+				page := v.read("notes/" + n.ID + ".html")
+				stub := strings.Contains(page, `http-equiv="refresh"`)
+				target, redirects := tt.stubs[n.ID]
+				if stub != redirects {
+					t.Errorf("%s: redirect stub = %t, want %t", n.ID, stub, redirects)
+				}
+				if redirects && !strings.Contains(page, `content="0; url=/notes/`+target+`.html"`) {
+					t.Errorf("%s: stub doesn't forward to %s", n.ID, target)
+				}
+				if searched[n.ID] == redirects || graphed[n.ID] == redirects {
+					t.Errorf("%s: in search.json %t, in graph.json %t, want %t", n.ID, searched[n.ID], graphed[n.ID], !redirects)
+				}
+			}
+		})
+	}
+}
+
+func TestIsRecent(t *testing.T) {
+	now := time.Date(2024, 2, 11, 12, 0, 0, 0, time.UTC)
+	boundary := now.AddDate(0, 0, -10)
+	tests := []struct {
+		name string
+		date time.Time
+		want bool
+	}{
+		{"at the boundary", boundary, true},
+		{"just inside", boundary.Add(time.Second), true},
+		{"just outside", boundary.Add(-time.Second), false},
+		{"a day outside", boundary.AddDate(0, 0, -1), false},
+		{"now", now, true},
+		{"future", now.AddDate(0, 0, 1), true},
+		{"undated", time.Time{}, false},
+	}
+	for _, tt := range tests {
+		if got := isRecent(tt.date, 10, now); got != tt.want {
+			t.Errorf("%s: isRecent(%v) = %t, want %t", tt.name, tt.date, got, tt.want)
+		}
+	}
+}
+
+func TestGraphRecent(t *testing.T) {
+	// b2 is dated 2024-02-01 12:00, a1 2024-01-01 12:00
+	t.Setenv("SOURCE_DATE_EPOCH", fmt.Sprint(time.Date(2024, 2, 11, 12, 0, 0, 0, time.UTC).Unix()))
+	tests := []struct {
+		days int
+		want []string
+	}{
+		{0, nil},
+		{9, nil},
+		{10, []string{"b2"}},
+		{40, []string{"b2"}},
+		{41, []string{"a1", "b2"}},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprint(tt.days), func(t *testing.T) {
+			v := newTestVault(t)
+			v.cfg.Display.RecentWindowDays = tt.days
+			v.build()
+
+			var g graph.Graph
+			v.readJSON("graph.json", &g)
+			var recent []string
+			for _, n := range g.Nodes {
+				if n.Recent {
+					recent = append(recent, n.ID)
+				}
+			}
+			slices.Sort(recent)
+			if !slices.Equal(recent, tt.want) {
+				t.Errorf("recent nodes = %v, want %v", recent, tt.want)
+			}
+		})
+	}
+}
+
+func TestNoExportExcerpts(t *testing.T) {
+	tests := []struct {
+		respect bool
+		excerpt string
+	}{
+		{true, "The public summary."},
+		{false, "Secret plans for the launch."},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprint(tt.respect), func(t *testing.T) {
+			v := newTestVault(t)
+			v.cfg.Display.RespectNoExport = tt.respect
+			v.cfg.Display.HideEmptyNotes = true
+			v.add(testNote{ID: "f6", Title: "Mixed", Body: "* Draft :noexport:\nSecret plans for the launch.\n\n* Summary\nThe public summary."})
+			v.add(testNote{ID: "g7", Title: "Only Private", Body: "#+begin_private\nPrivate aside.\n#+end_private"})
+			v.build()
+
+			var index search.SearchIndex
+			v.readJSON("search.json", &index)
+			excerpts := make(map[string]string)
+			for _, e := range index.Entries {
+				excerpts[e.ID] = e.Excerpt
+			}
+			if excerpts["f6"] != tt.excerpt {
+				t.Errorf("f6 excerpt = %q, want %q", excerpts["f6"], tt.excerpt)
+			}
+			if page := v.read("notes/f6.html"); strings.Contains(page, "Secret plans") != !tt.respect {
+				t.Errorf("f6 page shows the noexport subtree: %t", !tt.respect)
+			}
+
+			// A note with only a private block is empty once it is stripped
+			if listed := strings.Contains(v.read("index.html"), "/notes/g7.html"); listed != !tt.respect {
+				t.Errorf("g7 listed on the home page = %t, want %t", listed, !tt.respect)
+			}
+		})
+	}
+}
+
+func TestIncrementalSearchIndex(t *testing.T) {
+	v := newTestVault(t)
+	first := v.build()
+	entries := func() map[string]search.SearchEntry {
+		var index search.SearchIndex
+		v.readJSON("search.json", &index)
+		byID := make(map[string]search.SearchEntry)
+		for _, e := range index.Entries {
+			byID[e.ID] = e
+		}
+		return byID
+	}
+	before := entries()
+
+	// Edit b2, add f6 and delete c3
+	beta := filepath.Join(v.cfg.Paths.RoamDir, "20240201120000-beta.org")
+	v.writeFile("20240201120000-beta.org", ":PROPERTIES:\n:ID: b2\n:END:\n#+title: Beta Two\n\nRewritten.\n")
+	v.exec(`UPDATE nodes SET title = ? WHERE id = ?`, quote("Beta Two"), quote("b2"))
+	v.add(testNote{ID: "f6", Title: "Zeta", Body: "New note."})
+	v.exec(`DELETE FROM nodes WHERE id = ?`, quote("c3"))
+
+	r, err := NewRenderer(v.cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.UseIncremental(first.LastSearchIndex(), []string{beta, filepath.Join(v.cfg.Paths.RoamDir, "f6.org")})
+	if err := r.Build(); err != nil {
+		t.Fatal(err)
+	}
+	after := entries()
+
+	for id, e := range before {
+		switch id {
+		case "b2":
+			if after[id].Title != "Beta Two" || after[id].Excerpt != "Rewritten." {
+				t.Errorf("b2 entry not updated: %+v", after[id])
+			}
+		case "c3":
+			if _, ok := after[id]; ok {
+				t.Error("deleted note c3 left in the index")
+			}
+		default:
+			if !reflect.DeepEqual(after[id], e) {
+				t.Errorf("unchanged note %s: entry %+v, was %+v", id, after[id], e)
+			}
+		}
+	}
+	if after["f6"].Title != "Zeta" {
+		t.Errorf("added note f6 missing from the index: %+v", after["f6"])
+	}
+
+	// The result matches a full rebuild
+	v.build()
+	if full := entries(); !reflect.DeepEqual(after, full) {
+		t.Errorf("incremental index differs from a full rebuild:\n got %+v\nwant %+v", after, full)
+	}
+}
+
+func TestIncrementalExcerpts(t *testing.T) {
+	tests := []struct {
+		name    string
+		changed []string // Files passed to UseIncremental
+		want    map[string]string
+	}{
+		{
+			name:    "only changed files are read",
+			changed: []string{"20240201120000-beta.org"},
+			want:    map[string]string{"a1": "Alpha is the first note. It links to Beta.", "b2": "Beta rewritten."},
+		},
+		{
+			name:    "both changed",
+			changed: []string{"20240101120000-alpha.org", "20240201120000-beta.org"},
+			want:    map[string]string{"a1": "Alpha rewritten.", "b2": "Beta rewritten."},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := newTestVault(t)
+			first := v.build()
+
+			v.writeFile("20240101120000-alpha.org", ":PROPERTIES:\n:ID: a1\n:END:\n#+title: Alpha\n\nAlpha rewritten.\n")
+			v.writeFile("20240201120000-beta.org", ":PROPERTIES:\n:ID: b2\n:END:\n#+title: Beta\n\nBeta rewritten.\n")
+			r, err := NewRenderer(v.cfg)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var changed []string
+			for _, f := range tt.changed {
+				changed = append(changed, filepath.Join(v.cfg.Paths.RoamDir, f))
+			}
+			r.UseIncremental(first.LastSearchIndex(), changed)
+			if err := r.Build(); err != nil {
+				t.Fatal(err)
+			}
+
+			var index search.SearchIndex
+			v.readJSON("search.json", &index)
+			for _, e := range index.Entries {
+				want, ok := tt.want[e.ID]
+				if ok && e.Excerpt != want {
+					t.Errorf("excerpt of %s %q, want %q", e.ID, e.Excerpt, want)
+				}
+				if r.excerpts[e.ID] != e.Excerpt {
+					t.Errorf("excerpt of %s %q in search.json, %q on pages", e.ID, e.Excerpt, r.excerpts[e.ID])
+				}
+			}
+		})
+	}
+}
+
+var propertyRowRe = regexp.MustCompile(`<tr><th>([^<]*)</th><td>([^<]*)</td></tr>`)
+
+func TestNoteProperties(t *testing.T) {
+	tests := []struct {
+		name string
+		show []string
+		want []string // "Name=Value" rows, in order
+	}{
+		{"configured order", []string{"status", "Author"}, []string{"status=draft", "Author=Ann &amp; Bo"}},
+		{"reversed", []string{"Author", "status"}, []string{"Author=Ann &amp; Bo", "status=draft"}},
+		{"missing property", []string{"source", "status"}, []string{"status=draft"}},
+		{"escaped", []string{"quote"}, []string{"quote=&lt;em&gt;hi&lt;/em&gt;"}},
+		{"internal properties", []string{"ID", "category", "file", "status"}, []string{"status=draft"}},
+		{"none configured", nil, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := newTestVault(t)
+			v.cfg.Display.ShowProperties = tt.show
+			v.add(testNote{ID: "f6", Title: "Zeta", Props: map[string]string{
+				"STATUS":   "draft",
+				"AUTHOR":   "Ann & Bo",
+				"QUOTE":    "<em>hi</em>",
+				"CATEGORY": "zeta",
+				"SOURCE":   " ",
+			}})
+			v.build()
+
+			page := v.read("notes/f6.html")
+			var got []string
+			for _, m := range propertyRowRe.FindAllStringSubmatch(between(page, `<table class="note-properties">`, "</table>"), -1) {
+				got = append(got, m[1]+"="+m[2])
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("properties = %v, want %v", got, tt.want)
+			}
+			if tt.want == nil && strings.Contains(page, `<table class="note-properties">`) {
+				t.Error("empty properties table")
+			}
+		})
+	}
+}
+
+func TestTagJSON(t *testing.T) {
+	tests := []struct {
+		name     string
+		untagged bool
+		want     map[string]int // Tag -> note count
+	}{
+		{"fixture", false, map[string]int{"go": 3, "web": 2}},
+		{"with untagged", true, map[string]int{"go": 3, "web": 2, untaggedTag: 2}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := newTestVault(t)
+			v.cfg.Display.EmitTagJSON = true
+			v.cfg.Display.ShowUntagged = tt.untagged
+			v.add(testNote{ID: "f6", Title: "Zeta", Tags: []string{"web", "go"}})
+			v.build()
+
+			var index struct{ Tags []TagIndexEntry }
+			v.readJSON("tags.json", &index)
+			got := make(map[string]int)
+			var names []string
+			for _, tag := range index.Tags {
+				got[tag.Name] = tag.Count
+				names = append(names, tag.Name)
+				if tag.Count != len(tag.Notes) {
+					t.Errorf("%s: count %d for %d notes", tag.Name, tag.Count, len(tag.Notes))
+				}
+
+				// The same notes, in the same order, as the tag page
+				page := between(v.read("tags/"+tag.Name+".html"), `<ul class="note-list">`, "</main>")
+				var listed []string
+				for _, id := range noteHrefs(page) {
+					if len(listed) == 0 || listed[len(listed)-1] != id {
+						listed = append(listed, id)
+					}
+				}
+				if !slices.Equal(tag.Notes, listed) {
+					t.Errorf("%s: tags.json lists %v, tag page %v", tag.Name, tag.Notes, listed)
+				}
+
+				var perTag struct {
+					Tag   string
+					Notes []TagNoteEntry
+				}
+				v.readJSON("tags/"+tag.Name+".json", &perTag)
+				var ids []string
+				for _, n := range perTag.Notes {
+					ids = append(ids, n.ID)
+					if n.URL != "/notes/"+n.ID+".html" || n.Title == "" || n.Tags == nil {
+						t.Errorf("%s.json: incomplete entry %+v", tag.Name, n)
+					}
+				}
+				if perTag.Tag != tag.Name || !slices.Equal(ids, tag.Notes) {
+					t.Errorf("%s.json lists %v for tag %q, want %v", tag.Name, ids, perTag.Tag, tag.Notes)
+				}
+			}
+			if !maps.Equal(got, tt.want) {
+				t.Errorf("tag counts = %v, want %v", got, tt.want)
+			}
+			if !slices.IsSorted(names) {
+				t.Errorf("tags not sorted: %v", names)
+			}
+		})
+	}
+}
+
+func TestNestedOutputBuild(t *testing.T) {
+	tests := []struct {
+		allow   bool
+		wantErr bool
+	}{
+		{true, false},
+		{false, true},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprint(tt.allow), func(t *testing.T) {
+			v := newTestVault(t)
+			v.writeFile("img/pic.png", "png")
+			// The site is written inside the image directory it copies from
+			v.cfg.Paths.OutputDir = filepath.Join(v.cfg.Paths.RoamDir, "img", "site")
+			v.cfg.Paths.AllowNestedOutput = tt.allow
+
+			for build := 1; build <= 2; build++ {
+				_, err := v.tryBuild()
+				if tt.wantErr {
+					if err == nil || !strings.Contains(err.Error(), "allow_nested_output") {
+						t.Fatalf("build %d: error = %v, want the nesting error", build, err)
+					}
+					if v.exists("index.html") {
+						t.Error("site written despite the nesting error")
+					}
+					return
+				}
+				if err != nil {
+					t.Fatalf("build %d: %v", build, err)
+				}
+			}
+			if !v.exists("img/pic.png") {
+				t.Error("img/pic.png not copied")
+			}
+			if v.exists("img/site") {
+				t.Error("output directory copied into itself")
+			}
+		})
+	}
+}
+
+func TestGEXFOutput(t *testing.T) {
+	for _, emit := range []bool{false, true} {
+		v := newTestVault(t)
+		v.cfg.Display.EmitGEXF = emit
+		v.build()
+		if v.exists("graph.gexf") != emit {
+			t.Errorf("emit_gexf %t: graph.gexf written = %t", emit, !emit)
+			continue
+		}
+		if !emit {
+			continue
+		}
+		gexf := v.read("graph.gexf")
+		// Every published note, dated by its file name
+		for _, want := range []string{`<node id="a1" label="Alpha" start="2024-01-01">`, `<node id="c3" label="Gamma" start="2023-03-01">`, `mode="dynamic"`} {
+			if !strings.Contains(gexf, want) {
+				t.Errorf("graph.gexf lacks %s", want)
+			}
+		}
+		if strings.Contains(gexf, `id="d4"`) {
+			t.Error("graph.gexf has the excluded note d4")
+		}
+	}
+}
+
+func TestGraphComponentJSON(t *testing.T) {
+	tests := []struct {
+		seed string
+		want []string // Nodes of graph-component.json, nil for no file
+		warn string
+	}{
+		{"a1", []string{"a1", "b2", "c3"}, ""},
+		{"f6", []string{"f6", "g7"}, ""},
+		{"e5", []string{"e5"}, ""},
+		{"d4", nil, "graph_seed d4 is not a published note"},
+		{"zz", nil, "graph_seed zz is not a published note"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.seed, func(t *testing.T) {
+			v := newTestVault(t)
+			v.cfg.Display.GraphSeed = tt.seed
+			// A second component
+			v.add(testNote{ID: "f6", Title: "Zeta", Links: []string{"g7"}})
+			v.add(testNote{ID: "g7", Title: "Eta"})
+			out := captureStdout(t, func() { v.build() })
+
+			if tt.want == nil {
+				if v.exists("graph-component.json") {
+					t.Error("graph-component.json written for an unknown seed")
+				}
+				if !strings.Contains(out, tt.warn) {
+					t.Errorf("output %q lacks warning %q", out, tt.warn)
+				}
+				return
+			}
+			var g graph.Graph
+			v.readJSON("graph-component.json", &g)
+			var ids []string
+			for _, n := range g.Nodes {
+				ids = append(ids, n.ID)
+			}
+			slices.Sort(ids)
+			if !slices.Equal(ids, tt.want) {
+				t.Errorf("component nodes = %v, want %v", ids, tt.want)
+			}
+			for _, l := range g.Links {
+				if !slices.Contains(tt.want, l.Source) || !slices.Contains(tt.want, l.Target) {
+					t.Errorf("link %s-%s leaves the component", l.Source, l.Target)
+				}
+			}
+		})
+	}
+}
+
+var (
+	bannerRe  = regexp.MustCompile(`<img class="note-banner" src="([^"]*)"`)
+	ogImageRe = regexp.MustCompile(`<meta property="og:image" content="([^"]*)">`)
+)
+
+func TestNoteBanner(t *testing.T) {
+	tests := []struct {
+		name       string
+		banner     string // BANNER property
+		body       string
+		wantBanner string // Hero src, "" for no hero
+		wantOG     string
+	}{
+		{"banner", "img/hero.png", "Text.", "https://example.com/img/hero.png", "https://example.com/img/hero.png"},
+		{"banner over first image", "./img/hero.png", "[[file:img/inline.png]]", "https://example.com/img/hero.png", "https://example.com/img/hero.png"},
+		{"remote banner", "https://cdn.example.org/hero.jpg", "Text.", "https://cdn.example.org/hero.jpg", "https://cdn.example.org/hero.jpg"},
+		{"no banner", "", "[[file:img/inline.png]]", "", "https://example.com/img/inline.png"},
+		{"no banner or image", "", "Text.", "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := newTestVault(t)
+			v.cfg.Site.BaseURL = "https://example.com"
+			v.writeFile("img/hero.png", "hero")
+			v.writeFile("img/inline.png", "inline")
+			note := testNote{ID: "h1", Title: "Hero", Body: tt.body}
+			if tt.banner != "" {
+				note.Props = map[string]string{"BANNER": tt.banner}
+			}
+			v.add(note)
+			v.build()
+
+			page := v.read("notes/h1.html")
+			var banner, og string
+			if m := bannerRe.FindStringSubmatch(page); m != nil {
+				banner = m[1]
+			}
+			if m := ogImageRe.FindStringSubmatch(page); m != nil {
+				og = m[1]
+			}
+			if banner != tt.wantBanner {
+				t.Errorf("banner = %q, want %q", banner, tt.wantBanner)
+			}
+			if og != tt.wantOG {
+				t.Errorf("og:image = %q, want %q", og, tt.wantOG)
+			}
+			if !v.exists("img/hero.png") {
+				t.Error("banner image not copied")
+			}
+		})
+	}
+}
+
+func TestGraphTagColors(t *testing.T) {
+	v := newTestVault(t)
+	v.cfg.Display.TagColors = map[string]string{"web": "#123456"}
+	var colors []map[string]string
+	for range 2 {
+		v.build()
+		var g graph.Graph
+		v.readJSON("graph.json", &g)
+		colors = append(colors, g.TagColors)
+	}
+
+	want := map[string]string{"go": graph.TagColor("go", ""), "web": "#123456"}
+	for i, got := range colors {
+		if !maps.Equal(got, want) {
+			t.Errorf("build %d: tagColors = %v, want %v", i+1, got, want)
+		}
+	}
+}
+
+var statusBadgeRe = regexp.MustCompile(`<span class="status-badge ([^"]*)"( style="[^"]*")?>([^<]*)</span>`)
+
+func TestNoteStatus(t *testing.T) {
+	tests := []struct {
+		name        string
+		status      string // STATUS property
+		wantClass   string // Badge classes, "" for no badge
+		wantLabel   string
+		wantStyle   string
+		wantWarning bool
+	}{
+		{"known", "seedling", "status-seedling", "Seedling", ` style="--status-color: #7cb342"`, false},
+		{"known without label", "evergreen", "status-evergreen", "evergreen", "", false},
+		{"case-insensitive", "Seedling", "status-seedling", "Seedling", ` style="--status-color: #7cb342"`, false},
+		{"unknown", "draft", "status-draft status-unknown", "draft", "", true},
+		{"none", "", "", "", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := newTestVault(t)
+			v.cfg.Display.Statuses = []config.StatusConfig{
+				{Name: "seedling", Label: "Seedling", Color: "#7cb342"},
+				{Name: "evergreen"},
+			}
+			v.cfg.Display.GraphColorByStatus = true
+			note := testNote{ID: "s1", Title: "Status", Body: "Text."}
+			if tt.status != "" {
+				note.Props = map[string]string{"STATUS": tt.status}
+			}
+			v.add(note)
+			out := captureStdout(t, func() { v.build() })
+
+			if warned := strings.Contains(out, "unknown status"); warned != tt.wantWarning {
+				t.Errorf("warned = %t, want %t:\n%s", warned, tt.wantWarning, out)
+			}
+			m := statusBadgeRe.FindStringSubmatch(v.read("notes/s1.html"))
+			if tt.wantClass == "" {
+				if m != nil {
+					t.Errorf("unexpected badge %s", m[0])
+				}
+				return
+			}
+			if m == nil {
+				t.Fatal("no status badge")
+			}
+			if m[1] != tt.wantClass || m[2] != tt.wantStyle || m[3] != tt.wantLabel {
+				t.Errorf("badge %s, want class %q, style %q, label %q", m[0], tt.wantClass, tt.wantStyle, tt.wantLabel)
+			}
+
+			var index search.SearchIndex
+			v.readJSON("search.json", &index)
+			for _, e := range index.Entries {
+				if e.ID == "s1" && e.Status != tt.status {
+					t.Errorf("search.json status = %q, want %q", e.Status, tt.status)
+				}
+			}
+			var g graph.Graph
+			v.readJSON("graph.json", &g)
+			if g.StatusColors["seedling"] != "#7cb342" {
+				t.Errorf("graph.json statusColors = %v", g.StatusColors)
+			}
+			for _, n := range g.Nodes {
+				if n.ID == "s1" && n.Status != tt.status {
+					t.Errorf("graph.json status = %q, want %q", n.Status, tt.status)
+				}
+			}
+		})
+	}
+}
+
+func TestEmptyNotes(t *testing.T) {
+	tests := []struct {
+		name        string
+		hide        bool
+		placeholder string
+		wantText    string // Shown on the empty note's page
+	}{
+		{"placeholder", false, "This note has no content yet.", `<p class="empty-note">This note has no content yet.</p>`},
+		{"custom placeholder", false, "Coming soon", `<p class="empty-note">Coming soon</p>`},
+		{"no placeholder", false, "", ""},
+		{"hidden", true, "This note has no content yet.", `<p class="empty-note">This note has no content yet.</p>`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := newTestVault(t)
+			v.cfg.Site.BaseURL = "https://example.com"
+			v.cfg.Display.Timeline = true
+			v.cfg.Display.HideEmptyNotes = tt.hide
+			v.cfg.Display.EmptyNotePlaceholder = tt.placeholder
+			v.add(testNote{ID: "z9", Title: "Stub", Body: "  \n\t\n"})
+			v.add(testNote{ID: "l1", Title: "Linker", Body: "Text.", Links: []string{"z9"}})
+			v.build()
+
+			page := v.read("notes/z9.html")
+			if tt.wantText != "" && !strings.Contains(page, tt.wantText) {
+				t.Errorf("empty note page lacks %q", tt.wantText)
+			}
+			if tt.wantText == "" && strings.Contains(page, `class="empty-note"`) {
+				t.Error("empty note page has a placeholder")
+			}
+			if strings.Contains(v.read("notes/l1.html"), `class="empty-note"`) {
+				t.Error("note with content has a placeholder")
+			}
+
+			// Hidden notes keep their pages and the links to them
+			if !slices.Contains(noteHrefs(v.read("notes/l1.html")), "z9") {
+				t.Error("link to the empty note missing")
+			}
+			for _, name := range []string{"index.html", "timeline.html", "feed.xml"} {
+				listed := strings.Contains(v.read(name), "/notes/z9.html")
+				if listed == tt.hide {
+					t.Errorf("%s lists the empty note: %t, want %t", name, listed, !tt.hide)
+				}
+				if !strings.Contains(v.read(name), "/notes/l1.html") {
+					t.Errorf("%s doesn't list the note with content", name)
+				}
+			}
+		})
+	}
+}
+
+func TestBacklinkJSON(t *testing.T) {
+	for _, emit := range []bool{true, false} {
+		t.Run(fmt.Sprint(emit), func(t *testing.T) {
+			v := newTestVault(t)
+			v.cfg.Display.EmitBacklinkJSON = emit
+			v.build()
+
+			if !emit {
+				if v.exists("api/backlinks") {
+					t.Error("api/backlinks written while disabled")
+				}
+				return
+			}
+			for _, id := range []string{"a1", "b2", "c3", "e5"} {
+				var data struct {
+					ID        string     `json:"id"`
+					Backlinks []LinkData `json:"backlinks"`
+				}
+				v.readJSON("api/backlinks/"+id+".json", &data)
+				if data.ID != id || data.Backlinks == nil {
+					t.Errorf("%s: backlinks JSON %+v", id, data)
+				}
+
+				var ids []string
+				for _, l := range data.Backlinks {
+					ids = append(ids, l.ID)
+					if l.Title == "" || l.URL != "/notes/"+l.ID+".html" {
+						t.Errorf("%s: backlink %+v", id, l)
+					}
+				}
+				if page := backlinkIDs(v.read("notes/" + id + ".html")); !slices.Equal(ids, page) {
+					t.Errorf("%s: JSON backlinks %v, page backlinks %v", id, ids, page)
+				}
+			}
+			if v.exists("api/backlinks/d4.json") {
+				t.Error("excluded note has backlinks JSON")
+			}
+		})
+	}
+}
+
+func TestGraphHubs(t *testing.T) {
+	tests := []struct {
+		mode      string
+		wantNodes []string
+		wantHub   bool // a1 flagged as a hub
+	}{
+		{"remove", []string{"b2", "c3", "e5", "x1"}, false},
+		{"dim", []string{"a1", "b2", "c3", "e5", "x1"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.mode, func(t *testing.T) {
+			v := newTestVault(t)
+			// a1 has four links, b2 three
+			v.add(testNote{ID: "x1", Title: "Extra", Links: []string{"a1"}})
+			v.cfg.Display.GraphHideHubsOver = 3
+			v.cfg.Display.GraphHubMode = tt.mode
+			v.build()
+
+			var g graph.Graph
+			v.readJSON("graph.json", &g)
+			var ids []string
+			for _, n := range g.Nodes {
+				ids = append(ids, n.ID)
+				if n.Hub != (n.ID == "a1" && tt.wantHub) {
+					t.Errorf("%s: hub = %t", n.ID, n.Hub)
+				}
+			}
+			slices.Sort(ids)
+			if !slices.Equal(ids, tt.wantNodes) {
+				t.Errorf("graph nodes = %v, want %v", ids, tt.wantNodes)
+			}
+			for _, l := range g.Links {
+				if !slices.Contains(ids, l.Source) || !slices.Contains(ids, l.Target) {
+					t.Errorf("link %s-%s to a removed node", l.Source, l.Target)
+				}
+			}
+			if !v.exists("notes/a1.html") {
+				t.Error("hub note lost its page")
+			}
+		})
+	}
+}
+
+func TestBuildErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		setup   func(v *testVault)
+		wantErr error
+	}{
+		{"missing database", func(v *testVault) {
+			v.cfg.Paths.DBPath = filepath.Join(t.TempDir(), "missing.db")
+		}, db.ErrDBNotFound},
+		{"old schema", func(v *testVault) {
+			v.exec(`DROP TABLE links`)
+		}, db.ErrSchemaUnsupported},
+		{"unreadable note", func(v *testVault) {
+			v.cfg.Display.MaxParseErrors = 0
+			if err := os.Mkdir(filepath.Join(v.cfg.Paths.RoamDir, "bad.org"), 0755); err != nil {
+				t.Fatal(err)
+			}
+			v.exec(`INSERT INTO nodes (id, file, level, pos, title) VALUES ('"bad"', ?, 0, 1, '"Broken"')`,
+				quote(fixtureRoot+"/bad.org"))
+		}, parser.ErrParse},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := newTestVault(t)
+			tt.setup(v)
+			var err error
+			captureStdout(t, func() { _, err = v.tryBuild() })
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Build error = %v, want %v", err, tt.wantErr)
+			}
+			var pe *parser.ParseError
+			if isParse := errors.As(err, &pe); isParse != (tt.wantErr == parser.ErrParse) {
+				t.Errorf("Build error %v is a ParseError: %t", err, isParse)
+			} else if isParse && filepath.Base(pe.File) != "bad.org" {
+				t.Errorf("ParseError for %s, want bad.org", pe.File)
+			}
+		})
+	}
+}
+
+var (
+	minimapDotRe = regexp.MustCompile(`<a href="#([^"]+)" class="minimap-level-\d"`)
+	headingIDRe  = regexp.MustCompile(`<h\d id="([^"]+)"`)
+)
+
+func TestMinimap(t *testing.T) {
+	tests := []struct {
+		name     string
+		enabled  bool
+		headings int
+		want     bool
+	}{
+		{"enabled", true, 3, true},
+		{"enabled, few headings", true, 2, false},
+		{"disabled", false, 3, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := newTestVault(t)
+			v.cfg.Display.ReadingProgress = tt.enabled
+			var body strings.Builder
+			for i := range tt.headings {
+				fmt.Fprintf(&body, "* Section %d\nText.\n", i+1)
+			}
+			v.add(testNote{ID: "m1", Title: "Long", Body: body.String()})
+			v.build()
+
+			page := v.read("notes/m1.html")
+			hasNav := strings.Contains(page, `<nav class="minimap" id="minimap" aria-label="On this page" hidden>`)
+			hasBar := strings.Contains(page, `<div class="reading-progress" id="reading-progress" hidden>`)
+			hasScript := strings.Contains(page, "getElementById('minimap')")
+			if hasNav != tt.want || hasBar != tt.want || hasScript != tt.want {
+				t.Fatalf("minimap %t, progress bar %t, script %t, want %t", hasNav, hasBar, hasScript, tt.want)
+			}
+			if !tt.want {
+				return
+			}
+
+			var dots, anchors []string
+			for _, m := range minimapDotRe.FindAllStringSubmatch(page, -1) {
+				dots = append(dots, m[1])
+			}
+			for _, m := range headingIDRe.FindAllStringSubmatch(between(page, `<article class="note-main">`, "</article>"), -1) {
+				anchors = append(anchors, m[1])
+			}
+			if len(dots) != tt.headings || !slices.Equal(dots, anchors) {
+				t.Errorf("minimap links %v, heading anchors %v", dots, anchors)
+			}
+		})
+	}
+}
+
+var tagItemRe = regexp.MustCompile(`(?s)<li class="note-item">(.*?)</li>`)
+
+func TestTagPageNotes(t *testing.T) {
+	tests := []struct {
+		sort []string
+		want []string // Notes on the go tag page, in order
+	}{
+		{[]string{"date_desc"}, []string{"b2", "a1", "n1"}},
+		{[]string{"date_asc"}, []string{"n1", "a1", "b2"}},
+		{[]string{"title_asc"}, []string{"n1", "a1", "b2"}},
+		{[]string{"title_desc"}, []string{"b2", "a1", "n1"}},
+	}
+	for _, tt := range tests {
+		t.Run(strings.Join(tt.sort, ","), func(t *testing.T) {
+			v := newTestVault(t)
+			v.cfg.Display.TagSort = tt.sort
+			v.add(testNote{ID: "n1", Title: "Aardvark", File: "20200101120000-aardvark.org", Tags: []string{"go"}, Body: "Aardvarks dig."})
+			v.build()
+
+			var ids []string
+			for _, m := range tagItemRe.FindAllStringSubmatch(v.read("tags/go.html"), -1) {
+				item := m[1]
+				id := noteHrefs(item)[0]
+				ids = append(ids, id)
+				for _, want := range []string{`<span class="note-date">`, `<p class="note-excerpt">`, `class="tag">go</a>`} {
+					if !strings.Contains(item, want) {
+						t.Errorf("%s: tag page item lacks %q:\n%s", id, want, item)
+					}
+				}
+			}
+			if !slices.Equal(ids, tt.want) {
+				t.Errorf("go tag page lists %v, want %v", ids, tt.want)
+			}
+
+			item := tagItemRe.FindStringSubmatch(between(v.read("tags/web.html"), `<ul class="note-list">`, "</ul>"))
+			for _, want := range []string{"Feb 1, 2024", `class="tag">go</a>`, `class="tag">web</a>`, "Beta"} {
+				if item == nil || !strings.Contains(item[1], want) {
+					t.Errorf("web tag page item lacks %q", want)
+				}
+			}
+		})
+	}
+}
+
+func TestExcludeLinks(t *testing.T) {
+	tests := []struct {
+		rules         []string
+		wantGone      []string // "source->target" links missing from graph.json
+		wantBacklinks map[string][]string
+	}{
+		{nil, nil, map[string][]string{"a1": {"b2", "c3"}, "b2": {"a1"}, "c3": {"b2"}}},
+		{[]string{"b2->a1"}, []string{"b2->a1"}, map[string][]string{"a1": {"c3"}, "b2": {"a1"}, "c3": {"b2"}}},
+		{[]string{"a1<->b2"}, []string{"a1->b2", "b2->a1"}, map[string][]string{"a1": {"c3"}, "b2": nil, "c3": {"b2"}}},
+		{[]string{"*->a1"}, []string{"b2->a1", "c3->a1"}, map[string][]string{"a1": nil, "b2": {"a1"}, "c3": {"b2"}}},
+	}
+	for _, tt := range tests {
+		t.Run(strings.Join(tt.rules, ","), func(t *testing.T) {
+			v := newTestVault(t)
+			v.cfg.Exclude.Links = tt.rules
+			v.cfg.Display.GraphDirected = true
+			v.build()
+
+			var g graph.Graph
+			v.readJSON("graph.json", &g)
+			links := make(map[string]bool)
+			for _, l := range g.Links {
+				links[l.Source+"->"+l.Target] = true
+			}
+			for _, l := range []string{"a1->b2", "b2->a1", "b2->c3", "c3->a1"} {
+				if want := !slices.Contains(tt.wantGone, l); links[l] != want {
+					t.Errorf("graph.json has %s: %t, want %t", l, links[l], want)
+				}
+			}
+
+			for id, want := range tt.wantBacklinks {
+				page := v.read("notes/" + id + ".html")
+				if got := backlinkIDs(page); !slices.Equal(got, want) {
+					t.Errorf("%s backlinks = %v, want %v", id, got, want)
+				}
+				var local graph.Graph
+				if err := json.Unmarshal([]byte(between(page, "const graphData = ", ";\n")), &local); err != nil {
+					t.Fatalf("%s local graph: %v", id, err)
+				}
+				for _, l := range local.Links {
+					if slices.Contains(tt.wantGone, l.Source+"->"+l.Target) {
+						t.Errorf("%s local graph has excluded link %s->%s", id, l.Source, l.Target)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestFeaturedNote(t *testing.T) {
+	tests := []struct {
+		name         string
+		featuredID   string
+		property     bool // f1 has a FEATURED property
+		wantFeatured string
+		wantRecent   []string
+		wantWarning  bool
+	}{
+		{"none", "", false, "", []string{"b2", "a1", "c3", "e5", "f1"}, false},
+		{"by ID", "c3", false, "c3", []string{"b2", "a1", "e5", "f1"}, false},
+		{"by property", "", true, "f1", []string{"b2", "a1", "c3", "e5"}, false},
+		{"ID over property", "a1", true, "a1", []string{"b2", "c3", "e5", "f1"}, false},
+		{"missing ID", "zz", false, "", []string{"b2", "a1", "c3", "e5", "f1"}, true},
+		{"excluded ID falls back to property", "d4", true, "f1", []string{"b2", "a1", "c3", "e5"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := newTestVault(t)
+			v.cfg.Display.FeaturedID = tt.featuredID
+			f1 := testNote{ID: "f1", Title: "Pinned", File: "20000101120000-pinned.org", Body: "The pinned essay."}
+			if tt.property {
+				f1.Props = map[string]string{"FEATURED": "t"}
+			}
+			v.add(f1)
+			out := captureStdout(t, func() { v.build() })
+
+			if warned := strings.Contains(out, "Warning: featured note"); warned != tt.wantWarning {
+				t.Errorf("warned = %t, want %t:\n%s", warned, tt.wantWarning, out)
+			}
+			home := v.read("index.html")
+			featured := noteHrefs(between(home, `<section class="featured-section">`, "</section>"))
+			if tt.wantFeatured == "" {
+				if len(featured) != 0 {
+					t.Errorf("featured %v, want none", featured)
+				}
+			} else if !slices.Equal(featured, []string{tt.wantFeatured}) {
+				t.Errorf("featured %v, want %s", featured, tt.wantFeatured)
+			}
+			if tt.wantFeatured != "" && !strings.Contains(home, `<p class="featured-excerpt">`) {
+				t.Error("featured note has no excerpt")
+			}
+
+			recent := noteHrefs(between(home, `<section class="recent-section">`, "</section>"))
+			if !slices.Equal(recent, tt.wantRecent) {
+				t.Errorf("recent %v, want %v", recent, tt.wantRecent)
+			}
+		})
+	}
+}
+
+func TestRenderPageError(t *testing.T) {
+	tests := []struct {
+		name     string
+		existing string // Earlier content of the page, "" for none
+	}{
+		{"new page", ""},
+		{"existing page", "<p>Old</p>"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := newTestVault(t)
+			r, err := NewRenderer(v.cfg)
+			if err != nil {
+				t.Fatal(err)
+			}
+			out := filepath.Join(t.TempDir(), "page.html")
+			if tt.existing != "" {
+				if err := os.WriteFile(out, []byte(tt.existing), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			// The home page template reads fields NoteData lacks
+			data := NoteData{Site: r.siteData(), ID: "a1", Title: "Alpha"}
+			err = r.renderPage("home.html", out, data)
+			if err == nil {
+				t.Fatal("renderPage succeeded")
+			}
+			for _, want := range []string{"home.html", `note a1 ("Alpha")`, "can't evaluate field Featured"} {
+				if !strings.Contains(err.Error(), want) {
+					t.Errorf("error %q lacks %q", err, want)
+				}
+			}
+
+			got, readErr := os.ReadFile(out)
+			if tt.existing == "" {
+				if readErr == nil {
+					t.Errorf("failed page written: %q", got)
+				}
+			} else if string(got) != tt.existing {
+				t.Errorf("page = %q, want it kept as %q", got, tt.existing)
+			}
+		})
+	}
+}
+
+func TestGlobalGraphShared(t *testing.T) {
+	v := newTestVault(t)
+	r := v.build()
+
+	g := r.siteGraph
+	if g == nil {
+		t.Fatal("build left no site graph")
+	}
+	if r.globalGraph() != g {
+		t.Error("globalGraph built a second graph")
+	}
+	// Writing both outputs again reuses the same graph
+	if err := r.generateGraph(); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.generateGraphJSON(); err != nil {
+		t.Fatal(err)
+	}
+	if r.siteGraph != g {
+		t.Error("graph outputs rebuilt the site graph")
+	}
+
+	var page, file any
+	if err := json.Unmarshal([]byte(between(v.read("graph.html"), "const fullGraphData = ", ";\n")), &page); err != nil {
+		t.Fatalf("graph.html data: %v", err)
+	}
+	v.readJSON("graph.json", &file)
+	if !reflect.DeepEqual(page, file) {
+		t.Errorf("graph.html data %v differs from graph.json %v", page, file)
+	}
+
+	// A new build starts from a fresh graph
+	if err := r.Build(); err != nil {
+		t.Fatal(err)
+	}
+	if r.siteGraph == g {
+		t.Error("rebuild reused the previous build's graph")
+	}
+}
+
+func TestGlobalSearch(t *testing.T) {
+	tests := []struct {
+		name    string
+		enabled bool
+		baseURL string
+	}{
+		{"enabled", true, ""},
+		{"enabled under a path", true, "/wiki"},
+		{"disabled", false, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := newTestVault(t)
+			v.cfg.Display.GlobalSearch = tt.enabled
+			v.cfg.Site.BaseURL = tt.baseURL
+			v.build()
+
+			for _, name := range []string{"notes/a1.html", "tags/go.html", "graph.html"} {
+				page := v.read(name)
+				hasBox := strings.Contains(page, `<input type="text" class="search-input" id="header-search-input"`)
+				// The base URL is escaped for the script
+				fetch := fmt.Sprintf("fetch('%s/search.json')", strings.ReplaceAll(tt.baseURL, "/", `\/`))
+				hasIndex := strings.Contains(between(page, "getElementById('header-search-input')", "</script>"), fetch)
+				if hasBox != tt.enabled || hasIndex != tt.enabled {
+					t.Errorf("%s: search box %t, %s %t, want %t", name, hasBox, fetch, hasIndex, tt.enabled)
+				}
+			}
+		})
+	}
+}
+
+func TestGraphPreview(t *testing.T) {
+	tests := []struct {
+		max       int
+		wantNodes []string // On graph.html
+	}{
+		{0, []string{"a1", "b2", "c3", "e5"}},
+		{2, []string{"a1", "b2"}},
+		{10, []string{"a1", "b2", "c3", "e5"}},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprint(tt.max), func(t *testing.T) {
+			v := newTestVault(t)
+			v.cfg.Display.GraphPreviewNodes = tt.max
+			v.build()
+
+			page := v.read("graph.html")
+			var preview, full graph.Graph
+			if err := json.Unmarshal([]byte(between(page, "const fullGraphData = ", ";\n")), &preview); err != nil {
+				t.Fatalf("graph.html data: %v", err)
+			}
+			v.readJSON("graph.json", &full)
+
+			var ids []string
+			for _, n := range preview.Nodes {
+				ids = append(ids, n.ID)
+			}
+			slices.Sort(ids)
+			if !slices.Equal(ids, tt.wantNodes) {
+				t.Errorf("graph.html nodes = %v, want %v", ids, tt.wantNodes)
+			}
+			for _, l := range preview.Links {
+				if !slices.Contains(ids, l.Source) || !slices.Contains(ids, l.Target) {
+					t.Errorf("graph.html link %s-%s leaves the preview", l.Source, l.Target)
+				}
+			}
+			if len(full.Nodes) != 4 {
+				t.Errorf("graph.json has %d nodes, want all 4", len(full.Nodes))
+			}
+
+			previewed := len(tt.wantNodes) < len(full.Nodes)
+			if got := strings.Contains(page, "preview of 4 notes"); got != previewed {
+				t.Errorf("preview notice %t, want %t", got, previewed)
+			}
+		})
+	}
+}
+
+func TestGraphDirected(t *testing.T) {
+	tests := []struct {
+		directed  bool
+		wantLinks []string // "source-target", with "*" for bidirectional
+	}{
+		{true, []string{"a1-b2", "b2-a1", "b2-c3", "c3-a1"}},
+		{false, []string{"a1-b2*", "b2-c3", "c3-a1"}},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprint(tt.directed), func(t *testing.T) {
+			v := newTestVault(t)
+			v.cfg.Display.GraphDirected = tt.directed
+			v.build()
+
+			var g graph.Graph
+			v.readJSON("graph.json", &g)
+			if g.Directed != tt.directed {
+				t.Errorf("graph.json directed = %t, want %t", g.Directed, tt.directed)
+			}
+			var links []string
+			for _, l := range g.Links {
+				s := l.Source + "-" + l.Target
+				if l.Bidirectional {
+					s += "*"
+				}
+				links = append(links, s)
+			}
+			slices.Sort(links)
+			if !slices.Equal(links, tt.wantLinks) {
+				t.Errorf("graph.json links = %v, want %v", links, tt.wantLinks)
+			}
+			if !strings.Contains(v.read("graph.html"), fmt.Sprintf(`"directed": %t`, tt.directed)) {
+				t.Errorf("graph.html lacks directed %t", tt.directed)
+			}
+		})
+	}
+}
+
+func TestWhitespaceIDs(t *testing.T) {
+	v := newTestVault(t)
+	v.writeFile("w1.org", ":PROPERTIES:\n:ID:       w1\n:END:\n#+title: Spaced\n\nSee [[id:a1][Alpha]].\n")
+	v.exec(`INSERT INTO nodes (id, file, level, pos, title) VALUES ('"w1"' || char(10), ?, 0, 1, '"Spaced"')`,
+		quote(fixtureRoot+"/w1.org"))
+	v.exec(`INSERT INTO tags (node_id, tag) VALUES ('"w1 "', '"go"')`)
+	v.exec(`INSERT INTO links (pos, source, dest, type, properties) VALUES (1, '"w1" ', '"a1"' || char(13, 10), '"id"', '()')`)
+	v.build()
+
+	if got := backlinkIDs(v.read("notes/a1.html")); !slices.Contains(got, "w1") {
+		t.Errorf("a1 backlinks = %v, want w1 among them", got)
+	}
+	if !slices.Contains(noteHrefs(v.read("tags/go.html")), "w1") {
+		t.Error("w1 missing from its tag page")
+	}
+}
+
+var feedLinkRe = regexp.MustCompile(`<link rel="alternate" type="application/rss\+xml" title="([^"]*)" href="([^"]*)">`)
+
+func TestTagFeedLinks(t *testing.T) {
+	tests := []struct {
+		name    string
+		enabled bool
+		baseURL string
+	}{
+		{"site root", true, "https://example.com"},
+		{"under a path", true, "https://example.com/wiki"},
+		{"disabled", false, "https://example.com"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := newTestVault(t)
+			v.cfg.Site.BaseURL = tt.baseURL
+			v.cfg.Display.TagFeeds = tt.enabled
+			v.build()
+
+			page := v.read("tags/go.html")
+			discovery := feedLinkRe.FindAllStringSubmatch(page, -1)
+			follow := strings.Contains(page, `class="follow-link"`)
+			if !tt.enabled {
+				if len(discovery) != 0 || follow || v.exists("tags/go.xml") {
+					t.Error("tag feed advertised or written while disabled")
+				}
+				return
+			}
+
+			want := tt.baseURL + "/tags/go.xml"
+			if len(discovery) != 1 || discovery[0][2] != want || discovery[0][1] != "#go | "+v.cfg.Site.Title {
+				t.Errorf("tag page discovery links %q, want one to %s", discovery, want)
+			}
+			if !follow || !strings.Contains(page, `<a href="`+want+`" class="follow-link"`) {
+				t.Error("tag page lacks the follow link")
+			}
+			if !strings.Contains(v.read("tags/go.xml"), "<link>"+tt.baseURL+"/notes/a1.html</link>") {
+				t.Error("tag feed item lacks an absolute note link")
+			}
+
+			var graphFeeds []string
+			for _, m := range feedLinkRe.FindAllStringSubmatch(v.read("graph.html"), -1) {
+				graphFeeds = append(graphFeeds, m[2])
+			}
+			if wantFeeds := []string{tt.baseURL + "/tags/go.xml", tt.baseURL + "/tags/web.xml"}; !slices.Equal(graphFeeds, wantFeeds) {
+				t.Errorf("graph page discovery links %v, want %v", graphFeeds, wantFeeds)
+			}
+		})
+	}
+}
+
+// outputFiles returns the content of every file under dir but the build
+// cache, by slash-separated path
+func outputFiles(t testing.TB, dir string) map[string]string {
+	t.Helper()
+	files := make(map[string]string)
+	err := filepath.WalkDir(dir, func(path string, d os.DirEntry, err error) error {
+		if err != nil || d.IsDir() || d.Name() == buildCacheFile {
+			return err
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		rel, _ := filepath.Rel(dir, path)
+		files[filepath.ToSlash(rel)] = string(data)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return files
+}
+
+// diffOutputs returns the paths whose content differs between two builds
+func diffOutputs(a, b map[string]string) []string {
+	var diff []string
+	for path, content := range a {
+		if other, ok := b[path]; !ok || other != content {
+			diff = append(diff, path)
+		}
+	}
+	for path := range b {
+		if _, ok := a[path]; !ok {
+			diff = append(diff, path)
+		}
+	}
+	slices.Sort(diff)
+	return diff
+}
+
+func TestBatchedBuild(t *testing.T) {
+	v := newTestVault(t)
+	v.cfg.Display.EmitLLMsTxt = true
+	v.cfg.Display.EmitLLMsFullTxt = true
+	v.cfg.Display.TasksPage = true
+	for i := range 7 {
+		v.add(testNote{ID: fmt.Sprintf("n%d", i), Title: fmt.Sprintf("Note %d", i), Body: "* TODO Task\nText.", Links: []string{"a1"}})
+	}
+	v.cfg.Display.Workers = 1
+	v.build()
+	want := outputFiles(t, v.cfg.Paths.OutputDir)
+
+	tests := []struct {
+		workers, batch int
+	}{
+		{4, 0},
+		{1, 2},
+		{3, 2},
+		{2, 1},
+		{4, 100},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("workers=%d,batch=%d", tt.workers, tt.batch), func(t *testing.T) {
+			v.cfg.Paths.OutputDir = t.TempDir()
+			v.cfg.Display.Workers = tt.workers
+			v.cfg.Display.BatchSize = tt.batch
+			v.build()
+			if diff := diffOutputs(want, outputFiles(t, v.cfg.Paths.OutputDir)); len(diff) > 0 {
+				t.Errorf("output differs from the sequential build in %v", diff)
+			}
+		})
+	}
+}
+
+// peakHeap runs f and returns the most heap memory held by live and
+// unswept objects while it ran, sampled every 100µs
+func peakHeap(f func()) uint64 {
+	runtime.GC()
+	sample := []metrics.Sample{{Name: "/memory/classes/heap/objects:bytes"}}
+	var peak uint64
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		ticker := time.NewTicker(100 * time.Microsecond)
+		defer ticker.Stop()
+		for {
+			metrics.Read(sample)
+			peak = max(peak, sample[0].Value.Uint64())
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+	f()
+	close(done)
+	<-stopped
+	return peak
+}
+
+func BenchmarkBatchSize(b *testing.B) {
+	v := newTestVault(b)
+	body := strings.Repeat("Some text with a [[https://example.org][link]] and /emphasis/.\n\n", 200)
+	for i := range 300 {
+		v.add(testNote{ID: fmt.Sprintf("n%d", i), Title: fmt.Sprintf("Note %d", i), Body: body, Links: []string{"a1"}})
+	}
+	v.cfg.Display.EmitLLMsTxt = true
+
+	for _, size := range []int{0, 100, 20} {
+		b.Run(fmt.Sprintf("batch=%d", size), func(b *testing.B) {
+			v.cfg.Display.BatchSize = size
+			b.ReportAllocs()
+			var peak uint64
+			for i := 0; i < b.N; i++ {
+				v.cfg.Paths.OutputDir = b.TempDir()
+				captureStdout(b, func() {
+					peak = max(peak, peakHeap(func() { v.build() }))
+				})
+			}
+			b.ReportMetric(float64(peak), "peak-heap-B")
+		})
+	}
+}
+
+func TestSetupFileNote(t *testing.T) {
+	tests := []struct {
+		name       string
+		enabled    bool
+		wantAuthor bool
+		wantTag    bool
+	}{
+		{"enabled", true, true, true},
+		{"disabled", false, false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := newTestVault(t)
+			v.cfg.Display.SetupFiles = tt.enabled
+			v.writeFile("setup/common.org", "#+AUTHOR: Jane Doe\n#+FILETAGS: :shared:\n")
+			v.add(testNote{ID: "s1", Title: "Setup", Body: "#+SETUPFILE: setup/common.org\n\nText."})
+			captureStdout(t, func() { v.build() })
+
+			page := v.read("notes/s1.html")
+			if got := strings.Contains(page, `<span class="note-author">· Jane Doe</span>`); got != tt.wantAuthor {
+				t.Errorf("author shown = %t, want %t", got, tt.wantAuthor)
+			}
+			if got := v.exists("tags/shared.html"); got != tt.wantTag {
+				t.Errorf("tag page from the setup file written = %t, want %t", got, tt.wantTag)
+			}
+		})
+	}
+}
+
+func TestCompactSearchJSON(t *testing.T) {
+	outputs := make(map[bool]string)
+	for _, compact := range []bool{false, true} {
+		v := newTestVault(t)
+		v.cfg.Display.CompactSearchJSON = compact
+		v.build()
+		outputs[compact] = v.read("search.json")
+	}
+
+	if len(outputs[true]) >= len(outputs[false]) {
+		t.Errorf("compact search.json is %d bytes, pretty %d", len(outputs[true]), len(outputs[false]))
+	}
+	var pretty, compact search.SearchIndex
+	if err := json.Unmarshal([]byte(outputs[false]), &pretty); err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal([]byte(outputs[true]), &compact); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(pretty, compact) {
+		t.Errorf("compact search.json decodes to %+v, pretty to %+v", compact, pretty)
+	}
+	var ids []string
+	for _, e := range compact.Entries {
+		ids = append(ids, e.ID)
+	}
+	if want := []string{"a1", "b2", "e5", "c3"}; !slices.Equal(ids, want) {
+		t.Errorf("search.json entries %v, want %v", ids, want)
+	}
+}
+
+var linkTitleRe = regexp.MustCompile(`<span class="link-title">([^<]*)</span>`)
+
+func TestTitleStripPattern(t *testing.T) {
+	tests := []struct {
+		name      string
+		pattern   string
+		title     string
+		wantShown string // Title in link lists and the graph
+	}{
+		{"prefix stripped", `^zk-\d+ `, "zk-0421 Some Idea", "Some Idea"},
+		{"no match", `^zk-\d+ `, "Some Idea", "Some Idea"},
+		{"no pattern", "", "zk-0421 Some Idea", "zk-0421 Some Idea"},
+		{"title emptied kept as is", `^zk-\d+`, "zk-0421", "zk-0421"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := newTestVault(t)
+			v.cfg.Display.TitleStripPattern = tt.pattern
+			v.add(testNote{ID: "z1", Title: tt.title, Tags: []string{"go"}, Links: []string{"a1"}})
+			v.build()
+
+			var backlinks []string
+			for _, m := range linkTitleRe.FindAllStringSubmatch(v.read("notes/a1.html"), -1) {
+				backlinks = append(backlinks, m[1])
+			}
+			if !slices.Contains(backlinks, tt.wantShown) {
+				t.Errorf("link lists of a1 %q lack %q", backlinks, tt.wantShown)
+			}
+
+			var g graph.Graph
+			v.readJSON("graph.json", &g)
+			labels := make(map[string]string)
+			for _, n := range g.Nodes {
+				labels[n.ID] = n.Title
+			}
+			if labels["z1"] != tt.wantShown {
+				t.Errorf("graph label %q, want %q", labels["z1"], tt.wantShown)
+			}
+
+			if got := between(v.read("notes/z1.html"), `<h1 class="note-title">`, "</h1>"); got != tt.title {
+				t.Errorf("page header %q, want the full title %q", got, tt.title)
+			}
+		})
+	}
+}
+
+func TestTitleStripPatternInvalid(t *testing.T) {
+	v := newTestVault(t)
+	v.cfg.Display.TitleStripPattern = "(zk"
+	if _, err := NewRenderer(v.cfg); err == nil || !strings.Contains(err.Error(), "title_strip_pattern") {
+		t.Errorf("NewRenderer with an invalid pattern: err = %v", err)
+	}
+	if err := v.cfg.Validate(); err == nil {
+		t.Error("Validate accepted an invalid title_strip_pattern")
+	}
+}
+
+func TestGraphExclude(t *testing.T) {
+	tests := []struct {
+		name       string
+		tags, ids  []string
+		wantHidden []string // Notes missing from the graphs
+	}{
+		{"none", nil, nil, nil},
+		{"by tag", []string{"journal"}, nil, []string{"j1", "j2"}},
+		{"by ID", nil, []string{"j1"}, []string{"j1"}},
+		{"by tag and ID", []string{"journal"}, []string{"c3"}, []string{"c3", "j1", "j2"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := newTestVault(t)
+			v.cfg.Graph.ExcludeTags = tt.tags
+			v.cfg.Graph.ExcludeIDs = tt.ids
+			v.add(testNote{ID: "j1", Title: "2024-01-01", Tags: []string{"journal"}, Links: []string{"a1"}})
+			v.add(testNote{ID: "j2", Title: "2024-01-02", Tags: []string{"journal"}, Links: []string{"j1", "b2"}})
+			v.build()
+
+			var g graph.Graph
+			v.readJSON("graph.json", &g)
+			var shown []string
+			for _, n := range g.Nodes {
+				shown = append(shown, n.ID)
+			}
+			for _, id := range []string{"a1", "b2", "c3", "e5", "j1", "j2"} {
+				if want := !slices.Contains(tt.wantHidden, id); slices.Contains(shown, id) != want {
+					t.Errorf("graph.json has %s: %t, want %t", id, !want, want)
+				}
+			}
+			for _, l := range g.Links {
+				if slices.Contains(tt.wantHidden, l.Source) || slices.Contains(tt.wantHidden, l.Target) {
+					t.Errorf("graph.json has link %s->%s to a hidden note", l.Source, l.Target)
+				}
+			}
+
+			// Hidden notes keep their pages, search entries and backlinks
+			var index search.SearchIndex
+			v.readJSON("search.json", &index)
+			var indexed []string
+			for _, e := range index.Entries {
+				indexed = append(indexed, e.ID)
+			}
+			page := v.read("notes/a1.html")
+			for _, id := range []string{"j1", "j2"} {
+				if !v.exists("notes/" + id + ".html") {
+					t.Errorf("no page for %s", id)
+				}
+				if !slices.Contains(indexed, id) {
+					t.Errorf("search.json lacks %s", id)
+				}
+			}
+			if !slices.Contains(backlinkIDs(page), "j1") {
+				t.Errorf("a1 backlinks %v lack j1", backlinkIDs(page))
+			}
+
+			var local graph.Graph
+			if err := json.Unmarshal([]byte(between(page, "const graphData = ", ";\n")), &local); err != nil {
+				t.Fatalf("a1 local graph: %v", err)
+			}
+			for _, n := range local.Nodes {
+				if slices.Contains(tt.wantHidden, n.ID) {
+					t.Errorf("a1 local graph has hidden note %s", n.ID)
+				}
+			}
+		})
+	}
+}
+
+// enableRootOutputs turns on every optional page and file written at the
+// site root
+func enableRootOutputs(v *testVault) {
+	v.cfg.Site.BaseURL = "https://example.com"
+	d := &v.cfg.Display
+	d.Timeline = true
+	d.ChangesPage = true
+	d.TasksPage = true
+	d.Maintenance.Enabled = true
+	d.FolderSections = true
+	d.GenerateSitemap = true
+	d.FeedCount = 10
+	d.SyntaxTheme = "github"
+	d.GraphSeed = "a1"
+	d.EmitGEXF = true
+	d.EmitGraphML = true
+	d.ExportDOT = true
+	d.EmitCSV = true
+	d.EmitLLMsTxt = true
+	d.EmitBacklinkJSON = true
+	d.BrokenLinksReport = true
+	v.writeFile("refs.bib", "@book{knuth84,\n  title = {The TeXbook},\n  author = {Knuth, Donald},\n  year = {1984}\n}\n")
+	v.cfg.Paths.Bibliography = filepath.Join(v.cfg.Paths.RoamDir, "refs.bib")
+	v.add(testNote{ID: "s1", Title: "In a folder", File: "projects/s1.org", Props: map[string]string{"ROAM_REFS": "@knuth84"}})
+}
+
+func TestRootOutputsListed(t *testing.T) {
+	v := newTestVault(t)
+	enableRootOutputs(v)
+	captureStdout(t, func() { v.build() })
+
+	entries, err := os.ReadDir(v.cfg.Paths.OutputDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, e := range entries {
+		if e.Name() == "notes" {
+			continue
+		}
+		if !isRootOutput(e.Name()) {
+			t.Errorf("%s is written at the site root but missing from rootOutputs", e.Name())
+		}
+	}
+}
+
+func TestRootOutputsReserved(t *testing.T) {
+	ids := []string{"changes", "tasks", "maintenance", "bibliography", "timeline"}
+	v := newTestVault(t)
+	enableRootOutputs(v)
+	v.cfg.Display.NotesSubdir = ""
+	for _, id := range ids {
+		v.add(testNote{ID: id, Title: "Note " + id, Links: []string{"a1"}})
+	}
+	out := captureStdout(t, func() { v.build() })
+
+	for _, id := range ids {
+		if !strings.Contains(out, id+".html is reserved") {
+			t.Errorf("no warning for the reserved note ID %s", id)
+		}
+		if strings.Contains(v.read(id+".html"), `<h1 class="note-title">Note `+id) {
+			t.Errorf("note %s replaced the generated %s.html", id, id)
+		}
+	}
+}
+
+var changesItemRe = regexp.MustCompile(`<span class="note-date">([^<]*)</span>\s*<a href="/notes/(\w+)\.html"`)
+
+func TestChangesPage(t *testing.T) {
+	tests := []struct {
+		name  string
+		count int
+		want  []string // IDs, newest first
+	}{
+		{"limited", 3, []string{"n3", "n1", "n4"}},
+		{"all", 20, []string{"n3", "n1", "n4", "b2", "a1", "c3", "n2", "e5"}},
+		{"none", 0, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := newTestVault(t)
+			v.cfg.Display.ChangesPage = true
+			v.cfg.Display.ChangesCount = tt.count
+			v.add(testNote{ID: "n1", Title: "Note 1", File: "20240301000000-n1.org"})
+			v.add(testNote{ID: "n2", Title: "Note 2", File: "20230101000000-n2.org"})
+			v.add(testNote{ID: "n3", Title: "Note 3", File: "20240501000000-n3.org"})
+			// Same date as n1, so after it by title
+			v.add(testNote{ID: "n4", Title: "Note 4", File: "20240301000000-n4.org"})
+			v.build()
+
+			var ids []string
+			for _, m := range changesItemRe.FindAllStringSubmatch(v.read("changes.html"), -1) {
+				ids = append(ids, m[2])
+			}
+			if !slices.Equal(ids, tt.want) {
+				t.Errorf("changes %v, want %v", ids, tt.want)
+			}
+		})
+	}
+}
+
+func TestScriptJSON(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+	}{
+		{"script end", `{"title":"</script><script>alert(1)"}`},
+		{"comment", `{"title":"<!-- x -->"}`},
+		{"cdata end", `{"title":"]]>"}`},
+		{"ampersand", `{"title":"R&D"}`},
+		{"plain", `{"title":"Alpha","tags":["go"]}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := string(scriptJSON([]byte(tt.data)))
+			for _, bad := range []string{"</script", "<!--", "]]>", "<", ">", "&"} {
+				if strings.Contains(got, bad) {
+					t.Errorf("scriptJSON(%s) = %s, contains %q", tt.data, got, bad)
+				}
+			}
+			var want, decoded any
+			if err := json.Unmarshal([]byte(tt.data), &want); err != nil {
+				t.Fatal(err)
+			}
+			if err := json.Unmarshal([]byte(got), &decoded); err != nil {
+				t.Fatalf("scriptJSON output is not valid JSON: %v", err)
+			}
+			if !reflect.DeepEqual(decoded, want) {
+				t.Errorf("scriptJSON output decodes to %v, want %v", decoded, want)
+			}
+		})
+	}
+}
+
+func TestScriptTitleEscaped(t *testing.T) {
+	const title = "</script><script>alert(1)"
+	v := newTestVault(t)
+	v.add(testNote{ID: "x1", Title: title, Links: []string{"a1"}})
+	v.build()
+
+	for _, page := range []struct{ name, start string }{
+		{"notes/a1.html", "const graphData = "},
+		{"notes/x1.html", "const graphData = "},
+		{"graph.html", "const fullGraphData = "},
+	} {
+		html := v.read(page.name)
+		if strings.Contains(html, "<script>alert(1)") {
+			t.Errorf("%s has the title unescaped", page.name)
+		}
+		var g graph.Graph
+		if err := json.Unmarshal([]byte(between(html, page.start, ";\n")), &g); err != nil {
+			t.Fatalf("%s graph JSON: %v", page.name, err)
+		}
+		var found bool
+		for _, n := range g.Nodes {
+			found = found || n.ID == "x1" && n.Title == title
+		}
+		if !found {
+			t.Errorf("%s graph lacks x1 titled %q: %+v", page.name, title, g.Nodes)
+		}
+	}
+}
+
+func TestReproducibleBuild(t *testing.T) {
+	t.Setenv("SOURCE_DATE_EPOCH", "1735689600")
+	v := newTestVault(t)
+	enableRootOutputs(v)
+	v.cfg.Display.RecentWindowDays = 30
+	v.cfg.Display.Workers = 4
+	v.cfg.Display.ShowUntagged = true
+	for i := range 20 {
+		v.add(testNote{
+			ID:    fmt.Sprintf("r%d", i),
+			Title: fmt.Sprintf("Note %d", i%5), // Repeated titles must sort the same way
+			Tags:  []string{fmt.Sprintf("t%d", i%3), "shared"},
+			Links: []string{"a1", fmt.Sprintf("r%d", (i+1)%20)},
+		})
+	}
+
+	build := func(seed string) map[string]string {
+		v.cfg.Display.Seed = seed
+		v.cfg.Paths.OutputDir = t.TempDir()
+		captureStdout(t, func() { v.build() })
+		return outputFiles(t, v.cfg.Paths.OutputDir)
+	}
+
+	first := build("42")
+	if diff := diffOutputs(first, build("42")); len(diff) > 0 {
+		t.Errorf("files differing between builds with the same seed: %v", diff)
+	}
+	if !strings.Contains(first["graph.html"], "randomSource(d3.randomLcg(") {
+		t.Error("graph.html doesn't seed the layout")
+	}
+
+	// Another seed changes the tag colors and the layouts, nothing else
+	other := build("7")
+	for _, name := range diffOutputs(first, other) {
+		if !strings.HasSuffix(name, ".html") && name != "graph.json" && name != "graph-component.json" {
+			t.Errorf("%s changed with the seed", name)
+		}
+	}
+	if first["graph.json"] == other["graph.json"] {
+		t.Error("graph.json tag colors are the same with another seed")
+	}
+
+	unseeded := build("")
+	if strings.Contains(unseeded["graph.html"], "randomLcg") {
+		t.Error("graph.html seeds the layout without display.seed")
+	}
+}
+
+var linkCountRe = regexp.MustCompile(`<span class="link-title">([^<]*)</span><span class="link-count" title="Links here (\d+) times">\((\d+)\)</span>`)
+
+func TestDedupeBacklinks(t *testing.T) {
+	tests := []struct {
+		name       string
+		dedupe     bool
+		wantIDs    []string
+		wantCounts map[string]string // Title -> count shown
+	}{
+		{"deduped", true, []string{"a1", "x1"}, map[string]string{"Triple": "3"}},
+		{"listed per link", false, []string{"a1", "x1", "x1", "x1"}, map[string]string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := newTestVault(t)
+			v.cfg.Display.DedupeBacklinks = tt.dedupe
+			v.cfg.Display.EmitBacklinkJSON = true
+			v.add(testNote{ID: "x1", Title: "Triple", Links: []string{"b2", "b2", "b2"}})
+			v.build()
+
+			page := v.read("notes/b2.html")
+			ids := backlinkIDs(page)
+			slices.Sort(ids)
+			if !slices.Equal(ids, tt.wantIDs) {
+				t.Errorf("b2 backlinks %v, want %v", ids, tt.wantIDs)
+			}
+			counts := make(map[string]string)
+			for _, m := range linkCountRe.FindAllStringSubmatch(between(page, "<h3>Backlinks</h3>", "</section>"), -1) {
+				if m[2] != m[3] {
+					t.Errorf("count %s shown as (%s)", m[2], m[3])
+				}
+				counts[m[1]] = m[3]
+			}
+			if !maps.Equal(counts, tt.wantCounts) {
+				t.Errorf("backlink counts %v, want %v", counts, tt.wantCounts)
+			}
+
+			var api struct{ Backlinks []LinkData }
+			v.readJSON("api/backlinks/b2.json", &api)
+			if len(api.Backlinks) != len(tt.wantIDs) {
+				t.Errorf("backlinks JSON %+v, want %d entries", api.Backlinks, len(tt.wantIDs))
+			}
+		})
+	}
+}
+
+var editLinkRe = regexp.MustCompile(`<a href="([^"]*)" class="note-edit"`)
+
+func TestEditURL(t *testing.T) {
+	const tmpl = "https://github.com/me/notes/edit/main/{path}"
+	tests := []struct {
+		name     string
+		template string
+		note     testNote
+		want     string // "" for no link
+	}{
+		{
+			name:     "top level",
+			template: tmpl,
+			note:     testNote{ID: "x1", Title: "Top"},
+			want:     "https://github.com/me/notes/edit/main/x1.org",
+		},
+		{
+			name:     "subfolder",
+			template: tmpl,
+			note:     testNote{ID: "x1", Title: "Nested", File: "projects/2024/plan.org"},
+			want:     "https://github.com/me/notes/edit/main/projects/2024/plan.org",
+		},
+		{
+			name:     "escaped path",
+			template: tmpl,
+			note:     testNote{ID: "x1", Title: "Spaced", File: "daily notes/a&b #1.org"},
+			want:     "https://github.com/me/notes/edit/main/daily%20notes/a&amp;b%20%231.org",
+		},
+		{
+			name:     "path mid-template",
+			template: "https://git.example.com/{path}?mode=edit",
+			note:     testNote{ID: "x1", Title: "Query", File: "projects/plan.org"},
+			want:     "https://git.example.com/projects/plan.org?mode=edit",
+		},
+		{
+			name:     "opted out",
+			template: tmpl,
+			note:     testNote{ID: "x1", Title: "Locked", Props: map[string]string{"NO_EDIT_LINK": "t"}},
+		},
+		{
+			name:     "opt-out turned off",
+			template: tmpl,
+			note:     testNote{ID: "x1", Title: "Open", Props: map[string]string{"NO_EDIT_LINK": "nil"}},
+			want:     "https://github.com/me/notes/edit/main/x1.org",
+		},
+		{
+			name: "unset",
+			note: testNote{ID: "x1", Title: "Plain"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := newTestVault(t)
+			v.cfg.Site.EditURLTemplate = tt.template
+			v.add(tt.note)
+			v.build()
+
+			var got string
+			if m := editLinkRe.FindStringSubmatch(v.read("notes/x1.html")); m != nil {
+				got = m[1]
+			}
+			if got != tt.want {
+				t.Errorf("edit link %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+var failedNoteRe = regexp.MustCompile(`Warning: failed to generate note ([^:]*):`)
+
+func TestWorkerFailures(t *testing.T) {
+	var want string
+	for _, workers := range []int{1, 2, 8} {
+		t.Run(fmt.Sprintf("workers=%d", workers), func(t *testing.T) {
+			v := newTestVault(t)
+			v.cfg.Display.Workers = workers
+			// Notes whose file is a directory can't be read
+			for i := range 6 {
+				id := fmt.Sprintf("bad%d", i)
+				if err := os.Mkdir(filepath.Join(v.cfg.Paths.RoamDir, id+".org"), 0755); err != nil {
+					t.Fatal(err)
+				}
+				v.exec(`INSERT INTO nodes (id, file, level, pos, title) VALUES (?, ?, 0, 1, ?)`,
+					quote(id), quote(fixtureRoot+"/"+id+".org"), quote("Broken "+id))
+			}
+
+			out := captureStdout(t, func() { v.build() })
+			var failed []string
+			for _, m := range failedNoteRe.FindAllStringSubmatch(out, -1) {
+				failed = append(failed, m[1])
+			}
+			if len(failed) != 6 {
+				t.Fatalf("%d failed notes reported, want 6:\n%s", len(failed), out)
+			}
+			got := strings.Join(failed, ", ")
+			if want == "" {
+				want = got
+			} else if got != want {
+				t.Errorf("failures reported as %s, want %s as with one worker", got, want)
+			}
+			for _, id := range []string{"a1", "b2", "c3", "e5"} {
+				if !v.exists("notes/" + id + ".html") {
+					t.Errorf("notes/%s.html missing", id)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkWorkers(b *testing.B) {
+	v := newTestVault(b)
+	body := strings.Repeat("Some text with a [[https://example.org][link]], /emphasis/ and $x^2$.\n\n", 50)
+	for i := range 100 {
+		v.add(testNote{ID: fmt.Sprintf("n%d", i), Title: fmt.Sprintf("Note %d", i), Body: body, Links: []string{"a1"}})
+	}
+	var r *Renderer
+	captureStdout(b, func() { r = v.build() })
+
+	for _, workers := range []int{1, 2, 4, 8} {
+		b.Run(fmt.Sprintf("workers=%d", workers), func(b *testing.B) {
+			r.cfg.Display.Workers = workers
+			for i := 0; i < b.N; i++ {
+				if err := r.generateNotes(); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func TestSearchExcerpts(t *testing.T) {
+	body := "Über   die\nBrücke gehen wir  heute."
+	tests := []struct {
+		length int
+		want   string
+	}{
+		{160, "Über die Brücke gehen wir heute."},
+		{15, "Über die Brücke…"},
+		{12, "Über die…"},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprint(tt.length), func(t *testing.T) {
+			v := newTestVault(t)
+			v.cfg.Display.ExcerptLength = tt.length
+			v.add(testNote{ID: "x1", Title: "Brücke", Body: body})
+			v.build()
+
+			var index search.SearchIndex
+			v.readJSON("search.json", &index)
+			i := slices.IndexFunc(index.Entries, func(e search.SearchEntry) bool { return e.ID == "x1" })
+			if i < 0 {
+				t.Fatal("x1 missing from search.json")
+			}
+			if got := index.Entries[i].Excerpt; got != tt.want {
+				t.Errorf("excerpt %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNoteExcerptOnce(t *testing.T) {
+	v := newTestVault(t)
+	v.add(testNote{ID: "x1", Title: "Brief", Body: "First words."})
+	r := v.build()
+
+	var index search.SearchIndex
+	v.readJSON("search.json", &index)
+	if len(r.excerpts) != len(r.nodes) {
+		t.Errorf("%d excerpts for %d notes", len(r.excerpts), len(r.nodes))
+	}
+	for _, e := range index.Entries {
+		if want := r.excerpts[e.ID]; e.Excerpt != want {
+			t.Errorf("search.json excerpt of %s %q, computed %q", e.ID, e.Excerpt, want)
+		}
+	}
+
+	// Later uses within the build don't read the file again
+	v.writeFile("x1.org", ":PROPERTIES:\n:ID: x1\n:END:\n#+title: Brief\n\nOther words.\n")
+	i := slices.IndexFunc(r.nodes, func(n db.Node) bool { return n.ID == "x1" })
+	if got := r.noteExcerpt(r.nodes[i]); got != "First words." {
+		t.Errorf("excerpt after the file changed %q, want the first one", got)
+	}
+	if got := v.build().noteExcerpt(r.nodes[i]); got != "Other words." {
+		t.Errorf("excerpt in the next build %q, want the new one", got)
+	}
+}
+
+func TestRendererOrphans(t *testing.T) {
+	v := newTestVault(t)
+	v.add(testNote{ID: "x1", Title: "Lonely"})
+	v.add(testNote{ID: "x2", Title: "Hidden", Tags: []string{"private"}})
+	// Only linked to excluded notes, so an orphan once they're filtered out
+	v.add(testNote{ID: "x3", Title: "Abandoned", Links: []string{"d4", "x2"}})
+	r := v.build()
+
+	var got []string
+	for _, n := range r.Orphans() {
+		got = append(got, n.ID)
+	}
+	if want := []string{"x3", "e5", "x1"}; !slices.Equal(got, want) {
+		t.Errorf("orphans %v, want %v", got, want)
+	}
+}
+
+func TestNoteLayoutParts(t *testing.T) {
+	// What every note layout renders, by feature
+	want := map[string]string{
+		"banner":      `<img class="note-banner" src="/img/hero.png"`,
+		"date":        `<span class="note-date">`,
+		"edit link":   `<a href="https://example.org/edit/l1.org" class="note-edit"`,
+		"status":      `class="status-badge status-seedling status-unknown"`,
+		"properties":  `<tr><th>SOURCE</th><td>Interview</td></tr>`,
+		"references":  `<a href="https://example.org/paper" class="external-link"`,
+		"series":      `<nav class="series-nav">`,
+		"minimap":     `<nav class="minimap" id="minimap"`,
+		"mermaid":     `import mermaid from`,
+		"shared CSS":  `.status-badge {`,
+		"note styles": `.note-references {`,
+	}
+	for _, layout := range []string{"default", "index"} {
+		t.Run(layout, func(t *testing.T) {
+			v := newTestVault(t)
+			v.cfg.Site.EditURLTemplate = "https://example.org/edit/{path}"
+			v.cfg.Display.ShowProperties = []string{"SOURCE"}
+			v.cfg.Display.ReadingProgress = true
+			v.writeFile("img/hero.png", "hero")
+			v.add(testNote{
+				ID:    "l1",
+				Title: "Layout",
+				Props: map[string]string{
+					"LAYOUT": layout, "BANNER": "img/hero.png", "STATUS": "seedling",
+					"SOURCE": "Interview", "SERIES": "Tour", "SERIES_INDEX": "1",
+				},
+				Body:  "* One\nText.\n* Two\nText.\n* Three\n#+begin_src mermaid\ngraph TD; A-->B\n#+end_src\n",
+				Links: []string{"a1"},
+			})
+			v.add(testNote{ID: "l2", Title: "Next", Props: map[string]string{"SERIES": "Tour", "SERIES_INDEX": "2"}})
+			v.addRef("l1", "//example.org/paper", "https")
+			v.add(testNote{ID: "l3", Title: "Blank", Props: map[string]string{"LAYOUT": layout}})
+			captureStdout(t, func() { v.build() })
+
+			page := v.read("notes/l1.html")
+			if got := strings.Contains(page, `class="index-page"`); got != (layout == "index") {
+				t.Errorf("index layout used: %t", got)
+			}
+			for feature, s := range want {
+				if !strings.Contains(page, s) {
+					t.Errorf("%s missing: no %s", feature, s)
+				}
+			}
+			if blank := v.read("notes/l3.html"); !strings.Contains(blank, `<p class="empty-note">`) {
+				t.Error("empty note placeholder missing")
+			}
+		})
+	}
+}
+
+func TestSyntaxTheme(t *testing.T) {
+	tests := []struct {
+		theme   string
+		wantCSS bool
+		wantErr bool
+	}{
+		{"", false, false},
+		{"github", true, false},
+		{"nosuchtheme", false, true},
+	}
+	for _, tt := range tests {
+		t.Run(quote(tt.theme), func(t *testing.T) {
+			v := newTestVault(t)
+			v.cfg.Display.SyntaxTheme = tt.theme
+			v.add(testNote{ID: "s1", Title: "Code", Body: "#+begin_src go\nfunc main() {}\n#+end_src"})
+			_, err := v.tryBuild()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Build error = %v, want error %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+
+			if v.exists("syntax.css") != tt.wantCSS {
+				t.Errorf("syntax.css written: %v, want %v", !tt.wantCSS, tt.wantCSS)
+			}
+			page := v.read("notes/s1.html")
+			if linked := strings.Contains(page, `href="/syntax.css"`); linked != tt.wantCSS {
+				t.Errorf("syntax.css linked: %v, want %v", linked, tt.wantCSS)
+			}
+			if highlighted := strings.Contains(page, `class="chroma"`); highlighted != tt.wantCSS {
+				t.Errorf("source highlighted: %v, want %v", highlighted, tt.wantCSS)
+			}
+			if !tt.wantCSS {
+				return
+			}
+			want, err := parser.SyntaxCSS(tt.theme)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := v.read("syntax.css"); got != string(want) {
+				t.Errorf("syntax.css doesn't hold the %s theme", tt.theme)
+			}
+		})
+	}
+}
@@ -0,0 +1,120 @@
+package render
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/nicehiro/org-roam-web/internal/db"
+)
+
+// SectionLink links to the page of a folder section
+type SectionLink struct {
+	Name string // Last element of the folder path
+	URL  string
+}
+
+// SectionPageData holds data for rendering a folder section page
+type SectionPageData struct {
+	Site        SiteData
+	Folder      string // Slash-separated path relative to roam_dir
+	Name        string // Last element of Folder
+	Parents     []SectionLink
+	Subsections []SectionLink
+	Notes       []NotePreview // Notes anywhere under the folder
+}
+
+// noteFolder returns the slash-separated folder of a note relative to
+// roam_dir, or "" for notes at its root
+func (r *Renderer) noteFolder(n db.Node) string {
+	dir := path.Dir(filepath.ToSlash(r.relNotePath(n.File)))
+	if dir == "." {
+		return ""
+	}
+	return dir
+}
+
+// sectionURL returns the URL of a folder's section page
+func (r *Renderer) sectionURL(folder string) string {
+	return r.cfg.Site.BaseURL + "/sections/" + folder + ".html"
+}
+
+// sectionLink returns the link to a folder's section page
+func (r *Renderer) sectionLink(folder string) SectionLink {
+	return SectionLink{Name: path.Base(folder), URL: r.sectionURL(folder)}
+}
+
+// folderNotes maps each folder to the notes in it or its subfolders, in
+// home page order. Notes at the root of roam_dir belong to no folder.
+func (r *Renderer) folderNotes() map[string][]db.Node {
+	folders := make(map[string][]db.Node)
+	for _, n := range r.sortedNodes() {
+		for dir := r.noteFolder(n); dir != "" && dir != "."; dir = path.Dir(dir) {
+			folders[dir] = append(folders[dir], n)
+		}
+	}
+	return folders
+}
+
+// topSections returns the links to the top-level folders, sorted by name
+func (r *Renderer) topSections() []SectionLink {
+	var links []SectionLink
+	for folder := range r.folderNotes() {
+		if !strings.Contains(folder, "/") {
+			links = append(links, r.sectionLink(folder))
+		}
+	}
+	sort.Slice(links, func(i, j int) bool { return links[i].Name < links[j].Name })
+	return links
+}
+
+// generateSections writes sections/<folder>.html for every folder holding
+// published notes
+func (r *Renderer) generateSections() error {
+	sectionsDir := filepath.Join(r.cfg.Paths.OutputDir, "sections")
+	folders := r.folderNotes()
+
+	children := make(map[string][]string)
+	for folder := range folders {
+		if parent := path.Dir(folder); parent != "." {
+			children[parent] = append(children[parent], folder)
+		}
+	}
+
+	for folder, nodes := range folders {
+		data := SectionPageData{
+			Site:   r.siteData(),
+			Folder: folder,
+			Name:   path.Base(folder),
+		}
+		for dir := path.Dir(folder); dir != "."; dir = path.Dir(dir) {
+			data.Parents = append([]SectionLink{r.sectionLink(dir)}, data.Parents...)
+		}
+		sort.Strings(children[folder])
+		for _, child := range children[folder] {
+			data.Subsections = append(data.Subsections, r.sectionLink(child))
+		}
+		for _, n := range nodes {
+			data.Notes = append(data.Notes, NotePreview{
+				ID:      n.ID,
+				Title:   r.nodeMap[n.ID],
+				URL:     r.noteURL(n.ID),
+				Tags:    r.nodeTags[n.ID],
+				ModTime: r.noteDate(n),
+				Excerpt: r.noteExcerpt(n),
+			})
+		}
+
+		outPath := filepath.Join(sectionsDir, filepath.FromSlash(folder)+".html")
+		if err := os.MkdirAll(filepath.Dir(outPath), 0755); err != nil {
+			return fmt.Errorf("failed to create sections directory: %w", err)
+		}
+		if err := r.renderPage("section.html", outPath, data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,88 @@
+package render
+
+import (
+	"regexp"
+	"slices"
+	"testing"
+)
+
+var (
+	sectionNavRe = regexp.MustCompile(`href="/sections/([^"]+)\.html"`)
+	subsectionRe = regexp.MustCompile(`<a href="/sections/([^"]+)\.html">[^<]*/</a>`)
+)
+
+// sectionMatches returns the first group of every match of re in page
+func sectionMatches(re *regexp.Regexp, page string) []string {
+	var got []string
+	for _, m := range re.FindAllStringSubmatch(page, -1) {
+		got = append(got, m[1])
+	}
+	return got
+}
+
+func TestFolderSections(t *testing.T) {
+	v := newTestVault(t)
+	v.cfg.Display.FolderSections = true
+	v.add(testNote{ID: "p1", Title: "Project One", File: "projects/p1.org"})
+	v.add(testNote{ID: "p2", Title: "Project Two", File: "projects/web/p2.org"})
+	v.add(testNote{ID: "p3", Title: "Project Three", File: "projects/web/api/p3.org"})
+	v.add(testNote{ID: "r1", Title: "Area One", File: "areas/r1.org"})
+	v.add(testNote{ID: "x1", Title: "Excluded", File: "areas/x1.org", Tags: []string{"private"}})
+	v.build()
+
+	tests := []struct {
+		folder      string
+		wantNotes   []string // Sorted
+		wantSubs    []string
+		wantParents []string
+	}{
+		{"projects", []string{"p1", "p2", "p3"}, []string{"projects/web"}, nil},
+		{"projects/web", []string{"p2", "p3"}, []string{"projects/web/api"}, []string{"projects"}},
+		{"projects/web/api", []string{"p3"}, nil, []string{"projects", "projects/web"}},
+		{"areas", []string{"r1"}, nil, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.folder, func(t *testing.T) {
+			page := v.read("sections/" + tt.folder + ".html")
+			main := between(page, `<ul class="note-list">`, "</ul>")
+			notes := noteHrefs(main)
+			slices.Sort(notes)
+			if !slices.Equal(notes, tt.wantNotes) {
+				t.Errorf("notes %v, want %v", notes, tt.wantNotes)
+			}
+			if subs := sectionMatches(subsectionRe, page); !slices.Equal(subs, tt.wantSubs) {
+				t.Errorf("subsections %v, want %v", subs, tt.wantSubs)
+			}
+			header := between(page, `<h1 class="section-title">`, "</h1>")
+			if parents := sectionMatches(sectionNavRe, header); !slices.Equal(parents, tt.wantParents) {
+				t.Errorf("parents %v, want %v", parents, tt.wantParents)
+			}
+		})
+	}
+
+	// Root notes belong to no section, and only top-level folders are in
+	// the nav
+	if v.exists("sections/.html") {
+		t.Error("section page written for notes at the root of roam_dir")
+	}
+	nav := sectionMatches(sectionNavRe, between(v.read("index.html"), `<nav class="nav-links">`, "</nav>"))
+	if want := []string{"areas", "projects"}; !slices.Equal(nav, want) {
+		t.Errorf("nav sections %v, want %v", nav, want)
+	}
+	if !v.exists("notes/p3.html") {
+		t.Error("nested note page not written")
+	}
+}
+
+func TestFolderSectionsDisabled(t *testing.T) {
+	v := newTestVault(t)
+	v.add(testNote{ID: "p1", Title: "Project One", File: "projects/p1.org"})
+	v.build()
+
+	if v.exists("sections") {
+		t.Error("sections written with folder_sections disabled")
+	}
+	if !v.exists("notes/p1.html") {
+		t.Error("note in a subfolder not rendered")
+	}
+}
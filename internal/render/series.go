@@ -0,0 +1,81 @@
+package render
+
+import (
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/nicehiro/org-roam-web/internal/db"
+)
+
+// SeriesNav links a note to the neighboring parts of its series
+type SeriesNav struct {
+	Name  string
+	Index int // 1-based position of the note in the series
+	Total int
+	Prev  *LinkData
+	Next  *LinkData
+	Up    *LinkData // First part of the series; unset on the first part
+}
+
+// buildSeries groups notes by their SERIES property. Parts are ordered by
+// SERIES_INDEX; parts without a valid index follow the indexed ones in
+// date order.
+func (r *Renderer) buildSeries() map[string][]db.Node {
+	series := make(map[string][]db.Node)
+	for _, n := range r.nodes {
+		if name := strings.TrimSpace(n.Properties["SERIES"]); name != "" {
+			series[name] = append(series[name], n)
+		}
+	}
+
+	byDate := newNoteCompare([]string{"date_asc", "title_asc"}, r.noteDate)
+	for _, parts := range series {
+		slices.SortStableFunc(parts, func(a, b db.Node) int {
+			ai, aok := seriesIndex(a)
+			bi, bok := seriesIndex(b)
+			switch {
+			case aok && bok && ai != bi:
+				return ai - bi
+			case aok != bok:
+				return boolCompare(!aok, !bok)
+			}
+			return byDate(a, b)
+		})
+	}
+	return series
+}
+
+// seriesIndex returns a note's SERIES_INDEX, if it has a valid one
+func seriesIndex(n db.Node) (int, bool) {
+	i, err := strconv.Atoi(strings.TrimSpace(n.Properties["SERIES_INDEX"]))
+	return i, err == nil
+}
+
+// seriesNav returns the series navigation for a note, or nil when the note
+// isn't part of a series with more than one part
+func (r *Renderer) seriesNav(n db.Node) *SeriesNav {
+	name := strings.TrimSpace(n.Properties["SERIES"])
+	parts := r.series[name]
+	if name == "" || len(parts) < 2 {
+		return nil
+	}
+
+	pos := slices.IndexFunc(parts, func(p db.Node) bool { return p.ID == n.ID })
+	if pos < 0 {
+		return nil
+	}
+
+	link := func(p db.Node) *LinkData {
+		return &LinkData{ID: p.ID, Title: r.nodeMap[p.ID], URL: r.noteURL(p.ID)}
+	}
+	nav := &SeriesNav{Name: name, Index: pos + 1, Total: len(parts)}
+	if pos > 0 {
+		nav.Prev = link(parts[pos-1])
+		nav.Up = link(parts[0])
+	}
+	if pos < len(parts)-1 {
+		nav.Next = link(parts[pos+1])
+	}
+	return nav
+}
@@ -0,0 +1,100 @@
+package render
+
+import (
+	"fmt"
+	"regexp"
+	"testing"
+)
+
+var seriesPosRe = regexp.MustCompile(`· (\d+/\d+)</span>`)
+
+// seriesLinks describes the series navigation of a note page as
+// "prev up next position", with "-" for a missing link, or "" when the
+// page has none
+func seriesLinks(page string) string {
+	nav := between(page, `<nav class="series-nav">`, "</nav>")
+	if nav == "" {
+		return ""
+	}
+	id := func(class string) string {
+		if ids := noteHrefs(between(nav, `<span class="`+class+`">`, "</span>")); len(ids) > 0 {
+			return ids[0]
+		}
+		return "-"
+	}
+	pos := "?"
+	if m := seriesPosRe.FindStringSubmatch(nav); m != nil {
+		pos = m[1]
+	}
+	return fmt.Sprintf("%s %s %s %s", id("series-prev"), id("series-up"), id("series-next"), pos)
+}
+
+func TestSeriesNav(t *testing.T) {
+	part := func(id, file, series, index string) testNote {
+		props := map[string]string{"SERIES": series}
+		if index != "" {
+			props["SERIES_INDEX"] = index
+		}
+		return testNote{ID: id, Title: "Part " + id, File: file, Props: props}
+	}
+	tests := []struct {
+		name  string
+		notes []testNote
+		want  map[string]string // ID -> seriesLinks of its page
+	}{
+		{
+			"three parts",
+			[]testNote{
+				// Dated against index order, which wins
+				part("p3", "20240101000000-p3.org", "Tour", "3"),
+				part("p1", "20240301000000-p1.org", "Tour", "1"),
+				part("p2", "20240201000000-p2.org", "Tour", "2"),
+			},
+			map[string]string{
+				"p1": "- - p2 1/3",
+				"p2": "p1 p1 p3 2/3",
+				"p3": "p2 p1 - 3/3",
+			},
+		},
+		{
+			"missing indices follow by date",
+			[]testNote{
+				part("q2", "20240501000000-q2.org", "Guide", ""),
+				part("q3", "20240301000000-q3.org", "Guide", "x"),
+				part("q1", "20240601000000-q1.org", "Guide", "1"),
+			},
+			map[string]string{
+				"q1": "- - q3 1/3",
+				"q3": "q1 q1 q2 2/3",
+				"q2": "q3 q1 - 3/3",
+			},
+		},
+		{
+			"single part",
+			[]testNote{part("r1", "", "Alone", "1")},
+			map[string]string{"r1": ""},
+		},
+		{
+			"separate series",
+			[]testNote{part("s1", "", "One", "1"), part("s2", "", "Two", "1"), part("s3", "", "One", "2")},
+			map[string]string{"s1": "- - s3 1/2", "s2": "", "s3": "s1 s1 - 2/2"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := newTestVault(t)
+			for _, n := range tt.notes {
+				v.add(n)
+			}
+			v.build()
+			for id, want := range tt.want {
+				if got := seriesLinks(v.read("notes/" + id + ".html")); got != want {
+					t.Errorf("%s: series nav %q, want %q", id, got, want)
+				}
+			}
+			if got := seriesLinks(v.read("notes/a1.html")); got != "" {
+				t.Errorf("a1, in no series, has series nav %q", got)
+			}
+		})
+	}
+}
@@ -0,0 +1,108 @@
+package render
+
+import (
+	"fmt"
+	"html"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/nicehiro/org-roam-web/internal/db"
+	"github.com/nicehiro/org-roam-web/internal/parser"
+)
+
+// shortcodeContext carries the page data available to shortcodes
+type shortcodeContext struct {
+	node      db.Node
+	backlinks []LinkData
+	toc       []parser.ToCEntry
+}
+
+// shortcodeFunc renders a shortcode to HTML
+type shortcodeFunc func(r *Renderer, ctx shortcodeContext, args []string) (string, error)
+
+// shortcodes is the registry of built-in shortcodes
+var shortcodes = map[string]shortcodeFunc{
+	"recent":    recentShortcode,
+	"backlinks": backlinksShortcode,
+	"toc":       tocShortcode,
+}
+
+// shortcodeRe matches {{< name args >}} in rendered HTML, where go-org has
+// escaped the angle brackets. A shortcode alone in a paragraph replaces
+// the whole paragraph.
+var shortcodeRe = regexp.MustCompile(`(?:<p>\s*)?\{\{(?:<|&lt;)\s*(\w+)((?:\s+[^\s}&]+)*)\s*(?:>|&gt;)\}\}(?:\s*</p>)?`)
+
+// expandShortcodes replaces known shortcodes in content with their output.
+// Unknown or failing shortcodes are left unchanged with a warning.
+func (r *Renderer) expandShortcodes(content string, ctx shortcodeContext) string {
+	return shortcodeRe.ReplaceAllStringFunc(content, func(match string) string {
+		m := shortcodeRe.FindStringSubmatch(match)
+		name, args := m[1], strings.Fields(m[2])
+
+		fn, ok := shortcodes[name]
+		if !ok {
+			fmt.Printf("Warning: unknown shortcode '%s' in note %s\n", name, ctx.node.Title)
+			return match
+		}
+
+		out, err := fn(r, ctx, args)
+		if err != nil {
+			fmt.Printf("Warning: shortcode '%s' in note %s: %v\n", name, ctx.node.Title, err)
+			return match
+		}
+		return out
+	})
+}
+
+// recentShortcode lists the most recent notes: {{< recent 5 >}}
+func recentShortcode(r *Renderer, ctx shortcodeContext, args []string) (string, error) {
+	count := 5
+	if len(args) > 0 {
+		n, err := strconv.Atoi(args[0])
+		if err != nil || n < 1 {
+			return "", fmt.Errorf("invalid count %q", args[0])
+		}
+		count = n
+	}
+
+	var links []LinkData
+	for _, n := range r.sortedNodes() {
+		if len(links) == count {
+			break
+		}
+		if n.ID == ctx.node.ID {
+			continue
+		}
+		links = append(links, LinkData{ID: n.ID, Title: r.nodeMap[n.ID], URL: r.noteURL(n.ID)})
+	}
+
+	return linkListHTML("shortcode-recent", links), nil
+}
+
+// backlinksShortcode lists the notes linking to this one: {{< backlinks >}}
+func backlinksShortcode(r *Renderer, ctx shortcodeContext, args []string) (string, error) {
+	return linkListHTML("shortcode-backlinks", ctx.backlinks), nil
+}
+
+// tocShortcode renders the table of contents inline: {{< toc >}}
+func tocShortcode(r *Renderer, ctx shortcodeContext, args []string) (string, error) {
+	var b strings.Builder
+	b.WriteString(`<nav class="shortcode-toc"><ul>`)
+	for _, e := range ctx.toc {
+		fmt.Fprintf(&b, `<li class="toc-level-%d"><a href="#%s">%s</a></li>`, e.Level, e.ID, html.EscapeString(e.Title))
+	}
+	b.WriteString(`</ul></nav>`)
+	return b.String(), nil
+}
+
+// linkListHTML renders links as an HTML list
+func linkListHTML(class string, links []LinkData) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, `<ul class="%s">`, class)
+	for _, l := range links {
+		fmt.Fprintf(&b, `<li><a href="%s" class="internal-link"><span class="link-marker">#</span> %s</a></li>`, l.URL, html.EscapeString(l.Title))
+	}
+	b.WriteString(`</ul>`)
+	return b.String()
+}
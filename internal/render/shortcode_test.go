@@ -0,0 +1,63 @@
+package render
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestShortcodes(t *testing.T) {
+	v := newTestVault(t)
+	v.add(testNote{ID: "sc", Title: "Shortcodes", Body: `{{< recent 2 >}}
+
+* Linked from
+{{< backlinks >}}
+
+* Contents
+{{< toc >}}
+
+* Broken
+{{< nope 1 >}}
+
+{{< recent many >}}
+`})
+	v.add(testNote{ID: "ref", Title: "Referrer", Links: []string{"sc"}})
+	out := captureStdout(t, func() { v.build() })
+	page := v.read("notes/sc.html")
+
+	tests := []struct {
+		name  string
+		class string   // Class of the expanded list
+		want  []string // Notes it links to
+	}{
+		// Newest first, leaving out the note itself (the added notes are
+		// dated by mtime, so newest)
+		{"recent", "shortcode-recent", []string{"ref", "b2"}},
+		{"backlinks", "shortcode-backlinks", []string{"ref"}},
+	}
+	for _, tt := range tests {
+		list := between(page, `<ul class="`+tt.class+`">`, "</ul>")
+		if got := noteHrefs(list); !slices.Equal(got, tt.want) {
+			t.Errorf("%s lists %v, want %v", tt.name, got, tt.want)
+		}
+	}
+
+	toc := between(page, `<nav class="shortcode-toc">`, "</nav>")
+	for _, anchor := range []string{"#linked-from", "#contents", "#broken"} {
+		if !strings.Contains(toc, `href="`+anchor+`"`) {
+			t.Errorf("toc lacks %s: %q", anchor, toc)
+		}
+	}
+
+	// Unknown and invalid shortcodes stay in the page, with a warning
+	for _, code := range []string{"{{&lt; nope 1 &gt;}}", "{{&lt; recent many &gt;}}"} {
+		if !strings.Contains(page, code) {
+			t.Errorf("page lacks %s", code)
+		}
+	}
+	for _, warning := range []string{"unknown shortcode 'nope' in note Shortcodes", `shortcode 'recent' in note Shortcodes: invalid count "many"`} {
+		if !strings.Contains(out, warning) {
+			t.Errorf("output lacks warning %q", warning)
+		}
+	}
+}
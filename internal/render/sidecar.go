@@ -0,0 +1,54 @@
+package render
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/nicehiro/org-roam-web/internal/db"
+	"gopkg.in/yaml.v3"
+)
+
+// sidecarSuffix names the optional file next to a note (foo.org ->
+// foo.meta.yaml) whose data is passed to the note's template as .Extra
+const sidecarSuffix = ".meta.yaml"
+
+// sidecarPath returns the sidecar file for the note at path
+func sidecarPath(path string) string {
+	return strings.TrimSuffix(path, filepath.Ext(path)) + sidecarSuffix
+}
+
+// IsSidecar reports whether path is a note sidecar file
+func IsSidecar(path string) bool {
+	return strings.HasSuffix(path, sidecarSuffix)
+}
+
+// noteExtra reads the note's sidecar file, if any. A sidecar that can't be
+// parsed is reported and ignored. Nodes sharing a file share its sidecar,
+// which is read once.
+func (r *Renderer) noteExtra(n db.Node) map[string]any {
+	r.filesMu.Lock()
+	defer r.filesMu.Unlock()
+	f := r.fileEntry(r.resolveFilePath(n.File))
+	if !f.hasExtra {
+		f.extra = readSidecar(sidecarPath(r.resolveFilePath(n.File)))
+		f.hasExtra = true
+	}
+	return f.extra
+}
+
+// readSidecar parses a sidecar file
+func readSidecar(path string) map[string]any {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+
+	var extra map[string]any
+	if err := yaml.Unmarshal(data, &extra); err != nil {
+		fmt.Printf("Warning: ignoring %s: %v\n", filepath.Base(path), err)
+		return nil
+	}
+	return extra
+}
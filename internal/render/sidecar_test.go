@@ -0,0 +1,66 @@
+package render
+
+import (
+	"html/template"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/nicehiro/org-roam-web/internal/db"
+)
+
+func TestNoteExtra(t *testing.T) {
+	tests := []struct {
+		name        string
+		sidecar     string // Content of s1.meta.yaml, "" for none
+		want        map[string]any
+		wantWarning bool
+	}{
+		{
+			"sidecar",
+			"header: Field notes\nrelated:\n  - https://example.org/a\n  - https://example.org/b\nweight: 3\n",
+			map[string]any{
+				"header":  "Field notes",
+				"related": []any{"https://example.org/a", "https://example.org/b"},
+				"weight":  3,
+			},
+			false,
+		},
+		{"no sidecar", "", nil, false},
+		{"invalid sidecar", "header: [unclosed\n", nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := newTestVault(t)
+			v.add(testNote{ID: "s1", Title: "Sidecar", Body: "Text."})
+			if tt.sidecar != "" {
+				v.writeFile("s1.meta.yaml", tt.sidecar)
+			}
+			var r *Renderer
+			out := captureStdout(t, func() { r = v.build() })
+			if warned := strings.Contains(out, "Warning: ignoring s1.meta.yaml"); warned != tt.wantWarning {
+				t.Errorf("warned = %t, want %t:\n%s", warned, tt.wantWarning, out)
+			}
+
+			n := db.Node{ID: "s1", File: fixtureRoot + "/s1.org"}
+			extra := r.noteExtra(n)
+			if !reflect.DeepEqual(extra, tt.want) {
+				t.Errorf("noteExtra = %#v, want %#v", extra, tt.want)
+			}
+
+			// Templates reach the data through .Extra
+			tmpl := template.Must(template.New("").Parse(`{{with .Extra}}<h2>{{.header}}</h2>{{range .related}}<a href="{{.}}"></a>{{end}}{{end}}`))
+			var b strings.Builder
+			if err := tmpl.Execute(&b, NoteData{Extra: extra}); err != nil {
+				t.Fatal(err)
+			}
+			want := ""
+			if tt.want != nil {
+				want = `<h2>Field notes</h2><a href="https://example.org/a"></a><a href="https://example.org/b"></a>`
+			}
+			if b.String() != want {
+				t.Errorf("template output %q, want %q", b.String(), want)
+			}
+		})
+	}
+}
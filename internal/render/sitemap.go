@@ -0,0 +1,78 @@
+package render
+
+import (
+	"encoding/xml"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+type sitemapURLSet struct {
+	XMLName xml.Name     `xml:"http://www.sitemaps.org/schemas/sitemap/0.9 urlset"`
+	URLs    []sitemapURL `xml:"url"`
+}
+
+type sitemapURL struct {
+	Loc     string `xml:"loc"`
+	LastMod string `xml:"lastmod,omitempty"`
+}
+
+// generateSitemap writes sitemap.xml, listing the home page, the graph
+// page, every tag page and every published note. A note's lastmod is its
+// date; a tag page's is the date of its newest note. Undated pages have
+// no lastmod.
+func (r *Renderer) generateSitemap() error {
+	site := r.cfg.Site
+	set := sitemapURLSet{URLs: []sitemapURL{
+		{Loc: site.AbsURL("")},
+		{Loc: site.AbsURL("graph.html")},
+	}}
+
+	tagDates := make(map[string]time.Time)
+	var notes []sitemapURL
+	for _, n := range r.nodes {
+		date := r.noteDate(n)
+		tags := r.nodeTags[n.ID]
+		if len(tags) == 0 && r.cfg.Display.ShowUntagged {
+			tags = []string{untaggedTag}
+		}
+		for _, tag := range tags {
+			if last, ok := tagDates[tag]; !ok || date.After(last) {
+				tagDates[tag] = date
+			}
+		}
+		notes = append(notes, sitemapURL{Loc: r.noteURL(n.ID), LastMod: sitemapDate(date)})
+	}
+
+	tags := make([]string, 0, len(tagDates))
+	for tag := range tagDates {
+		tags = append(tags, tag)
+	}
+	sort.Strings(tags)
+	for _, tag := range tags {
+		set.URLs = append(set.URLs, sitemapURL{
+			Loc:     site.AbsURL("tags/" + tag + ".html"),
+			LastMod: sitemapDate(tagDates[tag]),
+		})
+	}
+
+	sort.Slice(notes, func(i, j int) bool { return notes[i].Loc < notes[j].Loc })
+	set.URLs = append(set.URLs, notes...)
+
+	data, err := xml.MarshalIndent(set, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to serialize sitemap: %w", err)
+	}
+	data = append([]byte(xml.Header), data...)
+	return os.WriteFile(filepath.Join(r.cfg.Paths.OutputDir, "sitemap.xml"), data, 0644)
+}
+
+// sitemapDate formats a lastmod date, or returns "" for the zero time
+func sitemapDate(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format("2006-01-02")
+}
@@ -0,0 +1,95 @@
+package render
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"path/filepath"
+	"sort"
+
+	"github.com/nicehiro/org-roam-web/internal/db"
+	"github.com/nicehiro/org-roam-web/internal/parser"
+)
+
+// assignSlugs picks the directory of each published note's page for
+// display.url_style "slug": its title as an ASCII slug, or its ID when the
+// title has no Latin or Cyrillic letters or digits. Of the notes sharing a
+// slug, the oldest keeps it and the others get a short hash of their ID
+// appended, so adding a dated note never moves an existing page. Undated
+// notes come after all dated ones, by ID. With note pages at the site
+// root, slugs naming a root output are hashed too.
+func (r *Renderer) assignSlugs() {
+	r.slugs = make(map[string]string)
+	if r.cfg.Display.URLStyle != "slug" {
+		return
+	}
+
+	nodes := append([]db.Node(nil), r.nodes...)
+	sort.SliceStable(nodes, func(i, j int) bool {
+		di, dj := r.dates[nodes[i].ID], r.dates[nodes[j].ID]
+		if di.IsZero() != dj.IsZero() {
+			return dj.IsZero()
+		}
+		if !di.Equal(dj) {
+			return di.Before(dj)
+		}
+		return nodes[i].ID < nodes[j].ID
+	})
+
+	taken := make(map[string]bool)
+	reserved := func(slug string) bool {
+		return taken[slug] || (r.cfg.Display.NotesSubdir == "" && isRootOutput(slug))
+	}
+	for _, n := range nodes {
+		slug := parser.ASCIISlug(r.nodeMap[n.ID])
+		if slug == "" {
+			slug = n.ID
+		}
+		if reserved(slug) {
+			// The hashed slug may itself be the slug of another title
+			base := slug + "-" + shortHash(n.ID)
+			slug = base
+			for i := 2; reserved(slug); i++ {
+				slug = fmt.Sprintf("%s-%d", base, i)
+			}
+		}
+		taken[slug] = true
+		r.slugs[n.ID] = slug
+	}
+}
+
+// shortHash returns the first 6 hex digits of the SHA-256 of s
+func shortHash(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(sum[:3])
+}
+
+// notePagePath returns the file a note's page is written to under notesDir
+func (r *Renderer) notePagePath(notesDir, id string) string {
+	if slug, ok := r.slugs[id]; ok {
+		return filepath.Join(notesDir, slug, "index.html")
+	}
+	return filepath.Join(notesDir, id+".html")
+}
+
+// generateSlugRedirects writes a stub at the ID path of each note with a
+// slug, so links to the old URLs keep working
+func (r *Renderer) generateSlugRedirects(notesDir string) error {
+	for _, n := range r.nodes {
+		if _, ok := r.slugs[n.ID]; !ok {
+			continue
+		}
+		if r.cfg.Display.NotesSubdir == "" && isRootOutput(n.ID+".html") {
+			continue
+		}
+		data := RedirectData{
+			Site:  r.siteData(),
+			Title: r.nodeMap[n.ID],
+			URL:   r.noteURL(n.ID),
+		}
+		if err := r.renderPage("redirect.html", filepath.Join(notesDir, n.ID+".html"), data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,139 @@
+package render
+
+import (
+	"maps"
+	"testing"
+	"time"
+
+	"github.com/nicehiro/org-roam-web/internal/config"
+	"github.com/nicehiro/org-roam-web/internal/db"
+)
+
+func TestAssignSlugs(t *testing.T) {
+	dated := func(id, title, date string) testNote {
+		return testNote{ID: id, Title: title, File: date + "-" + id + ".org"}
+	}
+	tests := []struct {
+		name        string
+		notesSubdir string
+		notes       []testNote
+		want        map[string]string // ID -> slug
+	}{
+		{
+			name:        "oldest keeps the slug",
+			notesSubdir: "notes",
+			notes:       []testNote{dated("s2", "Same", "20240301000000"), dated("s1", "Same", "20240201000000")},
+			want:        map[string]string{"s1": "same", "s2": "same-" + shortHash("s2")},
+		},
+		{
+			name:        "same date by ID",
+			notesSubdir: "notes",
+			notes:       []testNote{dated("s2", "Same", "20240201000000"), dated("s1", "Same", "20240201000000")},
+			want:        map[string]string{"s1": "same", "s2": "same-" + shortHash("s2")},
+		},
+		{
+			name:        "hashed slug already taken",
+			notesSubdir: "notes",
+			notes: []testNote{
+				dated("s1", "Same", "20240101000000"),
+				dated("s3", "Same "+shortHash("s2"), "20240201000000"),
+				dated("s2", "Same", "20240301000000"),
+			},
+			want: map[string]string{
+				"s1": "same",
+				"s3": "same-" + shortHash("s2"),
+				"s2": "same-" + shortHash("s2") + "-2",
+			},
+		},
+		{
+			name:        "root outputs at the site root",
+			notesSubdir: "",
+			notes:       []testNote{{ID: "r1", Title: "Tags"}, {ID: "r2", Title: "API"}, {ID: "r3", Title: "Img"}, {ID: "r4", Title: "Sections"}, {ID: "r5", Title: "Graph"}},
+			want: map[string]string{
+				"r1": "tags-" + shortHash("r1"),
+				"r2": "api-" + shortHash("r2"),
+				"r3": "img-" + shortHash("r3"),
+				"r4": "sections-" + shortHash("r4"),
+				"r5": "graph",
+			},
+		},
+		{
+			name:        "root outputs under a notes directory",
+			notesSubdir: "notes",
+			notes:       []testNote{{ID: "r1", Title: "Tags"}, {ID: "r2", Title: "API"}},
+			want:        map[string]string{"r1": "tags", "r2": "api"},
+		},
+		{
+			name:        "no ASCII title",
+			notesSubdir: "notes",
+			notes:       []testNote{{ID: "k1", Title: "日本語"}},
+			want:        map[string]string{"k1": "k1"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := newTestVault(t)
+			v.cfg.Display.URLStyle = "slug"
+			v.cfg.Display.NotesSubdir = tt.notesSubdir
+			for _, n := range tt.notes {
+				v.add(n)
+			}
+			var r *Renderer
+			captureStdout(t, func() { r = v.build() })
+
+			got := make(map[string]string)
+			for id := range tt.want {
+				got[id] = r.slugs[id]
+			}
+			if !maps.Equal(got, tt.want) {
+				t.Errorf("slugs %v, want %v", got, tt.want)
+			}
+			for _, slug := range r.slugs {
+				if tt.notesSubdir == "" && isRootOutput(slug) {
+					t.Errorf("slug %q replaces a root output", slug)
+				}
+			}
+		})
+	}
+}
+
+func TestAssignSlugsUndated(t *testing.T) {
+	jan := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name  string
+		dates map[string]time.Time
+		want  map[string]string
+	}{
+		{
+			"dated before undated",
+			map[string]time.Time{"b": jan},
+			map[string]string{"a": "same-" + shortHash("a"), "b": "same", "c": "same-" + shortHash("c")},
+		},
+		{
+			"undated by ID",
+			nil,
+			map[string]string{"a": "same", "b": "same-" + shortHash("b"), "c": "same-" + shortHash("c")},
+		},
+		{
+			"oldest first",
+			map[string]time.Time{"a": jan.AddDate(0, 1, 0), "c": jan},
+			map[string]string{"a": "same-" + shortHash("a"), "b": "same-" + shortHash("b"), "c": "same"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := config.DefaultConfig()
+			cfg.Display.URLStyle = "slug"
+			r := &Renderer{
+				cfg:     cfg,
+				nodes:   []db.Node{{ID: "a"}, {ID: "b"}, {ID: "c"}},
+				nodeMap: map[string]string{"a": "Same", "b": "Same", "c": "Same"},
+				dates:   tt.dates,
+			}
+			r.assignSlugs()
+			if !maps.Equal(r.slugs, tt.want) {
+				t.Errorf("slugs %v, want %v", r.slugs, tt.want)
+			}
+		})
+	}
+}
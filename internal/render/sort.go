@@ -0,0 +1,79 @@
+package render
+
+import (
+	"strings"
+	"time"
+
+	"github.com/nicehiro/org-roam-web/internal/db"
+)
+
+// noteCompare compares two nodes, returning a negative number when a sorts
+// before b, a positive number when b sorts before a, and zero on a tie
+type noteCompare func(a, b db.Node) int
+
+// newNoteCompare builds a comparator from sort keys such as "pinned",
+// "date_desc" or "title_asc". Keys are applied in order, each one breaking
+// ties left by the previous ones. Unknown keys are ignored (config.Validate
+// rejects them at load time).
+func newNoteCompare(keys []string, dateOf func(db.Node) time.Time) noteCompare {
+	var cmps []noteCompare
+	for _, key := range keys {
+		switch key {
+		case "pinned":
+			cmps = append(cmps, func(a, b db.Node) int {
+				return boolCompare(isPinned(b), isPinned(a))
+			})
+		case "date_desc":
+			cmps = append(cmps, func(a, b db.Node) int {
+				return dateOf(b).Compare(dateOf(a))
+			})
+		case "date_asc":
+			cmps = append(cmps, func(a, b db.Node) int {
+				return dateOf(a).Compare(dateOf(b))
+			})
+		case "title_asc":
+			cmps = append(cmps, func(a, b db.Node) int {
+				return strings.Compare(strings.ToLower(a.Title), strings.ToLower(b.Title))
+			})
+		case "title_desc":
+			cmps = append(cmps, func(a, b db.Node) int {
+				return strings.Compare(strings.ToLower(b.Title), strings.ToLower(a.Title))
+			})
+		}
+	}
+
+	return func(a, b db.Node) int {
+		for _, c := range cmps {
+			if r := c(a, b); r != 0 {
+				return r
+			}
+		}
+		return 0
+	}
+}
+
+// boolCompare orders false before true
+func boolCompare(a, b bool) int {
+	switch {
+	case a == b:
+		return 0
+	case a:
+		return 1
+	default:
+		return -1
+	}
+}
+
+// isPinned reports whether a node sets a truthy PINNED property
+func isPinned(n db.Node) bool {
+	return isTruthy(n.Properties["PINNED"])
+}
+
+// isTruthy interprets an org property value as a boolean
+func isTruthy(v string) bool {
+	switch strings.ToLower(strings.TrimSpace(v)) {
+	case "", "nil", "no", "false", "0":
+		return false
+	}
+	return true
+}
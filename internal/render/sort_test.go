@@ -0,0 +1,52 @@
+package render
+
+import (
+	"slices"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/nicehiro/org-roam-web/internal/db"
+)
+
+func TestNoteCompare(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2024, 1, d, 0, 0, 0, 0, time.UTC) }
+	dates := map[string]time.Time{"a": day(3), "b": day(1), "c": day(3), "d": day(2), "e": day(1)}
+	pinned := map[string]string{"PINNED": "t"}
+	nodes := []db.Node{
+		{ID: "a", Title: "beta"},
+		{ID: "b", Title: "Alpha", Properties: pinned},
+		{ID: "c", Title: "alpha"},
+		{ID: "d", Title: "Gamma", Properties: map[string]string{"PINNED": "nil"}},
+		{ID: "e", Title: "delta", Properties: pinned},
+	}
+	dateOf := func(n db.Node) time.Time { return dates[n.ID] }
+
+	tests := []struct {
+		keys []string
+		want []string
+	}{
+		{[]string{"date_desc"}, []string{"a", "c", "d", "b", "e"}},
+		{[]string{"date_asc"}, []string{"b", "e", "d", "a", "c"}},
+		{[]string{"title_asc"}, []string{"b", "c", "a", "e", "d"}},
+		{[]string{"title_desc"}, []string{"d", "e", "a", "b", "c"}},
+		{[]string{"date_desc", "title_asc"}, []string{"c", "a", "d", "b", "e"}},
+		{[]string{"pinned", "date_desc", "title_asc"}, []string{"b", "e", "c", "a", "d"}},
+		{[]string{"pinned", "title_desc"}, []string{"e", "b", "d", "a", "c"}},
+		{nil, []string{"a", "b", "c", "d", "e"}},
+	}
+	for _, tt := range tests {
+		compare := newNoteCompare(tt.keys, dateOf)
+		sorted := slices.Clone(nodes)
+		sort.SliceStable(sorted, func(i, j int) bool {
+			return compare(sorted[i], sorted[j]) < 0
+		})
+		var got []string
+		for _, n := range sorted {
+			got = append(got, n.ID)
+		}
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("%v: got %v, want %v", tt.keys, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,63 @@
+package render
+
+import (
+	"path/filepath"
+	"sort"
+)
+
+// TasksData holds data for the task dashboard
+type TasksData struct {
+	Site   SiteData
+	Groups []TaskGroup
+	Count  int
+}
+
+// TaskGroup holds the open tasks sharing a TODO keyword
+type TaskGroup struct {
+	Keyword string
+	Tasks   []TaskItem
+}
+
+// TaskItem is an open task with the note it belongs to
+type TaskItem struct {
+	Title     string
+	Priority  string
+	URL       string // The headline in its note
+	NoteTitle string
+	NoteURL   string
+}
+
+// generateTasks generates tasks.html, listing the open tasks of all notes
+// grouped by keyword. Within a group, tasks are ordered by priority (none
+// last), then by note title, then as they appear in the note.
+func (r *Renderer) generateTasks() error {
+	data := TasksData{Site: r.siteData()}
+
+	groups := make(map[string][]TaskItem)
+	for _, n := range r.listedNodes() {
+		for _, t := range r.noteTasks[n.ID] {
+			groups[t.Keyword] = append(groups[t.Keyword], TaskItem{
+				Title:     t.Title,
+				Priority:  t.Priority,
+				URL:       r.noteURL(n.ID) + "#" + t.ID,
+				NoteTitle: r.nodeMap[n.ID],
+				NoteURL:   r.noteURL(n.ID),
+			})
+			data.Count++
+		}
+	}
+
+	for keyword, tasks := range groups {
+		sort.SliceStable(tasks, func(i, j int) bool {
+			a, b := tasks[i], tasks[j]
+			if a.Priority != b.Priority {
+				return b.Priority == "" || (a.Priority != "" && a.Priority < b.Priority)
+			}
+			return a.NoteTitle < b.NoteTitle
+		})
+		data.Groups = append(data.Groups, TaskGroup{Keyword: keyword, Tasks: tasks})
+	}
+	sort.Slice(data.Groups, func(i, j int) bool { return data.Groups[i].Keyword < data.Groups[j].Keyword })
+
+	return r.renderPage("tasks.html", filepath.Join(r.cfg.Paths.OutputDir, "tasks.html"), data)
+}
@@ -0,0 +1,67 @@
+package render
+
+import (
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+var (
+	taskGroupRe = regexp.MustCompile(`(?s)<h2>(\w+) <span class="task-group-count">.*?</ul>`)
+	taskItemRe  = regexp.MustCompile(`(?s)<li class="task-item">\s*(?:<span class="priority">\[#(\w)\]</span>)?\s*<a href="([^"]*)" class="task-title">([^<]*)</a>\s*<a href="[^"]*" class="task-note">([^<]*)</a>`)
+)
+
+func TestTasksPage(t *testing.T) {
+	v := newTestVault(t)
+	v.cfg.Display.TasksPage = true
+	v.add(testNote{ID: "t1", Title: "Project", Body: "#+TODO: TODO NEXT | DONE\n\n* TODO [#B] Write draft\n* DONE Old thing\n* NEXT Call Bob\n"})
+	v.add(testNote{ID: "t2", Title: "Bugs", Body: "* TODO [#A] Fix crash\n* TODO Tidy up\n"})
+	v.add(testNote{ID: "t3", Title: "Waiting", Body: "#+TODO: WAIT | CANCELLED\n\n* WAIT Reply from Ann\n* CANCELLED Nope\n"})
+	v.add(testNote{ID: "t4", Title: "Hidden", Tags: []string{"private"}, Body: "* TODO Secret\n"})
+	v.build()
+	page := v.read("tasks.html")
+
+	// Keyword -> "[priority] title (note)", in order
+	got := make(map[string][]string)
+	var keywords []string
+	for _, g := range taskGroupRe.FindAllStringSubmatch(page, -1) {
+		keywords = append(keywords, g[1])
+		for _, m := range taskItemRe.FindAllStringSubmatch(g[0], -1) {
+			got[g[1]] = append(got[g[1]], strings.TrimSpace(m[1]+" "+m[3]+" ("+m[4]+")"))
+
+			// Each task links to its headline on the note page
+			target, anchor, _ := strings.Cut(strings.TrimPrefix(m[2], "/"), "#")
+			if !strings.Contains(v.read(target), `id="`+anchor+`"`) {
+				t.Errorf("task %q links to %s, which has no such anchor", m[3], m[2])
+			}
+		}
+	}
+
+	want := map[string][]string{
+		"NEXT": {"Call Bob (Project)"},
+		"TODO": {"A Fix crash (Bugs)", "B Write draft (Project)", "Tidy up (Bugs)"},
+		"WAIT": {"Reply from Ann (Waiting)"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("tasks %v, want %v", got, want)
+	}
+	if !reflect.DeepEqual(keywords, []string{"NEXT", "TODO", "WAIT"}) {
+		t.Errorf("groups %v, want them sorted by keyword", keywords)
+	}
+	if !strings.Contains(page, `<p class="tasks-count">5 open tasks</p>`) {
+		t.Errorf("task count missing or wrong: %s", between(page, `<p class="tasks-count">`, "</p>"))
+	}
+}
+
+func TestTasksPageDisabled(t *testing.T) {
+	v := newTestVault(t)
+	v.add(testNote{ID: "t1", Title: "Project", Body: "* TODO Write draft\n"})
+	v.build()
+	if v.exists("tasks.html") {
+		t.Error("tasks.html written with tasks_page off")
+	}
+	if strings.Contains(v.read("index.html"), "/tasks.html") {
+		t.Error("nav links to tasks.html with tasks_page off")
+	}
+}
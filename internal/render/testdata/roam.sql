@@ -0,0 +1,29 @@
+-- org-roam v2 database for testdata/vault. Values are stored the way
+-- org-roam writes them: strings as quoted elisp, file paths from the
+-- machine the database was synced on.
+CREATE TABLE files (file UNIQUE PRIMARY KEY, title, hash NOT NULL, atime NOT NULL, mtime NOT NULL);
+CREATE TABLE nodes (id NOT NULL PRIMARY KEY, file NOT NULL, level NOT NULL, pos NOT NULL, todo, priority, scheduled text, deadline text, title, properties, olp);
+CREATE TABLE aliases (node_id NOT NULL, alias);
+CREATE TABLE refs (node_id NOT NULL, ref NOT NULL, type NOT NULL);
+CREATE TABLE tags (node_id NOT NULL, tag);
+CREATE TABLE links (pos NOT NULL, source NOT NULL, dest NOT NULL, type NOT NULL, properties NOT NULL);
+
+INSERT INTO nodes (id, file, level, pos, title, properties) VALUES
+  ('"a1"', '"/home/user/org-roam/20240101120000-alpha.org"', 0, 1, '"Alpha"', '(("CATEGORY" . "alpha") ("ID" . "a1"))'),
+  ('"b2"', '"/home/user/org-roam/20240201120000-beta.org"', 0, 1, '"Beta"', '(("CATEGORY" . "beta") ("ID" . "b2"))'),
+  ('"c3"', '"/home/user/org-roam/20230301120000-gamma.org"', 0, 1, '"Gamma"', '(("CATEGORY" . "gamma") ("ID" . "c3"))'),
+  ('"d4"', '"/home/user/org-roam/20220401120000-delta.org"', 0, 1, '"Delta"', '(("CATEGORY" . "delta") ("ID" . "d4"))'),
+  ('"e5"', '"/home/user/org-roam/epsilon.org"', 0, 1, '"Epsilon"', '(("CATEGORY" . "epsilon") ("ID" . "e5"))');
+
+INSERT INTO tags (node_id, tag) VALUES
+  ('"a1"', '"go"'),
+  ('"b2"', '"go"'),
+  ('"b2"', '"web"'),
+  ('"d4"', '"private"');
+
+INSERT INTO links (pos, source, dest, type, properties) VALUES
+  (120, '"a1"', '"b2"', '"id"', '(:outline nil)'),
+  (95, '"b2"', '"a1"', '"id"', '(:outline nil)'),
+  (130, '"b2"', '"c3"', '"id"', '(:outline nil)'),
+  (90, '"c3"', '"a1"', '"id"', '(:outline nil)'),
+  (100, '"d4"', '"a1"', '"id"', '(:outline nil)');
@@ -0,0 +1,242 @@
+package render
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+
+	"github.com/nicehiro/org-roam-web/internal/config"
+)
+
+// fixtureRoot is the directory the notes of testdata/roam.sql were synced
+// from, standing in for a database made on another machine
+const fixtureRoot = "/home/user/org-roam"
+
+// testVault is a copy of testdata/vault and its org-roam database in a
+// temporary directory, which tests may add notes to before building. The
+// fixture holds:
+//
+//	a1 Alpha    go       links to b2
+//	b2 Beta     go, web  links to a1 and c3
+//	c3 Gamma    -        links to a1
+//	d4 Delta    private  links to a1 (excluded by default)
+//	e5 Epsilon  -        no links, no date in its file name, so dated by its
+//	                     mtime of 2021-05-10
+type testVault struct {
+	t   testing.TB
+	cfg *config.Config
+	db  *sql.DB
+}
+
+// testNote describes a note added to a testVault
+type testNote struct {
+	ID    string
+	Title string
+	File  string // Relative to roam_dir, default <ID>.org
+	Tags  []string
+	Props map[string]string // Properties besides ID
+	Body  string            // Org content after the title
+	Links []string          // IDs linked to, appended to Body
+}
+
+func newTestVault(t testing.TB) *testVault {
+	t.Helper()
+	dir := t.TempDir()
+	roamDir := filepath.Join(dir, "roam")
+	if err := os.CopyFS(roamDir, os.DirFS("testdata/vault")); err != nil {
+		t.Fatal(err)
+	}
+	mtime := time.Date(2021, 5, 10, 12, 0, 0, 0, time.UTC)
+	if err := os.Chtimes(filepath.Join(roamDir, "epsilon.org"), mtime, mtime); err != nil {
+		t.Fatal(err)
+	}
+
+	schema, err := os.ReadFile("testdata/roam.sql")
+	if err != nil {
+		t.Fatal(err)
+	}
+	dbPath := filepath.Join(dir, "roam.db")
+	database, err := sql.Open("sqlite3", dbPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { database.Close() })
+	if _, err := database.Exec(string(schema)); err != nil {
+		t.Fatalf("loading testdata/roam.sql: %v", err)
+	}
+
+	cfg := config.DefaultConfig()
+	cfg.Paths.RoamDir = roamDir
+	cfg.Paths.DBPath = dbPath
+	cfg.Paths.OutputDir = filepath.Join(dir, "dist")
+	return &testVault{t: t, cfg: cfg, db: database}
+}
+
+// add writes the org file of a note and records it in the database
+func (v *testVault) add(n testNote) {
+	v.t.Helper()
+	if n.File == "" {
+		n.File = n.ID + ".org"
+	}
+
+	var org strings.Builder
+	fmt.Fprintf(&org, ":PROPERTIES:\n:ID:       %s\n", n.ID)
+	for k, val := range n.Props {
+		fmt.Fprintf(&org, ":%s: %s\n", k, val)
+	}
+	fmt.Fprintf(&org, ":END:\n#+title: %s\n", n.Title)
+	if len(n.Tags) > 0 {
+		fmt.Fprintf(&org, "#+filetags: :%s:\n", strings.Join(n.Tags, ":"))
+	}
+	fmt.Fprintf(&org, "\n%s\n", n.Body)
+	for _, target := range n.Links {
+		fmt.Fprintf(&org, "\nSee [[id:%s][%s]].\n", target, target)
+	}
+	v.writeFile(n.File, org.String())
+
+	props := fmt.Sprintf("((%q . %q)", "ID", n.ID)
+	for k, val := range n.Props {
+		props += fmt.Sprintf(" (%q . %q)", k, val)
+	}
+	props += ")"
+	v.exec(`INSERT INTO nodes (id, file, level, pos, title, properties) VALUES (?, ?, 0, 1, ?, ?)`,
+		quote(n.ID), quote(fixtureRoot+"/"+filepath.ToSlash(n.File)), quote(n.Title), props)
+	for _, tag := range n.Tags {
+		v.exec(`INSERT INTO tags (node_id, tag) VALUES (?, ?)`, quote(n.ID), quote(tag))
+	}
+	for _, target := range n.Links {
+		v.link(n.ID, target)
+	}
+}
+
+// link records a link between two notes without touching their files
+func (v *testVault) link(source, target string) {
+	v.t.Helper()
+	v.exec(`INSERT INTO links (pos, source, dest, type, properties) VALUES (1, ?, ?, '"id"', '()')`,
+		quote(source), quote(target))
+}
+
+// exec runs a statement against the fixture database
+func (v *testVault) exec(query string, args ...any) {
+	v.t.Helper()
+	if _, err := v.db.Exec(query, args...); err != nil {
+		v.t.Fatalf("%s: %v", query, err)
+	}
+}
+
+// writeFile writes a file under roam_dir
+func (v *testVault) writeFile(name, content string) {
+	v.t.Helper()
+	path := filepath.Join(v.cfg.Paths.RoamDir, name)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		v.t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		v.t.Fatal(err)
+	}
+}
+
+// build renders the site, failing the test on error
+func (v *testVault) build() *Renderer {
+	v.t.Helper()
+	r, err := v.tryBuild()
+	if err != nil {
+		v.t.Fatalf("Build: %v", err)
+	}
+	return r
+}
+
+// tryBuild renders the site with a new Renderer
+func (v *testVault) tryBuild() (*Renderer, error) {
+	r, err := NewRenderer(v.cfg)
+	if err != nil {
+		return nil, err
+	}
+	return r, r.Build()
+}
+
+// read returns a file from the output directory
+func (v *testVault) read(name string) string {
+	v.t.Helper()
+	data, err := os.ReadFile(filepath.Join(v.cfg.Paths.OutputDir, name))
+	if err != nil {
+		v.t.Fatal(err)
+	}
+	return string(data)
+}
+
+// readJSON decodes a JSON file from the output directory into out
+func (v *testVault) readJSON(name string, out any) {
+	v.t.Helper()
+	if err := json.Unmarshal([]byte(v.read(name)), out); err != nil {
+		v.t.Fatalf("%s: %v", name, err)
+	}
+}
+
+// exists reports whether the output directory has a file
+func (v *testVault) exists(name string) bool {
+	_, err := os.Stat(filepath.Join(v.cfg.Paths.OutputDir, name))
+	return err == nil
+}
+
+// quote stores s the way org-roam does, as an elisp string
+func quote(s string) string {
+	return `"` + s + `"`
+}
+
+var noteHrefRe = regexp.MustCompile(`href="[^"]*/notes/([^"/]+)\.html"`)
+
+// noteHrefs returns the IDs of the note pages linked from page, in order
+func noteHrefs(page string) []string {
+	var ids []string
+	for _, m := range noteHrefRe.FindAllStringSubmatch(page, -1) {
+		ids = append(ids, m[1])
+	}
+	return ids
+}
+
+// captureStdout returns what f prints, where the build reports warnings
+func captureStdout(t testing.TB, f func()) string {
+	t.Helper()
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout := os.Stdout
+	os.Stdout = w
+	out := make(chan string)
+	go func() {
+		data, _ := io.ReadAll(r)
+		out <- string(data)
+	}()
+
+	defer func() { os.Stdout = stdout }()
+	f()
+	w.Close()
+	return <-out
+}
+
+// between returns the part of s after the first start and before the next
+// end, or "" if s has no start
+func between(s, start, end string) string {
+	_, after, ok := strings.Cut(s, start)
+	if !ok {
+		return ""
+	}
+	before, _, _ := strings.Cut(after, end)
+	return before
+}
+
+// backlinkIDs returns the notes listed under Backlinks on a note page
+func backlinkIDs(page string) []string {
+	return noteHrefs(between(page, "<h3>Backlinks</h3>", "</section>"))
+}
@@ -2,20 +2,41 @@ package search
 
 import (
 	"encoding/json"
+	"math"
+	"sort"
+	"strings"
+	"unicode"
 
 	"github.com/nicehiro/org-roam-web/internal/db"
 )
 
 // SearchEntry represents a searchable note
 type SearchEntry struct {
-	ID    string   `json:"id"`
-	Title string   `json:"title"`
-	Tags  []string `json:"tags"`
+	ID     string   `json:"id"`
+	Title  string   `json:"title"`
+	Tags   []string `json:"tags"`
+	URL    string   `json:"url,omitempty"`
+	Status string   `json:"status,omitempty"` // STATUS property
+
+	Aliases []string `json:"aliases,omitempty"` // ROAM_ALIASES
+	Refs    []string `json:"refs,omitempty"`    // ROAM_REFS: URLs, @cite keys
+	Excerpt string   `json:"excerpt,omitempty"` // Start of the note's text
 }
 
+// Alias display modes: show the note's title, or the alias that matched
+const (
+	AliasDisplayCanonical = "canonical"
+	AliasDisplayMatched   = "matched"
+)
+
 // SearchIndex holds all searchable entries
 type SearchIndex struct {
 	Entries []SearchEntry `json:"entries"`
+
+	// How alias matches are shown, and the score of an alias match
+	// relative to the same match on the title (0 ignores aliases)
+	AliasDisplay string  `json:"alias_display,omitempty"`
+	AliasWeight  float64 `json:"alias_weight,omitempty"`
 }
 
 // BuildIndex creates a search index from nodes
@@ -30,16 +51,193 @@ func BuildIndex(nodes []db.Node, nodeTags map[string][]string) *SearchIndex {
 			tags = []string{}
 		}
 		index.Entries = append(index.Entries, SearchEntry{
-			ID:    n.ID,
-			Title: n.Title,
-			Tags:  tags,
+			ID:     n.ID,
+			Title:  n.Title,
+			Tags:   tags,
+			Status: strings.TrimSpace(n.Properties["STATUS"]),
 		})
 	}
+	index.Sort()
 
 	return index
 }
 
+// Sort orders the entries by title, case-insensitively, then by ID, so the
+// JSON output is reproducible
+func (idx *SearchIndex) Sort() {
+	sort.SliceStable(idx.Entries, func(i, j int) bool {
+		a, b := strings.ToLower(idx.Entries[i].Title), strings.ToLower(idx.Entries[j].Title)
+		if a != b {
+			return a < b
+		}
+		return idx.Entries[i].ID < idx.Entries[j].ID
+	})
+}
+
+// Upsert adds an entry or replaces the entry with the same ID in place
+func (idx *SearchIndex) Upsert(e SearchEntry) {
+	for i := range idx.Entries {
+		if idx.Entries[i].ID == e.ID {
+			idx.Entries[i] = e
+			return
+		}
+	}
+	idx.Entries = append(idx.Entries, e)
+}
+
+// Remove deletes the entry with the given ID, if present
+func (idx *SearchIndex) Remove(id string) {
+	for i := range idx.Entries {
+		if idx.Entries[i].ID == id {
+			idx.Entries = append(idx.Entries[:i], idx.Entries[i+1:]...)
+			return
+		}
+	}
+}
+
+// SetURLs fills in each entry's page URL using urlFor
+func (idx *SearchIndex) SetURLs(urlFor func(id string) string) {
+	for i := range idx.Entries {
+		idx.Entries[i].URL = urlFor(idx.Entries[i].ID)
+	}
+}
+
+// SetAliases fills in each entry's aliases from aliases, keyed by note ID
+func (idx *SearchIndex) SetAliases(aliases map[string][]string) {
+	for i := range idx.Entries {
+		idx.Entries[i].Aliases = aliases[idx.Entries[i].ID]
+	}
+}
+
+// SetRefs fills in each entry's refs from refs, keyed by note ID
+func (idx *SearchIndex) SetRefs(refs map[string][]string) {
+	for i := range idx.Entries {
+		idx.Entries[i].Refs = refs[idx.Entries[i].ID]
+	}
+}
+
+// SetExcerpts fills in each entry's excerpt from excerpts, keyed by note ID
+func (idx *SearchIndex) SetExcerpts(excerpts map[string]string) {
+	for i := range idx.Entries {
+		idx.Entries[i].Excerpt = excerpts[idx.Entries[i].ID]
+	}
+}
+
 // ToJSON converts the index to JSON
 func (idx *SearchIndex) ToJSON() ([]byte, error) {
 	return json.MarshalIndent(idx, "", "  ")
 }
+
+// ToCompactJSON converts the index to unindented JSON
+func (idx *SearchIndex) ToCompactJSON() ([]byte, error) {
+	return json.Marshal(idx)
+}
+
+// Result is a search hit with its relevance score
+type Result struct {
+	Entry SearchEntry `json:"entry"`
+	Score int         `json:"score"`
+	Title string      `json:"title"`           // Title to show: the entry's, or the matched alias
+	Alias string      `json:"alias,omitempty"` // Alias that scored best, if any
+}
+
+// Tokenize splits text into lowercase search terms on any character that
+// is not a letter or digit
+func Tokenize(s string) []string {
+	return strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// Score rates how well an entry matches the query tokens. Every token must
+// match the title, a tag or a ref; otherwise the score is 0. Exact title
+// words rank above prefixes, which rank above substrings, tag matches and
+// ref matches.
+func Score(e SearchEntry, tokens []string) int {
+	if len(tokens) == 0 {
+		return 0
+	}
+
+	titleTokens := Tokenize(e.Title)
+	title := strings.ToLower(e.Title)
+
+	total := 0
+	for _, q := range tokens {
+		best := 0
+		for _, t := range titleTokens {
+			switch {
+			case t == q:
+				best = max(best, 10)
+			case strings.HasPrefix(t, q):
+				best = max(best, 5)
+			}
+		}
+		if best == 0 && strings.Contains(title, q) {
+			best = 2
+		}
+		for _, tag := range e.Tags {
+			if strings.ToLower(tag) == q {
+				best = max(best, 3)
+			}
+		}
+		for _, ref := range e.Refs {
+			if strings.Contains(strings.ToLower(ref), q) {
+				best = max(best, 1)
+			}
+		}
+		if best == 0 {
+			return 0
+		}
+		total += best
+	}
+
+	return total
+}
+
+// Search returns entries matching query, optionally restricted to those
+// carrying tag, ordered by descending score and then title
+func (idx *SearchIndex) Search(query string, tag string) []Result {
+	tokens := Tokenize(query)
+
+	var results []Result
+	for _, e := range idx.Entries {
+		if tag != "" && !hasTag(e, tag) {
+			continue
+		}
+		res := Result{Entry: e, Score: Score(e, tokens), Title: e.Title}
+		if idx.AliasWeight > 0 {
+			for _, alias := range e.Aliases {
+				score := Score(SearchEntry{Title: alias, Tags: e.Tags}, tokens)
+				if weighted := int(math.Round(float64(score) * idx.AliasWeight)); weighted > res.Score {
+					res.Score = weighted
+					res.Alias = alias
+				}
+			}
+		}
+		if res.Alias != "" && idx.AliasDisplay == AliasDisplayMatched {
+			res.Title = res.Alias
+		}
+		if res.Score > 0 {
+			results = append(results, res)
+		}
+	}
+
+	sort.SliceStable(results, func(i, j int) bool {
+		if results[i].Score != results[j].Score {
+			return results[i].Score > results[j].Score
+		}
+		return results[i].Title < results[j].Title
+	})
+
+	return results
+}
+
+// hasTag reports whether an entry carries tag
+func hasTag(e SearchEntry, tag string) bool {
+	for _, t := range e.Tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,153 @@
+package search
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"slices"
+	"testing"
+
+	"github.com/nicehiro/org-roam-web/internal/db"
+)
+
+// entryIDs returns the IDs of the index's entries, in order
+func entryIDs(idx *SearchIndex) []string {
+	var ids []string
+	for _, e := range idx.Entries {
+		ids = append(ids, e.ID)
+	}
+	return ids
+}
+
+func TestBuildIndexOrder(t *testing.T) {
+	nodes := []db.Node{
+		{ID: "c3", Title: "gamma"},
+		{ID: "b2", Title: "Beta"},
+		{ID: "z9", Title: "Alpha"},
+		{ID: "a1", Title: "Alpha"},
+		{ID: "d4", Title: "alpha"},
+	}
+	want := []string{"a1", "d4", "z9", "b2", "c3"}
+
+	tests := []struct {
+		name  string
+		order []int // Indexes into nodes
+	}{
+		{"as listed", []int{0, 1, 2, 3, 4}},
+		{"reversed", []int{4, 3, 2, 1, 0}},
+		{"shuffled", []int{2, 0, 4, 1, 3}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var input []db.Node
+			for _, i := range tt.order {
+				input = append(input, nodes[i])
+			}
+			if got := entryIDs(BuildIndex(input, nil)); !slices.Equal(got, want) {
+				t.Errorf("entries %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestSortAfterUpsert(t *testing.T) {
+	idx := BuildIndex([]db.Node{{ID: "a1", Title: "Alpha"}, {ID: "b2", Title: "Beta"}}, nil)
+	idx.Upsert(SearchEntry{ID: "a1", Title: "Zeta"})
+	idx.Upsert(SearchEntry{ID: "c3", Title: "Aardvark"})
+	idx.Sort()
+	if got, want := entryIDs(idx), []string{"c3", "b2", "a1"}; !slices.Equal(got, want) {
+		t.Errorf("entries %v, want %v", got, want)
+	}
+}
+
+func TestToCompactJSON(t *testing.T) {
+	var nodes []db.Node
+	tags := make(map[string][]string)
+	for _, id := range []string{"a1", "b2", "c3", "d4"} {
+		nodes = append(nodes, db.Node{ID: id, Title: "Note-" + id})
+		tags[id] = []string{"go", "web"}
+	}
+	idx := BuildIndex(nodes, tags)
+	idx.SetURLs(func(id string) string { return "/notes/" + id + ".html" })
+
+	pretty, err := idx.ToJSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+	compact, err := idx.ToCompactJSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(compact) >= len(pretty) {
+		t.Errorf("compact JSON is %d bytes, pretty %d", len(compact), len(pretty))
+	}
+	if bytes.ContainsAny(compact, "\n ") {
+		t.Errorf("compact JSON has whitespace: %s", compact)
+	}
+	var a, b SearchIndex
+	if err := json.Unmarshal(pretty, &a); err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(compact, &b); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(a, b) {
+		t.Errorf("compact JSON decodes to %+v, pretty to %+v", b, a)
+	}
+
+	again, err := idx.ToCompactJSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(compact, again) {
+		t.Error("compact JSON differs between calls")
+	}
+}
+
+func TestSearchAliases(t *testing.T) {
+	entries := []SearchEntry{
+		{ID: "k1", Title: "Kubernetes", Aliases: []string{"k8s", "kube"}},
+		{ID: "c1", Title: "k8s cheatsheet"},
+		{ID: "d1", Title: "Docker"},
+	}
+	tests := []struct {
+		name      string
+		display   string
+		weight    float64
+		query     string
+		want      []string // IDs in result order
+		wantTitle string   // Title shown for k1
+		wantScore int      // Score of k1
+	}{
+		{"canonical title", AliasDisplayCanonical, 0.8, "k8s", []string{"c1", "k1"}, "Kubernetes", 8},
+		{"matched alias", AliasDisplayMatched, 0.8, "k8s", []string{"c1", "k1"}, "k8s", 8},
+		{"alias outranks title", AliasDisplayMatched, 1.5, "k8s", []string{"k1", "c1"}, "k8s", 15},
+		{"aliases ignored", AliasDisplayMatched, 0, "k8s", []string{"c1"}, "", 0},
+		{"title beats alias on same note", AliasDisplayMatched, 0.8, "kubernetes", []string{"k1"}, "Kubernetes", 10},
+		{"tie keeps the title", AliasDisplayMatched, 1, "kub", []string{"k1"}, "Kubernetes", 5},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			idx := &SearchIndex{Entries: entries, AliasDisplay: tt.display, AliasWeight: tt.weight}
+			results := idx.Search(tt.query, "")
+
+			var ids []string
+			for _, res := range results {
+				ids = append(ids, res.Entry.ID)
+				if res.Entry.ID != "k1" {
+					continue
+				}
+				if res.Title != tt.wantTitle {
+					t.Errorf("k1 shown as %q, want %q", res.Title, tt.wantTitle)
+				}
+				if res.Score != tt.wantScore {
+					t.Errorf("k1 score %d, want %d", res.Score, tt.wantScore)
+				}
+			}
+			if !slices.Equal(ids, tt.want) {
+				t.Errorf("Search(%q) = %v, want %v", tt.query, ids, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,184 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// liveReloadPath is the Server-Sent Events endpoint served pages listen on
+const liveReloadPath = "/__livereload"
+
+// reloadDelay coalesces reload events from rebuilds in quick succession
+const reloadDelay = 100 * time.Millisecond
+
+// reloadHub tells connected browsers to reload after a rebuild
+type reloadHub struct {
+	mu      sync.Mutex
+	clients map[chan struct{}]bool
+	timer   *time.Timer
+	done    chan struct{} // Closed when the server shuts down
+}
+
+func newReloadHub() *reloadHub {
+	return &reloadHub{
+		clients: make(map[chan struct{}]bool),
+		done:    make(chan struct{}),
+	}
+}
+
+// notify schedules a reload event for every client; calls within
+// reloadDelay of each other send a single event
+func (h *reloadHub) notify() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.timer != nil {
+		h.timer.Stop()
+	}
+	h.timer = time.AfterFunc(reloadDelay, h.broadcast)
+}
+
+func (h *reloadHub) broadcast() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for c := range h.clients {
+		// Clients with an event pending reload once; drop the duplicate
+		select {
+		case c <- struct{}{}:
+		default:
+		}
+	}
+}
+
+func (h *reloadHub) subscribe() chan struct{} {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	c := make(chan struct{}, 1)
+	h.clients[c] = true
+	return c
+}
+
+func (h *reloadHub) unsubscribe(c chan struct{}) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	delete(h.clients, c)
+}
+
+// close ends all event streams so the server can shut down
+func (h *reloadHub) close() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.timer != nil {
+		h.timer.Stop()
+	}
+	select {
+	case <-h.done:
+	default:
+		close(h.done)
+	}
+}
+
+// ServeHTTP streams a "reload" event each time the site is rebuilt
+func (h *reloadHub) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	c := h.subscribe()
+	defer h.unsubscribe(c)
+	for {
+		select {
+		case <-c:
+			fmt.Fprint(w, "event: reload\ndata: {}\n\n")
+			flusher.Flush()
+		case <-req.Context().Done():
+			return
+		case <-h.done:
+			return
+		}
+	}
+}
+
+// liveReloadHandler serves the reload event stream and adds the script
+// listening to it to HTML responses from h. prefix is the path the site is
+// exposed under, so the script reaches the stream through a reverse proxy.
+func liveReloadHandler(hub *reloadHub, prefix string, h http.Handler) http.Handler {
+	script := []byte(`<script>new EventSource(` + strconv.Quote(prefix+liveReloadPath) +
+		`).addEventListener("reload", () => location.reload());</script>`)
+
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.URL.Path == liveReloadPath {
+			hub.ServeHTTP(w, req)
+			return
+		}
+		if !isPageRequest(req) {
+			h.ServeHTTP(w, req)
+			return
+		}
+
+		// A partial page can't be patched; always send the whole page
+		req.Header.Del("Range")
+		iw := &injectWriter{ResponseWriter: w}
+		h.ServeHTTP(iw, req)
+		iw.finish(script)
+	})
+}
+
+// injectWriter holds back HTML bodies so a script can be added before
+// </body>. Other responses pass through unchanged.
+type injectWriter struct {
+	http.ResponseWriter
+	status  int
+	html    bool
+	started bool
+	buf     bytes.Buffer
+}
+
+func (w *injectWriter) WriteHeader(status int) {
+	if w.started {
+		return
+	}
+	w.started = true
+	w.status = status
+	w.html = strings.HasPrefix(w.Header().Get("Content-Type"), "text/html")
+	if !w.html {
+		w.ResponseWriter.WriteHeader(status)
+	}
+}
+
+func (w *injectWriter) Write(p []byte) (int, error) {
+	if !w.started {
+		w.WriteHeader(http.StatusOK)
+	}
+	if !w.html {
+		return w.ResponseWriter.Write(p)
+	}
+	return w.buf.Write(p)
+}
+
+// finish writes a held-back HTML body with script inserted before the
+// closing body tag, or appended if there is none
+func (w *injectWriter) finish(script []byte) {
+	if !w.html {
+		return
+	}
+	body := w.buf.Bytes()
+	if i := bytes.LastIndex(body, []byte("</body>")); i >= 0 {
+		body = append(body[:i:i], append(script, body[i:]...)...)
+	} else {
+		body = append(body, script...)
+	}
+	w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+	w.ResponseWriter.WriteHeader(w.status)
+	w.ResponseWriter.Write(body)
+}
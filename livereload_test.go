@@ -0,0 +1,130 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLiveReloadHandler(t *testing.T) {
+	dir := t.TempDir()
+	png := []byte("\x89PNG\r\n\x1a\n</body>\x00\xff")
+	files := map[string][]byte{
+		"index.html":  []byte("<html><body><p>Home</p></body></html>"),
+		"bare.html":   []byte("<p>No body tag</p>"),
+		"image.png":   png,
+		"graph.json":  []byte(`{"nodes":[],"html":"</body>"}`),
+		"script.html": []byte("<body><p>Two</p></body><!-- </body> --></html>"),
+	}
+	for name, data := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), data, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	script := `<script>new EventSource("/__livereload").addEventListener("reload", () => location.reload());</script>`
+
+	tests := []struct {
+		name       string
+		path       string
+		rangeHdr   string
+		wantStatus int
+		wantBody   string
+	}{
+		{"page", "/", "", http.StatusOK, "<html><body><p>Home</p>" + script + "</body></html>"},
+		{"page without body tag", "/bare.html", "", http.StatusOK, "<p>No body tag</p>" + script},
+		{"last closing body tag", "/script.html", "", http.StatusOK, "<body><p>Two</p></body><!-- " + script + "</body> --></html>"},
+		{"range on a page", "/", "bytes=0-5", http.StatusOK, "<html><body><p>Home</p>" + script + "</body></html>"},
+		{"image", "/image.png", "", http.StatusOK, string(png)},
+		{"json", "/graph.json", "", http.StatusOK, string(files["graph.json"])},
+		{"range on an image", "/image.png", "bytes=0-3", http.StatusPartialContent, string(png[:4])},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := liveReloadHandler(newReloadHub(), "", http.FileServer(http.Dir(dir)))
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			if tt.rangeHdr != "" {
+				req.Header.Set("Range", tt.rangeHdr)
+			}
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("GET %s: status %d, want %d", tt.path, rec.Code, tt.wantStatus)
+			}
+			if got := rec.Body.String(); got != tt.wantBody {
+				t.Errorf("GET %s: body %q, want %q", tt.path, got, tt.wantBody)
+			}
+			if got, want := rec.Header().Get("Content-Length"), strconv.Itoa(rec.Body.Len()); got != want {
+				t.Errorf("GET %s: Content-Length %s, body of %s bytes", tt.path, got, want)
+			}
+		})
+	}
+}
+
+// subscribers returns the number of clients of hub
+func subscribers(hub *reloadHub) int {
+	hub.mu.Lock()
+	defer hub.mu.Unlock()
+	return len(hub.clients)
+}
+
+func TestReloadHubNotify(t *testing.T) {
+	tests := []struct {
+		name       string
+		bursts     []int // Calls to notify, with bursts more than reloadDelay apart
+		wantEvents int
+	}{
+		{"none", nil, 0},
+		{"single", []int{1}, 1},
+		{"coalesced", []int{5}, 1},
+		{"separate bursts", []int{3, 2}, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hub := newReloadHub()
+			srv := httptest.NewServer(liveReloadHandler(hub, "", http.NotFoundHandler()))
+			defer srv.Close()
+
+			resp, err := http.Get(srv.URL + liveReloadPath)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer resp.Body.Close()
+			if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+				t.Fatalf("Content-Type %q, want text/event-stream", ct)
+			}
+			for deadline := time.Now().Add(time.Second); subscribers(hub) == 0; {
+				if time.Now().After(deadline) {
+					t.Fatal("client never subscribed")
+				}
+				time.Sleep(time.Millisecond)
+			}
+
+			for _, n := range tt.bursts {
+				for range n {
+					hub.notify()
+				}
+				time.Sleep(3 * reloadDelay)
+			}
+			hub.close()
+
+			body, err := io.ReadAll(resp.Body)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := bytes.Count(body, []byte("event: reload\n")); got != tt.wantEvents {
+				t.Errorf("%d reload events, want %d:\n%s", got, tt.wantEvents, body)
+			}
+			if strings.Count(string(body), "data: {}\n\n") != tt.wantEvents {
+				t.Errorf("events without data:\n%s", body)
+			}
+		})
+	}
+}
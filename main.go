@@ -1,16 +1,22 @@
 package main
 
 import (
+	"context"
+	"encoding/json"
 	"flag"
 	"fmt"
 	"log"
 	"net/http"
 	"os"
+	"os/signal"
 	"path/filepath"
+	"strings"
+	"syscall"
 	"time"
 
 	"github.com/fsnotify/fsnotify"
 	"github.com/nicehiro/org-roam-web/internal/config"
+	"github.com/nicehiro/org-roam-web/internal/linkcheck"
 	"github.com/nicehiro/org-roam-web/internal/render"
 )
 
@@ -23,10 +29,22 @@ func main() {
 	}
 
 	switch os.Args[1] {
+	case "init":
+		initCmd(os.Args[2:])
 	case "build":
 		buildCmd(os.Args[2:])
 	case "serve":
 		serveCmd(os.Args[2:])
+	case "search":
+		searchCmd(os.Args[2:])
+	case "export-note":
+		exportNoteCmd(os.Args[2:])
+	case "validate-links":
+		validateLinksCmd(os.Args[2:])
+	case "diff":
+		diffCmd(os.Args[2:])
+	case "clean":
+		cleanCmd(os.Args[2:])
 	case "version":
 		fmt.Printf("org-roam-web %s\n", version)
 	case "help", "-h", "--help":
@@ -45,24 +63,66 @@ Usage:
   org-roam-web <command> [options]
 
 Commands:
+  init      Write a commented config.yaml
   build     Build the static site
   serve     Start development server with live reload
+  search    Search notes from the terminal
+  export-note  Export a note and its neighbors as one HTML file
+  validate-links  Check external URLs in notes for dead links
+  diff      Compare two build output directories
+  clean     Remove the output directory
   version   Print version information
   help      Print this help message
 
+Init Options:
+  -config string    Path of the config file to write (default "config.yaml")
+  -force            Overwrite an existing config file
+
 Build Options:
   -config string    Path to config file (default "config.yaml")
   -roam-dir string  Path to org-roam directory
   -db-path string   Path to org-roam database
   -output string    Output directory (default "dist")
+  -clean            Empty the output directory before building
+  -report-orphans   List published notes without links to or from other notes
+  -fail-on-broken-links  Exit non-zero if a note links to an ID no note has
 
 Serve Options:
   -config string    Path to config file (default "config.yaml")
   -port int         Server port (default 8080)
+  -base-path string Path prefix when served behind a reverse proxy
+
+Search Options:
+  -config string    Path to config file (default "config.yaml")
+  -tag string       Only return notes with this tag
+  -status string    Only return notes with this STATUS
+  -json             Print results as JSON
+  -limit int        Maximum number of results (default 20)
+
+Export Note Options:
+  -config string    Path to config file (default "config.yaml")
+  -depth int        Include notes up to this many links away (default 1)
+  -output string    Directory for <id>-bundle.html (default ".")
+
+Validate Links Options:
+  -config string    Path to config file (default "config.yaml")
+  -concurrency int  Parallel requests (default 8)
+  -timeout duration Per-request timeout (default 10s)
+  -skip string      Comma-separated URL prefixes to skip
+  -cache string     Cache file for results ("" disables, default ".linkcheck.json")
+  -cache-ttl duration  Reuse successful results this long (default 24h)
+
+Clean Options:
+  -config string    Path to config file (default "config.yaml")
+  -output string    Output directory
+  -yes              Don't ask for confirmation
 
 Examples:
   org-roam-web build --config config.yaml
   org-roam-web serve --port 3000
+  org-roam-web search -tag emacs org mode
+  org-roam-web export-note 20240101T120000 -depth 2
+  org-roam-web diff ./dist-old ./dist
   org-roam-web build --roam-dir ~/Documents/roam --output ./dist`)
 }
 
@@ -72,6 +132,9 @@ func buildCmd(args []string) {
 	roamDir := fs.String("roam-dir", "", "Path to org-roam directory")
 	dbPath := fs.String("db-path", "", "Path to org-roam database")
 	outputDir := fs.String("output", "", "Output directory")
+	reportOrphans := fs.Bool("report-orphans", false, "List published notes without links to or from other notes")
+	failOnBroken := fs.Bool("fail-on-broken-links", false, "Exit non-zero if a note links to an ID no note has")
+	clean := fs.Bool("clean", false, "Empty the output directory before building")
 	fs.Parse(args)
 
 	cfg, err := config.Load(*configPath)
@@ -91,22 +154,23 @@ func buildCmd(args []string) {
 	}
 
 	// Make paths absolute
-	cwd, err := os.Getwd()
-	if err != nil {
-		log.Fatalf("Failed to get working directory: %v", err)
-	}
-	if !filepath.IsAbs(cfg.Paths.RoamDir) {
-		cfg.Paths.RoamDir = filepath.Join(cwd, cfg.Paths.RoamDir)
-	}
-	if !filepath.IsAbs(cfg.Paths.DBPath) {
-		cfg.Paths.DBPath = filepath.Join(cfg.Paths.RoamDir, filepath.Base(cfg.Paths.DBPath))
-	}
+	resolvePaths(cfg)
 
 	fmt.Printf("Building site...\n")
 	fmt.Printf("  Roam dir: %s\n", cfg.Paths.RoamDir)
 	fmt.Printf("  Database: %s\n", cfg.Paths.DBPath)
 	fmt.Printf("  Output:   %s\n", cfg.Paths.OutputDir)
 
+	if *clean {
+		dir, err := cleanTarget(cfg)
+		if err != nil {
+			log.Fatalf("Refusing to clean: %v", err)
+		}
+		if err := emptyDir(dir); err != nil {
+			log.Fatalf("Failed to clean output directory: %v", err)
+		}
+	}
+
 	r, err := render.NewRenderer(cfg)
 	if err != nil {
 		log.Fatalf("Failed to create renderer: %v", err)
@@ -117,6 +181,20 @@ func buildCmd(args []string) {
 		log.Fatalf("Failed to build site: %v", err)
 	}
 
+	if *reportOrphans {
+		orphans := r.Orphans()
+		fmt.Printf("Orphans: %d\n", len(orphans))
+		for _, n := range orphans {
+			fmt.Printf("  %s (%s)\n", n.Title, n.ID)
+		}
+	}
+
+	if *failOnBroken {
+		if missing := r.MissingLinks(); missing > 0 {
+			log.Fatalf("Found %d broken links", missing)
+		}
+	}
+
 	fmt.Printf("Done in %v\n", time.Since(start).Round(time.Millisecond))
 }
 
@@ -125,31 +203,46 @@ func serveCmd(args []string) {
 	configPath := fs.String("config", "config.yaml", "Path to config file")
 	port := fs.Int("port", 8080, "Server port")
 	roamDir := fs.String("roam-dir", "", "Path to org-roam directory")
+	basePath := fs.String("base-path", "", "Path prefix when served behind a reverse proxy")
+	healthPath := fs.String("health-path", "/healthz", "Path of the health check endpoint (empty to disable)")
 	fs.Parse(args)
 
-	cfg, err := config.Load(*configPath)
-	if err != nil {
-		log.Fatalf("Failed to load config: %v", err)
+	prefix := ""
+	if *basePath != "" {
+		// Links must carry the prefix the proxy exposes the site under
+		prefix = "/" + strings.Trim(*basePath, "/")
 	}
-
-	if *roamDir != "" {
-		cfg.Paths.RoamDir = *roamDir
+	loadConfig := func() (*config.Config, error) {
+		cfg, err := config.Load(*configPath)
+		if err != nil {
+			return nil, err
+		}
+		if *roamDir != "" {
+			cfg.Paths.RoamDir = *roamDir
+		}
+		if prefix != "" {
+			cfg.Site.BaseURL = prefix
+		}
+		// The dev server compresses nothing and rebuilds often, so the
+		// copies would only slow rebuilds down
+		cfg.Display.Precompress.Enabled = false
+		// Make paths absolute
+		resolvePaths(cfg)
+		return cfg, nil
 	}
 
-	// Make paths absolute
-	cwd, err := os.Getwd()
+	cfg, err := loadConfig()
 	if err != nil {
-		log.Fatalf("Failed to get working directory: %v", err)
-	}
-	if !filepath.IsAbs(cfg.Paths.RoamDir) {
-		cfg.Paths.RoamDir = filepath.Join(cwd, cfg.Paths.RoamDir)
+		log.Fatalf("Failed to load config: %v", err)
 	}
-	if !filepath.IsAbs(cfg.Paths.DBPath) {
-		cfg.Paths.DBPath = filepath.Join(cfg.Paths.RoamDir, filepath.Base(cfg.Paths.DBPath))
+	configFile, err := filepath.Abs(*configPath)
+	if err != nil {
+		log.Fatalf("Failed to resolve config path: %v", err)
 	}
 
 	// Initial build
-	rebuild(cfg)
+	state := &buildState{changed: make(map[string]bool), reload: newReloadHub()}
+	rebuild(cfg, state, true)
 
 	// Set up file watcher
 	watcher, err := fsnotify.NewWatcher()
@@ -158,65 +251,295 @@ func serveCmd(args []string) {
 	}
 	defer watcher.Close()
 
-	// Watch org files directory
-	if err := watcher.Add(cfg.Paths.RoamDir); err != nil {
-		log.Printf("Warning: Failed to watch roam directory: %v", err)
-	}
+	// Watch the org files, the database and the config file
+	watchPaths(watcher, cfg, configFile)
 
 	// Watch for changes
+	changes := &changeWatcher{
+		watcher:    watcher,
+		configFile: configFile,
+		loadConfig: loadConfig,
+		state:      state,
+		debounce:   500 * time.Millisecond,
+		rebuild: func(cfg *config.Config, full bool) {
+			go rebuild(cfg, state, full)
+		},
+	}
+	watching := make(chan struct{})
 	go func() {
-		var debounceTimer *time.Timer
-		for {
-			select {
-			case event, ok := <-watcher.Events:
-				if !ok {
-					return
-				}
-				// Only rebuild on write events for .org files
-				if event.Has(fsnotify.Write) && filepath.Ext(event.Name) == ".org" {
-					// Debounce rebuilds
-					if debounceTimer != nil {
-						debounceTimer.Stop()
-					}
-					debounceTimer = time.AfterFunc(500*time.Millisecond, func() {
-						fmt.Printf("\nFile changed: %s\n", filepath.Base(event.Name))
-						rebuild(cfg)
-					})
-				}
-			case err, ok := <-watcher.Errors:
-				if !ok {
-					return
-				}
-				log.Printf("Watcher error: %v", err)
-			}
-		}
+		defer close(watching)
+		changes.run(cfg)
 	}()
 
 	// Start HTTP server
 	addr := fmt.Sprintf(":%d", *port)
-	fmt.Printf("\nServing at http://localhost%s\n", addr)
+	fmt.Printf("\nServing at http://localhost%s%s/\n", addr, prefix)
 	fmt.Printf("Press Ctrl+C to stop\n\n")
 
-	http.Handle("/", http.FileServer(http.Dir(cfg.Paths.OutputDir)))
-	if err := http.ListenAndServe(addr, nil); err != nil {
+	mux := http.NewServeMux()
+	if *healthPath != "" {
+		mux.Handle("/"+strings.Trim(*healthPath, "/"), healthHandler())
+	}
+	mux.Handle("/", siteHandler(state, prefix, cfg.Paths.OutputDir))
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+	srv := &http.Server{Addr: addr, Handler: mux}
+	srv.RegisterOnShutdown(state.reload.close)
+	if err := serveUntilDone(ctx, srv); err != nil {
 		log.Fatalf("Server error: %v", err)
 	}
+
+	// Stop watching, then let a rebuild in progress finish writing
+	watcher.Close()
+	<-watching
+	state.mu.Lock()
+	fmt.Println("Stopped")
 }
 
-func rebuild(cfg *config.Config) {
+func searchCmd(args []string) {
+	fs := flag.NewFlagSet("search", flag.ExitOnError)
+	configPath := fs.String("config", "config.yaml", "Path to config file")
+	roamDir := fs.String("roam-dir", "", "Path to org-roam directory")
+	dbPath := fs.String("db-path", "", "Path to org-roam database")
+	tag := fs.String("tag", "", "Only return notes with this tag")
+	status := fs.String("status", "", "Only return notes with this STATUS")
+	jsonOut := fs.Bool("json", false, "Print results as JSON")
+	limit := fs.Int("limit", 20, "Maximum number of results")
+	fs.Parse(args)
+
+	query := strings.Join(fs.Args(), " ")
+	if query == "" {
+		log.Fatalf("Usage: org-roam-web search [options] <query>")
+	}
+
+	cfg, err := config.Load(*configPath)
+	if err != nil {
+		log.Fatalf("Failed to load config: %v", err)
+	}
+
+	if *roamDir != "" {
+		cfg.Paths.RoamDir = *roamDir
+	}
+	if *dbPath != "" {
+		cfg.Paths.DBPath = *dbPath
+	}
+
+	resolvePaths(cfg)
+
+	r, err := render.NewRenderer(cfg)
+	if err != nil {
+		log.Fatalf("Failed to create renderer: %v", err)
+	}
+
+	index, err := r.SearchIndex()
+	if err != nil {
+		log.Fatalf("Failed to build search index: %v", err)
+	}
+
+	results := index.Search(query, *tag)
+	if *status != "" {
+		filtered := results[:0]
+		for _, res := range results {
+			if strings.EqualFold(res.Entry.Status, *status) {
+				filtered = append(filtered, res)
+			}
+		}
+		results = filtered
+	}
+	if *limit > 0 && len(results) > *limit {
+		results = results[:*limit]
+	}
+
+	if *jsonOut {
+		data, err := json.MarshalIndent(results, "", "  ")
+		if err != nil {
+			log.Fatalf("Failed to encode results: %v", err)
+		}
+		fmt.Println(string(data))
+		return
+	}
+
+	for _, res := range results {
+		fmt.Printf("%-40s  %s\n", res.Title, res.Entry.ID)
+	}
+}
+
+func exportNoteCmd(args []string) {
+	fs := flag.NewFlagSet("export-note", flag.ExitOnError)
+	configPath := fs.String("config", "config.yaml", "Path to config file")
+	roamDir := fs.String("roam-dir", "", "Path to org-roam directory")
+	dbPath := fs.String("db-path", "", "Path to org-roam database")
+	depth := fs.Int("depth", 1, "Include notes up to this many links away")
+	outputDir := fs.String("output", ".", "Directory for the bundle file")
+	fs.Parse(args)
+
+	// Accept flags after the note ID as well as before it
+	if fs.NArg() == 0 {
+		log.Fatalf("Usage: org-roam-web export-note [options] <id>")
+	}
+	id := fs.Arg(0)
+	fs.Parse(fs.Args()[1:])
+
+	cfg, err := config.Load(*configPath)
+	if err != nil {
+		log.Fatalf("Failed to load config: %v", err)
+	}
+
+	if *roamDir != "" {
+		cfg.Paths.RoamDir = *roamDir
+	}
+	if *dbPath != "" {
+		cfg.Paths.DBPath = *dbPath
+	}
+
+	resolvePaths(cfg)
+
+	r, err := render.NewRenderer(cfg)
+	if err != nil {
+		log.Fatalf("Failed to create renderer: %v", err)
+	}
+
+	outPath := filepath.Join(*outputDir, id+"-bundle.html")
+	if err := r.ExportBundle(id, *depth, outPath); err != nil {
+		log.Fatalf("Failed to export note: %v", err)
+	}
+
+	fmt.Printf("Wrote %s\n", outPath)
+}
+
+func validateLinksCmd(args []string) {
+	fs := flag.NewFlagSet("validate-links", flag.ExitOnError)
+	configPath := fs.String("config", "config.yaml", "Path to config file")
+	roamDir := fs.String("roam-dir", "", "Path to org-roam directory")
+	dbPath := fs.String("db-path", "", "Path to org-roam database")
+	concurrency := fs.Int("concurrency", 8, "Parallel requests")
+	timeout := fs.Duration("timeout", 10*time.Second, "Per-request timeout")
+	skip := fs.String("skip", "", "Comma-separated URL prefixes to skip")
+	cachePath := fs.String("cache", ".linkcheck.json", "Cache file for results (empty disables caching)")
+	cacheTTL := fs.Duration("cache-ttl", 24*time.Hour, "Reuse successful results this long")
+	fs.Parse(args)
+
+	cfg, err := config.Load(*configPath)
+	if err != nil {
+		log.Fatalf("Failed to load config: %v", err)
+	}
+
+	if *roamDir != "" {
+		cfg.Paths.RoamDir = *roamDir
+	}
+	if *dbPath != "" {
+		cfg.Paths.DBPath = *dbPath
+	}
+
+	resolvePaths(cfg)
+
+	r, err := render.NewRenderer(cfg)
+	if err != nil {
+		log.Fatalf("Failed to create renderer: %v", err)
+	}
+
+	sources, err := r.ExternalLinks()
+	if err != nil {
+		log.Fatalf("Failed to collect links: %v", err)
+	}
+
+	checker := linkcheck.NewChecker(*timeout, *concurrency)
+	checker.CacheTTL = *cacheTTL
+	if *skip != "" {
+		checker.Skip = strings.Split(*skip, ",")
+	}
+	if *cachePath != "" {
+		cache, err := linkcheck.LoadCache(*cachePath)
+		if err != nil {
+			log.Fatalf("Failed to load cache: %v", err)
+		}
+		checker.Cache = cache
+	}
+
+	urls := make([]string, 0, len(sources))
+	for u := range sources {
+		urls = append(urls, u)
+	}
+	results := checker.Check(urls)
+
+	if *cachePath != "" {
+		if err := linkcheck.SaveCache(*cachePath, checker.Cache); err != nil {
+			log.Printf("Failed to save cache: %v", err)
+		}
+	}
+
+	broken := 0
+	for _, res := range results {
+		if res.OK() {
+			continue
+		}
+		broken++
+		if res.Error != "" {
+			fmt.Printf("ERR  %s\n     %s\n", res.URL, res.Error)
+		} else {
+			fmt.Printf("%d  %s\n", res.Status, res.URL)
+		}
+		fmt.Printf("     in: %s\n", strings.Join(sources[res.URL], ", "))
+	}
+
+	fmt.Printf("Checked %d URLs, %d broken\n", len(results), broken)
+	if broken > 0 {
+		os.Exit(1)
+	}
+}
+
+// resolvePaths makes the roam dir absolute and resolves the database
+// path relative to it
+func resolvePaths(cfg *config.Config) {
+	cwd, err := os.Getwd()
+	if err != nil {
+		log.Fatalf("Failed to get working directory: %v", err)
+	}
+	if !filepath.IsAbs(cfg.Paths.RoamDir) {
+		cfg.Paths.RoamDir = filepath.Join(cwd, cfg.Paths.RoamDir)
+	}
+	if !filepath.IsAbs(cfg.Paths.DBPath) {
+		cfg.Paths.DBPath = filepath.Join(cfg.Paths.RoamDir, filepath.Base(cfg.Paths.DBPath))
+	}
+	if cfg.Paths.Bibliography != "" && !filepath.IsAbs(cfg.Paths.Bibliography) {
+		cfg.Paths.Bibliography = filepath.Join(cfg.Paths.RoamDir, cfg.Paths.Bibliography)
+	}
+}
+
+// rebuild builds the site for serve. full rebuilds the search index from
+// scratch instead of updating it for the changed files.
+func rebuild(cfg *config.Config, state *buildState, full bool) {
+	state.mu.Lock()
+	defer state.mu.Unlock()
+	// Open pages reload to show the result, including a build error
+	defer state.reload.notify()
+
 	fmt.Printf("Building...")
 	start := time.Now()
 
 	r, err := render.NewRenderer(cfg)
 	if err != nil {
 		log.Printf("Failed to create renderer: %v", err)
+		state.setError(fmt.Errorf("failed to create renderer: %w", err))
 		return
 	}
 
+	// Reuse the previous search index, updating only what changed
+	changed := state.takeChanged()
+	if state.searchIndex != nil && !full {
+		r.UseIncremental(state.searchIndex, changed)
+	}
+
 	if err := r.Build(); err != nil {
+		// The next build must still update the notes this one didn't
+		state.restoreChanged(changed)
 		log.Printf("Failed to build: %v", err)
+		state.setError(err)
 		return
 	}
+	state.searchIndex = r.LastSearchIndex()
+	state.setError(nil)
 
 	fmt.Printf(" done in %v\n", time.Since(start).Round(time.Millisecond))
 }
@@ -0,0 +1,369 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"html/template"
+	"io/fs"
+	"log"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/nicehiro/org-roam-web/internal/config"
+	"github.com/nicehiro/org-roam-web/internal/render"
+	"github.com/nicehiro/org-roam-web/internal/search"
+)
+
+// buildState is carried between rebuilds in serve mode so that each build
+// only redoes the work affected by the files that changed
+type buildState struct {
+	mu          sync.Mutex // Serializes rebuilds
+	searchIndex *search.SearchIndex
+
+	changedMu sync.Mutex
+	changed   map[string]bool
+
+	errMu   sync.Mutex
+	lastErr error // Set while the most recent build has failed
+
+	reload *reloadHub // Tells open pages to reload after each build
+}
+
+// setError records the outcome of the latest build; nil clears the error
+func (s *buildState) setError(err error) {
+	s.errMu.Lock()
+	defer s.errMu.Unlock()
+	s.lastErr = err
+}
+
+// buildError returns the error of the latest build, or nil if it succeeded
+func (s *buildState) buildError() error {
+	s.errMu.Lock()
+	defer s.errMu.Unlock()
+	return s.lastErr
+}
+
+var buildErrorPage = template.Must(template.New("error").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="UTF-8">
+<meta http-equiv="refresh" content="2">
+<title>Build failed</title>
+<style>
+  body { margin: 0; padding: 2rem; background: #1a1a1a; color: #eee; font-family: system-ui, sans-serif; }
+  h1 { color: #ff6b6b; font-size: 1.25rem; }
+  pre { white-space: pre-wrap; background: #2a1a1a; border: 1px solid #ff6b6b; border-radius: 0.5rem; padding: 1rem; }
+  p { color: #999; font-size: 0.875rem; }
+</style>
+</head>
+<body>
+<h1>Build failed</h1>
+<pre>{{.}}</pre>
+<p>Showing this page until the next successful build. It reloads automatically.</p>
+</body>
+</html>
+`))
+
+// errorPageHandler serves an error page in place of HTML pages while the
+// latest build has failed, so a stale page isn't mistaken for the result of
+// the last edit. Other files are served by h as usual.
+func errorPageHandler(state *buildState, h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		err := state.buildError()
+		if err == nil || !isPageRequest(req) {
+			h.ServeHTTP(w, req)
+			return
+		}
+
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Header().Set("Cache-Control", "no-store")
+		w.WriteHeader(http.StatusInternalServerError)
+		buildErrorPage.Execute(w, err.Error())
+	})
+}
+
+// isPageRequest reports whether req asks for an HTML page
+func isPageRequest(req *http.Request) bool {
+	if strings.HasSuffix(req.URL.Path, "/") {
+		return true
+	}
+	switch path.Ext(req.URL.Path) {
+	case ".html", "":
+		return true
+	}
+	return false
+}
+
+// shutdownTimeout bounds how long open requests may take to finish once the
+// server is asked to stop
+const shutdownTimeout = 5 * time.Second
+
+// healthHandler answers health checks from a process supervisor
+func healthHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.Header().Set("Cache-Control", "no-store")
+		fmt.Fprintln(w, "ok")
+	})
+}
+
+// serveUntilDone runs srv until ctx is cancelled, then shuts it down
+// gracefully. It returns nil after a clean shutdown.
+func serveUntilDone(ctx context.Context, srv *http.Server) error {
+	errc := make(chan error, 1)
+	go func() {
+		errc <- srv.ListenAndServe()
+	}()
+
+	select {
+	case err := <-errc:
+		return err
+	case <-ctx.Done():
+	}
+
+	fmt.Printf("\nShutting down...\n")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		return err
+	}
+	if err := <-errc; !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+// watchPaths adds the roam directory and its subdirectories, and the
+// directories holding the database and the config file, to watcher.
+// Directories rather than the files themselves are watched so that files
+// replaced by an atomic save keep being seen.
+func watchPaths(watcher *fsnotify.Watcher, cfg *config.Config, configFile string) {
+	watchTree(watcher, cfg, cfg.Paths.RoamDir)
+	for _, dir := range []string{filepath.Dir(cfg.Paths.DBPath), filepath.Dir(configFile)} {
+		if err := watcher.Add(dir); err != nil {
+			log.Printf("Warning: Failed to watch %s: %v", dir, err)
+		}
+	}
+}
+
+// watchTree adds root and every directory below it to watcher, skipping
+// .git and the output directory
+func watchTree(watcher *fsnotify.Watcher, cfg *config.Config, root string) {
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() {
+			return nil
+		}
+		if d.Name() == ".git" || cfg.Paths.InOutputDir(path) {
+			return filepath.SkipDir
+		}
+		if err := watcher.Add(path); err != nil {
+			log.Printf("Warning: Failed to watch %s: %v", path, err)
+		}
+		return nil
+	})
+	if err != nil {
+		log.Printf("Warning: Failed to watch %s: %v", root, err)
+	}
+}
+
+// isNewDir reports whether event created a directory
+func isNewDir(event fsnotify.Event) bool {
+	if !event.Has(fsnotify.Create) {
+		return false
+	}
+	info, err := os.Stat(event.Name)
+	return err == nil && info.IsDir()
+}
+
+// changeWatcher rebuilds the site as the notes, the database or the config
+// file change
+type changeWatcher struct {
+	watcher    *fsnotify.Watcher
+	configFile string
+	loadConfig func() (*config.Config, error) // Reads configFile again
+	state      *buildState
+	debounce   time.Duration // Quiet time after the last change before a rebuild
+	rebuild    func(cfg *config.Config, full bool)
+}
+
+// run handles the watcher's events until it is closed, starting with cfg
+func (w *changeWatcher) run(cfg *config.Config) {
+	var debounceTimer *time.Timer
+	fire := make(chan struct{}, 1)
+	stop := func() {
+		if debounceTimer != nil {
+			debounceTimer.Stop()
+		}
+	}
+
+	// What changed since the last rebuild
+	var lastChanged string
+	configChanged, dbChanged := false, false
+
+	for {
+		select {
+		case event, ok := <-w.watcher.Events:
+			if !ok {
+				stop()
+				return
+			}
+			// Editors often save by writing a new file and renaming
+			// it over the old one, so creates and renames count too
+			if !event.Has(fsnotify.Write | fsnotify.Create | fsnotify.Rename) {
+				continue
+			}
+			name := filepath.Clean(event.Name)
+			if isNewDir(event) {
+				// Notes in a new folder of the roam directory reach the
+				// site through the database; only start watching it
+				if cfg.Paths.InRoamDir(name) {
+					watchTree(w.watcher, cfg, name)
+				}
+				continue
+			}
+			switch {
+			case name == w.configFile:
+				configChanged = true
+			case isDBFile(name, cfg.Paths.DBPath):
+				dbChanged = true
+			case isWatchedNote(cfg, name):
+				w.state.markChanged(name)
+			default:
+				continue
+			}
+			lastChanged = name
+
+			// Debounce rebuilds
+			stop()
+			debounceTimer = time.AfterFunc(w.debounce, func() {
+				select {
+				case fire <- struct{}{}:
+				default:
+				}
+			})
+		case <-fire:
+			fmt.Printf("\nFile changed: %s\n", filepath.Base(lastChanged))
+			if configChanged {
+				newCfg, err := w.loadConfig()
+				if err != nil {
+					log.Printf("Failed to reload config: %v", err)
+					w.state.setError(fmt.Errorf("failed to reload config: %w", err))
+					w.state.reload.notify()
+					configChanged, dbChanged = false, false
+					continue
+				}
+				if newCfg.Paths.OutputDir != cfg.Paths.OutputDir {
+					log.Printf("Warning: output_dir changes take effect when serve is restarted")
+					newCfg.Paths.OutputDir = cfg.Paths.OutputDir
+				}
+				cfg = newCfg
+				watchPaths(w.watcher, cfg, w.configFile)
+			}
+			// Notes may have changed without their files changing, so
+			// the search index is rebuilt from scratch
+			full := configChanged || dbChanged
+			configChanged, dbChanged = false, false
+			w.rebuild(cfg, full)
+		case err, ok := <-w.watcher.Errors:
+			if !ok {
+				stop()
+				return
+			}
+			log.Printf("Watcher error: %v", err)
+		}
+	}
+}
+
+// isDBFile reports whether path is the database at dbPath or one of the
+// files SQLite writes changes to before they reach it. The -shm index of a
+// WAL database changes on reads too, so it doesn't count.
+func isDBFile(path, dbPath string) bool {
+	switch path {
+	case dbPath, dbPath + "-wal", dbPath + "-journal":
+		return true
+	}
+	return false
+}
+
+// isWatchedNote reports whether a change to path affects the notes: note
+// files with one of paths.note_extensions and their sidecars, never the
+// generated output
+func isWatchedNote(cfg *config.Config, path string) bool {
+	return (cfg.Paths.IsNoteFile(path) || render.IsSidecar(path)) && !cfg.Paths.InOutputDir(path)
+}
+
+// markChanged records a file changed since the last rebuild
+func (s *buildState) markChanged(path string) {
+	s.changedMu.Lock()
+	defer s.changedMu.Unlock()
+	s.changed[path] = true
+}
+
+// takeChanged returns and clears the files changed since the last rebuild
+func (s *buildState) takeChanged() []string {
+	s.changedMu.Lock()
+	defer s.changedMu.Unlock()
+	var files []string
+	for f := range s.changed {
+		files = append(files, f)
+	}
+	s.changed = make(map[string]bool)
+	return files
+}
+
+// restoreChanged marks files as changed again after a failed rebuild,
+// keeping any changed since
+func (s *buildState) restoreChanged(files []string) {
+	s.changedMu.Lock()
+	defer s.changedMu.Unlock()
+	for _, f := range files {
+		s.changed[f] = true
+	}
+}
+
+// siteHandler serves the built site in dir under prefix, with the live
+// reload script added to pages and an error page while a build has failed
+func siteHandler(state *buildState, prefix, dir string) http.Handler {
+	site := errorPageHandler(state, http.FileServer(http.Dir(dir)))
+	return prefixHandler(prefix, liveReloadHandler(state.reload, prefix, site))
+}
+
+// prefixHandler serves h under basePath, so the dev server can sit behind a
+// reverse proxy that mounts the site at a sub-path. Requests that arrive
+// without the prefix, because the proxy already stripped it (and signals
+// so with X-Forwarded-Prefix), are served unchanged.
+func prefixHandler(basePath string, h http.Handler) http.Handler {
+	basePath = "/" + strings.Trim(basePath, "/")
+	if basePath == "/" {
+		return h
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		path := req.URL.Path
+		switch {
+		case path == basePath:
+			http.Redirect(w, req, forwardedPrefix(req)+basePath+"/", http.StatusMovedPermanently)
+			return
+		case strings.HasPrefix(path, basePath+"/"):
+			http.StripPrefix(basePath, h).ServeHTTP(w, req)
+			return
+		}
+		h.ServeHTTP(w, req)
+	})
+}
+
+// forwardedPrefix returns the path prefix a reverse proxy stripped from the
+// request, if any
+func forwardedPrefix(req *http.Request) string {
+	return strings.TrimRight(req.Header.Get("X-Forwarded-Prefix"), "/")
+}
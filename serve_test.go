@@ -0,0 +1,677 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	_ "github.com/mattn/go-sqlite3"
+
+	"github.com/nicehiro/org-roam-web/internal/config"
+)
+
+func TestIsWatchedNote(t *testing.T) {
+	root := t.TempDir()
+	cfg := config.DefaultConfig()
+	cfg.Paths.RoamDir = root
+	cfg.Paths.OutputDir = filepath.Join(root, "dist")
+
+	tests := []struct {
+		name       string
+		extensions []string
+		path       string
+		want       bool
+	}{
+		{"default org", []string{".org"}, "note.org", true},
+		{"default ignores archive", []string{".org"}, "note.org_archive", false},
+		{"configured archive", []string{".org", ".org_archive"}, "note.org_archive", true},
+		{"configured markdown without dot", []string{"md"}, "daily/note.md", true},
+		{"extension case", []string{".org"}, "NOTE.ORG", true},
+		{"unlisted extension", []string{".org", ".md"}, "notes.txt", false},
+		{"org dropped from list", []string{".md"}, "note.org", false},
+		{"sidecar", []string{".md"}, "note.org.meta.yaml", true},
+		{"output directory", []string{".html", ".org"}, "dist/notes/a1.html", false},
+		{"output directory note", []string{".org"}, "dist/note.org", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg.Paths.NoteExtensions = tt.extensions
+			if got := isWatchedNote(cfg, filepath.Join(root, tt.path)); got != tt.want {
+				t.Errorf("isWatchedNote(%s) with %v = %t, want %t", tt.path, tt.extensions, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSiteHandlerPrefix(t *testing.T) {
+	dir := t.TempDir()
+	page := "<!DOCTYPE html><html><body><p>Alpha</p></body></html>"
+	if err := os.MkdirAll(filepath.Join(dir, "notes"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	for name, content := range map[string]string{"index.html": page, "notes/a1.html": page, "graph.json": "{}"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name       string
+		prefix     string
+		path       string
+		forwarded  string // X-Forwarded-Prefix
+		wantStatus int
+		wantBody   string // Expected in the body, or the Location of a redirect
+	}{
+		{"page under prefix", "/wiki", "/wiki/notes/a1.html", "", http.StatusOK, `EventSource("/wiki/__livereload")`},
+		{"home under prefix", "/wiki", "/wiki/", "", http.StatusOK, "<p>Alpha</p>"},
+		{"prefix without slash", "/wiki", "/wiki", "", http.StatusMovedPermanently, "/wiki/"},
+		{"prefix behind outer proxy prefix", "/wiki", "/wiki", "/outer", http.StatusMovedPermanently, "/outer/wiki/"},
+		{"prefix stripped by proxy", "/wiki", "/notes/a1.html", "/wiki", http.StatusOK, `EventSource("/wiki/__livereload")`},
+		{"other file under prefix", "/wiki/", "/wiki/graph.json", "", http.StatusOK, "{}"},
+		{"missing page", "/wiki", "/wiki/notes/zz.html", "", http.StatusNotFound, ""},
+		{"no prefix", "", "/notes/a1.html", "", http.StatusOK, `EventSource("/__livereload")`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			state := &buildState{changed: make(map[string]bool), reload: newReloadHub()}
+			h := siteHandler(state, tt.prefix, dir)
+
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			if tt.forwarded != "" {
+				req.Header.Set("X-Forwarded-Prefix", tt.forwarded)
+			}
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("GET %s: status %d, want %d", tt.path, rec.Code, tt.wantStatus)
+			}
+			if tt.wantStatus == http.StatusMovedPermanently {
+				if loc := rec.Header().Get("Location"); loc != tt.wantBody {
+					t.Errorf("GET %s: redirected to %q, want %q", tt.path, loc, tt.wantBody)
+				}
+			} else if !strings.Contains(rec.Body.String(), tt.wantBody) {
+				t.Errorf("GET %s: body %q lacks %q", tt.path, rec.Body.String(), tt.wantBody)
+			}
+		})
+	}
+}
+
+// newTestConfig returns a config for a copy of the vault and org-roam
+// database in internal/render/testdata
+func newTestConfig(t *testing.T) *config.Config {
+	t.Helper()
+	dir := t.TempDir()
+	roamDir := filepath.Join(dir, "roam")
+	if err := os.CopyFS(roamDir, os.DirFS("internal/render/testdata/vault")); err != nil {
+		t.Fatal(err)
+	}
+	schema, err := os.ReadFile("internal/render/testdata/roam.sql")
+	if err != nil {
+		t.Fatal(err)
+	}
+	dbPath := filepath.Join(dir, "roam.db")
+	database, err := sql.Open("sqlite3", dbPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer database.Close()
+	if _, err := database.Exec(string(schema)); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := config.DefaultConfig()
+	cfg.Paths.RoamDir = roamDir
+	cfg.Paths.DBPath = dbPath
+	cfg.Paths.OutputDir = filepath.Join(dir, "dist")
+	return cfg
+}
+
+func TestRebuildChangedFiles(t *testing.T) {
+	tests := []struct {
+		name        string
+		breakDB     bool
+		wantChanged []string // Still pending after the rebuild
+	}{
+		{"success", false, nil},
+		{"failure", true, []string{"alpha.org", "beta.org"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := newTestConfig(t)
+			state := &buildState{changed: make(map[string]bool), reload: newReloadHub()}
+			rebuild(cfg, state, true)
+			if err := state.buildError(); err != nil {
+				t.Fatalf("initial build: %v", err)
+			}
+
+			if tt.breakDB {
+				if err := os.Remove(cfg.Paths.DBPath); err != nil {
+					t.Fatal(err)
+				}
+			}
+			state.markChanged("alpha.org")
+			state.markChanged("beta.org")
+			rebuild(cfg, state, false)
+			if failed := state.buildError() != nil; failed != tt.breakDB {
+				t.Fatalf("build failed = %t, want %t", failed, tt.breakDB)
+			}
+
+			changed := state.takeChanged()
+			slices.Sort(changed)
+			if !slices.Equal(changed, tt.wantChanged) {
+				t.Errorf("changed after rebuild = %v, want %v", changed, tt.wantChanged)
+			}
+		})
+	}
+}
+
+func TestWatchTree(t *testing.T) {
+	root := t.TempDir()
+	for _, dir := range []string{"daily", "daily/2024", ".git/objects", "dist/notes", "img"} {
+		if err := os.MkdirAll(filepath.Join(root, dir), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	cfg := config.DefaultConfig()
+	cfg.Paths.RoamDir = root
+	cfg.Paths.OutputDir = filepath.Join(root, "dist")
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer watcher.Close()
+	watchTree(watcher, cfg, root)
+
+	var got []string
+	for _, path := range watcher.WatchList() {
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, filepath.ToSlash(rel))
+	}
+	slices.Sort(got)
+	if want := []string{".", "daily", "daily/2024", "img"}; !slices.Equal(got, want) {
+		t.Errorf("watched %v, want %v", got, want)
+	}
+}
+
+func TestBuildErrorPage(t *testing.T) {
+	cfg := newTestConfig(t)
+	state := &buildState{changed: make(map[string]bool), reload: newReloadHub()}
+	h := siteHandler(state, "", cfg.Paths.OutputDir)
+	moved := cfg.Paths.DBPath + ".moved"
+
+	steps := []struct {
+		name    string
+		prepare func() error
+		// Status of a page and of a non-page file, and text the page shows
+		wantPage, wantFile int
+		wantBody           string
+	}{
+		{"good build", func() error { return nil }, http.StatusOK, http.StatusOK, "<title>Alpha"},
+		{"failed build", func() error { return os.Rename(cfg.Paths.DBPath, moved) }, http.StatusInternalServerError, http.StatusOK, "database not found"},
+		{"fixed build", func() error { return os.Rename(moved, cfg.Paths.DBPath) }, http.StatusOK, http.StatusOK, "<title>Alpha"},
+	}
+	for _, step := range steps {
+		if err := step.prepare(); err != nil {
+			t.Fatal(err)
+		}
+		rebuild(cfg, state, true)
+
+		for _, path := range []string{"/notes/a1.html", "/", "/graph.json"} {
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+			want := step.wantPage
+			if path == "/graph.json" {
+				want = step.wantFile
+			}
+			if rec.Code != want {
+				t.Errorf("%s: GET %s: status %d, want %d", step.name, path, rec.Code, want)
+			}
+			if path == "/notes/a1.html" && !strings.Contains(rec.Body.String(), step.wantBody) {
+				t.Errorf("%s: GET %s lacks %q:\n%s", step.name, path, step.wantBody, rec.Body.String())
+			}
+		}
+	}
+}
+
+func TestHealthHandler(t *testing.T) {
+	tests := []struct {
+		method   string
+		wantBody string
+	}{
+		{http.MethodGet, "ok\n"},
+		{http.MethodHead, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.method, func(t *testing.T) {
+			srv := httptest.NewServer(healthHandler())
+			defer srv.Close()
+
+			req, err := http.NewRequest(tt.method, srv.URL+"/healthz", nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp, err := srv.Client().Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer resp.Body.Close()
+			body, err := io.ReadAll(resp.Body)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if resp.StatusCode != http.StatusOK {
+				t.Errorf("status %d, want %d", resp.StatusCode, http.StatusOK)
+			}
+			if string(body) != tt.wantBody {
+				t.Errorf("body %q, want %q", body, tt.wantBody)
+			}
+			if cc := resp.Header.Get("Cache-Control"); cc != "no-store" {
+				t.Errorf("Cache-Control %q, want no-store", cc)
+			}
+		})
+	}
+}
+
+// freeAddr returns a local address no server is listening on
+func freeAddr(t *testing.T) string {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	return l.Addr().String()
+}
+
+// waitGoroutines waits for the number of goroutines to drop to n, and
+// returns the number left
+func waitGoroutines(n int) int {
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		got := runtime.NumGoroutine()
+		if got <= n || time.Now().After(deadline) {
+			return got
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestServeUntilDone(t *testing.T) {
+	tests := []struct {
+		name       string
+		liveReload bool // Keep a live reload stream open while shutting down
+	}{
+		{"idle", false},
+		{"open live reload stream", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			before := runtime.NumGoroutine()
+
+			state := &buildState{changed: make(map[string]bool), reload: newReloadHub()}
+			mux := http.NewServeMux()
+			mux.Handle("/healthz", healthHandler())
+			mux.Handle("/", siteHandler(state, "", t.TempDir()))
+			addr := freeAddr(t)
+			srv := &http.Server{Addr: addr, Handler: mux}
+			srv.RegisterOnShutdown(state.reload.close)
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			done := make(chan error, 1)
+			go func() { done <- serveUntilDone(ctx, srv) }()
+
+			client := &http.Client{Transport: &http.Transport{DisableKeepAlives: true}}
+			var resp *http.Response
+			var err error
+			for range 100 {
+				if resp, err = client.Get("http://" + addr + "/healthz"); err == nil {
+					break
+				}
+				time.Sleep(10 * time.Millisecond)
+			}
+			if err != nil {
+				t.Fatalf("server not up: %v", err)
+			}
+			resp.Body.Close()
+
+			streamEnded := make(chan struct{})
+			if tt.liveReload {
+				stream, err := client.Get("http://" + addr + liveReloadPath)
+				if err != nil {
+					t.Fatal(err)
+				}
+				go func() {
+					defer close(streamEnded)
+					defer stream.Body.Close()
+					io.Copy(io.Discard, stream.Body)
+				}()
+			} else {
+				close(streamEnded)
+			}
+
+			start := time.Now()
+			cancel()
+			select {
+			case err := <-done:
+				if err != nil {
+					t.Fatalf("serveUntilDone: %v", err)
+				}
+			case <-time.After(shutdownTimeout):
+				t.Fatal("server did not shut down")
+			}
+			if elapsed := time.Since(start); elapsed >= shutdownTimeout {
+				t.Errorf("shutdown took %v, the whole timeout", elapsed)
+			}
+			select {
+			case <-streamEnded:
+			case <-time.After(time.Second):
+				t.Fatal("live reload stream still open after shutdown")
+			}
+
+			if _, err := client.Get("http://" + addr + "/healthz"); err == nil {
+				t.Error("server still answering after shutdown")
+			}
+			client.CloseIdleConnections()
+			if after := waitGoroutines(before); after > before {
+				t.Errorf("%d goroutines after shutdown, %d before", after, before)
+			}
+		})
+	}
+}
+
+func TestServeUntilDoneListenError(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	srv := &http.Server{Addr: l.Addr().String(), Handler: healthHandler()}
+	if err := serveUntilDone(context.Background(), srv); err == nil {
+		t.Error("serveUntilDone on a busy address returned nil")
+	}
+}
+
+func TestIsDBFile(t *testing.T) {
+	dbPath := filepath.Join("/data", "roam.db")
+	tests := []struct {
+		path string
+		want bool
+	}{
+		{"/data/roam.db", true},
+		{"/data/roam.db-wal", true},
+		{"/data/roam.db-journal", true},
+		{"/data/roam.db-shm", false},
+		{"/data/other.db", false},
+		{"/data/roam.db.bak", false},
+		{"/elsewhere/roam.db", false},
+		{"/elsewhere/roam.db-wal", false},
+	}
+	for _, tt := range tests {
+		if got := isDBFile(filepath.FromSlash(tt.path), dbPath); got != tt.want {
+			t.Errorf("isDBFile(%q) = %v, want %v", tt.path, got, tt.want)
+		}
+	}
+}
+
+// watchedRebuild is a rebuild started by a changeWatcher
+type watchedRebuild struct {
+	title string // Site title of the config it was given
+	full  bool
+}
+
+// testChangeWatcher is a changeWatcher over a roam directory, a database
+// directory and a config directory in a temporary directory, recording
+// rebuilds instead of building
+type testChangeWatcher struct {
+	*changeWatcher
+	cfg      *config.Config
+	rebuilds chan watchedRebuild
+	done     chan struct{}
+}
+
+func newTestChangeWatcher(t *testing.T) *testChangeWatcher {
+	t.Helper()
+	dir := t.TempDir()
+	for _, sub := range []string{"roam", "db", "conf"} {
+		if err := os.Mkdir(filepath.Join(dir, sub), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	cfg := config.DefaultConfig()
+	cfg.Site.Title = "Initial"
+	cfg.Paths.RoamDir = filepath.Join(dir, "roam")
+	cfg.Paths.DBPath = filepath.Join(dir, "db", "roam.db")
+	cfg.Paths.OutputDir = filepath.Join(dir, "roam", "dist")
+	configFile := filepath.Join(dir, "conf", "config.yaml")
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		t.Fatal(err)
+	}
+	watchPaths(watcher, cfg, configFile)
+
+	w := &testChangeWatcher{cfg: cfg, rebuilds: make(chan watchedRebuild, 10), done: make(chan struct{})}
+	w.changeWatcher = &changeWatcher{
+		watcher:    watcher,
+		configFile: configFile,
+		// The config file holds just the site title, or "bad"
+		loadConfig: func() (*config.Config, error) {
+			data, err := os.ReadFile(configFile)
+			if err != nil {
+				return nil, err
+			}
+			if string(data) == "bad" {
+				return nil, errors.New("bad config")
+			}
+			newCfg := *cfg
+			newCfg.Site.Title = string(data)
+			return &newCfg, nil
+		},
+		state:    &buildState{changed: make(map[string]bool), reload: newReloadHub()},
+		debounce: 20 * time.Millisecond,
+		rebuild: func(cfg *config.Config, full bool) {
+			w.rebuilds <- watchedRebuild{cfg.Site.Title, full}
+		},
+	}
+	go func() {
+		defer close(w.done)
+		w.run(cfg)
+	}()
+	t.Cleanup(func() {
+		watcher.Close()
+		<-w.done
+	})
+	return w
+}
+
+// write writes a file below the temporary directory
+func (w *testChangeWatcher) write(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// nextRebuild returns the next rebuild, or false if none starts in time
+func (w *testChangeWatcher) nextRebuild(wait time.Duration) (watchedRebuild, bool) {
+	select {
+	case r := <-w.rebuilds:
+		return r, true
+	case <-time.After(wait):
+		return watchedRebuild{}, false
+	}
+}
+
+func TestChangeWatcherRebuilds(t *testing.T) {
+	tests := []struct {
+		name        string
+		change      func(t *testing.T, w *testChangeWatcher)
+		wantRebuild bool
+		want        watchedRebuild
+		wantChanged bool // The note is marked changed
+		wantError   bool // The build state holds an error
+	}{
+		{
+			name: "config replaced by rename",
+			change: func(t *testing.T, w *testChangeWatcher) {
+				tmp := w.configFile + ".tmp"
+				w.write(t, tmp, "Renamed")
+				if err := os.Rename(tmp, w.configFile); err != nil {
+					t.Fatal(err)
+				}
+			},
+			wantRebuild: true,
+			want:        watchedRebuild{"Renamed", true},
+		},
+		{
+			name:        "config created",
+			change:      func(t *testing.T, w *testChangeWatcher) { w.write(t, w.configFile, "Created") },
+			wantRebuild: true,
+			want:        watchedRebuild{"Created", true},
+		},
+		{
+			name:      "broken config",
+			change:    func(t *testing.T, w *testChangeWatcher) { w.write(t, w.configFile, "bad") },
+			wantError: true,
+		},
+		{
+			name:        "database",
+			change:      func(t *testing.T, w *testChangeWatcher) { w.write(t, w.cfg.Paths.DBPath, "db") },
+			wantRebuild: true,
+			want:        watchedRebuild{"Initial", true},
+		},
+		{
+			name:        "database WAL",
+			change:      func(t *testing.T, w *testChangeWatcher) { w.write(t, w.cfg.Paths.DBPath+"-wal", "wal") },
+			wantRebuild: true,
+			want:        watchedRebuild{"Initial", true},
+		},
+		{
+			name:   "database shared memory",
+			change: func(t *testing.T, w *testChangeWatcher) { w.write(t, w.cfg.Paths.DBPath+"-shm", "shm") },
+		},
+		{
+			name: "other database",
+			change: func(t *testing.T, w *testChangeWatcher) {
+				w.write(t, filepath.Join(filepath.Dir(w.cfg.Paths.DBPath), "other.db"), "db")
+			},
+		},
+		{
+			name: "same name in the roam directory",
+			change: func(t *testing.T, w *testChangeWatcher) {
+				w.write(t, filepath.Join(w.cfg.Paths.RoamDir, "roam.db"), "db")
+			},
+		},
+		{
+			name: "note",
+			change: func(t *testing.T, w *testChangeWatcher) {
+				w.write(t, filepath.Join(w.cfg.Paths.RoamDir, "note.org"), "* Note")
+			},
+			wantRebuild: true,
+			want:        watchedRebuild{"Initial", false},
+			wantChanged: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := newTestChangeWatcher(t)
+			tt.change(t, w)
+
+			wait := 2 * time.Second
+			if !tt.wantRebuild {
+				wait = 10 * w.debounce
+			}
+			got, rebuilt := w.nextRebuild(wait)
+			if rebuilt != tt.wantRebuild {
+				t.Fatalf("rebuilt = %v, want %v", rebuilt, tt.wantRebuild)
+			}
+			if got != tt.want {
+				t.Errorf("rebuild %+v, want %+v", got, tt.want)
+			}
+			if extra, ok := w.nextRebuild(5 * w.debounce); ok {
+				t.Errorf("second rebuild %+v", extra)
+			}
+
+			note := filepath.Join(w.cfg.Paths.RoamDir, "note.org")
+			if changed := slices.Contains(w.state.takeChanged(), note); changed != tt.wantChanged {
+				t.Errorf("note marked changed: %v, want %v", changed, tt.wantChanged)
+			}
+			if failed := w.state.buildError() != nil; failed != tt.wantError {
+				t.Errorf("build error: %v, want %v", w.state.buildError(), tt.wantError)
+			}
+		})
+	}
+}
+
+func TestChangeWatcherNewDirs(t *testing.T) {
+	tests := []struct {
+		name        string
+		dir         string // Created below the roam directory
+		wantWatched []string
+	}{
+		{"folder", "daily", []string{"daily"}},
+		{"nested folders", "projects/web", []string{"projects", "projects/web"}},
+		{"git directory", ".git/objects", nil},
+		{"output directory", "dist/notes", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := newTestChangeWatcher(t)
+			root := w.cfg.Paths.RoamDir
+			// Created after the watcher started
+			if err := os.MkdirAll(filepath.Join(root, tt.dir), 0755); err != nil {
+				t.Fatal(err)
+			}
+
+			watched := func() []string {
+				var dirs []string
+				for _, path := range w.watcher.WatchList() {
+					if rel, err := filepath.Rel(root, path); err == nil && rel != "." && !strings.HasPrefix(rel, "..") {
+						dirs = append(dirs, filepath.ToSlash(rel))
+					}
+				}
+				slices.Sort(dirs)
+				return dirs
+			}
+			deadline := time.Now().Add(2 * time.Second)
+			for len(tt.wantWatched) > 0 && !slices.Equal(watched(), tt.wantWatched) && time.Now().Before(deadline) {
+				time.Sleep(5 * time.Millisecond)
+			}
+			if len(tt.wantWatched) == 0 {
+				time.Sleep(10 * w.debounce)
+			}
+			if got := watched(); !slices.Equal(got, tt.wantWatched) {
+				t.Fatalf("watching %v, want %v", got, tt.wantWatched)
+			}
+
+			// A note saved in the new folder is seen, unless it isn't watched
+			note := filepath.Join(root, tt.dir, "note.org")
+			w.write(t, note, "* Note")
+			_, rebuilt := w.nextRebuild(10 * w.debounce)
+			if want := len(tt.wantWatched) > 0; rebuilt != want {
+				t.Errorf("rebuilt after a note in %s changed: %v, want %v", tt.dir, rebuilt, want)
+			}
+		})
+	}
+}